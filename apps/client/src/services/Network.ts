@@ -277,8 +277,7 @@ export default class Network {
             this.mySessionId = sessionId;
           }
           const users: Array<{
-            id?: string;
-            userId?: string;
+            userId: string;
             x: number;
             y: number;
             avatarName?: string;
@@ -286,12 +285,11 @@ export default class Network {
           }> = data.payload?.users ?? [];
 
           users.forEach((u) => {
-            const uid = u.id || u.userId;
+            const uid = u.userId;
             if (!uid) return;
             if (!this.knownUsers.has(uid)) {
               this.knownUsers.add(uid);
-              const x = u.x ?? 0;
-              const y = u.y ?? 0;
+              const { x, y } = u;
               this.userSnapshots.set(uid, { x, y, name: u.name || "" });
               const avatar = (u.avatarName || "ron").toLowerCase();
               const player: IPlayer = {
@@ -9,30 +9,96 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// ErrSecretNotConfigured is returned when the server has no JWT secret to validate against
+var ErrSecretNotConfigured = errors.New("JWT secret not configured")
+
+// ErrUnknownKeyID is returned when a keyset is configured and the token's kid isn't in it
+var ErrUnknownKeyID = errors.New("unknown signing key ID")
+
+// ErrUnexpectedSigningMethod is returned for tokens signed with a method no key is configured for
+var ErrUnexpectedSigningMethod = errors.New("unexpected signing method")
+
 // Claims represents the JWT token claims
 type Claims struct {
 	UserID string `json:"userId"`
 	Role   string `json:"role"`
+	// Spaces, if set, restricts the token to these space IDs
+	Spaces []string `json:"spaces,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// AllowsSpace reports whether the token may join spaceID. Tokens without a
+// spaces claim aren't restricted.
+func (c *Claims) AllowsSpace(spaceID string) bool {
+	if len(c.Spaces) == 0 {
+		return true
+	}
+	for _, id := range c.Spaces {
+		if id == spaceID {
+			return true
+		}
+	}
+	return false
+}
+
 // ValidateToken parses and validates a JWT token
 func ValidateToken(tokenString string) (*Claims, error) {
 	// Remove "Bearer " prefix if present
 	tokenString = strings.TrimPrefix(tokenString, "Bearer ")
 	tokenString = strings.TrimSpace(tokenString)
 
-	if config.AppConfig.JWTSecret == "" {
-		return nil, errors.New("JWT secret not configured")
+	keys := config.Current().JWTKeys
+	secrets := config.Current().HMACSecrets()
+	publicKey := config.Current().JWTPublicKey
+	hmacConfigured := len(secrets) > 0 || len(keys) > 0
+	if !hmacConfigured && publicKey == nil {
+		return nil, ErrSecretNotConfigured
+	}
+
+	parse := func(secret string) (*jwt.Token, error) {
+		return jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+			// Only accept the method a key is configured for, so an HMAC token can't be
+			// verified with the public key (or an RSA one with the secret)
+			switch token.Method.(type) {
+			case *jwt.SigningMethodRSA:
+				if publicKey == nil {
+					return nil, ErrUnexpectedSigningMethod
+				}
+				return publicKey, nil
+			case *jwt.SigningMethodHMAC:
+				if !hmacConfigured {
+					return nil, ErrUnexpectedSigningMethod
+				}
+			default:
+				return nil, ErrUnexpectedSigningMethod
+			}
+			// With a keyset the token must name one of its keys; the secrets are not a fallback
+			if len(keys) > 0 {
+				kid, _ := token.Header["kid"].(string)
+				secret, ok := keys[kid]
+				if !ok {
+					return nil, ErrUnknownKeyID
+				}
+				return []byte(secret), nil
+			}
+			return []byte(secret), nil
+		})
 	}
 
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		// Validate signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
+	// Without a keyset, try the primary secret first: older ones only matter during
+	// rotation. Only a bad signature moves on; an expired token is expired under any secret.
+	candidates := secrets
+	if len(keys) > 0 || len(candidates) == 0 {
+		candidates = []string{""}
+	}
+	var token *jwt.Token
+	var err error
+	for _, secret := range candidates {
+		token, err = parse(secret)
+		if !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+			break
 		}
-		return []byte(config.AppConfig.JWTSecret), nil
-	})
+	}
 
 	if err != nil {
 		return nil, err
@@ -1,6 +1,11 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
 	"testing"
 	"time"
 
@@ -12,9 +17,9 @@ import (
 func TestValidateToken(t *testing.T) {
 	// Setup config
 	secret := "test-secret"
-	config.AppConfig = &config.Config{
+	config.Set(&config.Config{
 		JWTSecret: secret,
-	}
+	})
 
 	// Helper to generate token
 	generateToken := func(userID, role string, expiration time.Time, signingKey string) string {
@@ -86,3 +91,155 @@ func TestValidateToken(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateTokenSecretNotConfigured(t *testing.T) {
+	config.Set(&config.Config{JWTSecret: ""})
+
+	_, err := ValidateToken("any.token.value")
+	if !errors.Is(err, ErrSecretNotConfigured) {
+		t.Errorf("ValidateToken() error = %v, want ErrSecretNotConfigured", err)
+	}
+}
+
+func TestValidateTokenKeyset(t *testing.T) {
+	config.Set(&config.Config{
+		JWTSecret: "legacy-secret",
+		JWTKeys:   map[string]string{"2024-01": "old-secret", "2024-06": "new-secret"},
+	})
+
+	sign := func(kid, secret string) string {
+		claims := &Claims{
+			UserID: "user1",
+			RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		ss, _ := token.SignedString([]byte(secret))
+		return ss
+	}
+
+	// Both keys in the set are accepted during rotation
+	for _, tc := range [][2]string{{"2024-01", "old-secret"}, {"2024-06", "new-secret"}} {
+		claims, err := ValidateToken(sign(tc[0], tc[1]))
+		if err != nil {
+			t.Errorf("kid %s: ValidateToken() error = %v", tc[0], err)
+		} else if claims.UserID != "user1" {
+			t.Errorf("kid %s: UserID = %v, want user1", tc[0], claims.UserID)
+		}
+	}
+
+	// A kid must select its own key
+	if _, err := ValidateToken(sign("2024-06", "old-secret")); err == nil {
+		t.Error("token signed with another kid's secret was accepted")
+	}
+
+	// Unknown or missing kids are rejected, even when signed with the legacy secret
+	if _, err := ValidateToken(sign("2023-01", "old-secret")); !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("unknown kid: error = %v, want ErrUnknownKeyID", err)
+	}
+	if _, err := ValidateToken(sign("", "legacy-secret")); !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("missing kid: error = %v, want ErrUnknownKeyID", err)
+	}
+}
+
+func TestValidateTokenRS256(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Set(&config.Config{JWTPublicKey: &privateKey.PublicKey})
+
+	claims := &Claims{
+		UserID: "user1",
+		Role:   "user",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ValidateToken(signed)
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	if got.UserID != "user1" {
+		t.Errorf("UserID = %v, want user1", got.UserID)
+	}
+
+	// Another key's signature doesn't verify
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, _ := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(otherKey)
+	if _, err := ValidateToken(forged); err == nil {
+		t.Error("token signed with another RSA key was accepted")
+	}
+
+	// Algorithm confusion: an HS256 token keyed with the public key PEM must not pass
+	der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	confused, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(publicPEM)
+	if _, err := ValidateToken(confused); !errors.Is(err, ErrUnexpectedSigningMethod) {
+		t.Errorf("HS256 token with only RSA configured: error = %v, want ErrUnexpectedSigningMethod", err)
+	}
+
+	// And with only a secret configured, RSA tokens are refused
+	config.Set(&config.Config{JWTSecret: "test-secret"})
+	if _, err := ValidateToken(signed); !errors.Is(err, ErrUnexpectedSigningMethod) {
+		t.Errorf("RS256 token with only a secret configured: error = %v, want ErrUnexpectedSigningMethod", err)
+	}
+}
+
+func TestValidateTokenSecretRotation(t *testing.T) {
+	config.Set(&config.Config{
+		JWTSecret:  "primary",
+		JWTSecrets: []string{"previous", "primary", "oldest"},
+	})
+
+	sign := func(secret string, expiresIn time.Duration) string {
+		claims := &Claims{
+			UserID: "user1",
+			RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			},
+		}
+		ss, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+		return ss
+	}
+
+	for _, secret := range []string{"primary", "previous", "oldest"} {
+		if _, err := ValidateToken(sign(secret, time.Hour)); err != nil {
+			t.Errorf("token signed with %q: error = %v", secret, err)
+		}
+	}
+
+	// A secret dropped from the list no longer validates
+	next := *config.Current()
+	next.JWTSecrets = []string{"previous"}
+	config.Set(&next)
+	if _, err := ValidateToken(sign("oldest", time.Hour)); !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+		t.Errorf("removed secret: error = %v, want ErrTokenSignatureInvalid", err)
+	}
+
+	// An expired token is reported as expired, not as a bad signature
+	if _, err := ValidateToken(sign("previous", -time.Hour)); !errors.Is(err, jwt.ErrTokenExpired) {
+		t.Errorf("expired token: error = %v, want ErrTokenExpired", err)
+	}
+
+	// JWT_SECRETS alone is enough
+	config.Set(&config.Config{JWTSecrets: []string{"only"}})
+	if _, err := ValidateToken(sign("only", time.Hour)); err != nil {
+		t.Errorf("JWT_SECRETS without JWT_SECRET: error = %v", err)
+	}
+}
@@ -1,9 +1,18 @@
 package config
 
 import (
+	"crypto/rsa"
+	"fmt"
+	"log"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/joho/godotenv"
 )
 
@@ -11,39 +20,441 @@ import (
 type Config struct {
 	Port              string
 	JWTSecret         string
+	// JWTSecrets are further secrets accepted during rotation, tried after JWTSecret
+	JWTSecrets        []string
+	// JWTKeys maps token kid headers to signing secrets for key rotation.
+	// When set, tokens must carry a known kid and JWTSecret is ignored.
+	JWTKeys           map[string]string
+	// JWTPublicKey verifies RS256 tokens signed by the auth service (nil accepts none)
+	JWTPublicKey      *rsa.PublicKey
 	DBUrl             string
 	ServerURL         string
 	WorldServerSecret string
 	AudioRadius       float64
 	VideoRadius       float64
+	// ScreenRadius is how close a user must be to a presenter to receive their screen share
+	ScreenRadius      float64
+	// WhisperRadius is a tight circle inside audio range for private-feeling conversation
+	WhisperRadius     float64
+	// ViewRadius limits movement broadcasts to users this close to the mover (0 sends
+	// them to the whole space). It should be larger than AudioRadius.
+	ViewRadius        float64
+
+	// ProximityBridgeURL receives proximity changes for the media backend, encoded as
+	// ProximityBridgeFormat ("trpc", "plain" or "off"). Empty disables the bridge.
+	ProximityBridgeURL    string
+	ProximityBridgeFormat string
+
+	// MoveSpeed is the avatar walking speed in units/second, used to time movement intents
+	MoveSpeed float64
+
+	// MeetingJitter is the maximum per-pair offset added to the video dwell and meeting cooldown
+	MeetingJitter time.Duration
+
+	// A pair gets at most MeetingPromptLimit auto-prompts per MeetingPromptWindow (0 disables)
+	MeetingPromptLimit  int
+	MeetingPromptWindow time.Duration
+
+	// JoinCooldown is the minimum time between joins by the same user (0 disables)
+	JoinCooldown time.Duration
+
+	// MaxUsersPerSpace rejects joins to a space that already has this many users (0 = unlimited)
+	MaxUsersPerSpace int
+
+	// CoordinatePrecision is the grid incoming coordinates are rounded to (0 disables)
+	CoordinatePrecision float64
+
+	// AvatarWidth and AvatarHeight are the avatar collision box, in client units,
+	// anchored at the avatar's position
+	AvatarWidth  float64
+	AvatarHeight float64
+
+	// ProximityEventsPerTick caps proximity events emitted per space per tick (0 = unlimited).
+	// Leave events beyond the cap are deferred to later ticks.
+	ProximityEventsPerTick int
+
+	// ProximityLeaveMargin widens the radius a pair must exceed to leave proximity, as a
+	// fraction of the radius (0.15 = leave at 115%), so users hovering at the edge don't flap
+	ProximityLeaveMargin float64
+
+	// AudioDwell is how long users must stay in audio range before "enter" fires (0 = immediately)
+	AudioDwell time.Duration
+
+	// ExtraMedia are additional proximity channels beyond audio and video
+	ExtraMedia []ProximityMedia
+
+	// MoveTickInterval is the reconciliation window for movement.
+	// MoveTickBudget is the total distance a client may cover within one window (0 disables).
+	MoveTickInterval time.Duration
+	MoveTickBudget   float64
+
+	// MaxMoveSpeed caps sustained movement in units per second; moves that would exceed
+	// it are rejected (0 disables)
+	MaxMoveSpeed float64
+
+	// MovementRateHz caps movement messages per second per client; extra moves are dropped (0 disables)
+	MovementRateHz float64
+
+	// CollisionCooldown drops repeats of a move just rejected for a collision (0 disables)
+	CollisionCooldown time.Duration
+
+	// MaxLatencyCompensation caps the client-reported RTT used to widen the movement budget
+	MaxLatencyCompensation time.Duration
+
+	// ReconnectGrace keeps a dropped user in their space this long so a reconnect can
+	// resume the session without peers seeing them leave (0 disables)
+	ReconnectGrace time.Duration
+
+	// AppIdleTimeout disconnects joined clients that send no application message for this long,
+	// even if they still answer pings (0 disables)
+	AppIdleTimeout time.Duration
+
+	// MaxMessageBytes is the largest message a client may send; bigger ones get a
+	// message-too-large and the connection is closed. There is always a limit.
+	MaxMessageBytes int
+
+	// JoinTimeout disconnects connections that haven't joined a space this long after
+	// connecting (0 disables)
+	JoinTimeout time.Duration
+
+	// AwayTimeout marks joined users idle to their space once they've sent no application
+	// message for this long; they're active again on their next message (0 disables)
+	AwayTimeout time.Duration
+
+	// WSCompression offers permessage-deflate and compresses writes on connections that
+	// negotiate it; off saves the CPU at the cost of bandwidth
+	WSCompression bool
+
+	// MapDir holds per-space collision maps named <spaceID>.json ("" disables them)
+	MapDir string
+
+	// SpawnSeed seeds the random spawn offsets so placements can be reproduced (0 seeds
+	// from the clock)
+	SpawnSeed int64
+
+	// SnapshotDir holds space layout snapshots, restored at startup ("" disables them);
+	// they're saved every SnapshotInterval (0 only saves on shutdown)
+	SnapshotDir      string
+	SnapshotInterval time.Duration
+
+	// SessionExpiryWarning is how long before a client's token expires it is sent
+	// session-expiring, so it can refresh in time (0 disables the warning)
+	SessionExpiryWarning time.Duration
+
+	// A client is disconnected after more than ProtocolViolationLimit malformed or
+	// invalid messages within ProtocolViolationWindow (0 disables).
+	ProtocolViolationLimit  int
+	ProtocolViolationWindow time.Duration
+
+	// RolePermissions overrides which roles may send a message type (see hub.Can)
+	RolePermissions map[string][]string
+
+	// SpaceCoords overrides the coordinate system for specific spaces (keyed by space ID)
+	SpaceCoords map[string]SpaceCoordinates
+
+	// SpaceProximityStrategies overrides how specific spaces update proximity (keyed by space ID)
+	SpaceProximityStrategies map[string]string
+
+	// ChatMaxRunes truncates space chat messages to this many characters (0 = unlimited)
+	ChatMaxRunes int
+
+	// CustomEventSubtypes lists the subtypes admins may send via custom-broadcast
+	CustomEventSubtypes []string
+
+	// ReportReasons lists the reasons users may give when reporting another user.
+	// A user may file at most ReportLimit reports per ReportWindow (0 disables).
+	ReportReasons []string
+	ReportLimit   int
+	ReportWindow  time.Duration
+
+	// AllowedOrigins lists the browser origins allowed to open a WebSocket. Entries are
+	// exact origins or wildcard subdomains ("*.example.com", "https://*.example.com").
+	AllowedOrigins []string
+}
+
+// DefaultAllowedOrigins are the frontends allowed when ALLOWED_ORIGINS is unset
+var DefaultAllowedOrigins = []string{
+	"http://localhost:3001",
+	"https://raashed.cloud",
+	"https://game.raashed.cloud",
+	"https://k8s-game.raashed.cloud",
+	"https://metaverse.raashed.cloud",
+	"https://k8s-metaverse.raashed.cloud",
+}
+
+// HMACSecrets returns the secrets HS256 tokens may be signed with: JWTSecret first,
+// as the primary, then JWTSecrets in order, without duplicates
+func (c *Config) HMACSecrets() []string {
+	secrets := make([]string, 0, 1+len(c.JWTSecrets))
+	seen := make(map[string]bool)
+	for _, secret := range append([]string{c.JWTSecret}, c.JWTSecrets...) {
+		if secret != "" && !seen[secret] {
+			seen[secret] = true
+			secrets = append(secrets, secret)
+		}
+	}
+	return secrets
+}
+
+// OriginAllowed reports whether a WebSocket upgrade from origin is allowed. An empty
+// origin (same-origin or native clients) always is.
+func (c *Config) OriginAllowed(origin string) bool {
+	if origin == "" {
+		return true
+	}
+	for _, pattern := range c.AllowedOrigins {
+		if pattern == origin || matchWildcardOrigin(pattern, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchWildcardOrigin matches origin against a "*.domain" pattern, optionally prefixed
+// with a scheme. The wildcard covers one or more subdomain labels but not the bare domain.
+func matchWildcardOrigin(pattern, origin string) bool {
+	scheme, host, hasScheme := strings.Cut(pattern, "://")
+	if !hasScheme {
+		host = pattern
+	}
+	if !strings.HasPrefix(host, "*.") {
+		return false
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if hasScheme && u.Scheme != scheme {
+		return false
+	}
+	return strings.HasSuffix(u.Hostname(), host[1:])
+}
+
+// SpaceCoordinates maps a space's internal grid onto the client's coordinate system:
+// client = Origin + internal * Scale
+type SpaceCoordinates struct {
+	OriginX float64
+	OriginY float64
+	Scale   float64
+}
+
+// DefaultSpaceCoordinates is a top-left origin at unit scale
+var DefaultSpaceCoordinates = SpaceCoordinates{Scale: 1}
+
+// Coordinates returns the coordinate system for spaceID
+func (c *Config) Coordinates(spaceID string) SpaceCoordinates {
+	if coords, ok := c.SpaceCoords[spaceID]; ok {
+		return coords
+	}
+	return DefaultSpaceCoordinates
+}
+
+// Proximity update strategies
+const (
+	// ProximityEvented recomputes a mover's proximity on every move
+	ProximityEvented = "event"
+	// ProximityPolled recomputes everyone who moved since the last tick in one batch
+	ProximityPolled = "polled"
+)
+
+// ProximityStrategy returns the proximity update strategy for spaceID
+func (c *Config) ProximityStrategy(spaceID string) string {
+	if strategy, ok := c.SpaceProximityStrategies[spaceID]; ok {
+		return strategy
+	}
+	return ProximityEvented
+}
+
+// Default proximity radii
+const (
+	DefaultAudioRadius = 300
+	DefaultVideoRadius = 120
+	// DefaultScreenRadius is wider than video so an audience can stand back from a presenter
+	DefaultScreenRadius = 180
+	// DefaultWhisperRadius is half of video: close enough to lean in
+	DefaultWhisperRadius = 60
+)
+
+// DefaultMovementRateHz sits above the 60fps the client sends moves at while walking, so
+// only floods are dropped
+const DefaultMovementRateHz = 120
+
+// DefaultMaxMessageBytes is the message size limit when MAX_MESSAGE_BYTES is unset or invalid,
+// large enough for WebRTC signaling whose SDP offers run to several KB
+const DefaultMaxMessageBytes = 64 << 10
+
+// Built-in proximity media
+const (
+	MediaAudio   = "audio"
+	MediaVideo   = "video"
+	MediaScreen  = "screen"
+	MediaWhisper = "whisper"
+)
+
+// ProximityMedia describes one proximity channel and how it behaves
+type ProximityMedia struct {
+	Name   string
+	Radius float64
+	// Dwell is how long a pair must stay in range before "enter" fires (0 = immediately)
+	Dwell time.Duration
+	// Meeting marks the channel whose dwell drives meeting prompts instead of enter events
+	Meeting bool
+}
+
+// ProximityMedia returns every configured proximity channel: audio, video, screen and
+// whisper first, followed by any extra media from PROXIMITY_MEDIA.
+func (c *Config) ProximityMedia() []ProximityMedia {
+	media := []ProximityMedia{
+		{Name: MediaAudio, Radius: c.AudioRadius, Dwell: c.AudioDwell},
+		{Name: MediaVideo, Radius: c.VideoRadius, Meeting: true},
+		{Name: MediaScreen, Radius: c.ScreenRadius},
+		{Name: MediaWhisper, Radius: c.WhisperRadius},
+	}
+	return append(media, c.ExtraMedia...)
+}
+
+// Media looks up a proximity channel by name
+func (c *Config) Media(name string) (ProximityMedia, bool) {
+	for _, m := range c.ProximityMedia() {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return ProximityMedia{Name: name}, false
+}
+
+// current holds the active config. Reloads swap in a new copy, so readers go through
+// Current and never see a half-written Config.
+var current atomic.Pointer[Config]
+
+// Current returns the active configuration
+func Current() *Config {
+	return current.Load()
 }
 
-// Global config instance
-var AppConfig *Config
+// Set installs c as the active configuration
+func Set(c *Config) {
+	current.Store(c)
+}
+
+// envPath points at the shared .env in packages/config (relative to apps/world)
+var envPath = filepath.Join("..", "..", "packages", "config", ".env")
 
 // Load initializes configuration from environment variables
 func Load() error {
-	// Try to load .env from packages/config (relative to apps/world)
-	envPath := filepath.Join("..", "..", "packages", "config", ".env")
 	if err := godotenv.Load(envPath); err != nil {
 		// Fallback: try loading from current directory
 		_ = godotenv.Load()
 	}
 
-	AppConfig = &Config{
+	publicKey, err := getEnvPublicKey("JWT_PUBLIC_KEY")
+	if err != nil {
+		return err
+	}
+
+	serverURL := getEnv("BACKEND_URL", "http://localhost:8082")
+	cfg := &Config{
 		Port:              getEnv("WS_PORT", "8083"),
 		JWTSecret:         getEnv("JWT_SECRET", ""),
+		JWTSecrets:        getEnvList("JWT_SECRETS", nil),
+		JWTKeys:           getEnvKeyset("JWT_KEYS"),
+		JWTPublicKey:      publicKey,
 		DBUrl:             getEnv("DATABASE_URL", ""),
-		ServerURL:         getEnv("BACKEND_URL", "http://localhost:8082"),
+		ServerURL:         serverURL,
 		WorldServerSecret: getEnv("WORLD_SERVER_SECRET", ""),
-		// Hard-coded proximity radii to keep behavior deterministic.
-		AudioRadius: 300,
-		VideoRadius: 120,
+		AudioRadius:       getEnvRadius("AUDIO_RADIUS", DefaultAudioRadius),
+		VideoRadius:       getEnvRadius("VIDEO_RADIUS", DefaultVideoRadius),
+		ScreenRadius:      getEnvRadius("SCREEN_RADIUS", DefaultScreenRadius),
+		WhisperRadius:     getEnvRadius("WHISPER_RADIUS", DefaultWhisperRadius),
+		ViewRadius:        getEnvFloat("VIEW_RADIUS", 0),
+		AudioDwell:        getEnvDuration("AUDIO_DWELL_MS", 0),
+		ExtraMedia:        getEnvMedia("PROXIMITY_MEDIA"),
+
+		ProximityBridgeURL:    getEnv("PROXIMITY_BRIDGE_URL", ""),
+		ProximityBridgeFormat: getEnv("PROXIMITY_BRIDGE_FORMAT", "trpc"),
+
+		ProximityLeaveMargin:   getEnvFloat("PROXIMITY_LEAVE_MARGIN", 0.15),
+		ProximityEventsPerTick: getEnvInt("PROXIMITY_EVENTS_PER_TICK", 0),
+		CoordinatePrecision:    getEnvFloat("COORD_PRECISION", 1),
+		AvatarWidth:            getEnvFloat("AVATAR_WIDTH", 1),
+		AvatarHeight:           getEnvFloat("AVATAR_HEIGHT", 1),
+		JoinCooldown:           getEnvDuration("JOIN_COOLDOWN_MS", time.Second),
+		MaxUsersPerSpace:       getEnvInt("MAX_USERS_PER_SPACE", 0),
+		MoveSpeed:              getEnvFloat("MOVE_SPEED", 200),
+		MeetingJitter:          getEnvDuration("MEETING_JITTER_MS", time.Second),
+		MeetingPromptLimit:     getEnvInt("MEETING_PROMPT_LIMIT", 3),
+		MeetingPromptWindow:    getEnvDuration("MEETING_PROMPT_WINDOW_MS", 10*time.Minute),
+
+		MoveTickInterval: getEnvDuration("MOVE_TICK_MS", 100*time.Millisecond),
+		MoveTickBudget:   getEnvFloat("MOVE_TICK_BUDGET", 40),
+		MovementRateHz:   getEnvFloat("MOVEMENT_RATE_HZ", DefaultMovementRateHz),
+		MaxMoveSpeed:     getEnvFloat("MAX_MOVE_SPEED", 300),
+
+		MaxLatencyCompensation: getEnvDuration("MAX_LATENCY_COMPENSATION_MS", 300*time.Millisecond),
+		CollisionCooldown:      getEnvDuration("COLLISION_COOLDOWN_MS", 250*time.Millisecond),
+
+		ReconnectGrace:          getEnvDuration("RECONNECT_GRACE_MS", 10*time.Second),
+		AppIdleTimeout:          getEnvDuration("APP_IDLE_TIMEOUT_MS", 0),
+		MaxMessageBytes:         getEnvMessageLimit("MAX_MESSAGE_BYTES", DefaultMaxMessageBytes),
+		JoinTimeout:             getEnvDuration("JOIN_TIMEOUT_MS", 15*time.Second),
+		AwayTimeout:             getEnvDuration("AWAY_TIMEOUT_MS", 5*time.Minute),
+		SessionExpiryWarning:    getEnvDuration("SESSION_EXPIRY_WARNING_MS", time.Minute),
+
+		MapDir:        getEnv("MAP_DIR", "maps"),
+		SpawnSeed:     int64(getEnvInt("SPAWN_SEED", 0)),
+		SnapshotDir:      getEnv("SNAPSHOT_DIR", ""),
+		SnapshotInterval: getEnvDuration("SNAPSHOT_INTERVAL_MS", time.Minute),
+		WSCompression: getEnvBool("WS_COMPRESSION", true),
+		ProtocolViolationLimit:  getEnvInt("PROTOCOL_VIOLATION_LIMIT", 20),
+		ProtocolViolationWindow: getEnvDuration("PROTOCOL_VIOLATION_WINDOW_MS", time.Minute),
+
+		SpaceProximityStrategies: getEnvSpaceStrategies("PROXIMITY_STRATEGY"),
+
+		SpaceCoords:         getEnvSpaceCoords("SPACE_COORDS"),
+		RolePermissions:     getEnvPermissions("ROLE_PERMISSIONS"),
+		CustomEventSubtypes: getEnvList("CUSTOM_EVENT_SUBTYPES", []string{"trivia", "poll"}),
+		ChatMaxRunes:        getEnvInt("CHAT_MAX_RUNES", 500),
+
+		ReportReasons: getEnvList("REPORT_REASONS", []string{"harassment", "spam", "inappropriate", "other"}),
+		ReportLimit:   getEnvInt("REPORT_LIMIT", 5),
+		ReportWindow:  getEnvDuration("REPORT_WINDOW_MS", 10*time.Minute),
+
+		AllowedOrigins: getEnvList("ALLOWED_ORIGINS", DefaultAllowedOrigins),
+	}
+	warnRadii(cfg.AudioRadius, cfg.VideoRadius, cfg.WhisperRadius)
+	if view := cfg.ViewRadius; view > 0 && view < cfg.AudioRadius {
+		log.Printf("WARNING: VIEW_RADIUS (%v) is smaller than AUDIO_RADIUS (%v); users will hear peers they can't see", view, cfg.AudioRadius)
 	}
+	Set(cfg)
 
 	return nil
 }
 
+// ReloadProximityRadii re-reads AUDIO_RADIUS, VIDEO_RADIUS, SCREEN_RADIUS and
+// WHISPER_RADIUS and swaps in a config carrying the new values. A radius in the .env
+// file wins over the environment, so edits to the file take effect; nothing else is
+// read from it or written back to the environment. Unset values keep their current setting.
+func ReloadProximityRadii() (audio, video, screen, whisper float64) {
+	fileEnv, _ := godotenv.Read(envPath)
+	lookup := func(key string) (string, bool) {
+		if value, exists := fileEnv[key]; exists {
+			return value, true
+		}
+		return os.LookupEnv(key)
+	}
+
+	next := *Current()
+	next.AudioRadius = lookupRadius(lookup, "AUDIO_RADIUS", next.AudioRadius)
+	next.VideoRadius = lookupRadius(lookup, "VIDEO_RADIUS", next.VideoRadius)
+	next.ScreenRadius = lookupRadius(lookup, "SCREEN_RADIUS", next.ScreenRadius)
+	next.WhisperRadius = lookupRadius(lookup, "WHISPER_RADIUS", next.WhisperRadius)
+	warnRadii(next.AudioRadius, next.VideoRadius, next.WhisperRadius)
+	Set(&next)
+
+	return next.AudioRadius, next.VideoRadius, next.ScreenRadius, next.WhisperRadius
+}
+
 // getEnv retrieves an environment variable with a fallback default
 func getEnv(key, fallback string) string {
 	if value, exists := os.LookupEnv(key); exists {
@@ -52,3 +463,219 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+
+// getEnvFloat retrieves an environment variable as a float64 with a fallback default
+func getEnvFloat(key string, fallback float64) float64 {
+	if value, exists := os.LookupEnv(key); exists {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return fallback
+}
+
+// getEnvRadius retrieves a proximity radius. A zero, negative or unparseable value would
+// silently disable the channel, so it logs a warning and keeps the fallback instead.
+func getEnvRadius(key string, fallback float64) float64 {
+	return lookupRadius(os.LookupEnv, key, fallback)
+}
+
+// lookupRadius is getEnvRadius reading key through lookup
+func lookupRadius(lookup func(string) (string, bool), key string, fallback float64) float64 {
+	value, exists := lookup(key)
+	if !exists {
+		return fallback
+	}
+	radius, err := strconv.ParseFloat(value, 64)
+	if err != nil || radius <= 0 {
+		log.Printf("WARNING: invalid %s %q; using %v", key, value, fallback)
+		return fallback
+	}
+	return radius
+}
+
+// getEnvMessageLimit retrieves a message size limit. Zero or negative would leave clients
+// unlimited, so like an unparseable value it logs a warning and keeps the fallback.
+func getEnvMessageLimit(key string, fallback int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 {
+		log.Printf("WARNING: invalid %s %q; using %v", key, value, fallback)
+		return fallback
+	}
+	return limit
+}
+
+// warnRadii warns when the video or whisper circle is wider than the audio one, which is
+// almost always a misconfiguration
+func warnRadii(audio, video, whisper float64) {
+	if video > audio {
+		log.Printf("WARNING: VIDEO_RADIUS (%v) is larger than AUDIO_RADIUS (%v); video proximity is normally the tighter circle", video, audio)
+	}
+	if whisper > audio {
+		log.Printf("WARNING: WHISPER_RADIUS (%v) is larger than AUDIO_RADIUS (%v); whispers would reach beyond hearing range", whisper, audio)
+	}
+}
+
+// getEnvBool retrieves an environment variable as a bool (1/0, true/false, ...) with a fallback default
+func getEnvBool(key string, fallback bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		if b, err := strconv.ParseBool(strings.TrimSpace(value)); err == nil {
+			return b
+		}
+	}
+	return fallback
+}
+
+// getEnvInt retrieves an environment variable as an int with a fallback default
+func getEnvInt(key string, fallback int) int {
+	if value, exists := os.LookupEnv(key); exists {
+		if i, err := strconv.Atoi(value); err == nil {
+			return i
+		}
+	}
+	return fallback
+}
+
+// getEnvList retrieves a comma-separated environment variable with a fallback default
+func getEnvList(key string, fallback []string) []string {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getEnvMedia parses extra proximity media from a comma-separated list of
+// name:radius[:dwellMs] entries. Malformed entries are skipped.
+func getEnvMedia(key string) []ProximityMedia {
+	media := make([]ProximityMedia, 0)
+	for _, entry := range getEnvList(key, nil) {
+		parts := strings.Split(entry, ":")
+		if len(parts) < 2 || len(parts) > 3 || isBuiltinMedia(parts[0]) {
+			continue
+		}
+		radius, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil || radius <= 0 {
+			continue
+		}
+		m := ProximityMedia{Name: parts[0], Radius: radius}
+		if len(parts) == 3 {
+			ms, err := strconv.Atoi(parts[2])
+			if err != nil || ms < 0 {
+				continue
+			}
+			m.Dwell = time.Duration(ms) * time.Millisecond
+		}
+		media = append(media, m)
+	}
+	return media
+}
+
+// isBuiltinMedia reports whether name is one of the channels PROXIMITY_MEDIA can't redefine
+func isBuiltinMedia(name string) bool {
+	return name == MediaAudio || name == MediaVideo || name == MediaScreen || name == MediaWhisper
+}
+
+// getEnvKeyset parses a comma-separated list of kid:secret entries.
+// Only the first colon separates, so secrets may contain colons. Malformed entries are skipped.
+func getEnvKeyset(key string) map[string]string {
+	keys := make(map[string]string)
+	for _, entry := range getEnvList(key, nil) {
+		kid, secret, ok := strings.Cut(entry, ":")
+		if !ok || kid == "" || secret == "" {
+			continue
+		}
+		keys[kid] = secret
+	}
+	return keys
+}
+
+// getEnvPublicKey parses a PEM-encoded RSA public key. Literal `\n` escapes are
+// accepted so the key fits on one line of a .env file. Unset returns nil.
+func getEnvPublicKey(key string) (*rsa.PublicKey, error) {
+	value := strings.TrimSpace(getEnv(key, ""))
+	if value == "" {
+		return nil, nil
+	}
+	publicKey, err := jwt.ParseRSAPublicKeyFromPEM([]byte(strings.ReplaceAll(value, `\n`, "\n")))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return publicKey, nil
+}
+
+// getEnvPermissions parses a comma-separated list of type:role|role entries. The last colon
+// separates, so actions like lower-hand:others can be named. A role of * opens the type
+// to everyone. Malformed entries are skipped.
+func getEnvPermissions(key string) map[string][]string {
+	permissions := make(map[string][]string)
+	for _, entry := range getEnvList(key, nil) {
+		i := strings.LastIndex(entry, ":")
+		if i <= 0 || i == len(entry)-1 {
+			continue
+		}
+		permissions[entry[:i]] = strings.Split(entry[i+1:], "|")
+	}
+	return permissions
+}
+
+// getEnvSpaceStrategies parses per-space proximity strategies from a comma-separated
+// list of spaceId:strategy entries. Unknown strategies and malformed entries are skipped.
+func getEnvSpaceStrategies(key string) map[string]string {
+	strategies := make(map[string]string)
+	for _, entry := range getEnvList(key, nil) {
+		spaceID, strategy, ok := strings.Cut(entry, ":")
+		if !ok || spaceID == "" || (strategy != ProximityEvented && strategy != ProximityPolled) {
+			continue
+		}
+		strategies[spaceID] = strategy
+	}
+	return strategies
+}
+
+// getEnvSpaceCoords parses per-space coordinate systems from a comma-separated list of
+// spaceId:originX:originY:scale entries. Malformed entries are skipped.
+func getEnvSpaceCoords(key string) map[string]SpaceCoordinates {
+	coords := make(map[string]SpaceCoordinates)
+	for _, entry := range getEnvList(key, nil) {
+		parts := strings.Split(entry, ":")
+		if len(parts) != 4 || parts[0] == "" {
+			continue
+		}
+		var values [3]float64
+		valid := true
+		for i, part := range parts[1:] {
+			v, err := strconv.ParseFloat(part, 64)
+			if err != nil {
+				valid = false
+				break
+			}
+			values[i] = v
+		}
+		if !valid || values[2] <= 0 {
+			continue
+		}
+		coords[parts[0]] = SpaceCoordinates{OriginX: values[0], OriginY: values[1], Scale: values[2]}
+	}
+	return coords
+}
+
+// getEnvDuration retrieves an environment variable in milliseconds with a fallback default
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	if value, exists := os.LookupEnv(key); exists {
+		if ms, err := strconv.Atoi(value); err == nil {
+			return time.Duration(ms) * time.Millisecond
+		}
+	}
+	return fallback
+}
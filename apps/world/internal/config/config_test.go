@@ -0,0 +1,318 @@
+package config
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetEnvMedia(t *testing.T) {
+	t.Setenv("PROXIMITY_MEDIA", "presence:500, stage:200:1500,bad,audio:10,screen:400,neg:-1,dwell:5:x")
+
+	media := getEnvMedia("PROXIMITY_MEDIA")
+	want := []ProximityMedia{
+		{Name: "presence", Radius: 500},
+		{Name: "stage", Radius: 200, Dwell: 1500 * time.Millisecond},
+	}
+	if len(media) != len(want) {
+		t.Fatalf("got %+v, want %+v", media, want)
+	}
+	for i := range want {
+		if media[i] != want[i] {
+			t.Errorf("media[%d] = %+v, want %+v", i, media[i], want[i])
+		}
+	}
+}
+
+func TestProximityMediaDefaults(t *testing.T) {
+	c := &Config{AudioRadius: 300, VideoRadius: 120, ScreenRadius: 180, WhisperRadius: 60, ExtraMedia: []ProximityMedia{{Name: "presence", Radius: 500}}}
+
+	media := c.ProximityMedia()
+	if len(media) != 5 || media[0].Name != MediaAudio || media[1].Name != MediaVideo || !media[1].Meeting {
+		t.Fatalf("unexpected media %+v", media)
+	}
+	if screen := media[2]; screen.Name != MediaScreen || screen.Radius != 180 || screen.Meeting || screen.Dwell != 0 {
+		t.Errorf("screen media = %+v", screen)
+	}
+	if whisper := media[3]; whisper.Name != MediaWhisper || whisper.Radius != 60 || whisper.Meeting {
+		t.Errorf("whisper media = %+v", whisper)
+	}
+	if m, ok := c.Media("presence"); !ok || m.Radius != 500 {
+		t.Errorf("Media(presence) = %+v, %v", m, ok)
+	}
+}
+
+func TestGetEnvSpaceCoords(t *testing.T) {
+	t.Setenv("TEST_SPACE_COORDS", "lobby:-640:-480:1, big:0:0:2.5,bad:1:2,zero:0:0:0,nan:x:0:1")
+
+	coords := getEnvSpaceCoords("TEST_SPACE_COORDS")
+	if len(coords) != 2 {
+		t.Fatalf("got %d entries, want 2: %v", len(coords), coords)
+	}
+	if got := coords["lobby"]; got != (SpaceCoordinates{OriginX: -640, OriginY: -480, Scale: 1}) {
+		t.Errorf("lobby = %+v", got)
+	}
+	if got := coords["big"]; got != (SpaceCoordinates{Scale: 2.5}) {
+		t.Errorf("big = %+v", got)
+	}
+
+	c := &Config{SpaceCoords: coords}
+	if got := c.Coordinates("unknown"); got != DefaultSpaceCoordinates {
+		t.Errorf("unknown space = %+v, want the default", got)
+	}
+}
+
+func TestGetEnvKeyset(t *testing.T) {
+	t.Setenv("TEST_JWT_KEYS", "k1:secret-one, k2:with:colons,nokey,:empty,k3:")
+
+	keys := getEnvKeyset("TEST_JWT_KEYS")
+	if len(keys) != 2 || keys["k1"] != "secret-one" || keys["k2"] != "with:colons" {
+		t.Errorf("keys = %v", keys)
+	}
+}
+
+func TestGetEnvPermissions(t *testing.T) {
+	t.Setenv("TEST_ROLE_PERMISSIONS", "kick:admin|moderator, lower-hand:others:admin,bad,empty:")
+
+	permissions := getEnvPermissions("TEST_ROLE_PERMISSIONS")
+	if len(permissions) != 2 {
+		t.Fatalf("got %v, want 2 entries", permissions)
+	}
+	if got := permissions["kick"]; len(got) != 2 || got[0] != "admin" || got[1] != "moderator" {
+		t.Errorf("kick = %v", got)
+	}
+	if got := permissions["lower-hand:others"]; len(got) != 1 || got[0] != "admin" {
+		t.Errorf("lower-hand:others = %v", got)
+	}
+}
+
+func TestGetEnvSpaceStrategies(t *testing.T) {
+	t.Setenv("TEST_PROXIMITY_STRATEGY", "arena:event, plaza:polled,bad,odd:sometimes,:polled")
+
+	c := &Config{SpaceProximityStrategies: getEnvSpaceStrategies("TEST_PROXIMITY_STRATEGY")}
+	if len(c.SpaceProximityStrategies) != 2 {
+		t.Errorf("got %v, want arena and plaza only", c.SpaceProximityStrategies)
+	}
+	for spaceID, want := range map[string]string{"arena": ProximityEvented, "plaza": ProximityPolled, "other": ProximityEvented} {
+		if got := c.ProximityStrategy(spaceID); got != want {
+			t.Errorf("ProximityStrategy(%s) = %s, want %s", spaceID, got, want)
+		}
+	}
+}
+
+// captureLog redirects the standard logger for the rest of the test
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return &buf
+}
+
+func TestGetEnvRadius(t *testing.T) {
+	cases := []struct {
+		value   string
+		set     bool
+		want    float64
+		warning bool
+	}{
+		{set: false, want: DefaultAudioRadius},
+		{value: "240", set: true, want: 240},
+		{value: "12.5", set: true, want: 12.5},
+		{value: "wide", set: true, want: DefaultAudioRadius, warning: true},
+		{value: "0", set: true, want: DefaultAudioRadius, warning: true},
+		{value: "-50", set: true, want: DefaultAudioRadius, warning: true},
+	}
+	for _, tc := range cases {
+		buf := captureLog(t)
+		if tc.set {
+			t.Setenv("TEST_RADIUS", tc.value)
+		}
+		if got := getEnvRadius("TEST_RADIUS", DefaultAudioRadius); got != tc.want {
+			t.Errorf("%q: radius = %v, want %v", tc.value, got, tc.want)
+		}
+		if warned := strings.Contains(buf.String(), "WARNING"); warned != tc.warning {
+			t.Errorf("%q: warned = %v, want %v", tc.value, warned, tc.warning)
+		}
+	}
+}
+
+func TestGetEnvMessageLimit(t *testing.T) {
+	cases := []struct {
+		value   string
+		set     bool
+		want    int
+		warning bool
+	}{
+		{set: false, want: DefaultMaxMessageBytes},
+		{value: "4096", set: true, want: 4096},
+		{value: "big", set: true, want: DefaultMaxMessageBytes, warning: true},
+		{value: "0", set: true, want: DefaultMaxMessageBytes, warning: true},
+		{value: "-1", set: true, want: DefaultMaxMessageBytes, warning: true},
+	}
+	for _, tc := range cases {
+		buf := captureLog(t)
+		if tc.set {
+			t.Setenv("TEST_MESSAGE_LIMIT", tc.value)
+		}
+		if got := getEnvMessageLimit("TEST_MESSAGE_LIMIT", DefaultMaxMessageBytes); got != tc.want {
+			t.Errorf("%q: limit = %v, want %v", tc.value, got, tc.want)
+		}
+		if warned := strings.Contains(buf.String(), "WARNING"); warned != tc.warning {
+			t.Errorf("%q: warned = %v, want %v", tc.value, warned, tc.warning)
+		}
+	}
+}
+
+func TestWarnRadii(t *testing.T) {
+	buf := captureLog(t)
+	warnRadii(300, 120, 60)
+	if buf.Len() != 0 {
+		t.Errorf("unexpected warning for tighter video and whisper circles: %s", buf)
+	}
+	warnRadii(100, 200, 60)
+	if !strings.Contains(buf.String(), "VIDEO_RADIUS") {
+		t.Error("no warning when video radius exceeds audio radius")
+	}
+	warnRadii(100, 80, 150)
+	if !strings.Contains(buf.String(), "WHISPER_RADIUS") {
+		t.Error("no warning when whisper radius exceeds audio radius")
+	}
+}
+
+func TestLoadReadsRadii(t *testing.T) {
+	captureLog(t)
+	t.Setenv("AUDIO_RADIUS", "400")
+	t.Setenv("VIDEO_RADIUS", "-1")
+	if err := Load(); err != nil {
+		t.Fatal(err)
+	}
+	if Current().AudioRadius != 400 || Current().VideoRadius != DefaultVideoRadius {
+		t.Errorf("radii = %v/%v, want 400/%v", Current().AudioRadius, Current().VideoRadius, DefaultVideoRadius)
+	}
+}
+
+func TestReloadProximityRadiiReadsOnlyRadii(t *testing.T) {
+	captureLog(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".env")
+	if err := os.WriteFile(path, []byte("AUDIO_RADIUS=150\nJWT_SECRET=from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	old := envPath
+	envPath = path
+	t.Cleanup(func() { envPath = old })
+
+	t.Setenv("AUDIO_RADIUS", "400")
+	t.Setenv("VIDEO_RADIUS", "90")
+	t.Setenv("JWT_SECRET", "from-process")
+	Set(&Config{AudioRadius: 300, VideoRadius: 120, ScreenRadius: 180, WhisperRadius: 60, JWTSecret: "from-process"})
+
+	audio, video, screen, _ := ReloadProximityRadii()
+	if audio != 150 || video != 90 || screen != 180 {
+		t.Errorf("radii = %v/%v/%v, want 150/90/180", audio, video, screen)
+	}
+	if got := os.Getenv("JWT_SECRET"); got != "from-process" {
+		t.Errorf("JWT_SECRET = %q after reload, want the process value", got)
+	}
+	if got := os.Getenv("AUDIO_RADIUS"); got != "400" {
+		t.Errorf("AUDIO_RADIUS = %q after reload; the environment should be left alone", got)
+	}
+	if Current().JWTSecret != "from-process" {
+		t.Errorf("JWTSecret = %q after reload", Current().JWTSecret)
+	}
+}
+
+func TestOriginAllowed(t *testing.T) {
+	t.Setenv("ALLOWED_ORIGINS", "https://app.example.com, *.raashed.xyz,https://*.secure.io")
+	if err := Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"", true},
+		{"https://app.example.com", true},
+		{"http://app.example.com", false},
+		{"https://app.example.com.evil.net", false},
+		{"https://game.raashed.xyz", true},
+		{"http://a.b.raashed.xyz:3000", true},
+		{"https://raashed.xyz", false},
+		{"https://evilraashed.xyz", false},
+		{"https://meet.secure.io", true},
+		{"http://meet.secure.io", false},
+		{"https://unlisted.com", false},
+		{"null", false},
+	}
+	for _, tt := range tests {
+		if got := Current().OriginAllowed(tt.origin); got != tt.want {
+			t.Errorf("OriginAllowed(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestAllowedOriginsDefault(t *testing.T) {
+	os.Unsetenv("ALLOWED_ORIGINS")
+	if err := Load(); err != nil {
+		t.Fatal(err)
+	}
+	if !Current().OriginAllowed("https://game.raashed.cloud") || Current().OriginAllowed("https://unlisted.com") {
+		t.Errorf("default origins = %v", Current().AllowedOrigins)
+	}
+}
+
+func TestProximityBridgeOffByDefault(t *testing.T) {
+	os.Unsetenv("PROXIMITY_BRIDGE_URL")
+	t.Setenv("BACKEND_URL", "http://backend")
+	if err := Load(); err != nil {
+		t.Fatal(err)
+	}
+	if Current().ProximityBridgeURL != "" {
+		t.Errorf("bridge URL = %q, want it off unless configured", Current().ProximityBridgeURL)
+	}
+}
+
+func TestLoadReadsPublicKey(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	// Escaped onto one line, as in a .env file
+	t.Setenv("JWT_PUBLIC_KEY", strings.ReplaceAll(publicPEM, "\n", `\n`))
+	if err := Load(); err != nil {
+		t.Fatal(err)
+	}
+	if Current().JWTPublicKey == nil || !Current().JWTPublicKey.Equal(&privateKey.PublicKey) {
+		t.Error("JWT_PUBLIC_KEY was not loaded")
+	}
+
+	t.Setenv("JWT_PUBLIC_KEY", "not a key")
+	if err := Load(); err == nil {
+		t.Error("Load accepted an invalid JWT_PUBLIC_KEY")
+	}
+}
+
+func TestHMACSecretsPrimaryFirst(t *testing.T) {
+	c := &Config{JWTSecret: "primary", JWTSecrets: []string{"old", "primary", "older"}}
+	got := c.HMACSecrets()
+	want := []string{"primary", "old", "older"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("HMACSecrets() = %v, want %v", got, want)
+	}
+}
@@ -2,10 +2,19 @@ package hub
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"log"
+	"math"
+	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"world/internal/auth"
+	"world/internal/config"
+	"world/internal/messages"
+
 	"github.com/gorilla/websocket"
 )
 
@@ -19,14 +28,43 @@ const (
 	// Send pings to peer with this period (must be less than pongWait)
 	pingPeriod = (pongWait * 9) / 10
 
-	// Maximum message size allowed from peer
-	maxMessageSize = 512
+	// How far past MaxMessageBytes a message is still read, so the client can be told it
+	// was too large. Anything bigger is cut off by the websocket library with a bare close.
+	oversizeGrace = 1024
+
+	// Smallest message worth compressing. permessage-deflate here compresses each frame
+	// without a shared dictionary, so smaller frames (single moves) come out larger.
+	minCompressSize = 256
+
+	// Weight of the previous estimate when smoothing pong latency, as in TCP's SRTT:
+	// each new sample moves the estimate 1/latencySmoothing of the way
+	latencySmoothing = 8
 )
 
+// ErrClientClosed is returned when sending to a client that has been disconnected
+var ErrClientClosed = errors.New("client closed")
+
+// ErrSendBufferFull is returned when a client's Send buffer is full. The message is
+// dropped and the client disconnected so one slow reader can't stall the hub.
+var ErrSendBufferFull = errors.New("send buffer full")
+
+// Transport is the part of *websocket.Conn the client uses, so pumps can run against a fake in tests
+type Transport interface {
+	SetReadLimit(limit int64)
+	SetReadDeadline(t time.Time) error
+	SetPongHandler(h func(appData string) error)
+	ReadMessage() (messageType int, p []byte, err error)
+	SetWriteDeadline(t time.Time) error
+	WriteMessage(messageType int, data []byte) error
+	NextWriter(messageType int) (io.WriteCloser, error)
+	EnableWriteCompression(enable bool)
+	Close() error
+}
+
 // Client represents a single WebSocket connection
 type Client struct {
 	Hub     *Hub
-	Conn    *websocket.Conn
+	Conn    Transport
 	Send    chan []byte
 	UserID  string
 	Role    string
@@ -37,23 +75,146 @@ type Client struct {
 	AvatarName string
 	Anim       string
 	mu         sync.Mutex
+
+	// ConfirmMoves makes the server confirm each accepted move to this client,
+	// for clients that don't predict movement locally
+	ConfirmMoves bool
+
+	// BatchProximity coalesces the proximity updates for this client from one round
+	// of events into a single proximity-batch message
+	BatchProximity bool
+
+	// hiddenFrom holds user IDs this client is invisible to
+	hiddenFrom map[string]bool
+
+	// grid is the spatial index of the client's space, kept current by SetPosition
+	grid *spatialGrid
+
+	// Movement reconciliation state for the current tick window
+	tickStart    time.Time
+	tickDistance float64
+
+	// Movement rate token bucket, refilled at MovementRateHz
+	moveTokens float64
+	lastMoveAt time.Time
+
+	// Distance the client may still cover under MaxMoveSpeed as of its last accepted move
+	speedAllowance float64
+	acceptedMoveAt time.Time
+
+	// dropCause is why the connection went away (a user-left reason), set once by
+	// whichever side notices first
+	dropCause string
+
+	// registeredAt is when the hub registered the connection and joined is set once it
+	// first joins a space; both are guarded by the hub's mu
+	registeredAt time.Time
+	joined       bool
+
+	// lastActivity is when the client last sent an application message; idle is set
+	// once the space has been told the client went idle
+	lastActivity time.Time
+	idle         bool
+
+	// tokenExpiresAt is when the client's token expires (zero if it doesn't);
+	// expiryWarned is set once session-expiring has been sent for it
+	tokenExpiresAt time.Time
+	expiryWarned   bool
+	// claims are from the token the client joined with, for access checks after the
+	// join such as portals into other spaces
+	claims *auth.Claims
+
+	// typingScope is the chat scope the client is typing in ("" when not typing);
+	// typingAt is when it last said it was typing
+	typingScope string
+	typingAt    time.Time
+
+	// rtt is the round trip time last reported by the client
+	rtt time.Duration
+
+	// pingSentAt is when the outstanding ping went out (zero once its pong is back);
+	// latency is the smoothed ping/pong round trip measured by the server
+	pingSentAt time.Time
+	latency    time.Duration
+
+	// Last target rejected for a collision, so wall-pushing can be dropped cheaply
+	rejectedX, rejectedY float64
+	rejectedAt           time.Time
+
+	// Protocol violations counted since violationStart
+	violations     int
+	violationStart time.Time
+
+	// closeReason is sent in the close frame for server-initiated disconnects
+	closeReason CloseReason
+	closeOnce   sync.Once
+
+	// sendMu guards closing Send against concurrent SendJSON calls
+	sendMu     sync.RWMutex
+	sendClosed bool
+
+	// compress is the per-connection permessage-deflate choice
+	compress bool
+
+	// writerDone is closed when WritePump returns
+	writerDone chan struct{}
+
+	// Payload bytes written with and without write compression
+	bytesCompressed   atomic.Int64
+	bytesUncompressed atomic.Int64
 }
 
-// NewClient creates a new client instance
-func NewClient(hub *Hub, conn *websocket.Conn) *Client {
+// NewClient creates a new client instance. compress is the connection's negotiated
+// choice for write compression.
+func NewClient(hub *Hub, conn Transport, compress bool) *Client {
 	return &Client{
-		Hub:  hub,
-		Conn: conn,
-		Send: make(chan []byte, 256),
+		Hub:      hub,
+		Conn:     conn,
+		Send:     make(chan []byte, 256),
+		compress: compress,
+
+		writerDone: make(chan struct{}),
 	}
 }
 
+// CompressionStats returns the payload bytes sent with and without write compression
+func (c *Client) CompressionStats() (compressed, uncompressed int64) {
+	return c.bytesCompressed.Load(), c.bytesUncompressed.Load()
+}
+
 // SetPosition updates the client's position
 func (c *Client) SetPosition(x, y float64) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
 	c.X = x
 	c.Y = y
+	grid := c.grid
+	c.mu.Unlock()
+
+	// Reindex outside c.mu: the grid locks before the client
+	if grid != nil {
+		grid.update(c)
+	}
+}
+
+// setGrid sets the spatial grid the client reindexes itself in when it moves
+func (c *Client) setGrid(grid *spatialGrid) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.grid = grid
+}
+
+// SetAnim sets the animation the client's avatar plays
+func (c *Client) SetAnim(anim string) {
+	c.mu.Lock()
+	c.Anim = anim
+	c.mu.Unlock()
+}
+
+// GetAnim returns the animation the client's avatar plays
+func (c *Client) GetAnim() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Anim
 }
 
 // GetPosition returns the client's current position
@@ -63,35 +224,357 @@ func (c *Client) GetPosition() (float64, float64) {
 	return c.X, c.Y
 }
 
+// SetHiddenFrom hides (or reveals) this client from the given user
+func (c *Client) SetHiddenFrom(userID string, hidden bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !hidden {
+		delete(c.hiddenFrom, userID)
+		return
+	}
+	if c.hiddenFrom == nil {
+		c.hiddenFrom = make(map[string]bool)
+	}
+	c.hiddenFrom[userID] = true
+}
+
+// HidesFrom reports whether this client is invisible to the given user
+func (c *Client) HidesFrom(userID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hiddenFrom[userID]
+}
+
+// moveRateBurst is how many movement messages may arrive back to back before the
+// MovementRateHz limit applies, so network jitter doesn't drop honest moves
+const moveRateBurst = 3
+
+// allowMoveRate takes a token from the client's movement rate bucket. Moves beyond
+// MovementRateHz (plus a small burst) are refused.
+func (c *Client) allowMoveRate(now time.Time) bool {
+	rate := config.Current().MovementRateHz
+	if rate <= 0 {
+		return true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.lastMoveAt.IsZero() {
+		c.moveTokens = moveRateBurst
+	} else {
+		c.moveTokens = min(moveRateBurst, c.moveTokens+now.Sub(c.lastMoveAt).Seconds()*rate)
+	}
+	c.lastMoveAt = now
+
+	if c.moveTokens < 1 {
+		return false
+	}
+	c.moveTokens--
+	return true
+}
+
+// consumeMoveBudget charges dist against the client's per-tick movement budget.
+// Returns false (without charging) if the move would exceed the budget for the current window.
+func (c *Client) consumeMoveBudget(dist float64, now time.Time) bool {
+	budget := config.Current().MoveTickBudget
+	interval := config.Current().MoveTickInterval
+	if budget <= 0 || interval <= 0 {
+		return true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Laggy clients deliver moves in bursts, so widen the budget by the share of
+	// a tick their latency spans. The RTT is capped so clients can't buy free speed.
+	rtt := min(c.rtt, config.Current().MaxLatencyCompensation)
+	if rtt > 0 {
+		budget += budget * float64(rtt) / float64(interval)
+	}
+
+	// Reset the accumulator once the tick window has elapsed
+	if now.Sub(c.tickStart) >= interval {
+		c.tickStart = now
+		c.tickDistance = 0
+	}
+
+	if c.tickDistance+dist > budget {
+		return false
+	}
+	c.tickDistance += dist
+	return true
+}
+
+// moveSpeedBurst is how much travel time at MaxMoveSpeed a client can bank while
+// standing still, so moves sent at a legal speed but delivered in bunches still pass
+const moveSpeedBurst = 250 * time.Millisecond
+
+// maxStepDistance is the longest single step IsValidMove accepts, a diagonal one
+const maxStepDistance = 20 * math.Sqrt2
+
+// withinMoveSpeed reports whether moving dist at now keeps the client under
+// MaxMoveSpeed, given the moves accepted so far.
+func (c *Client) withinMoveSpeed(dist float64, now time.Time) bool {
+	speed := config.Current().MaxMoveSpeed
+	if speed <= 0 {
+		return true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return dist <= c.speedAllowanceLocked(speed, now)
+}
+
+// acceptMove charges an accepted move of dist against the client's speed allowance
+func (c *Client) acceptMove(dist float64, now time.Time) {
+	speed := config.Current().MaxMoveSpeed
+	if speed <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.speedAllowance = max(0, c.speedAllowanceLocked(speed, now)-dist)
+	c.acceptedMoveAt = now
+}
+
+// speedAllowanceLocked returns how far the client may move at now: what was left after
+// its last accepted move, refilled at speed since then. The allowance is capped at
+// moveSpeedBurst of travel, but never below one full step so a first move after
+// standing still always passes.
+func (c *Client) speedAllowanceLocked(speed float64, now time.Time) float64 {
+	limit := max(speed*moveSpeedBurst.Seconds(), maxStepDistance)
+	if c.acceptedMoveAt.IsZero() {
+		return limit
+	}
+	return min(limit, c.speedAllowance+now.Sub(c.acceptedMoveAt).Seconds()*speed)
+}
+
+// isRepeatedRejection reports whether (x, y) is the target that was just rejected
+// for a collision and the collision cooldown hasn't passed yet
+func (c *Client) isRepeatedRejection(x, y float64, now time.Time) bool {
+	cooldown := config.Current().CollisionCooldown
+	if cooldown <= 0 {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !c.rejectedAt.IsZero() && c.rejectedX == x && c.rejectedY == y && now.Sub(c.rejectedAt) < cooldown
+}
+
+// setRejectedTarget records the target of a move rejected for a collision.
+// A zero time clears it.
+func (c *Client) setRejectedTarget(x, y float64, at time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rejectedX, c.rejectedY, c.rejectedAt = x, y, at
+}
+
+// touch records application traffic from the client. It reports whether the client
+// was idle, clearing the flag.
+func (c *Client) touch(now time.Time) (wasIdle bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastActivity = now
+	wasIdle, c.idle = c.idle, false
+	return wasIdle
+}
+
+// idleFor returns how long the client has gone without sending an application message.
+// Clients that never sent one report zero.
+func (c *Client) idleFor(now time.Time) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lastActivity.IsZero() {
+		return 0
+	}
+	return now.Sub(c.lastActivity)
+}
+
+// SetRTT stores the client's measured round trip time
+func (c *Client) SetRTT(rtt time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rtt = rtt
+}
+
+// RTT returns the client's last reported round trip time
+func (c *Client) RTT() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rtt
+}
+
+// markPingSent records when a ping frame went out
+func (c *Client) markPingSent(at time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pingSentAt = at
+}
+
+// recordPong folds the round trip of the outstanding ping into the smoothed latency.
+// Unsolicited pongs, with no ping outstanding, are ignored.
+func (c *Client) recordPong(at time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pingSentAt.IsZero() {
+		return
+	}
+	sample := at.Sub(c.pingSentAt)
+	c.pingSentAt = time.Time{}
+	if c.latency == 0 {
+		c.latency = sample
+	} else {
+		c.latency += (sample - c.latency) / latencySmoothing
+	}
+	pongLatencySeconds.Observe(sample.Seconds())
+}
+
+// Latency returns the smoothed ping/pong round trip time, zero until the first pong
+func (c *Client) Latency() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.latency
+}
+
+// addViolation records a protocol violation and returns the count within the current window.
+// The count starts over once the window since the first violation has passed.
+func (c *Client) addViolation(now time.Time, window time.Duration) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.violations == 0 || now.Sub(c.violationStart) >= window {
+		c.violations = 0
+		c.violationStart = now
+	}
+	c.violations++
+	return c.violations
+}
+
+// Disconnect asks the hub to drop this client, recording the reason for the close frame.
+// Safe to call more than once; only the first reason is kept.
+func (c *Client) Disconnect(reason CloseReason) {
+	if c.setCloseReason(reason) {
+		go c.Hub.unregister(c)
+	}
+}
+
+// setCloseReason records why the server is closing the connection. Only the first
+// reason counts; it returns false if one was already set.
+func (c *Client) setCloseReason(reason CloseReason) bool {
+	first := false
+	c.closeOnce.Do(func() {
+		c.mu.Lock()
+		c.closeReason = reason
+		c.mu.Unlock()
+		first = true
+	})
+	return first
+}
+
+// closedByServer reports whether the server initiated this client's disconnect
+func (c *Client) closedByServer() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !c.closeReason.IsZero()
+}
+
+// closeMessage builds the close frame payload for this client
+func (c *Client) closeMessage() []byte {
+	c.mu.Lock()
+	reason := c.closeReason
+	c.mu.Unlock()
+	return reason.frame()
+}
+
 // ReadPump pumps messages from the WebSocket connection to the hub
 // This implements the "fan-in" pattern - all client messages flow into the hub
 func (c *Client) ReadPump() {
 	defer func() {
-		c.Hub.Unregister <- c
+		c.Hub.unregister(c)
 		c.Conn.Close()
 	}()
 
-	c.Conn.SetReadLimit(maxMessageSize)
+	// config.Load never leaves this unset, but a hand-built Config might
+	limit := config.Current().MaxMessageBytes
+	if limit <= 0 {
+		limit = config.DefaultMaxMessageBytes
+	}
+	c.Conn.SetReadLimit(int64(limit + oversizeGrace))
 	c.Conn.SetReadDeadline(time.Now().Add(pongWait))
-	c.Conn.SetPongHandler(func(string) error {
-		c.Conn.SetReadDeadline(time.Now().Add(pongWait))
-		return nil
-	})
+	c.Conn.SetPongHandler(c.handlePong)
 
+	tooLarge := false
 	for {
 		_, message, err := c.Conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("error: %v", err)
 			}
+			c.recordDrop(err)
 			break
 		}
 
+		// Keep reading until the write pump has delivered the error and closed the
+		// connection, but act on nothing more from this client
+		if tooLarge {
+			continue
+		}
+		if len(message) > limit {
+			tooLarge = true
+			c.rejectOversized(len(message), limit)
+			continue
+		}
+
 		// Process the message through the hub
 		c.Hub.ProcessMessage(c, message)
 	}
 }
 
+// rejectOversized tells the client its message of size bytes was over limit and
+// disconnects it
+func (c *Client) rejectOversized(size, limit int) {
+	log.Printf("Disconnecting %s: %d-byte message exceeds the %d-byte limit", c.UserID, size, limit)
+	c.SendJSON(messages.BaseMessage{
+		Type:    messages.TypeMessageTooLarge,
+		Payload: messages.MessageTooLargePayload{Size: size, Limit: limit},
+	})
+	c.Disconnect(CloseMessageTooLarge)
+}
+
+// recordDrop notes why the connection went away: a timeout (the peer stopped answering
+// pings, or writes to it stalled) or a close of any other kind. Only the first cause
+// counts, since the pump that fails first brings the other one down with it.
+func (c *Client) recordDrop(err error) {
+	cause := messages.UserLeftClosed
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		cause = messages.UserLeftTimeout
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.dropCause == "" {
+		c.dropCause = cause
+	}
+}
+
+// leaveReason is the user-left reason for the client, empty unless it disconnected
+func (c *Client) leaveReason() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.dropCause
+}
+
+// handlePong extends the read deadline and records the ping's round trip
+func (c *Client) handlePong(string) error {
+	now := time.Now()
+	c.recordPong(now)
+	return c.Conn.SetReadDeadline(now.Add(pongWait))
+}
+
 // WritePump pumps messages from the hub to the WebSocket connection
 // This implements the "fan-out" pattern - messages from hub go to individual clients
 func (c *Client) WritePump() {
@@ -99,6 +582,9 @@ func (c *Client) WritePump() {
 	defer func() {
 		ticker.Stop()
 		c.Conn.Close()
+		if c.writerDone != nil {
+			close(c.writerDone)
+		}
 	}()
 
 	for {
@@ -107,34 +593,76 @@ func (c *Client) WritePump() {
 			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
 				// Hub closed the channel
-				c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
+				c.Conn.WriteMessage(websocket.CloseMessage, c.closeMessage())
 				return
 			}
 
+			// Deflating a frame on its own only pays off past minCompressSize. Only takes
+			// effect if permessage-deflate was negotiated during the handshake.
+			compress := c.compress && len(message) >= minCompressSize
+			c.Conn.EnableWriteCompression(compress)
+
 			w, err := c.Conn.NextWriter(websocket.TextMessage)
 			if err != nil {
+				c.recordDrop(err)
 				return
 			}
 			w.Write(message)
 
 			if err := w.Close(); err != nil {
+				c.recordDrop(err)
 				return
 			}
+
+			if compress {
+				c.bytesCompressed.Add(int64(len(message)))
+			} else {
+				c.bytesUncompressed.Add(int64(len(message)))
+			}
 		case <-ticker.C:
 			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+			c.markPingSent(time.Now())
 			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.recordDrop(err)
 				return
 			}
 		}
 	}
 }
 
-// SendJSON sends a JSON-encoded message to the client
+// SendJSON sends a JSON-encoded message to the client.
+// Messages to a client whose Send channel was closed are dropped with ErrClientClosed.
+// It never blocks: if the buffer is full the message is dropped, counted, and the
+// client is disconnected as a slow consumer.
 func (c *Client) SendJSON(v interface{}) error {
 	data, err := json.Marshal(v)
 	if err != nil {
 		return err
 	}
-	c.Send <- data
-	return nil
+
+	c.sendMu.RLock()
+	defer c.sendMu.RUnlock()
+	if c.sendClosed {
+		return ErrClientClosed
+	}
+	select {
+	case c.Send <- data:
+		return nil
+	default:
+		messagesDroppedTotal.Inc()
+		log.Printf("Client %s: send buffer full, disconnecting slow consumer", c.UserID)
+		c.Disconnect(CloseSlowConsumer)
+		return ErrSendBufferFull
+	}
+}
+
+// closeSend closes the Send channel, which makes WritePump send the close frame.
+// Concurrent SendJSON calls either finish first or see the client as closed.
+func (c *Client) closeSend() {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	if !c.sendClosed {
+		c.sendClosed = true
+		close(c.Send)
+	}
 }
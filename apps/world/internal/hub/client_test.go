@@ -0,0 +1,627 @@
+package hub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"world/internal/config"
+	"world/internal/messages"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeTransport records what the pumps do with the connection
+type fakeTransport struct {
+	mu          sync.Mutex
+	compression []bool
+	written     [][]byte
+	controls    []int
+	closeFrames [][]byte
+	reads       chan []byte
+	closed      bool
+	// readErr is what ReadMessage fails with once reads is closed (a normal close frame if nil)
+	readErr   error
+	readLimit int64
+}
+
+func newFakeTransport() *fakeTransport {
+	return &fakeTransport{reads: make(chan []byte, 16)}
+}
+
+func (f *fakeTransport) SetReadLimit(limit int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.readLimit = limit
+}
+
+func (f *fakeTransport) SetReadDeadline(time.Time) error   { return nil }
+func (f *fakeTransport) SetPongHandler(func(string) error) {}
+func (f *fakeTransport) SetWriteDeadline(time.Time) error  { return nil }
+
+func (f *fakeTransport) ReadMessage() (int, []byte, error) {
+	data, ok := <-f.reads
+	if !ok {
+		if f.readErr != nil {
+			return 0, nil, f.readErr
+		}
+		return 0, nil, &websocket.CloseError{Code: websocket.CloseNormalClosure}
+	}
+	return websocket.TextMessage, data, nil
+}
+
+func (f *fakeTransport) WriteMessage(messageType int, data []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if messageType == websocket.CloseMessage {
+		f.closeFrames = append(f.closeFrames, data)
+	}
+	f.controls = append(f.controls, messageType)
+	return nil
+}
+
+func (f *fakeTransport) NextWriter(int) (io.WriteCloser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return nil, errors.New("closed")
+	}
+	return &fakeWriter{f: f}, nil
+}
+
+func (f *fakeTransport) EnableWriteCompression(enable bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.compression = append(f.compression, enable)
+}
+
+func (f *fakeTransport) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+type fakeWriter struct {
+	f   *fakeTransport
+	buf bytes.Buffer
+}
+
+func (w *fakeWriter) Write(p []byte) (int, error) { return w.buf.Write(p) }
+
+func (w *fakeWriter) Close() error {
+	w.f.mu.Lock()
+	defer w.f.mu.Unlock()
+	w.f.written = append(w.f.written, w.buf.Bytes())
+	return nil
+}
+
+// runWritePump queues msgs, closes Send, and runs WritePump to completion
+func runWritePump(c *Client, msgs ...[]byte) {
+	for _, m := range msgs {
+		c.Send <- m
+	}
+	close(c.Send)
+	c.WritePump()
+}
+
+func TestWritePumpCompressionNegotiation(t *testing.T) {
+	large := []byte(`{"type":"space-joined","payload":"` + strings.Repeat("x", minCompressSize) + `"}`)
+	for _, compress := range []bool{true, false} {
+		transport := newFakeTransport()
+		c := NewClient(NewHub(), transport, compress)
+
+		runWritePump(c, []byte(`{"type":"a"}`), large)
+
+		// Small frames are never compressed; large ones follow the connection's choice
+		if want := []bool{false, compress}; len(transport.compression) != 2 ||
+			transport.compression[0] != want[0] || transport.compression[1] != want[1] {
+			t.Errorf("compress=%v: EnableWriteCompression calls = %v, want %v", compress, transport.compression, want)
+		}
+		if len(transport.written) != 2 {
+			t.Fatalf("compress=%v: wrote %d messages, want 2", compress, len(transport.written))
+		}
+
+		compressed, uncompressed := c.CompressionStats()
+		wantCompressed, wantUncompressed := int64(len(large)), int64(12)
+		if !compress {
+			wantCompressed, wantUncompressed = 0, int64(12+len(large))
+		}
+		if compressed != wantCompressed || uncompressed != wantUncompressed {
+			t.Errorf("compress=%v: stats = (%d, %d), want (%d, %d)", compress, compressed, uncompressed, wantCompressed, wantUncompressed)
+		}
+	}
+}
+
+func TestWritePumpSendsCloseReason(t *testing.T) {
+	reasons := []CloseReason{
+		CloseShutdown,
+		CloseServerError,
+		CloseProtocolViolations,
+		CloseKicked,
+		CloseBanned,
+		CloseSpaceFull,
+		CloseIdle,
+		CloseSlowConsumer,
+	}
+	for _, reason := range reasons {
+		transport := newFakeTransport()
+		h := NewHub()
+		c := NewClient(h, transport, false)
+
+		c.Disconnect(reason)
+		c.Disconnect(CloseServerError) // only the first reason is kept
+		<-h.Unregister
+
+		runWritePump(c)
+
+		if len(transport.closeFrames) != 1 {
+			t.Fatalf("%s: wrote %d close frames, want 1", reason.Text, len(transport.closeFrames))
+		}
+		frame := transport.closeFrames[0]
+		code := int(frame[0])<<8 | int(frame[1])
+		if code != reason.Code || string(frame[2:]) != reason.Text {
+			t.Errorf("close frame = (%d, %q), want (%d, %q)", code, frame[2:], reason.Code, reason.Text)
+		}
+	}
+}
+
+func TestWritePumpEmptyCloseWithoutReason(t *testing.T) {
+	transport := newFakeTransport()
+	c := NewClient(NewHub(), transport, false)
+
+	runWritePump(c)
+
+	if len(transport.closeFrames) != 1 || len(transport.closeFrames[0]) != 0 {
+		t.Errorf("close frames = %v, want one empty frame", transport.closeFrames)
+	}
+}
+
+// countingConn counts the bytes read from the underlying connection
+type countingConn struct {
+	net.Conn
+	read *atomic.Int64
+}
+
+func (c countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.read.Add(int64(n))
+	return n, err
+}
+
+// wireBytes sends msgs through a real WritePump and returns the bytes the client
+// read off the socket. It also checks a ping from the client gets its pong.
+func wireBytes(t *testing.T, compress bool, msgs []messages.BaseMessage) int64 {
+	t.Helper()
+	upgrader := websocket.Upgrader{EnableCompression: true}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		pinged := make(chan struct{})
+		conn.SetPingHandler(func(data string) error {
+			defer close(pinged)
+			return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+		})
+		c := NewClient(NewHub(), conn, compress)
+		go c.WritePump()
+		// Reading answers the client's ping; wait for it so the pong goes out first
+		go func() {
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+		select {
+		case <-pinged:
+		case <-time.After(5 * time.Second):
+			t.Error("server never got the client's ping")
+		}
+		for _, m := range msgs {
+			c.SendJSON(m)
+		}
+		c.closeSend()
+	}))
+	defer srv.Close()
+
+	var read atomic.Int64
+	dialer := websocket.Dialer{
+		EnableCompression: true,
+		NetDialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := (&net.Dialer{}).DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			return countingConn{Conn: conn, read: &read}, nil
+		},
+	}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ponged := make(chan struct{}, 1)
+	conn.SetPongHandler(func(string) error {
+		ponged <- struct{}{}
+		return nil
+	})
+	if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	received := 0
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNoStatusReceived, websocket.CloseNormalClosure) {
+				t.Fatalf("read: %v", err)
+			}
+			break
+		}
+		var msg testMessage
+		if err := json.Unmarshal(data, &msg); err != nil || msg.Type != msgs[received].Type {
+			t.Fatalf("message %d = %s, want a %s", received, data, msgs[received].Type)
+		}
+		received++
+	}
+	if received != len(msgs) {
+		t.Errorf("compress=%v: received %d messages, want %d", compress, received, len(msgs))
+	}
+	select {
+	case <-ponged:
+	default:
+		t.Errorf("compress=%v: ping was not answered", compress)
+	}
+	return read.Load()
+}
+
+func TestWriteCompressionOnMovement(t *testing.T) {
+	moves := make([]messages.BaseMessage, 200)
+	for i := range moves {
+		moves[i] = messages.BaseMessage{
+			Type: messages.TypeMovement,
+			Payload: messages.MovementPayload{
+				X:      float64(400 + i*3),
+				Y:      float64(300 + i%40),
+				UserID: "user-3f2a9c7e",
+				Anim:   "walk-right",
+			},
+		}
+	}
+
+	// Single moves are below minCompressSize: sent as is rather than inflated by deflate
+	plain := wireBytes(t, false, moves)
+	deflated := wireBytes(t, true, moves)
+	t.Logf("200 moves: %d bytes uncompressed, %d with compression on", plain, deflated)
+	if deflated > plain {
+		t.Errorf("compression on sent %d bytes, more than the uncompressed %d", deflated, plain)
+	}
+
+	// The same moves as one batch of positions (as in space-joined) shrink a lot
+	users := make([]messages.UserInfo, len(moves))
+	for i, m := range moves {
+		p := m.Payload.(messages.MovementPayload)
+		users[i] = messages.UserInfo{UserID: fmt.Sprintf("user-%08x", i*7919), X: p.X, Y: p.Y}
+	}
+	batch := []messages.BaseMessage{{
+		Type:    messages.TypeSpaceJoined,
+		Payload: messages.SpaceJoinedPayload{SessionID: "me", Users: users},
+	}}
+	plain = wireBytes(t, false, batch)
+	deflated = wireBytes(t, true, batch)
+	t.Logf("batch of 200 positions: %d bytes uncompressed, %d with compression on", plain, deflated)
+	if deflated >= plain/2 {
+		t.Errorf("compressed batch is %d bytes, want under half of %d", deflated, plain)
+	}
+}
+
+func TestSlowClientDisconnectedWithoutBlocking(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	mover := addTestClient(h, space, "mover", 100, 100)
+	slow := addTestClient(h, space, "slow", 120, 100)
+	slow.Send = make(chan []byte, 1)
+	slow.Send <- []byte("unread")
+	before := scrapeMetrics(t, h)["world_messages_dropped_total"]
+
+	done := make(chan struct{})
+	go func() {
+		h.handleMovement(mover, messages.IncomingPayload{X: 101, Y: 100})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("broadcast blocked on a full send buffer")
+	}
+
+	select {
+	case got := <-h.Unregister:
+		if got != slow {
+			t.Fatalf("unregistered %s, want slow", got.UserID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("slow client was not unregistered")
+	}
+	if frame := slow.closeMessage(); !bytes.Equal(frame, CloseSlowConsumer.frame()) {
+		t.Errorf("close frame = %q, want slow_consumer", frame)
+	}
+	if err := slow.SendJSON(messages.BaseMessage{Type: messages.TypeUserCount}); !errors.Is(err, ErrSendBufferFull) {
+		t.Errorf("SendJSON on full buffer = %v, want ErrSendBufferFull", err)
+	}
+	if got := scrapeMetrics(t, h)["world_messages_dropped_total"] - before; got < 1 {
+		t.Errorf("dropped messages delta = %v, want at least 1", got)
+	}
+}
+
+func TestPongLatencySmoothed(t *testing.T) {
+	c := NewClient(NewHub(), newFakeTransport(), false)
+	start := time.Now()
+
+	c.recordPong(start)
+	if got := c.Latency(); got != 0 {
+		t.Fatalf("latency after unsolicited pong = %v, want 0", got)
+	}
+
+	c.markPingSent(start)
+	c.recordPong(start.Add(100 * time.Millisecond))
+	if got := c.Latency(); got != 100*time.Millisecond {
+		t.Fatalf("first sample latency = %v, want 100ms", got)
+	}
+
+	// A faster round trip pulls the estimate an eighth of the way down
+	c.markPingSent(start.Add(time.Second))
+	c.recordPong(start.Add(time.Second + 20*time.Millisecond))
+	if got := c.Latency(); got != 90*time.Millisecond {
+		t.Errorf("smoothed latency = %v, want 90ms", got)
+	}
+}
+
+func TestPongHandlerRecordsLatency(t *testing.T) {
+	h := NewHub()
+	c := NewClient(h, newFakeTransport(), false)
+	before := scrapeMetrics(t, h)["world_pong_latency_seconds"]
+
+	const delay = 30 * time.Millisecond
+	c.markPingSent(time.Now())
+	time.Sleep(delay)
+	if err := c.handlePong(""); err != nil {
+		t.Fatalf("handlePong: %v", err)
+	}
+
+	if got := c.Latency(); got < delay || got > delay+500*time.Millisecond {
+		t.Errorf("latency = %v, want about %v", got, delay)
+	}
+	if got := scrapeMetrics(t, h)["world_pong_latency_seconds"] - before; got != 1 {
+		t.Errorf("latency samples delta = %v, want 1", got)
+	}
+}
+
+func TestUserLeftReason(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		readErr error
+		want    string
+	}{
+		// The read deadline pongs keep extending ran out
+		{"pong timeout", os.ErrDeadlineExceeded, messages.UserLeftTimeout},
+		{"clean close", nil, messages.UserLeftClosed},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			setupTestConfig(t)
+			h := NewHub()
+			space := newTestSpace(h, "s1")
+			stayer := addTestClient(h, space, "stayer", 100, 100)
+			leaver := addTestClient(h, space, "leaver", 600, 600)
+			transport := newFakeTransport()
+			transport.readErr = tc.readErr
+			leaver.Conn = transport
+
+			go leaver.ReadPump()
+			close(transport.reads)
+			select {
+			case got := <-h.Unregister:
+				h.handleDisconnect(got)
+			case <-time.After(time.Second):
+				t.Fatal("ReadPump did not unregister the client")
+			}
+
+			left := messagesOfType(drainMessages(t, stayer), messages.TypeUserLeft)
+			if len(left) != 1 {
+				t.Fatalf("got %d user-left messages, want 1", len(left))
+			}
+			var payload messages.UserLeftPayload
+			if err := json.Unmarshal(left[0].Payload, &payload); err != nil {
+				t.Fatal(err)
+			}
+			if payload.UserID != "leaver" || payload.Reason != tc.want {
+				t.Errorf("user-left = %+v, want reason %q", payload, tc.want)
+			}
+		})
+	}
+}
+
+func TestKickedUserLeftAsClosed(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	stayer := addTestClient(h, space, "stayer", 100, 100)
+	leaver := addTestClient(h, space, "leaver", 600, 600)
+
+	// Server-initiated: unregistered before the pumps see anything
+	leaver.Disconnect(CloseKicked)
+	h.handleDisconnect(<-h.Unregister)
+
+	left := messagesOfType(drainMessages(t, stayer), messages.TypeUserLeft)
+	if len(left) != 1 || !strings.Contains(string(left[0].Payload), `"reason":"closed"`) {
+		t.Errorf("user-left = %+v, want reason closed", left)
+	}
+}
+
+func TestOversizedMessageRejected(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.MaxMessageBytes = 64 })
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	c := addTestClient(h, space, "big", 100, 100)
+	transport := newFakeTransport()
+	c.Conn = transport
+
+	go c.ReadPump()
+	transport.reads <- []byte(`{"type":"chat","payload":{"text":"` + strings.Repeat("x", 100) + `"}}`)
+	// Sent after the oversized message, so it must be ignored
+	transport.reads <- []byte(`{"type":"movement","payload":{"x":110,"y":100}}`)
+
+	select {
+	case got := <-h.Unregister:
+		if got.closeReason != CloseMessageTooLarge {
+			t.Errorf("close reason = %v, want %v", got.closeReason, CloseMessageTooLarge)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("oversized message did not disconnect the client")
+	}
+	close(transport.reads)
+	<-h.Unregister // ReadPump exiting
+
+	errs := messagesOfType(drainMessages(t, c), messages.TypeMessageTooLarge)
+	if len(errs) != 1 {
+		t.Fatalf("got %d message-too-large errors, want 1", len(errs))
+	}
+	var payload messages.MessageTooLargePayload
+	if err := json.Unmarshal(errs[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Limit != 64 || payload.Size <= 64 {
+		t.Errorf("payload = %+v", payload)
+	}
+	if x, _ := c.GetPosition(); x != 100 {
+		t.Errorf("message after the oversized one moved the client to x=%v", x)
+	}
+
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+	if transport.readLimit != 64+oversizeGrace {
+		t.Errorf("read limit = %d, want %d", transport.readLimit, 64+oversizeGrace)
+	}
+}
+
+func TestMessageAtLimitAccepted(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	c := addTestClient(h, space, "fits", 100, 100)
+	move := []byte(`{"type":"movement","payload":{"x":110,"y":100}}`)
+	updateTestConfig(func(cfg *config.Config) { cfg.MaxMessageBytes = len(move) })
+	transport := newFakeTransport()
+	c.Conn = transport
+
+	go c.ReadPump()
+	transport.reads <- move
+	close(transport.reads)
+	got := <-h.Unregister
+
+	if got.closedByServer() {
+		t.Errorf("closed with %v for a message at the limit", got.closeReason)
+	}
+	if x, _ := c.GetPosition(); x != 110 {
+		t.Errorf("message at the limit was not processed: x=%v", x)
+	}
+}
+
+func TestReadLimitWithoutConfiguredLimit(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.MaxMessageBytes = 0 })
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	c := addTestClient(h, space, "big", 100, 100)
+	transport := newFakeTransport()
+	c.Conn = transport
+
+	go c.ReadPump()
+	transport.reads <- []byte(`{"type":"chat","payload":{"text":"` + strings.Repeat("x", config.DefaultMaxMessageBytes) + `"}}`)
+
+	select {
+	case got := <-h.Unregister:
+		if got.closeReason != CloseMessageTooLarge {
+			t.Errorf("close reason = %v, want %v", got.closeReason, CloseMessageTooLarge)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message over the default limit did not disconnect the client")
+	}
+	close(transport.reads)
+	<-h.Unregister // ReadPump exiting
+
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+	if want := int64(config.DefaultMaxMessageBytes + oversizeGrace); transport.readLimit != want {
+		t.Errorf("read limit = %d, want %d", transport.readLimit, want)
+	}
+}
+
+func TestDefaultLimitFitsSignalingAndChat(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	c := addTestClient(h, space, "a", 100, 100)
+	peer := addTestClient(h, space, "b", 150, 100)
+	startTestMeeting(space, "a", "b")
+	transport := newFakeTransport()
+	c.Conn = transport
+
+	data, _ := json.Marshal(map[string]string{"type": "offer", "sdp": testSDPOffer()})
+	offer, _ := json.Marshal(messages.BaseMessage{
+		Type:    messages.TypeSignal,
+		Payload: map[string]interface{}{"targetUserId": "b", "signalType": "offer", "data": json.RawMessage(data)},
+	})
+	text := strings.Repeat("🎉", 500)
+	chat, _ := json.Marshal(messages.BaseMessage{
+		Type:    messages.TypeChat,
+		Payload: map[string]string{"text": text},
+	})
+
+	go c.ReadPump()
+	transport.reads <- offer
+	transport.reads <- chat
+	close(transport.reads)
+	got := <-h.Unregister
+
+	if got.closedByServer() {
+		t.Fatalf("closed with %v for a %d-byte offer or a %d-byte chat", got.closeReason, len(offer), len(chat))
+	}
+	if len(messagesOfType(drainMessages(t, peer), messages.TypeSignal)) != 1 {
+		t.Error("offer was not relayed")
+	}
+	chats := messagesOfType(drainMessages(t, c), messages.TypeChat)
+	if len(chats) != 1 {
+		t.Fatalf("got %d chat messages, want 1", len(chats))
+	}
+	var payload messages.ChatPayload
+	if err := json.Unmarshal(chats[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Text != text {
+		t.Errorf("chat text has %d runes, want 500", len([]rune(payload.Text)))
+	}
+}
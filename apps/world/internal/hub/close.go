@@ -0,0 +1,38 @@
+package hub
+
+import "github.com/gorilla/websocket"
+
+// CloseReason is the code and text sent in the close frame when the server drops a client.
+// Application-specific reasons use the 4000-4999 range reserved for private use.
+type CloseReason struct {
+	Code int
+	Text string
+}
+
+// Close reasons for server-initiated disconnects
+var (
+	CloseShutdown           = CloseReason{websocket.CloseGoingAway, "server_shutdown"}
+	CloseServerError        = CloseReason{websocket.CloseInternalServerErr, "server_error"}
+	CloseProtocolViolations = CloseReason{websocket.ClosePolicyViolation, "protocol_violations"}
+	CloseMessageTooLarge    = CloseReason{websocket.CloseMessageTooBig, "message_too_large"}
+	CloseKicked             = CloseReason{4001, "kicked"}
+	CloseBanned             = CloseReason{4003, "banned"}
+	CloseSpaceFull          = CloseReason{4004, "space_full"}
+	CloseIdle               = CloseReason{4008, "idle_timeout"}
+	CloseSlowConsumer       = CloseReason{4009, "slow_consumer"}
+	CloseSessionExpired     = CloseReason{4010, "session_expired"}
+	CloseJoinTimeout        = CloseReason{4011, "join_timeout"}
+)
+
+// IsZero reports whether no reason was recorded
+func (r CloseReason) IsZero() bool {
+	return r.Code == 0
+}
+
+// frame formats the close frame payload, empty when no reason was recorded
+func (r CloseReason) frame() []byte {
+	if r.IsZero() {
+		return []byte{}
+	}
+	return websocket.FormatCloseMessage(r.Code, r.Text)
+}
@@ -0,0 +1,166 @@
+package hub
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventType names a world event published on the EventBus
+type EventType string
+
+const (
+	EventUserJoined       EventType = "user_joined"
+	EventUserLeft         EventType = "user_left"
+	EventUserMoved        EventType = "user_moved"
+	EventMeetingStarted   EventType = "meeting_started"
+	EventMeetingEnded     EventType = "meeting_ended"
+	EventProximityChanged EventType = "proximity_changed"
+)
+
+// Event is something that happened in the world. Fields that don't apply to
+// the event type are left empty.
+type Event struct {
+	Type    EventType
+	SpaceID string
+	UserID  string
+	// PeerID is the other user for meeting and proximity events
+	PeerID string
+	// Participants lists everyone in a meeting, for meeting events
+	Participants []string
+	MeetingID    string
+	// Media and Change ("enter"/"leave") describe proximity events
+	Media  string
+	Change string
+	// Reason says why a meeting ended
+	Reason string
+	X, Y   float64
+	At     time.Time
+}
+
+// defaultEventBuffer is the per-subscriber queue length when Subscribe is given none
+const defaultEventBuffer = 256
+
+// EventBus fans world events out to external integrations (analytics, moderation,
+// media bridges). Each subscriber has its own buffered queue and goroutine, so a
+// slow subscriber only drops its own events and never blocks the publisher.
+// A nil *EventBus is a valid no-op bus.
+type EventBus struct {
+	mu   sync.RWMutex
+	subs []*Subscription
+}
+
+// Subscription is a registered subscriber
+type Subscription struct {
+	bus     *EventBus
+	events  chan Event
+	dropped atomic.Int64
+	once    sync.Once
+}
+
+// NewEventBus creates a bus with no subscribers
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe calls fn for every published event, in order, on a dedicated goroutine.
+// buffer is how many events may queue up before new ones are dropped for this subscriber.
+func (b *EventBus) Subscribe(fn func(Event), buffer int) *Subscription {
+	sub := newSubscription(b, buffer)
+
+	go func() {
+		for event := range sub.events {
+			fn(event)
+		}
+	}()
+
+	b.add(sub)
+	return sub
+}
+
+// SubscribeBatch is Subscribe for subscribers that handle events in bulk: fn gets each
+// event together with whatever has queued up behind it, in order, so a slow fn sees
+// fewer, larger batches instead of falling behind.
+func (b *EventBus) SubscribeBatch(fn func([]Event), buffer int) *Subscription {
+	sub := newSubscription(b, buffer)
+
+	go func() {
+		for event := range sub.events {
+			batch := []Event{event}
+		drain:
+			for len(batch) < cap(sub.events) {
+				select {
+				case next, ok := <-sub.events:
+					if !ok {
+						break drain
+					}
+					batch = append(batch, next)
+				default:
+					break drain
+				}
+			}
+			fn(batch)
+		}
+	}()
+
+	b.add(sub)
+	return sub
+}
+
+// newSubscription creates an unregistered subscriber queue of buffer events
+func newSubscription(b *EventBus, buffer int) *Subscription {
+	if buffer <= 0 {
+		buffer = defaultEventBuffer
+	}
+	return &Subscription{bus: b, events: make(chan Event, buffer)}
+}
+
+// add registers sub for published events
+func (b *EventBus) add(sub *Subscription) {
+	b.mu.Lock()
+	b.subs = append(b.subs, sub)
+	b.mu.Unlock()
+}
+
+// Publish queues event for every subscriber without blocking
+func (b *EventBus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, sub := range b.subs {
+		select {
+		case sub.events <- event:
+		default:
+			if sub.dropped.Add(1) == 1 {
+				log.Printf("EventBus: subscriber is falling behind, dropping %s events", event.Type)
+			}
+		}
+	}
+}
+
+// Unsubscribe stops delivery; events already queued are still handled
+func (s *Subscription) Unsubscribe() {
+	s.once.Do(func() {
+		s.bus.mu.Lock()
+		for i, sub := range s.bus.subs {
+			if sub == s {
+				s.bus.subs = append(s.bus.subs[:i], s.bus.subs[i+1:]...)
+				break
+			}
+		}
+		s.bus.mu.Unlock()
+		close(s.events)
+	})
+}
+
+// Dropped returns how many events were dropped because this subscriber's queue was full
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
@@ -0,0 +1,177 @@
+package hub
+
+import (
+	"testing"
+	"time"
+
+	"world/internal/messages"
+)
+
+// collect subscribes to bus and returns a channel receiving every event
+func collect(bus *EventBus) (<-chan Event, *Subscription) {
+	out := make(chan Event, 64)
+	sub := bus.Subscribe(func(e Event) { out <- e }, 64)
+	return out, sub
+}
+
+// nextEvent waits for the next event from ch
+func nextEvent(t *testing.T, ch <-chan Event) Event {
+	t.Helper()
+	select {
+	case e := <-ch:
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for an event")
+		return Event{}
+	}
+}
+
+func TestEventBusDelivery(t *testing.T) {
+	bus := NewEventBus()
+	a, subA := collect(bus)
+	b, _ := collect(bus)
+
+	bus.Publish(Event{Type: EventUserJoined, UserID: "u1"})
+	bus.Publish(Event{Type: EventUserLeft, UserID: "u1"})
+
+	for _, ch := range []<-chan Event{a, b} {
+		if e := nextEvent(t, ch); e.Type != EventUserJoined || e.At.IsZero() {
+			t.Errorf("first event = %+v, want a timestamped user_joined", e)
+		}
+		if e := nextEvent(t, ch); e.Type != EventUserLeft {
+			t.Errorf("second event = %+v, want user_left", e)
+		}
+	}
+
+	// Unsubscribed subscribers get nothing more
+	subA.Unsubscribe()
+	subA.Unsubscribe()
+	bus.Publish(Event{Type: EventUserMoved})
+	if e := nextEvent(t, b); e.Type != EventUserMoved {
+		t.Errorf("remaining subscriber got %+v", e)
+	}
+	select {
+	case e := <-a:
+		t.Errorf("unsubscribed subscriber got %+v", e)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	var nilBus *EventBus
+	nilBus.Publish(Event{Type: EventUserMoved}) // no-op, must not panic
+}
+
+func TestEventBusSlowSubscriberIsolated(t *testing.T) {
+	bus := NewEventBus()
+
+	gate := make(chan struct{})
+	defer close(gate)
+	slow := bus.Subscribe(func(Event) { <-gate }, 1)
+	fast, _ := collect(bus)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			bus.Publish(Event{Type: EventUserMoved, X: float64(i)})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish blocked on a slow subscriber")
+	}
+
+	for i := 0; i < 10; i++ {
+		if e := nextEvent(t, fast); e.X != float64(i) {
+			t.Fatalf("fast subscriber event %d = %+v", i, e)
+		}
+	}
+	if slow.Dropped() == 0 {
+		t.Error("slow subscriber dropped nothing")
+	}
+}
+
+func TestEventBusSubscribeBatch(t *testing.T) {
+	bus := NewEventBus()
+	batches := make(chan []Event, 4)
+	gate := make(chan struct{})
+	sub := bus.SubscribeBatch(func(batch []Event) {
+		batches <- batch
+		<-gate
+	}, 16)
+	defer sub.Unsubscribe()
+
+	bus.Publish(Event{Type: EventUserMoved, X: 0})
+	var first []Event
+	select {
+	case first = <-batches:
+	case <-time.After(time.Second):
+		t.Fatal("no batch delivered")
+	}
+	// These queue up while the first batch is being handled
+	for i := 1; i <= 4; i++ {
+		bus.Publish(Event{Type: EventUserMoved, X: float64(i)})
+	}
+	close(gate)
+
+	var second []Event
+	select {
+	case second = <-batches:
+	case <-time.After(time.Second):
+		t.Fatal("queued events were not delivered")
+	}
+	if len(first) != 1 || first[0].X != 0 {
+		t.Errorf("first batch = %+v, want the one event", first)
+	}
+	if len(second) != 4 {
+		t.Fatalf("second batch has %d events, want the 4 that queued", len(second))
+	}
+	for i, e := range second {
+		if e.X != float64(i+1) {
+			t.Errorf("second batch out of order: %+v", second)
+			break
+		}
+	}
+}
+
+func TestHubPublishesEvents(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	events, _ := collect(h.Events)
+
+	c := joinTestClient(t, h, "s1", "u1")
+	if e := nextEvent(t, events); e.Type != EventUserJoined || e.SpaceID != "s1" || e.UserID != "u1" {
+		t.Errorf("join event = %+v", e)
+	}
+
+	x, y := c.GetPosition()
+	h.handleMovement(c, messages.IncomingPayload{X: x + 5, Y: y})
+	if e := nextEvent(t, events); e.Type != EventUserMoved || e.X != x+5 {
+		t.Errorf("move event = %+v", e)
+	}
+
+	h.handleDisconnect(c)
+	if e := nextEvent(t, events); e.Type != EventUserLeft || e.UserID != "u1" {
+		t.Errorf("leave event = %+v", e)
+	}
+}
+
+func TestSpacePublishesMeetingEvents(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	space.Events = h.Events
+	events, _ := collect(h.Events)
+
+	a, b, state := promptPair(t, h, space)
+	h.handleMeetingResponse(a, messages.IncomingPayload{PeerID: "b", RequestID: state.RequestID, Accept: true})
+	h.handleMeetingResponse(b, messages.IncomingPayload{PeerID: "a", RequestID: state.RequestID, Accept: true})
+	if e := nextEvent(t, events); e.Type != EventMeetingStarted || e.MeetingID != state.MeetingID {
+		t.Errorf("start event = %+v", e)
+	}
+
+	h.handleMeetingEnd(a, messages.IncomingPayload{PeerID: "b"})
+	if e := nextEvent(t, events); e.Type != EventMeetingEnded || e.Reason != MeetingEndUserEnded {
+		t.Errorf("end event = %+v", e)
+	}
+}
@@ -0,0 +1,93 @@
+package hub
+
+import (
+	"math"
+	"sync"
+)
+
+// defaultGridCell is the spatial grid cell size, about the default audio radius
+const defaultGridCell = 300
+
+// gridCell identifies one cell of a spatialGrid
+type gridCell struct {
+	X, Y int
+}
+
+// spatialGrid buckets a space's users into square cells so proximity only has to look
+// at nearby cells. It has its own lock so clients can reindex themselves on every
+// position change, even while the space lock is held. Lock order: space, grid, client.
+type spatialGrid struct {
+	mu    sync.Mutex
+	size  float64
+	cells map[gridCell]map[string]*Client
+	// at records the cell each user is indexed in
+	at map[string]gridCell
+}
+
+func newSpatialGrid(size float64) *spatialGrid {
+	return &spatialGrid{
+		size:  size,
+		cells: make(map[gridCell]map[string]*Client),
+		at:    make(map[string]gridCell),
+	}
+}
+
+func (g *spatialGrid) cellOf(x, y float64) gridCell {
+	return gridCell{int(math.Floor(x / g.size)), int(math.Floor(y / g.size))}
+}
+
+// update indexes client at its current position
+func (g *spatialGrid) update(client *Client) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	cell := g.cellOf(client.GetPosition())
+	if old, ok := g.at[client.UserID]; ok {
+		if old == cell && g.cells[old][client.UserID] == client {
+			return
+		}
+		g.removeLocked(client.UserID, old)
+	}
+	users, ok := g.cells[cell]
+	if !ok {
+		users = make(map[string]*Client)
+		g.cells[cell] = users
+	}
+	users[client.UserID] = client
+	g.at[client.UserID] = cell
+}
+
+// remove drops userID from the grid
+func (g *spatialGrid) remove(userID string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if cell, ok := g.at[userID]; ok {
+		g.removeLocked(userID, cell)
+	}
+}
+
+func (g *spatialGrid) removeLocked(userID string, cell gridCell) {
+	delete(g.cells[cell], userID)
+	if len(g.cells[cell]) == 0 {
+		delete(g.cells, cell)
+	}
+	delete(g.at, userID)
+}
+
+// near returns the users in every cell within radius of (x, y). It may include
+// users slightly further away; callers still check the exact distance.
+func (g *spatialGrid) near(x, y, radius float64) map[string]*Client {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	out := make(map[string]*Client)
+	min, max := g.cellOf(x-radius, y-radius), g.cellOf(x+radius, y+radius)
+	for cx := min.X; cx <= max.X; cx++ {
+		for cy := min.Y; cy <= max.Y; cy++ {
+			for userID, client := range g.cells[gridCell{cx, cy}] {
+				out[userID] = client
+			}
+		}
+	}
+	return out
+}
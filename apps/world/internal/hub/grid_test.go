@@ -0,0 +1,110 @@
+package hub
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+
+	"world/internal/config"
+)
+
+// eventKeys renders events as sorted strings so runs can be compared regardless of order
+func eventKeys(events []ProximityEvent) []string {
+	keys := make([]string, 0, len(events))
+	for _, e := range events {
+		keys = append(keys, fmt.Sprintf("%s:%s:%s:%s", e.Type, e.UserA, e.UserB, e.Media))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestGridProximityMatchesFullScan(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) {
+		cfg.ExtraMedia = []config.ProximityMedia{{Name: "whisper", Radius: 40}, {Name: "presence", Radius: 700}}
+	})
+	log.SetOutput(io.Discard) // video dwell logs every pair
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	h := NewHub()
+	rng := rand.New(rand.NewSource(1))
+	randomSpot := func() (float64, float64) {
+		return float64(rng.Intn(1280)), float64(rng.Intn(960))
+	}
+
+	gridded := newTestSpace(h, "grid")
+	scanned := newTestSpace(h, "scan")
+	scanned.grid = nil
+
+	const users = 500
+	type pair struct{ grid, scan *Client }
+	clients := make([]pair, users)
+	for i := range clients {
+		id := fmt.Sprintf("u%d", i)
+		x, y := randomSpot()
+		clients[i] = pair{addTestClient(h, gridded, id, x, y), addTestClient(h, scanned, id, x, y)}
+	}
+
+	update := func(space *Space, c *Client) []string {
+		return eventKeys(space.updateProximityAllMedia(c))
+	}
+	for i, p := range clients {
+		if got, want := update(gridded, p.grid), update(scanned, p.scan); !reflect.DeepEqual(got, want) {
+			t.Fatalf("initial pass for user %d: grid %v, scan %v", i, got, want)
+		}
+	}
+
+	// Random walks and long jumps, so users both drift between cells and leave their
+	// neighborhoods entirely
+	for step := 0; step < 500; step++ {
+		p := clients[rng.Intn(users)]
+		x, y := p.grid.GetPosition()
+		if rng.Intn(4) == 0 {
+			x, y = randomSpot()
+		} else {
+			x = min(1279, max(0, x+float64(rng.Intn(201)-100)))
+			y = min(959, max(0, y+float64(rng.Intn(201)-100)))
+		}
+		p.grid.SetPosition(x, y)
+		p.scan.SetPosition(x, y)
+
+		if got, want := update(gridded, p.grid), update(scanned, p.scan); !reflect.DeepEqual(got, want) {
+			t.Fatalf("step %d (%s to %v,%v): grid %v, scan %v", step, p.grid.UserID, x, y, got, want)
+		}
+	}
+
+	if !reflect.DeepEqual(gridded.Proximity, scanned.Proximity) {
+		t.Error("proximity maps diverged")
+	}
+	if len(gridded.VideoDwellStart) != len(scanned.VideoDwellStart) {
+		t.Errorf("video dwells: grid %d, scan %d", len(gridded.VideoDwellStart), len(scanned.VideoDwellStart))
+	}
+}
+
+func TestGridTracksJoinsMovesAndLeaves(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 10, 10)
+	b := addTestClient(h, space, "b", 1000, 900)
+
+	if got := space.grid.near(10, 10, 50); len(got) != 1 || got["a"] != a {
+		t.Errorf("near a = %v", got)
+	}
+
+	b.SetPosition(20, 20)
+	if got := space.grid.near(10, 10, 50); len(got) != 2 {
+		t.Errorf("b not reindexed after moving: %v", got)
+	}
+
+	space.RemoveUserAndCollectProximityLeaves(b)
+	b.SetPosition(30, 30) // a removed client no longer touches the grid
+	if got := space.grid.near(10, 10, 50); len(got) != 1 {
+		t.Errorf("b still indexed after leaving: %v", got)
+	}
+}
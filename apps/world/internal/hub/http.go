@@ -0,0 +1,126 @@
+package hub
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"world/internal/auth"
+	"world/internal/config"
+	"world/internal/messages"
+
+	"github.com/gorilla/mux"
+)
+
+// WorldSecretHeader carries WORLD_SERVER_SECRET on requests between the world server and the backend
+const WorldSecretHeader = "X-World-Server-Secret"
+
+// writeJSON writes v as a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// ServeAllowedSpaces lists the spaces the bearer of the Authorization token may join,
+// so the lobby can hide rooms the user would be rejected from
+func (h *Hub) ServeAllowedSpaces(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.ValidateToken(r.Header.Get("Authorization"))
+	if errors.Is(err, auth.ErrSecretNotConfigured) {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "server is misconfigured"})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+		return
+	}
+
+	spaces, err := h.AllowedSpaces(claims.UserID)
+	if err != nil {
+		log.Printf("Allowed spaces lookup failed for %s: %v", claims.UserID, err)
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": "space lookup failed"})
+		return
+	}
+	writeJSON(w, http.StatusOK, messages.AllowedSpacesPayload{Spaces: spaces})
+}
+
+// authorize validates the bearer token and checks its role may perform action,
+// writing the error response and returning false if not
+func authorize(w http.ResponseWriter, r *http.Request, action string) (*auth.Claims, bool) {
+	claims, err := auth.ValidateToken(r.Header.Get("Authorization"))
+	if errors.Is(err, auth.ErrSecretNotConfigured) {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "server is misconfigured"})
+		return nil, false
+	}
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+		return nil, false
+	}
+	if !Can(claims.Role, action) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+		return nil, false
+	}
+	return claims, true
+}
+
+// ServeUserPosition is the admin API for a user's position in a space. GET returns it;
+// PUT with {"x":..,"y":..} teleports the user there if the spot is in bounds and free.
+func (h *Hub) ServeUserPosition(w http.ResponseWriter, r *http.Request) {
+	claims, ok := authorize(w, r, PermissionAdminPosition)
+	if !ok {
+		return
+	}
+	vars := mux.Vars(r)
+	spaceID, userID := vars["spaceId"], vars["userId"]
+
+	if r.Method == http.MethodPut {
+		var target messages.Position
+		if err := json.NewDecoder(r.Body).Decode(&target); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid position body"})
+			return
+		}
+		err := h.SetUserPosition(spaceID, userID, target.X, target.Y)
+		switch {
+		case errors.Is(err, ErrUserNotFound):
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		case errors.Is(err, ErrInvalidPosition):
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+			return
+		}
+		log.Printf("Position of %s in %s set by %s", userID, spaceID, claims.UserID)
+	}
+
+	x, y, err := h.UserPosition(spaceID, userID)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, messages.Position{X: x, Y: y})
+}
+
+// requireWorldSecret checks the request carries WORLD_SERVER_SECRET, writing the error
+// response and returning false if not. Without a configured secret nothing is allowed.
+func requireWorldSecret(w http.ResponseWriter, r *http.Request) bool {
+	secret := config.Current().WorldServerSecret
+	if secret == "" {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "server is misconfigured"})
+		return false
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get(WorldSecretHeader)), []byte(secret)) != 1 {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid secret"})
+		return false
+	}
+	return true
+}
+
+// ServeSpaces lists the active spaces with their occupancy and active meetings for
+// operators. ?detail=1 adds each space's user IDs. Requires WorldSecretHeader.
+func (h *Hub) ServeSpaces(w http.ResponseWriter, r *http.Request) {
+	if !requireWorldSecret(w, r) {
+		return
+	}
+	writeJSON(w, http.StatusOK, h.SpaceStats(r.URL.Query().Get("detail") == "1"))
+}
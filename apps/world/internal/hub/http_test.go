@@ -0,0 +1,77 @@
+package hub
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"world/internal/config"
+	"world/internal/messages"
+)
+
+// getSpaces calls ServeSpaces with the given secret header and query
+func getSpaces(h *Hub, secret, query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/spaces"+query, nil)
+	if secret != "" {
+		req.Header.Set(WorldSecretHeader, secret)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeSpaces(rec, req)
+	return rec
+}
+
+func TestServeSpaces(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.WorldServerSecret = "ops-secret" })
+
+	h := NewHub()
+	lobby := newTestSpace(h, "lobby")
+	addTestClient(h, lobby, "b", 100, 100)
+	addTestClient(h, lobby, "a", 200, 100)
+	startTestMeeting(lobby, "a", "b")
+	addTestClient(h, newTestSpace(h, "arena"), "c", 100, 100)
+
+	rec := getSpaces(h, "ops-secret", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var stats []messages.SpaceStats
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	want := []messages.SpaceStats{
+		{SpaceID: "arena", UserCount: 1},
+		{SpaceID: "lobby", UserCount: 2, ActiveMeetings: 1},
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+
+	rec = getSpaces(h, "ops-secret", "?detail=1")
+	stats = nil
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 2 || !reflect.DeepEqual(stats[1].UserIDs, []string{"a", "b"}) {
+		t.Errorf("detailed stats = %+v", stats)
+	}
+}
+
+func TestServeSpacesRequiresSecret(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	newTestSpace(h, "lobby")
+
+	if rec := getSpaces(h, "anything", ""); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("unconfigured secret: status = %d, want 503", rec.Code)
+	}
+
+	updateTestConfig(func(cfg *config.Config) { cfg.WorldServerSecret = "ops-secret" })
+	for _, secret := range []string{"", "wrong"} {
+		if rec := getSpaces(h, secret, ""); rec.Code != http.StatusUnauthorized {
+			t.Errorf("secret %q: status = %d, want 401", secret, rec.Code)
+		}
+	}
+}
@@ -2,8 +2,11 @@ package hub
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
+	"math"
 	"math/rand"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,6 +15,9 @@ import (
 	"world/internal/messages"
 )
 
+// misconfiguredOnce ensures the missing-secret error is only logged once
+var misconfiguredOnce sync.Once
+
 // Hub maintains the set of active clients and broadcasts messages
 // It serves as the central coordinator for the fan-in/fan-out pattern
 type Hub struct {
@@ -28,6 +34,38 @@ type Hub struct {
 	Unregister chan *Client
 
 	mu sync.RWMutex
+
+	// reloadMu serializes proximity radius reloads
+	reloadMu sync.Mutex
+
+	// lastJoin records when each user last joined a space (guarded by mu)
+	lastJoin map[string]time.Time
+
+	// Store decides space membership; nil means every space is public
+	Store SpaceStore
+
+	// Reports receives user reports for moderation
+	Reports ReportSink
+
+	// Bans holds per-space bans, checked on join
+	Bans BanList
+
+	// Events publishes world events to external subscribers
+	Events *EventBus
+
+	// reportWindows rate-limits reports per user (guarded by mu)
+	reportWindows map[string]*reportWindow
+
+	// pendingReconnect holds dropped users waiting out the reconnect grace (guarded by mu)
+	pendingReconnect map[string]*suspendedSession
+
+	// SpawnRand picks random spawn offsets (guarded by mu). Set it before the first join
+	// to make spawns reproducible; nil is replaced with one seeded from SPAWN_SEED.
+	SpawnRand *rand.Rand
+
+	// done is closed by Shutdown to stop Run and the dwell checker
+	done         chan struct{}
+	shutdownOnce sync.Once
 }
 
 // NewHub creates a new Hub instance
@@ -37,6 +75,14 @@ func NewHub() *Hub {
 		Clients:    make(map[*Client]bool),
 		Register:   make(chan *Client),
 		Unregister: make(chan *Client),
+		lastJoin:   make(map[string]time.Time),
+		Reports:    nopReportSink{},
+		Bans:       NewMemoryBanList(),
+		Events:     NewEventBus(),
+
+		reportWindows:    make(map[string]*reportWindow),
+		pendingReconnect: make(map[string]*suspendedSession),
+		done:             make(chan struct{}),
 	}
 }
 
@@ -47,11 +93,11 @@ func (h *Hub) Run() {
 
 	for {
 		select {
+		case <-h.done:
+			return
+
 		case client := <-h.Register:
-			h.mu.Lock()
-			h.Clients[client] = true
-			h.mu.Unlock()
-			log.Printf("Client connected, total clients: %d", len(h.Clients))
+			h.registerClient(client, time.Now())
 
 		case client := <-h.Unregister:
 			h.handleDisconnect(client)
@@ -59,12 +105,32 @@ func (h *Hub) Run() {
 	}
 }
 
+// registerClient adds a new connection to the hub. It lands in the lobby, where it has
+// JoinTimeout to join a space.
+func (h *Hub) registerClient(client *Client, now time.Time) {
+	h.mu.Lock()
+	h.Clients[client] = true
+	client.registeredAt = now
+	total := len(h.Clients)
+	h.mu.Unlock()
+	log.Printf("Client connected, total clients: %d", total)
+
+	// New connections land in the lobby until they join a space
+	h.sendSpaceList(client)
+}
+
 // runDwellTimerChecker periodically checks all spaces for expired dwell timers
 func (h *Hub) runDwellTimerChecker() {
 	ticker := time.NewTicker(500 * time.Millisecond)
 	defer ticker.Stop()
 
-	for range ticker.C {
+	for {
+		select {
+		case <-h.done:
+			return
+		case <-ticker.C:
+		}
+
 		h.mu.RLock()
 		spaces := make([]*Space, 0, len(h.Spaces))
 		for _, space := range h.Spaces {
@@ -75,56 +141,213 @@ func (h *Hub) runDwellTimerChecker() {
 		for _, space := range spaces {
 			// Now calls the updated method which handles Meeting Prompt emission directly
 			space.CheckVideoDwellTimers()
+			space.CheckActiveMeetingProximity(time.Now())
+			h.handleProximityEvents(space.FlushDeferredProximityEvents())
+			h.handleProximityEvents(space.ShedProximityEvents(space.CheckPendingEnters()))
+			h.handleProximityEvents(space.ShedProximityEvents(space.ProximityStrategy.Tick(space)))
+			h.resolveOverlaps(space)
+		}
+
+		h.reapIdleClients(time.Now())
+		h.reapUnjoinedClients(time.Now())
+		h.markIdleClients(time.Now())
+		h.checkSessionExpiry(time.Now())
+		h.clearStaleTyping(time.Now())
+		h.expireSuspendedSessions(time.Now())
+	}
+}
+
+// reapIdleClients disconnects joined clients that have sent no application message
+// within AppIdleTimeout. Pongs keep the socket alive but don't count as activity.
+func (h *Hub) reapIdleClients(now time.Time) {
+	timeout := config.Current().AppIdleTimeout
+	if timeout <= 0 {
+		return
+	}
+
+	h.mu.RLock()
+	idle := make([]*Client, 0)
+	for client := range h.Clients {
+		if client.SpaceID != "" && client.idleFor(now) >= timeout {
+			idle = append(idle, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, client := range idle {
+		log.Printf("Disconnecting %s: no application messages for %v", client.UserID, timeout)
+		client.Disconnect(CloseIdle)
+	}
+}
+
+// reapUnjoinedClients disconnects connections that haven't joined a space within
+// JoinTimeout of registering, telling them with join-timeout first. Clients that have
+// joined once are exempt for good.
+func (h *Hub) reapUnjoinedClients(now time.Time) {
+	timeout := config.Current().JoinTimeout
+	if timeout <= 0 {
+		return
+	}
+
+	h.mu.RLock()
+	stuck := make([]*Client, 0)
+	for client := range h.Clients {
+		if !client.joined && !client.registeredAt.IsZero() && now.Sub(client.registeredAt) >= timeout {
+			stuck = append(stuck, client)
 		}
 	}
+	h.mu.RUnlock()
+
+	for _, client := range stuck {
+		log.Printf("Disconnecting client: no join within %v of connecting", timeout)
+		client.SendJSON(messages.BaseMessage{Type: messages.TypeJoinTimeout})
+		client.Disconnect(CloseJoinTimeout)
+	}
 }
 
-// handleDisconnect handles client disconnection
+// handleDisconnect handles client disconnection. It runs as a pipeline of steps, each
+// taking at most one lock at a time:
+//
+//  1. detachClient: drop the client from the hub (h.mu)
+//  2. suspendSession: within the reconnect grace, keep the user in the space and
+//     skip to 7; removeFromSpace runs later if they don't come back (h.mu)
+//  3. RemoveUserAndCollectProximityLeaves: leave the space, ending meetings (space.mu)
+//  4. emit the collected proximity leaves
+//  5. announceDeparture: user-left, user-count and hand queue to those remaining
+//  6. removeSpaceIfEmpty (h.mu)
+//  7. closeSend: only now close Send, so the client still gets anything sent while
+//     it was leaving and WritePump ends with the close frame
 func (h *Hub) handleDisconnect(client *Client) {
+	// Server-initiated disconnects get here before the pumps have seen an error
+	client.recordDrop(nil)
+	space, registered := h.detachClient(client)
+
+	if space != nil {
+		if !registered || !h.suspendSession(space, client, time.Now()) {
+			h.removeFromSpace(space, client)
+		}
+	}
+
+	if registered {
+		client.closeSend()
+	}
+	log.Printf("Client %s disconnected", client.UserID)
+}
+
+// removeFromSpace takes client out of space for good, telling those remaining
+func (h *Hub) removeFromSpace(space *Space, client *Client) {
+	if removed, proximityEvents := space.RemoveUserAndCollectProximityLeaves(client); removed {
+		h.finishRemoval(space, client, proximityEvents)
+	}
+}
+
+// finishRemoval tells those remaining in space that client, already removed from its
+// users, has gone, and drops the space if it's now empty
+func (h *Hub) finishRemoval(space *Space, client *Client, proximityEvents []ProximityEvent) {
+	h.handleProximityEvents(space.ShedProximityEvents(proximityEvents))
+	h.announceDeparture(space, client.UserID, client.leaveReason())
+	h.endFollows(space, client.UserID)
+	leavesTotal.Inc()
+	h.Events.Publish(Event{Type: EventUserLeft, SpaceID: space.ID, UserID: client.UserID})
+	h.removeSpaceIfEmpty(space)
+}
+
+// detachClient removes client from the hub's client set and returns the space it was in.
+// registered is false if the client had already been detached.
+func (h *Hub) detachClient(client *Client) (space *Space, registered bool) {
 	h.mu.Lock()
+	defer h.mu.Unlock()
 
-	if _, ok := h.Clients[client]; ok {
+	if _, registered = h.Clients[client]; registered {
 		delete(h.Clients, client)
-		close(client.Send)
 	}
+	if client.SpaceID != "" {
+		space = h.Spaces[client.SpaceID]
+	}
+	return space, registered
+}
 
-	spaceID := client.SpaceID
-	userID := client.UserID
-	var space *Space
-	if spaceID != "" {
-		space = h.Spaces[spaceID]
+// announceDeparture tells the users remaining in space that userID has left, and why
+// if they disconnected
+func (h *Hub) announceDeparture(space *Space, userID, reason string) {
+	leaveMsg := messages.BaseMessage{
+		Type: messages.TypeUserLeft,
+		Payload: messages.UserLeftPayload{
+			UserID: userID,
+			Reason: reason,
+		},
 	}
-	h.mu.Unlock()
+	for _, recipient := range space.GetUsers(userID) {
+		recipient.SendJSON(leaveMsg)
+	}
+	h.broadcastUserCount(space)
+	if space.LowerHand(userID) {
+		h.broadcastHandQueue(space)
+	}
+}
 
-	if space != nil {
-		// If client was in a space, remove them and notify others
-		removed, proximityEvents := space.RemoveUserAndCollectProximityLeaves(client)
-
-		if removed {
-			h.handleProximityEvents(proximityEvents)
-			
-			// Broadcast user-left to remaining users
-			leaveMsg := messages.BaseMessage{
-				Type: messages.TypeUserLeft,
-				Payload: messages.UserLeftPayload{
-					UserID: userID,
-				},
-			}
-			h.broadcastToSpace(spaceID, leaveMsg, userID)
-
-			// Clean up empty spaces
-			if space.IsEmpty() {
-				h.mu.Lock()
-				// Double check existence under lock
-				if _, ok := h.Spaces[spaceID]; ok && space.IsEmpty() {
-					delete(h.Spaces, spaceID)
-					log.Printf("Space %s removed (empty)", spaceID)
-				}
-				h.mu.Unlock()
-			}
+// removeSpaceIfEmpty drops space from the hub once its last user has left.
+// Joins add users while holding h.mu, so emptiness can't change between the check
+// and the delete. The identity check stops a stale disconnect from deleting a newer
+// space that was created under the same ID after this one was already removed.
+func (h *Hub) removeSpaceIfEmpty(space *Space) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.Spaces[space.ID] == space && space.IsEmpty() {
+		delete(h.Spaces, space.ID)
+		log.Printf("Space %s removed (empty)", space.ID)
+	}
+}
+
+// recomputeProximity re-evaluates a user's proximity for every configured media and emits the resulting events
+func (h *Hub) recomputeProximity(space *Space, client *Client) {
+	h.handleProximityEvents(space.ShedProximityEvents(space.updateProximityAllMedia(client)))
+}
+
+// proximityMoved hands a move to the space's proximity strategy and emits any resulting events
+func (h *Hub) proximityMoved(space *Space, client *Client) {
+	h.handleProximityEvents(space.ShedProximityEvents(space.ProximityStrategy.Moved(space, client)))
+}
+
+// RecomputeAllProximity re-evaluates proximity for every user in every active space
+func (h *Hub) RecomputeAllProximity() {
+	h.mu.RLock()
+	spaces := make([]*Space, 0, len(h.Spaces))
+	for _, space := range h.Spaces {
+		spaces = append(spaces, space)
+	}
+	h.mu.RUnlock()
+
+	for _, space := range spaces {
+		for _, client := range space.GetAllUsers() {
+			h.recomputeProximity(space, client)
 		}
 	}
-	log.Printf("Client %s disconnected", userID)
+}
+
+// ReloadProximityRadii re-reads the proximity radii from the environment and applies them
+// to all connected users immediately, emitting the enter/leave events the change implies.
+func (h *Hub) ReloadProximityRadii() {
+	h.reloadMu.Lock()
+	defer h.reloadMu.Unlock()
+
+	audio, video, screen, whisper := config.ReloadProximityRadii()
+	log.Printf("Proximity radii reloaded: audio=%.1f video=%.1f screen=%.1f whisper=%.1f", audio, video, screen, whisper)
+	h.RecomputeAllProximity()
+}
+
+// CompressionStats sums payload bytes sent with and without write compression across connected clients
+func (h *Hub) CompressionStats() (compressed, uncompressed int64) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for client := range h.Clients {
+		c, u := client.CompressionStats()
+		compressed += c
+		uncompressed += u
+	}
+	return compressed, uncompressed
 }
 
 // handleProximityEvents broadcasts proximity updates (mainly Audio) via WebSocket to relevant peers
@@ -134,38 +357,75 @@ func (h *Hub) handleProximityEvents(events []ProximityEvent) {
 		return
 	}
 
-	// Group events by Space to minimize lock contention if we need to look up space?
-	// Actually we just need to send to UserA and UserB.
-	
+	// Recipients that asked for batching get their share of events in one message
+	batches := newProximityBatches(h)
+	defer batches.flush()
+
 	for _, event := range events {
-		// Only handle audio events here (Video events are handled by Meeting Prompts)
-		// Or handle leaving video events if necessary?
-		// ProximityLeave events for video might be useful to ensure client cleanup?
-		// But MeetingEnd handles cleanup mostly.
-		// Let's send all proximity updates to clients so they can decide (e.g. mute volume/stop subscribing).
-
-		// Construct payload
-		payload := map[string]interface{}{
-			"type": event.Type, // "enter" or "leave"
-			"peerId": event.UserB, // For UserA, the peer is UserB
-			"media": event.Media,
+		if event.Type == ProximityVolume {
+			h.sendProximityVolume(event, batches)
+			continue
 		}
-
-		// We need to send to UserA: "UserB entered/left your radius"
-		h.sendToUser(event.SpaceID, event.UserA, messages.BaseMessage{
-			Type: messages.TypeProximityUpdate,
-			Payload: payload,
+		h.Events.Publish(Event{
+			Type:    EventProximityChanged,
+			SpaceID: event.SpaceID,
+			UserID:  event.UserA,
+			PeerID:  event.UserB,
+			Media:   event.Media,
+			Change:  event.Type,
 		})
 
-		// And to UserB: "UserA entered/left your radius"
-		payloadB := map[string]interface{}{
-			"type": event.Type,
-			"peerId": event.UserA,
-			"media": event.Media,
+		// Every proximity event is symmetric: both sides are told about the other.
+		// The peer is always derived from the recipient, so it doesn't matter which
+		// side's move produced the event.
+		for _, recipient := range []string{event.UserA, event.UserB} {
+			update := proximityUpdateFor(event, recipient)
+			if batch := batches.get(event.SpaceID, recipient); batch != nil {
+				batch.updates = append(batch.updates, update)
+				continue
+			}
+			h.sendToUser(event.SpaceID, recipient, messages.BaseMessage{
+				Type:    messages.TypeProximityUpdate,
+				Payload: update,
+			})
+		}
+	}
+}
+
+// proximityUpdateFor builds the proximity-update payload for one side of an event
+func proximityUpdateFor(event ProximityEvent, recipientID string) messages.ProximityUpdatePayload {
+	peerID := event.UserB
+	if recipientID == event.UserB {
+		peerID = event.UserA
+	}
+	payload := messages.ProximityUpdatePayload{
+		Type:   event.Type,
+		PeerID: peerID,
+		Media:  event.Media,
+	}
+	if event.Media == config.MediaAudio {
+		volume := event.Volume
+		payload.Volume = &volume
+	}
+	return payload
+}
+
+// sendProximityVolume tells both sides of a pair their new audio volume. Batching
+// recipients get it after their batch.
+func (h *Hub) sendProximityVolume(event ProximityEvent, batches *proximityBatches) {
+	for _, recipient := range []string{event.UserA, event.UserB} {
+		peerID := event.UserB
+		if recipient == event.UserB {
+			peerID = event.UserA
+		}
+		volume := messages.ProximityVolumePayload{PeerID: peerID, Volume: event.Volume}
+		if batch := batches.get(event.SpaceID, recipient); batch != nil {
+			batch.volumes = append(batch.volumes, volume)
+			continue
 		}
-		h.sendToUser(event.SpaceID, event.UserB, messages.BaseMessage{
-			Type: messages.TypeProximityUpdate,
-			Payload: payloadB,
+		h.sendToUser(event.SpaceID, recipient, messages.BaseMessage{
+			Type:    messages.TypeProximityVolume,
+			Payload: volume,
 		})
 	}
 }
@@ -190,9 +450,23 @@ func (h *Hub) sendToUser(spaceID, userID string, msg messages.BaseMessage) {
 
 // ProcessMessage handles incoming messages from clients
 func (h *Hub) ProcessMessage(client *Client, rawMessage []byte) {
+	if client.touch(time.Now()) {
+		h.broadcastIdle(client, false)
+	}
+
 	var msg messages.IncomingMessage
 	if err := json.Unmarshal(rawMessage, &msg); err != nil {
 		log.Printf("Error parsing message: %v", err)
+		h.recordViolation(client, "malformed message")
+		return
+	}
+
+	if !Can(client.Role, msg.Type) {
+		log.Printf("Forbidden: %s (role %q) may not send %s", client.UserID, client.Role, msg.Type)
+		client.SendJSON(messages.BaseMessage{
+			Type:    messages.TypeForbidden,
+			Payload: messages.ForbiddenPayload{Type: msg.Type},
+		})
 		return
 	}
 
@@ -203,14 +477,88 @@ func (h *Hub) ProcessMessage(client *Client, rawMessage []byte) {
 		h.handleMovement(client, msg.Payload)
 	case messages.TypeTeleport:
 		h.handleTeleport(client, msg.Payload)
+	case messages.TypeMoveIntent:
+		h.handleMoveIntent(client, msg.Payload)
+	case messages.TypePauseDwell:
+		h.handlePauseDwell(client, msg.Payload)
+	case messages.TypeKickUser:
+		h.handleKick(client, msg.Payload)
+	case messages.TypeBan:
+		h.handleBan(client, msg.Payload)
+	case messages.TypeUnban:
+		h.handleUnban(client, msg.Payload)
+	case messages.TypeLatency:
+		h.handleLatency(client, msg.Payload)
+	case messages.TypeReport:
+		h.handleReport(client, msg.Payload)
 	case messages.TypeMeetingResponse: // NEW Handler
 		h.handleMeetingResponse(client, msg.Payload)
+	case messages.TypeMeetingInvite:
+		h.handleMeetingInvite(client, msg.Payload)
 	case messages.TypeMeetingEnd:
 		h.handleMeetingEnd(client, msg.Payload)
+	case messages.TypeMeetingLeave:
+		h.handleMeetingLeave(client, msg.Payload)
 	case messages.TypeCameraToggle:
 		h.handleCameraToggle(client, msg.Payload)
+	case messages.TypeHideFrom:
+		h.handleVisibility(client, msg.Payload, true)
+	case messages.TypeUnhideFrom:
+		h.handleVisibility(client, msg.Payload, false)
+	case messages.TypeCustomBroadcast:
+		h.handleCustomBroadcast(client, msg.Payload)
+	case messages.TypeAnnouncement:
+		h.handleAnnouncement(client, msg.Payload)
+	case messages.TypeRenegotiate:
+		h.handleRenegotiate(client, msg.Payload)
+	case messages.TypeSignal:
+		h.handleSignal(client, msg.Payload)
+	case messages.TypeAuthRefresh:
+		h.handleAuthRefresh(client, msg.Payload)
+	case messages.TypeListSpaces:
+		h.sendSpaceList(client)
+	case messages.TypeLobbyChat:
+		h.handleLobbyChat(client, msg.Payload)
+	case messages.TypeChat:
+		h.handleChat(client, msg.Payload)
+	case messages.TypeTyping:
+		h.handleTyping(client, msg.Payload)
+	case messages.TypeDirectMessage:
+		h.handleDirectMessage(client, msg.Payload)
+	case messages.TypeEmote:
+		h.handleEmote(client, msg.Payload)
+	case messages.TypeAnimation:
+		h.handleAnimation(client, msg.Payload)
+	case messages.TypeFollow:
+		h.handleFollow(client, msg.Payload)
+	case messages.TypeUnfollow:
+		h.handleUnfollow(client)
+	case messages.TypeRequestUsers:
+		h.handleGetUsers(client)
+	case messages.TypePlaceObject:
+		h.handlePlaceObject(client, msg.Payload)
+	case messages.TypeRemoveObject:
+		h.handleRemoveObject(client, msg.Payload)
+	case messages.TypeRaiseHand, messages.TypeLowerHand, messages.TypeAdvanceHand, messages.TypeClearHands:
+		h.handleHand(client, msg.Type, msg.Payload)
 	default:
 		log.Printf("Unknown message type: %s", msg.Type)
+		h.recordViolation(client, "unknown message type")
+	}
+}
+
+// recordViolation counts a protocol violation against the client and disconnects
+// them once the configured limit is exceeded within the window.
+func (h *Hub) recordViolation(client *Client, what string) {
+	limit := config.Current().ProtocolViolationLimit
+	if limit <= 0 {
+		return
+	}
+
+	count := client.addViolation(time.Now(), config.Current().ProtocolViolationWindow)
+	if count > limit {
+		log.Printf("Disconnecting %s: %d protocol violations (last: %s)", client.UserID, count, what)
+		client.Disconnect(CloseProtocolViolations)
 	}
 }
 
@@ -218,6 +566,20 @@ func (h *Hub) ProcessMessage(client *Client, rawMessage []byte) {
 func (h *Hub) handleJoin(client *Client, payload messages.IncomingPayload) {
 	// Validate token
 	claims, err := auth.ValidateToken(payload.Token)
+	if errors.Is(err, auth.ErrSecretNotConfigured) {
+		// Deployment problem, not the user's token: say so instead of blaming the client
+		misconfiguredOnce.Do(func() {
+			log.Printf("CRITICAL: neither JWT_SECRET nor JWT_PUBLIC_KEY is configured; every join will be rejected")
+		})
+		client.SendJSON(messages.BaseMessage{
+			Type: messages.TypeJoinError,
+			Payload: messages.JoinErrorPayload{
+				Error: "Server is misconfigured",
+				Code:  messages.JoinErrorServerMisconfigured,
+			},
+		})
+		return
+	}
 	if err != nil {
 		log.Printf("Invalid token: %v", err)
 		errorMsg := messages.BaseMessage{
@@ -228,62 +590,196 @@ func (h *Hub) handleJoin(client *Client, payload messages.IncomingPayload) {
 		return
 	}
 
+	// Checked before resuming too, so a revoked membership doesn't survive a reconnect
+	allowed, err := h.authorizeJoin(claims, payload.SpaceID)
+	if err != nil {
+		log.Printf("Join rejected: could not check %s's access to space %s: %v", claims.UserID, payload.SpaceID, err)
+		client.SendJSON(messages.BaseMessage{
+			Type: messages.TypeJoinError,
+			Payload: messages.JoinErrorPayload{
+				Error: "Could not verify access to this space",
+				Code:  messages.JoinErrorAuthUnavailable,
+			},
+		})
+		return
+	}
+	if !allowed {
+		log.Printf("Join rejected: %s is not authorized for space %s", claims.UserID, payload.SpaceID)
+		client.SendJSON(messages.BaseMessage{
+			Type: messages.TypeJoinError,
+			Payload: messages.JoinErrorPayload{
+				Error: "not authorized for this space",
+				Code:  messages.JoinErrorNotAuthorized,
+			},
+		})
+		return
+	}
+
+	// Also before resuming, so a ban can't be dodged with a quick reconnect
+	banned, err := h.isBanned(payload.SpaceID, claims.UserID)
+	if err != nil {
+		log.Printf("Join rejected: could not check bans for %s in space %s: %v", claims.UserID, payload.SpaceID, err)
+		client.SendJSON(messages.BaseMessage{
+			Type: messages.TypeJoinError,
+			Payload: messages.JoinErrorPayload{
+				Error: "Could not verify access to this space",
+				Code:  messages.JoinErrorAuthUnavailable,
+			},
+		})
+		return
+	}
+	if banned {
+		log.Printf("Join rejected: %s is banned from space %s", claims.UserID, payload.SpaceID)
+		client.SendJSON(messages.BaseMessage{
+			Type: messages.TypeJoinError,
+			Payload: messages.JoinErrorPayload{
+				Error: "banned from this space",
+				Code:  messages.JoinErrorBanned,
+			},
+		})
+		return
+	}
+
+	// A reconnect within the grace picks up where the dropped connection left off
+	if h.resumeSession(client, claims, payload, time.Now()) {
+		return
+	}
+
+	if !h.allowJoin(claims.UserID, time.Now()) {
+		log.Printf("Join rejected: %s is joining too frequently", claims.UserID)
+		client.SendJSON(messages.BaseMessage{
+			Type: messages.TypeJoinError,
+			Payload: messages.JoinErrorPayload{
+				Error: "Joining too frequently, please wait",
+				Code:  messages.JoinErrorTooFrequent,
+			},
+		})
+		return
+	}
+
 	client.UserID = claims.UserID
 	client.Role = claims.Role
-	client.SpaceID = payload.SpaceID
+	client.setTokenExpiry(claims)
 	client.Name = payload.Name
 	client.AvatarName = payload.AvatarName
 
+	// The capacity check and the add share h.mu, so two joins can't both take the last place
 	h.mu.Lock()
 	space, exists := h.Spaces[payload.SpaceID]
-	if !exists {
-		space = NewSpace(payload.SpaceID, 1280, 960)
-		h.Spaces[payload.SpaceID] = space
-		log.Printf("Created new space: %s", payload.SpaceID)
-	}
-
-	existingUsers := make([]messages.UserInfo, 0)
-	for _, u := range space.GetAllUsers() {
-		ux, uy := u.GetPosition()
-		existingUsers = append(existingUsers, messages.UserInfo{
-			UserID:     u.UserID,
-			X:          ux,
-			Y:          uy,
-			Name:       u.Name,
-			AvatarName: u.AvatarName,
+	if exists && !space.hasRoomFor(claims.UserID, config.Current().MaxUsersPerSpace) {
+		h.mu.Unlock()
+		log.Printf("Join rejected: space %s is full", payload.SpaceID)
+		client.SendJSON(messages.BaseMessage{
+			Type: messages.TypeJoinError,
+			Payload: messages.JoinErrorPayload{
+				Error: "space full",
+				Code:  messages.JoinErrorSpaceFull,
+			},
 		})
+		return
+	}
+	client.SpaceID = payload.SpaceID
+	client.joined = true
+	if !exists {
+		space = h.createSpaceLocked(payload.SpaceID)
 	}
 
-	// Spawn logic
-	var spawnX, spawnY float64
-	centerX := 705.0
-	centerY := 500.0
-	maxAttempts := 100
-	for i := 0; i < maxAttempts; i++ {
-		spawnX = centerX + float64(rand.Intn(101)-50)
-		spawnY = centerY + float64(rand.Intn(101)-50)
-		if !space.IsColliding(spawnX, spawnY, "") {
-			break
-		}
+	// Spawn logic: next to the friend from an invite link if they're here, else in the spawn zone
+	spawnX, spawnY, nearFriend := space.spawnNearUser(payload.JoinNearUserID, client.UserID)
+	spawnFallback := false
+	if !nearFriend {
+		spawnX, spawnY, spawnFallback = h.spawnInZoneLocked(space, client.UserID)
 	}
 	client.SetPosition(spawnX, spawnY)
 
+	// Must stay under h.mu: removeSpaceIfEmpty relies on it (see there)
 	space.AddUser(client)
 	h.mu.Unlock()
 
+	h.announceArrival(space, client, spawnFallback)
+
+	log.Printf("User %s joined space %s at (%f, %f)", client.UserID, payload.SpaceID, spawnX, spawnY)
+}
+
+// createSpaceLocked creates and registers spaceID from its snapshot, or else its map.
+// Callers hold h.mu and have checked it doesn't exist.
+func (h *Hub) createSpaceLocked(spaceID string) *Space {
+	space := loadSnapshotFor(config.Current().SnapshotDir, spaceID)
+	if space == nil {
+		space = NewSpace(spaceID, 1280, 960)
+		applySpaceMap(space, config.Current().MapDir)
+	}
+	h.addSpaceLocked(space)
+	log.Printf("Created new space: %s", spaceID)
+	return space
+}
+
+// addSpaceLocked wires a new space up to the hub and registers it. Callers hold h.mu.
+func (h *Hub) addSpaceLocked(space *Space) {
+	space.Coords = config.Current().Coordinates(space.ID)
+	space.Events = h.Events
+	space.ProximityStrategy = newProximityStrategy(space.ID)
+	h.Spaces[space.ID] = space
+}
+
+// spawnInZoneLocked picks a free point in space's spawn zone for userID: random ones
+// first, then a search of the zone and the rest of the space. fallback is set, and
+// logged, if the space is full and the user may overlap something. Callers hold h.mu.
+func (h *Hub) spawnInZoneLocked(space *Space, userID string) (x, y float64, fallback bool) {
+	const maxAttempts = 100
+	zone := space.spawnZone()
+	rng := h.spawnRandLocked()
+	found := false
+	scale := space.Coords.Scale
+	for i := 0; i < maxAttempts && !found; i++ {
+		// Whole tiles from the zone's corner, so the default zone spans 705±50, 500±50
+		x = NormalizeCoord(zone.X + math.Floor(rng.Float64()*zone.Width/scale)*scale)
+		y = NormalizeCoord(zone.Y + math.Floor(rng.Float64()*zone.Height/scale)*scale)
+		found = zone.Contains(x, y) && !space.IsColliding(x, y, "")
+	}
+	if !found {
+		// A crowded zone: search it tile by tile, then outward, instead of stacking avatars
+		var fx, fy float64
+		if fx, fy, found = space.FindFreeSpawnIn(zone); !found {
+			fx, fy, found = space.FindFreeSpawn(zone.X+zone.Width/2, zone.Y+zone.Height/2)
+		}
+		if found {
+			x, y = fx, fy
+		}
+	}
+	if !found {
+		spawnFallbacks.Add(1)
+		log.Printf("WARNING: no free spawn in space %s; %s may overlap at (%v, %v)", space.ID, userID, x, y)
+	}
+	return x, y, !found
+}
+
+// announceArrival completes a client's entry into space once it's been added there:
+// proximity is computed, the client gets space-joined with the users it can see and
+// they get user-join.
+func (h *Hub) announceArrival(space *Space, client *Client, spawnFallback bool) {
+	spawnX, spawnY := client.GetPosition()
+	existingUsers := visibleUserInfos(space, client.UserID)
+
 	// Initial proximity
-	proximityEvents := append(
-		space.UpdateProximityForUser(client, config.AppConfig.AudioRadius, "audio"),
-		space.UpdateProximityForUser(client, config.AppConfig.VideoRadius, "video")...,
-	)
-	h.handleProximityEvents(proximityEvents)
+	h.recomputeProximity(space, client)
+
+	// With an area of interest the joiner only gets, and is only shown to, users in view
+	var inView []*Client
+	if interestEnabled() {
+		inView, _ = space.UpdateInterest(client)
+		existingUsers = userInfosIn(existingUsers, inView)
+	}
 
 	joinedMsg := messages.BaseMessage{
 		Type: messages.TypeSpaceJoined,
 		Payload: messages.SpaceJoinedPayload{
 			SessionID: client.UserID,
+			SpaceID:   space.ID,
 			Spawn:     messages.Position{X: spawnX, Y: spawnY},
 			Users:     existingUsers,
+			SpawnFallback: spawnFallback,
+			Objects:   space.ObjectPayloads(),
 		},
 	}
 	client.SendJSON(joinedMsg)
@@ -298,9 +794,79 @@ func (h *Hub) handleJoin(client *Client, payload messages.IncomingPayload) {
 			AvatarName: client.AvatarName,
 		},
 	}
-	h.broadcastToSpace(payload.SpaceID, userJoinMsg, client.UserID)
+	if interestEnabled() {
+		for _, other := range inView {
+			other.SendJSON(userJoinMsg)
+		}
+	} else {
+		h.broadcastToSpace(space.ID, userJoinMsg, client.UserID)
+	}
+	h.broadcastUserCount(space)
 
-	log.Printf("User %s joined space %s at (%f, %f)", client.UserID, payload.SpaceID, spawnX, spawnY)
+	joinsTotal.Inc()
+	h.Events.Publish(Event{Type: EventUserJoined, SpaceID: space.ID, UserID: client.UserID, X: spawnX, Y: spawnY})
+}
+
+// visibleUserInfos lists the other users in space that viewerID can see
+func visibleUserInfos(space *Space, viewerID string) []messages.UserInfo {
+	users := make([]messages.UserInfo, 0)
+	for _, u := range space.GetVisibleUsers(viewerID) {
+		if u.UserID == viewerID {
+			continue
+		}
+		ux, uy := u.GetPosition()
+		users = append(users, messages.UserInfo{
+			UserID:     u.UserID,
+			X:          ux,
+			Y:          uy,
+			Name:       u.Name,
+			AvatarName: u.AvatarName,
+			Anim:       u.GetAnim(),
+			Idle:       u.isIdle(),
+		})
+	}
+	return users
+}
+
+// userInfosIn keeps the users that are among clients
+func userInfosIn(users []messages.UserInfo, clients []*Client) []messages.UserInfo {
+	ids := make(map[string]bool, len(clients))
+	for _, c := range clients {
+		ids[c.UserID] = true
+	}
+	kept := make([]messages.UserInfo, 0, len(clients))
+	for _, u := range users {
+		if ids[u.UserID] {
+			kept = append(kept, u)
+		}
+	}
+	return kept
+}
+
+// allowJoin enforces the per-user join cooldown, recording the join if allowed
+func (h *Hub) allowJoin(userID string, now time.Time) bool {
+	cooldown := config.Current().JoinCooldown
+	if cooldown <= 0 {
+		return true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if last, ok := h.lastJoin[userID]; ok && now.Sub(last) < cooldown {
+		return false
+	}
+	h.lastJoin[userID] = now
+
+	// Keep the map from growing without bound
+	if len(h.lastJoin) > 1024 {
+		for id, last := range h.lastJoin {
+			if now.Sub(last) >= cooldown {
+				delete(h.lastJoin, id)
+			}
+		}
+	}
+	return true
 }
 
 // handleMovement processes a movement request
@@ -313,13 +879,42 @@ func (h *Hub) handleMovement(client *Client, payload messages.IncomingPayload) {
 
 	if !exists { return }
 
+	now := time.Now()
+	// Floods are dropped silently; the client keeps predicting and later moves catch up.
+	// A move that changes the animation is kept, since it may be the last one: the stop
+	// at the end of a walk has no later move to catch up with.
+	if !client.allowMoveRate(now) && (!validAnim(payload.Anim) || payload.Anim == client.GetAnim()) {
+		return
+	}
+
 	oldX, oldY := client.GetPosition()
-	newX, newY := payload.X, payload.Y
+	newX, newY := NormalizeCoord(payload.X), NormalizeCoord(payload.Y)
+
+	// Pushing into a wall repeats the same target; the client already has its rejection
+	if client.isRepeatedRejection(newX, newY, now) {
+		return
+	}
 
 	validMove := IsValidMove(oldX, oldY, newX, newY)
 	isColliding := space.IsColliding(newX, newY, client.UserID)
+
+	if !validMove {
+		h.recordViolation(client, "invalid move")
+	}
+
+	dist := distance(oldX, oldY, newX, newY)
+	if validMove && !isColliding {
+		// Each step may be valid on its own, but the total covered within a tick must be
+		// humanly possible, as must the sustained speed
+		validMove = client.withinMoveSpeed(dist, now) && client.consumeMoveBudget(dist, now)
+	}
 	
+	if validMove && isColliding {
+		client.setRejectedTarget(newX, newY, now)
+	}
+
 	if !validMove || isColliding {
+		movementsRejectedTotal.Inc()
 		rejectMsg := messages.BaseMessage{
 			Type: messages.TypeMovementRejected,
 			Payload: messages.MovementRejectedPayload{X: oldX, Y: oldY},
@@ -329,27 +924,42 @@ func (h *Hub) handleMovement(client *Client, payload messages.IncomingPayload) {
 	}
 
 	client.SetPosition(newX, newY)
-	client.Anim = payload.Anim
+	anim := applyMoveAnim(client, payload.Anim)
+	client.setRejectedTarget(0, 0, time.Time{})
+	client.acceptMove(dist, now)
+
+	if client.ConfirmMoves {
+		client.SendJSON(messages.BaseMessage{
+			Type:    messages.TypeMovementAccepted,
+			Payload: messages.MovementAcceptedPayload{X: newX, Y: newY},
+		})
+	}
 
-	proximityEvents := append(
-		space.UpdateProximityForUser(client, config.AppConfig.AudioRadius, "audio"),
-		space.UpdateProximityForUser(client, config.AppConfig.VideoRadius, "video")...,
-	)
-	h.handleProximityEvents(proximityEvents)
+	h.proximityMoved(space, client)
 
 	moveMsg := messages.BaseMessage{
 		Type: messages.TypeMovement,
 		Payload: messages.MovementPayload{
-			X:      newX,
-			Y:      newY,
-			UserID: client.UserID,
-			Anim:   client.Anim,
+			X:          newX,
+			Y:          newY,
+			UserID:     client.UserID,
+			Anim:       anim,
+			DurationMs: durationMs(moveDurationMs(dist)),
 		},
 	}
-	h.broadcastToSpace(client.SpaceID, moveMsg, client.UserID)
+	h.broadcastMovement(space, client, moveMsg)
+	h.Events.Publish(Event{Type: EventUserMoved, SpaceID: space.ID, UserID: client.UserID, X: newX, Y: newY})
+	h.applyFollows(space, client, oldX, oldY)
+
+	// Stepping onto a portal carries on to its destination
+	if portal, ok := space.portalAt(newX, newY); ok {
+		h.usePortal(space, client, portal)
+	}
 }
 
-// handleTeleport processes a teleport request
+// handleTeleport moves a client without the step limit, for meeting navigation and
+// portals (see Space.TeleportAllowed). A destination within one step is handled as an
+// ordinary move; anything else is rejected, though not counted as a violation.
 func (h *Hub) handleTeleport(client *Client, payload messages.IncomingPayload) {
 	if client.SpaceID == "" { return }
 
@@ -360,11 +970,21 @@ func (h *Hub) handleTeleport(client *Client, payload messages.IncomingPayload) {
 	if !exists { return }
 
 	oldX, oldY := client.GetPosition()
-	newX, newY := payload.X, payload.Y
+	newX, newY := NormalizeCoord(payload.X), NormalizeCoord(payload.Y)
+
+	// Clients walking a path to a meeting send each node as a teleport
+	if IsValidMove(oldX, oldY, newX, newY) {
+		h.handleMovement(client, payload)
+		return
+	}
+
+	// Teleports skip the step limit, so they're only for reaching a meeting peer or using a portal
+	validTeleport := space.TeleportAllowed(client.UserID, newX, newY, time.Now())
 
 	isColliding := space.IsColliding(newX, newY, client.UserID)
 
-	if isColliding {
+	if !validTeleport || isColliding {
+		movementsRejectedTotal.Inc()
 		rejectMsg := messages.BaseMessage{
 			Type: messages.TypeMovementRejected,
 			Payload: messages.MovementRejectedPayload{X: oldX, Y: oldY},
@@ -374,24 +994,23 @@ func (h *Hub) handleTeleport(client *Client, payload messages.IncomingPayload) {
 	}
 
 	client.SetPosition(newX, newY)
-	client.Anim = payload.Anim
+	anim := applyMoveAnim(client, payload.Anim)
 
-	proximityEvents := append(
-		space.UpdateProximityForUser(client, config.AppConfig.AudioRadius, "audio"),
-		space.UpdateProximityForUser(client, config.AppConfig.VideoRadius, "video")...,
-	)
-	h.handleProximityEvents(proximityEvents)
+	h.proximityMoved(space, client)
 
 	moveMsg := messages.BaseMessage{
 		Type: messages.TypeMovement,
 		Payload: messages.MovementPayload{
-			X:      newX,
-			Y:      newY,
-			UserID: client.UserID,
-			Anim:   client.Anim,
+			X:          newX,
+			Y:          newY,
+			UserID:     client.UserID,
+			Anim:       anim,
+			DurationMs: durationMs(0),
 		},
 	}
-	h.broadcastToSpace(client.SpaceID, moveMsg, client.UserID)
+	h.broadcastMovement(space, client, moveMsg)
+	h.Events.Publish(Event{Type: EventUserMoved, SpaceID: space.ID, UserID: client.UserID, X: newX, Y: newY})
+	h.applyFollows(space, client, oldX, oldY)
 }
 
 // handleMeetingResponse processes a user accepting or declining a meeting prompt
@@ -407,13 +1026,10 @@ func (h *Hub) handleMeetingResponse(client *Client, payload messages.IncomingPay
 	space.mu.Lock()
 	defer space.mu.Unlock()
 
-	// Find the meeting state - key is sort(UserA, UserB) or RequestID?
-	// We might not have the key handy unless we reconstruct it or search.
-	// But we have PeerID, so we can construct key.
-	if payload.PeerID == "" { return }
-	key := dwellKey(client.UserID, payload.PeerID)
-	
-	state, ok := space.MeetingStates[key]
+	// Find the meeting state: through the peer if given (any peer works for a group),
+	// otherwise by the prompt's request ID
+	if payload.PeerID == "" && payload.RequestID == "" { return }
+	key, state, ok := space.meetingForResponseLocked(client.UserID, payload.PeerID, payload.RequestID)
 	if !ok {
 		log.Printf("Meeting response ignored: no active meeting state for %s-%s", client.UserID, payload.PeerID)
 		return
@@ -432,50 +1048,47 @@ func (h *Hub) handleMeetingResponse(client *Client, payload messages.IncomingPay
 	if !payload.Accept {
 		// Declined
 		log.Printf("Meeting declined by %s", client.UserID)
-		delete(space.MeetingStates, key)
-		// Send cancellation/declined info?
+		meetingFunnel.PromptsDeclined.Add(1)
+		// Keep the state in cooldown so staying close doesn't re-prompt right away.
+		// Clearing the request ID makes a simultaneous decline from the peer a no-op.
+		// In a group, one decline cools down the whole group.
+		state.CooldownUntil = time.Now().Add(MeetingCooldown + pairJitter(key))
+		state.RequestID = ""
+		state.Accepted = nil
 		return
 	}
 
 	// Accepted
-	if client.UserID == state.UserA {
-		state.AcceptA = true
-	} else if client.UserID == state.UserB {
-		state.AcceptB = true
+	if !state.Accepted[client.UserID] {
+		if state.Accepted == nil {
+			state.Accepted = make(map[string]bool)
+		}
+		state.Accepted[client.UserID] = true
+		meetingFunnel.PromptsAccepted.Add(1)
 	}
 
-	if state.AcceptA && state.AcceptB {
-		log.Printf("Meeting STARTING between %s and %s", state.UserA, state.UserB)
+	if state.allAccepted() {
+		log.Printf("Meeting STARTING between %s", strings.Join(state.participants(), ", "))
+		meetingFunnel.MeetingsStarted.Add(1)
+		space.Events.Publish(Event{Type: EventMeetingStarted, SpaceID: space.ID, UserID: state.UserA, PeerID: state.UserB, Participants: state.participants(), MeetingID: state.MeetingID})
 		state.Status = MeetingStatusActive
 		state.RequestID = "" // Clear request ID
 		
-		// Send MEETING_START to both
-		msg := messages.BaseMessage{
-			Type: messages.TypeMeetingStart,
-		}
-		
-		// To A
-		msg.Payload = map[string]string{
-			"peerId": state.UserB,
-			"meetingId": state.MeetingID,
-		}
-		if uA, ok := space.Users[state.UserA]; ok {
-			uA.SendJSON(msg)
-		}
-		
-		// To B
-		msg.Payload = map[string]string{
-			"peerId": state.UserA,
-			"meetingId": state.MeetingID,
-		}
-		if uB, ok := space.Users[state.UserB]; ok {
-			uB.SendJSON(msg)
+		// Send MEETING_START to everyone
+		for _, id := range state.participants() {
+			if u, ok := space.Users[id]; ok {
+				u.SendJSON(messages.BaseMessage{
+					Type:    messages.TypeMeetingStart,
+					Payload: meetingStartPayload(state, id),
+				})
+			}
 		}
 	}
 }
 
 
-// handleMeetingEnd processes a user request to end a meeting
+// handleMeetingEnd processes a user request to end a meeting. In a group meeting
+// the user leaves and the others carry on.
 func (h *Hub) handleMeetingEnd(client *Client, payload messages.IncomingPayload) {
 	if client.SpaceID == "" { return }
 
@@ -489,57 +1102,56 @@ func (h *Hub) handleMeetingEnd(client *Client, payload messages.IncomingPayload)
 
 	// If PeerID is provided, use it to find the meeting efficiently
 	if payload.PeerID != "" {
-		key := dwellKey(client.UserID, payload.PeerID)
-		if state, ok := space.MeetingStates[key]; ok {
-			// Notify peer
-			var peerID string
-			if state.UserA == client.UserID {
-				peerID = state.UserB
-			} else {
-				peerID = state.UserA
-			}
-
-			if peerClient, ok := space.Users[peerID]; ok {
-				msg := messages.BaseMessage{
-					Type: messages.TypeMeetingEnd,
-					Payload: map[string]string{
-						"peerId":    client.UserID,
-						"meetingId": state.MeetingID,
-						"reason":    "user_ended",
-					},
-				}
-				peerClient.SendJSON(msg)
-			}
-			delete(space.MeetingStates, key)
+		if key, state, ok := space.meetingBetweenLocked(client.UserID, payload.PeerID); ok {
+			space.leaveMeetingLocked(key, state, client.UserID, MeetingEndUserEnded)
 		}
 	} else {
 		// Fallback: search for active meeting involving this user
-		for key, state := range space.MeetingStates {
-			if state.Status == MeetingStatusActive && (state.UserA == client.UserID || state.UserB == client.UserID) {
-				var peerID string
-				if state.UserA == client.UserID {
-					peerID = state.UserB
-				} else {
-					peerID = state.UserA
-				}
-
-				if peerClient, ok := space.Users[peerID]; ok {
-					msg := messages.BaseMessage{
-						Type: messages.TypeMeetingEnd,
-						Payload: map[string]string{
-							"peerId":    client.UserID,
-							"meetingId": state.MeetingID,
-							"reason":    "user_ended",
-						},
-					}
-					peerClient.SendJSON(msg)
-				}
-				delete(space.MeetingStates, key)
-			}
+		if key, state, ok := space.activeMeetingForLocked(client.UserID); ok {
+			space.leaveMeetingLocked(key, state, client.UserID, MeetingEndUserEnded)
 		}
 	}
 }
 
+// handleMeetingLeave takes the client out of their active meeting without leaving the
+// space. The others get a meeting-end, and the client cools down with each of them so
+// staying close doesn't prompt (or rejoin) them straight away.
+func (h *Hub) handleMeetingLeave(client *Client, payload messages.IncomingPayload) {
+	if client.SpaceID == "" { return }
+
+	h.mu.RLock()
+	space, exists := h.Spaces[client.SpaceID]
+	h.mu.RUnlock()
+	if !exists { return }
+
+	space.mu.Lock()
+	defer space.mu.Unlock()
+
+	key, state, ok := space.activeMeetingForLocked(client.UserID)
+	if !ok || (payload.MeetingID != "" && payload.MeetingID != state.MeetingID) {
+		log.Printf("Meeting leave ignored: %s is not in an active meeting %s", client.UserID, payload.MeetingID)
+		return
+	}
+
+	peers := state.peersOf(client.UserID)
+	space.leaveMeetingLocked(key, state, client.UserID, MeetingEndUserLeftMeeting)
+	now := time.Now()
+	for _, peerID := range peers {
+		space.coolDownPairLocked(client.UserID, peerID, now)
+	}
+}
+
+// broadcastUserCount sends the current occupancy to everyone in the space
+func (h *Hub) broadcastUserCount(space *Space) {
+	msg := messages.BaseMessage{
+		Type:    messages.TypeUserCount,
+		Payload: messages.UserCountPayload{Count: space.UserCount()},
+	}
+	for _, client := range space.GetAllUsers() {
+		client.SendJSON(msg)
+	}
+}
+
 // broadcastToSpace sends a message to all users in a space except the sender
 func (h *Hub) broadcastToSpace(spaceID string, message interface{}, excludeUserID string) {
 	h.mu.RLock()
@@ -0,0 +1,58 @@
+package hub
+
+import (
+	"log"
+
+	"world/internal/messages"
+)
+
+// handlePauseDwell lets an admin freeze (enabled=true) or resume the dwell/meeting
+// state machine for their space while inspecting it. Access is checked in ProcessMessage.
+func (h *Hub) handlePauseDwell(client *Client, payload messages.IncomingPayload) {
+	if client.SpaceID == "" {
+		return
+	}
+
+	h.mu.RLock()
+	space, exists := h.Spaces[client.SpaceID]
+	h.mu.RUnlock()
+	if !exists {
+		return
+	}
+
+	if space.SetDwellPaused(payload.Enabled) {
+		log.Printf("Space %s: dwell checker paused=%v by %s", space.ID, payload.Enabled, client.UserID)
+	}
+}
+
+// handleKick lets an admin remove a user from their space. The target is told why,
+// then closed with CloseKicked; the unregister that follows announces user-left, and
+// the server-side close keeps the session from being held for a reconnect. Access is
+// checked in ProcessMessage.
+func (h *Hub) handleKick(client *Client, payload messages.IncomingPayload) {
+	if client.SpaceID == "" || payload.TargetUserID == client.UserID {
+		return
+	}
+
+	h.mu.RLock()
+	space, exists := h.Spaces[client.SpaceID]
+	h.mu.RUnlock()
+	if !exists {
+		return
+	}
+
+	space.mu.RLock()
+	target, ok := space.Users[payload.TargetUserID]
+	space.mu.RUnlock()
+	if !ok {
+		log.Printf("Kick by %s ignored: %s is not in space %s", client.UserID, payload.TargetUserID, space.ID)
+		return
+	}
+
+	log.Printf("Space %s: %s kicked by %s", space.ID, target.UserID, client.UserID)
+	target.SendJSON(messages.BaseMessage{
+		Type:    messages.TypeKicked,
+		Payload: messages.KickedPayload{By: client.UserID, Reason: payload.Reason},
+	})
+	target.Disconnect(CloseKicked)
+}
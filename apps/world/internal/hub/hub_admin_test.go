@@ -0,0 +1,82 @@
+package hub
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"world/internal/messages"
+)
+
+func TestAdminKick(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	admin := joinTestClient(t, h, "lobby", "admin")
+	admin.Role = RoleAdmin
+	target := joinTestClient(t, h, "lobby", "target")
+	bystander := joinTestClient(t, h, "lobby", "bystander")
+	drainMessages(t, admin)
+	drainMessages(t, target)
+	drainMessages(t, bystander)
+
+	h.ProcessMessage(admin, []byte(`{"type":"kick","payload":{"targetUserId":"target","reason":"spam"}}`))
+
+	kicked := messagesOfType(drainMessages(t, target), messages.TypeKicked)
+	if len(kicked) != 1 {
+		t.Fatalf("target got %d kicked messages, want 1", len(kicked))
+	}
+	var payload messages.KickedPayload
+	if err := json.Unmarshal(kicked[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.By != "admin" || payload.Reason != "spam" {
+		t.Errorf("kicked payload = %+v", payload)
+	}
+
+	select {
+	case got := <-h.Unregister:
+		if got != target {
+			t.Fatalf("unregistered %s, want target", got.UserID)
+		}
+		h.handleDisconnect(got)
+	case <-time.After(time.Second):
+		t.Fatal("kicked user was not unregistered")
+	}
+	if frame := target.closeMessage(); string(frame) != string(CloseKicked.frame()) {
+		t.Errorf("close frame = %q, want kicked", frame)
+	}
+
+	left := messagesOfType(drainMessages(t, bystander), messages.TypeUserLeft)
+	if len(left) != 1 {
+		t.Fatalf("bystander got %d user-left messages, want 1", len(left))
+	}
+	if _, ok := h.Spaces["lobby"].Users["target"]; ok {
+		t.Error("kicked user is still in the space")
+	}
+}
+
+func TestNonAdminKickIgnored(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	user := joinTestClient(t, h, "lobby", "user")
+	target := joinTestClient(t, h, "lobby", "target")
+	drainMessages(t, user)
+	drainMessages(t, target)
+
+	h.ProcessMessage(user, []byte(`{"type":"kick","payload":{"targetUserId":"target"}}`))
+
+	if got := messagesOfType(drainMessages(t, target), messages.TypeKicked); len(got) != 0 {
+		t.Errorf("target got %d kicked messages from a non-admin", len(got))
+	}
+	if got := messagesOfType(drainMessages(t, user), messages.TypeForbidden); len(got) != 1 {
+		t.Errorf("sender got %d forbidden messages, want 1", len(got))
+	}
+	select {
+	case got := <-h.Unregister:
+		t.Fatalf("%s was unregistered by a non-admin kick", got.UserID)
+	case <-time.After(20 * time.Millisecond):
+	}
+	if _, ok := h.Spaces["lobby"].Users["target"]; !ok {
+		t.Error("target was removed from the space")
+	}
+}
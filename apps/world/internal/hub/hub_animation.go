@@ -0,0 +1,87 @@
+package hub
+
+import (
+	"strings"
+
+	"world/internal/messages"
+)
+
+// maxAvatarKeyLength bounds the avatar part of an animation key
+const maxAvatarKeyLength = 32
+
+// Animation keys are <avatar>_<state>_<direction>, as in the client's sprite sheets.
+// Only the states and directions clients know how to play are accepted.
+var (
+	allowedAnimStates = map[string]bool{
+		"idle": true,
+		"run":  true,
+		"sit":  true,
+	}
+	allowedAnimDirections = map[string]bool{
+		"up":    true,
+		"down":  true,
+		"left":  true,
+		"right": true,
+	}
+)
+
+// validAnim reports whether anim is an animation key clients can play: an avatar name of
+// lowercase letters and digits, then an allowed state and direction
+func validAnim(anim string) bool {
+	parts := strings.Split(anim, "_")
+	if len(parts) != 3 || !allowedAnimStates[parts[1]] || !allowedAnimDirections[parts[2]] {
+		return false
+	}
+	avatar := parts[0]
+	if avatar == "" || len(avatar) > maxAvatarKeyLength {
+		return false
+	}
+	for _, r := range avatar {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// applyMoveAnim plays the animation a move or teleport asks for, keeping the current
+// one when it isn't a valid animation key, and returns the animation now playing
+func applyMoveAnim(client *Client, anim string) string {
+	if !validAnim(anim) {
+		return client.GetAnim()
+	}
+	client.SetAnim(anim)
+	return anim
+}
+
+// handleAnimation changes the animation the sender's avatar plays without moving it,
+// e.g. to sit down, and tells the users who can see it
+func (h *Hub) handleAnimation(client *Client, payload messages.IncomingPayload) {
+	if client.SpaceID == "" {
+		return
+	}
+	if !validAnim(payload.Anim) {
+		h.recordViolation(client, "unknown animation")
+		return
+	}
+
+	h.mu.RLock()
+	space, exists := h.Spaces[client.SpaceID]
+	h.mu.RUnlock()
+	if !exists {
+		return
+	}
+
+	client.SetAnim(payload.Anim)
+	msg := messages.BaseMessage{
+		Type:    messages.TypeAnimation,
+		Payload: messages.AnimationPayload{UserID: client.UserID, Anim: payload.Anim},
+	}
+	if !interestEnabled() {
+		h.broadcastToSpace(space.ID, msg, client.UserID)
+		return
+	}
+	for _, viewer := range space.ViewersOf(client.UserID) {
+		viewer.SendJSON(msg)
+	}
+}
@@ -0,0 +1,171 @@
+package hub
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"world/internal/config"
+	"world/internal/messages"
+)
+
+func TestValidAnim(t *testing.T) {
+	for _, tc := range []struct {
+		anim string
+		want bool
+	}{
+		{"ginny_sit_left", true},
+		{"harry_idle_down", true},
+		{"ron2_run_up", true},
+		{"ginny_dance_left", false},
+		{"ginny_sit_sideways", false},
+		{"ginny_sit", false},
+		{"_sit_left", false},
+		{"Ginny_sit_left", false},
+		{"gin ny_sit_left", false},
+		{"ginny_sit_left_extra", false},
+		{strings.Repeat("a", maxAvatarKeyLength+1) + "_sit_left", false},
+		{"", false},
+	} {
+		if got := validAnim(tc.anim); got != tc.want {
+			t.Errorf("validAnim(%q) = %v, want %v", tc.anim, got, tc.want)
+		}
+	}
+}
+
+func TestAnimationBroadcastWhileStationary(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	b := addTestClient(h, space, "b", 900, 900)
+
+	h.ProcessMessage(a, []byte(`{"type":"animation","payload":{"anim":"ginny_sit_left"}}`))
+
+	got := messagesOfType(drainMessages(t, b), messages.TypeAnimation)
+	if len(got) != 1 {
+		t.Fatalf("b got %d animation messages, want 1", len(got))
+	}
+	var payload messages.AnimationPayload
+	if err := json.Unmarshal(got[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload != (messages.AnimationPayload{UserID: "a", Anim: "ginny_sit_left"}) {
+		t.Errorf("payload = %+v", payload)
+	}
+	if x, y := a.GetPosition(); x != 100 || y != 100 || a.Anim != "ginny_sit_left" {
+		t.Errorf("a at (%v, %v) playing %q, want (100, 100) playing ginny_sit_left", x, y, a.Anim)
+	}
+	if n := len(messagesOfType(drainMessages(t, a), messages.TypeAnimation)); n != 0 {
+		t.Errorf("sender got %d echoes of its own animation", n)
+	}
+}
+
+func TestUnknownAnimationRejected(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) {
+		cfg.ProtocolViolationLimit = 10
+		cfg.ProtocolViolationWindow = time.Minute
+	})
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	a.Anim = "ginny_idle_down"
+	b := addTestClient(h, space, "b", 900, 900)
+
+	h.handleAnimation(a, messages.IncomingPayload{Anim: "ginny_dance_down"})
+
+	if n := len(messagesOfType(drainMessages(t, b), messages.TypeAnimation)); n != 0 {
+		t.Errorf("unknown animation broadcast %d times", n)
+	}
+	if a.Anim != "ginny_idle_down" {
+		t.Errorf("anim changed to %q", a.Anim)
+	}
+	if a.violations != 1 {
+		t.Errorf("violations = %d, want 1", a.violations)
+	}
+}
+
+func TestSpaceJoinedCarriesAnim(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	sitter := joinTestClient(t, h, "s1", "sitter")
+	h.handleAnimation(sitter, messages.IncomingPayload{Anim: "ginny_sit_left"})
+
+	late := joinTestClient(t, h, "s1", "late")
+	joined := messagesOfType(drainMessages(t, late), messages.TypeSpaceJoined)
+	if len(joined) != 1 {
+		t.Fatalf("got %d space-joined messages, want 1", len(joined))
+	}
+	var payload messages.SpaceJoinedPayload
+	if err := json.Unmarshal(joined[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if len(payload.Users) != 1 || payload.Users[0].UserID != "sitter" || payload.Users[0].Anim != "ginny_sit_left" {
+		t.Errorf("users = %+v, want sitter playing ginny_sit_left", payload.Users)
+	}
+}
+
+func TestSpawnIntoViewCarriesAnim(t *testing.T) {
+	h, space, mover, _, far := setupInterestTest(t)
+	far.Anim = "ron_sit_down"
+	space.Portals = []Portal{{MapRect: MapRect{X: 100, Y: 100, Width: 1, Height: 1}, To: MapPoint{X: 1000, Y: 100}}}
+
+	h.handleTeleport(mover, messages.IncomingPayload{X: 1000, Y: 100})
+
+	spawns := messagesOfType(drainMessages(t, mover), messages.TypeUserJoin)
+	if len(spawns) != 1 {
+		t.Fatalf("mover got %d user-joins, want 1", len(spawns))
+	}
+	var payload messages.UserJoinPayload
+	if err := json.Unmarshal(spawns[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.UserID != "far" || payload.Anim != "ron_sit_down" {
+		t.Errorf("user-join = %+v, want far playing ron_sit_down", payload)
+	}
+}
+
+func TestMovesKeepAnimWhenInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		move func(h *Hub, c *Client, payload messages.IncomingPayload)
+		x, y float64
+	}{
+		{"movement", (*Hub).handleMovement, 110, 100},
+		{"teleport", (*Hub).handleTeleport, 860, 700},
+		{"move-intent", (*Hub).handleMoveIntent, 300, 100},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			setupTestConfig(t)
+			h := NewHub()
+			space := newTestSpace(h, "s1")
+			a := addTestClient(h, space, "a", 100, 100)
+			addTestClient(h, space, "b", 900, 700)
+			observer := addTestClient(h, space, "c", 500, 500)
+			startTestMeeting(space, "a", "b")
+			a.SetAnim("ginny_idle_down")
+
+			tc.move(h, a, messages.IncomingPayload{X: tc.x, Y: tc.y, Anim: "ginny_dance_<b>"})
+
+			if x, _ := a.GetPosition(); x != tc.x {
+				t.Fatalf("move with a bad anim was not applied: x=%v", x)
+			}
+			if got := a.GetAnim(); got != "ginny_idle_down" {
+				t.Errorf("anim = %q, want the previous ginny_idle_down", got)
+			}
+			moves := messagesOfType(drainMessages(t, observer), messages.TypeMovement)
+			if len(moves) != 1 {
+				t.Fatalf("observer got %d movements, want 1", len(moves))
+			}
+			var payload messages.MovementPayload
+			if err := json.Unmarshal(moves[0].Payload, &payload); err != nil {
+				t.Fatal(err)
+			}
+			if payload.Anim != "ginny_idle_down" {
+				t.Errorf("broadcast anim = %q, want ginny_idle_down", payload.Anim)
+			}
+		})
+	}
+}
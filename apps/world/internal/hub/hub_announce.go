@@ -0,0 +1,47 @@
+package hub
+
+import (
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"world/internal/messages"
+)
+
+// maxAnnouncementRunes caps an announcement banner's text
+const maxAnnouncementRunes = 500
+
+// handleAnnouncement broadcasts an admin's banner to everyone in their space, the
+// admin included. Over-long text and unknown levels are dropped rather than trimmed,
+// since a host would rather retype a banner than show a cut-off one. Access is
+// checked in ProcessMessage.
+func (h *Hub) handleAnnouncement(client *Client, payload messages.IncomingPayload) {
+	if client.SpaceID == "" {
+		return
+	}
+
+	text := strings.TrimSpace(payload.Text)
+	if text == "" || utf8.RuneCountInString(text) > maxAnnouncementRunes {
+		log.Printf("Announcement from %s ignored: text must be 1-%d characters", client.UserID, maxAnnouncementRunes)
+		return
+	}
+	level := payload.Level
+	if level == "" {
+		level = messages.AnnouncementLevelInfo
+	}
+	if level != messages.AnnouncementLevelInfo && level != messages.AnnouncementLevelWarning {
+		log.Printf("Announcement from %s ignored: unknown level %q", client.UserID, level)
+		return
+	}
+
+	h.broadcastToSpace(client.SpaceID, messages.BaseMessage{
+		Type: messages.TypeAnnouncement,
+		Payload: messages.AnnouncementPayload{
+			From:      client.UserID,
+			Text:      text,
+			Level:     level,
+			Timestamp: time.Now().UnixMilli(),
+		},
+	}, "")
+}
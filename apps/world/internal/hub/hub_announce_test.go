@@ -0,0 +1,80 @@
+package hub
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"world/internal/messages"
+)
+
+func TestAdminAnnouncementReachesEveryone(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	admin := addTestClient(h, space, "admin", 100, 100)
+	admin.Role = RoleAdmin
+	near := addTestClient(h, space, "near", 150, 100)
+	far := addTestClient(h, space, "far", 900, 900)
+
+	h.ProcessMessage(admin, []byte(`{"type":"announcement","payload":{"text":" Talks start in 5 minutes ","level":"warning"}}`))
+
+	for _, c := range []*Client{admin, near, far} {
+		got := messagesOfType(drainMessages(t, c), messages.TypeAnnouncement)
+		if len(got) != 1 {
+			t.Fatalf("%s got %d announcements, want 1", c.UserID, len(got))
+		}
+		var payload messages.AnnouncementPayload
+		if err := json.Unmarshal(got[0].Payload, &payload); err != nil {
+			t.Fatal(err)
+		}
+		if payload.From != "admin" || payload.Text != "Talks start in 5 minutes" || payload.Level != messages.AnnouncementLevelWarning {
+			t.Errorf("%s: unexpected payload %+v", c.UserID, payload)
+		}
+	}
+
+	// The level defaults to info
+	h.handleAnnouncement(admin, messages.IncomingPayload{Text: "Welcome"})
+	got := messagesOfType(drainMessages(t, far), messages.TypeAnnouncement)
+	if len(got) != 1 {
+		t.Fatalf("got %d announcements, want 1", len(got))
+	}
+	var payload messages.AnnouncementPayload
+	if err := json.Unmarshal(got[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Level != messages.AnnouncementLevelInfo {
+		t.Errorf("level = %q, want info", payload.Level)
+	}
+}
+
+func TestAnnouncementRejected(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	admin := addTestClient(h, space, "admin", 100, 100)
+	admin.Role = RoleAdmin
+	user := addTestClient(h, space, "user", 150, 100)
+
+	h.ProcessMessage(user, []byte(`{"type":"announcement","payload":{"text":"free tickets"}}`))
+	if n := len(messagesOfType(drainMessages(t, admin), messages.TypeAnnouncement)); n != 0 {
+		t.Errorf("non-admin announcement delivered %d messages", n)
+	}
+	if n := len(messagesOfType(drainMessages(t, user), messages.TypeForbidden)); n != 1 {
+		t.Errorf("non-admin got %d forbidden messages, want 1", n)
+	}
+
+	invalid := []messages.IncomingPayload{
+		{Text: "   "},
+		{Text: strings.Repeat("a", maxAnnouncementRunes+1)},
+		{Text: "hello", Level: "critical"},
+	}
+	for _, payload := range invalid {
+		h.handleAnnouncement(admin, payload)
+		if n := len(messagesOfType(drainMessages(t, user), messages.TypeAnnouncement)); n != 0 {
+			t.Errorf("invalid announcement (level %q, %d chars) delivered", payload.Level, len(payload.Text))
+		}
+	}
+}
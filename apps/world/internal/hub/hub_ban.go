@@ -0,0 +1,152 @@
+package hub
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"world/internal/messages"
+)
+
+// BanList records which users are banned from which spaces. The hub starts with an
+// in-memory list; swap in one backed by the backend to keep bans across restarts.
+type BanList interface {
+	// Ban bars userID from spaceID until the given time; a zero time is permanent
+	Ban(spaceID, userID string, until time.Time) error
+	Unban(spaceID, userID string) error
+	IsBanned(spaceID, userID string, now time.Time) (bool, error)
+}
+
+// memoryBanList is a BanList held in process memory
+type memoryBanList struct {
+	mu   sync.Mutex
+	bans map[string]map[string]time.Time // spaceID -> userID -> until
+}
+
+// NewMemoryBanList returns an empty in-memory BanList
+func NewMemoryBanList() BanList {
+	return &memoryBanList{bans: make(map[string]map[string]time.Time)}
+}
+
+func (l *memoryBanList) Ban(spaceID, userID string, until time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.bans[spaceID] == nil {
+		l.bans[spaceID] = make(map[string]time.Time)
+	}
+	l.bans[spaceID][userID] = until
+	return nil
+}
+
+func (l *memoryBanList) Unban(spaceID, userID string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.deleteLocked(spaceID, userID)
+	return nil
+}
+
+// IsBanned reports whether userID is banned from spaceID at now, forgetting expired bans
+func (l *memoryBanList) IsBanned(spaceID, userID string, now time.Time) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	until, ok := l.bans[spaceID][userID]
+	if !ok {
+		return false, nil
+	}
+	if !until.IsZero() && !now.Before(until) {
+		l.deleteLocked(spaceID, userID)
+		return false, nil
+	}
+	return true, nil
+}
+
+func (l *memoryBanList) deleteLocked(spaceID, userID string) {
+	delete(l.bans[spaceID], userID)
+	if len(l.bans[spaceID]) == 0 {
+		delete(l.bans, spaceID)
+	}
+}
+
+// handleBan bars payload.TargetUserID from the admin's space, for payload.DurationMs
+// if set and for good otherwise. A connected target is told and closed with
+// CloseBanned; a suspended session is ended so it can't be resumed. Access is checked
+// in ProcessMessage.
+func (h *Hub) handleBan(client *Client, payload messages.IncomingPayload) {
+	if client.SpaceID == "" || payload.TargetUserID == "" || payload.TargetUserID == client.UserID {
+		return
+	}
+	if payload.DurationMs < 0 {
+		h.recordViolation(client, "negative ban duration")
+		return
+	}
+
+	h.mu.RLock()
+	space, exists := h.Spaces[client.SpaceID]
+	bans := h.Bans
+	h.mu.RUnlock()
+	if !exists {
+		return
+	}
+
+	var until time.Time
+	if payload.DurationMs > 0 {
+		until = time.Now().Add(time.Duration(payload.DurationMs) * time.Millisecond)
+	}
+	if err := bans.Ban(space.ID, payload.TargetUserID, until); err != nil {
+		log.Printf("Ban of %s in space %s by %s failed: %v", payload.TargetUserID, space.ID, client.UserID, err)
+		return
+	}
+	log.Printf("Space %s: %s banned by %s (until %v)", space.ID, payload.TargetUserID, client.UserID, until)
+
+	h.mu.Lock()
+	session, suspended := h.pendingReconnect[payload.TargetUserID]
+	if suspended && session.space == space {
+		delete(h.pendingReconnect, payload.TargetUserID)
+	}
+	h.mu.Unlock()
+	if suspended && session.space == space {
+		h.removeFromSpace(space, session.client)
+		return
+	}
+
+	space.mu.RLock()
+	target, ok := space.Users[payload.TargetUserID]
+	space.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	banned := messages.BannedPayload{By: client.UserID, Reason: payload.Reason}
+	if !until.IsZero() {
+		banned.ExpiresAt = until.UnixMilli()
+	}
+	target.SendJSON(messages.BaseMessage{Type: messages.TypeBanned, Payload: banned})
+	target.Disconnect(CloseBanned)
+}
+
+// handleUnban lifts payload.TargetUserID's ban from the admin's space. Access is
+// checked in ProcessMessage.
+func (h *Hub) handleUnban(client *Client, payload messages.IncomingPayload) {
+	if client.SpaceID == "" || payload.TargetUserID == "" {
+		return
+	}
+
+	h.mu.RLock()
+	bans := h.Bans
+	h.mu.RUnlock()
+
+	if err := bans.Unban(client.SpaceID, payload.TargetUserID); err != nil {
+		log.Printf("Unban of %s in space %s by %s failed: %v", payload.TargetUserID, client.SpaceID, client.UserID, err)
+		return
+	}
+	log.Printf("Space %s: %s unbanned by %s", client.SpaceID, payload.TargetUserID, client.UserID)
+}
+
+// isBanned reports whether userID is banned from spaceID. An error means the ban list
+// couldn't be asked.
+func (h *Hub) isBanned(spaceID, userID string) (bool, error) {
+	h.mu.RLock()
+	bans := h.Bans
+	h.mu.RUnlock()
+	return bans.IsBanned(spaceID, userID, time.Now())
+}
@@ -0,0 +1,87 @@
+package hub
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"world/internal/messages"
+)
+
+func TestBanDisconnectsAndBlocksRejoin(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	admin := joinTestClient(t, h, "lobby", "admin")
+	admin.Role = RoleAdmin
+	target := joinTestClient(t, h, "lobby", "target")
+	drainMessages(t, target)
+
+	h.ProcessMessage(admin, []byte(`{"type":"ban","payload":{"targetUserId":"target","reason":"abuse"}}`))
+
+	banned := messagesOfType(drainMessages(t, target), messages.TypeBanned)
+	if len(banned) != 1 {
+		t.Fatalf("target got %d banned messages, want 1", len(banned))
+	}
+	var payload messages.BannedPayload
+	if err := json.Unmarshal(banned[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.By != "admin" || payload.Reason != "abuse" || payload.ExpiresAt != 0 {
+		t.Errorf("banned payload = %+v", payload)
+	}
+
+	select {
+	case got := <-h.Unregister:
+		if got != target {
+			t.Fatalf("unregistered %s, want target", got.UserID)
+		}
+		h.handleDisconnect(got)
+	case <-time.After(time.Second):
+		t.Fatal("banned user was not unregistered")
+	}
+	if frame := target.closeMessage(); string(frame) != string(CloseBanned.frame()) {
+		t.Errorf("close frame = %q, want banned", frame)
+	}
+
+	if code := joinErrorCode(t, joinTestClient(t, h, "lobby", "target")); code != messages.JoinErrorBanned {
+		t.Errorf("rejoin code = %q, want %q", code, messages.JoinErrorBanned)
+	}
+	if banned, _ := h.Bans.IsBanned("arena", "target", time.Now()); banned {
+		t.Error("ban leaked into another space")
+	}
+
+	h.ProcessMessage(admin, []byte(`{"type":"unban","payload":{"targetUserId":"target"}}`))
+	if code := joinErrorCode(t, joinTestClient(t, h, "lobby", "target")); code != "" {
+		t.Errorf("join after unban rejected with %q", code)
+	}
+}
+
+func TestBanExpiry(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+
+	if err := h.Bans.Ban("lobby", "target", time.Now().Add(50*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if code := joinErrorCode(t, joinTestClient(t, h, "lobby", "target")); code != messages.JoinErrorBanned {
+		t.Fatalf("join during ban code = %q, want %q", code, messages.JoinErrorBanned)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if code := joinErrorCode(t, joinTestClient(t, h, "lobby", "target")); code != "" {
+		t.Errorf("join after ban expired rejected with %q", code)
+	}
+}
+
+func TestNonAdminBanIgnored(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	user := joinTestClient(t, h, "lobby", "user")
+	joinTestClient(t, h, "lobby", "target")
+
+	h.ProcessMessage(user, []byte(`{"type":"ban","payload":{"targetUserId":"target"}}`))
+
+	if banned, _ := h.Bans.IsBanned("lobby", "target", time.Now()); banned {
+		t.Error("non-admin was able to ban")
+	}
+}
@@ -20,27 +20,21 @@ func (h *Hub) handleCameraToggle(client *Client, payload messages.IncomingPayloa
 	space.mu.Lock()
 	defer space.mu.Unlock()
 
-	// Find active meeting
-	for _, state := range space.MeetingStates {
-		if state.Status == MeetingStatusActive && (state.UserA == client.UserID || state.UserB == client.UserID) {
-			var peerID string
-			if state.UserA == client.UserID {
-				peerID = state.UserB
-			} else {
-				peerID = state.UserA
-			}
-
-			if peerClient, ok := space.Users[peerID]; ok {
-				msg := messages.BaseMessage{
-					Type: messages.TypeCameraToggle,
-					Payload: map[string]interface{}{
-						"peerId":  client.UserID,
-						"enabled": payload.Enabled,
-					},
-				}
-				peerClient.SendJSON(msg)
-			}
-			break
+	// Tell everyone in the user's active meeting
+	_, state, ok := space.activeMeetingForLocked(client.UserID)
+	if !ok {
+		return
+	}
+	msg := messages.BaseMessage{
+		Type: messages.TypeCameraToggle,
+		Payload: map[string]interface{}{
+			"peerId":  client.UserID,
+			"enabled": payload.Enabled,
+		},
+	}
+	for _, peerID := range state.peersOf(client.UserID) {
+		if peerClient, ok := space.Users[peerID]; ok {
+			peerClient.SendJSON(msg)
 		}
 	}
 }
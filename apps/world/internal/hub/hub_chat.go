@@ -0,0 +1,126 @@
+package hub
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"world/internal/config"
+	"world/internal/messages"
+)
+
+// chatText trims text and truncates it to maxRunes (0 = unlimited). ok is false for
+// messages with nothing to say.
+func chatText(text string, maxRunes int) (string, bool) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", false
+	}
+	if maxRunes > 0 && utf8.RuneCountInString(text) > maxRunes {
+		text = string([]rune(text)[:maxRunes])
+	}
+	return text, true
+}
+
+// handleChat relays a text message from the sender's space. The default "space" scope
+// reaches everyone; "local" only reaches users within audio range, so an isolated
+// sender only hears themselves. The sender is always included so their client shows
+// the server's timestamp and any truncation, and is always the connection's user,
+// never a payload field.
+func (h *Hub) handleChat(client *Client, payload messages.IncomingPayload) {
+	if client.SpaceID == "" {
+		return
+	}
+
+	scope := payload.Scope
+	if scope == "" {
+		scope = messages.ChatScopeSpace
+	}
+	if scope != messages.ChatScopeSpace && scope != messages.ChatScopeLocal {
+		h.recordViolation(client, "unknown chat scope")
+		return
+	}
+
+	text, ok := chatText(payload.Text, config.Current().ChatMaxRunes)
+	if !ok {
+		return
+	}
+
+	msg := messages.BaseMessage{
+		Type: messages.TypeChat,
+		Payload: messages.ChatPayload{
+			UserID:    client.UserID,
+			Text:      text,
+			Timestamp: time.Now().UnixMilli(),
+			Scope:     scope,
+		},
+	}
+	if scope == messages.ChatScopeSpace {
+		h.broadcastToSpace(client.SpaceID, msg, "")
+		return
+	}
+
+	h.mu.RLock()
+	space, exists := h.Spaces[client.SpaceID]
+	h.mu.RUnlock()
+	if !exists {
+		return
+	}
+	for _, recipient := range append(space.GetAudioNeighbors(client.UserID), client) {
+		recipient.SendJSON(msg)
+	}
+}
+
+// handleDirectMessage delivers a private message to one user in the sender's space
+// and echoes it back to the sender. Failures go back to the sender as dm-error.
+func (h *Hub) handleDirectMessage(client *Client, payload messages.IncomingPayload) {
+	if client.SpaceID == "" {
+		return
+	}
+
+	reject := func(reason string) {
+		client.SendJSON(messages.BaseMessage{
+			Type: messages.TypeDMError,
+			Payload: messages.DMErrorPayload{
+				TargetUserID: payload.TargetUserID,
+				Error:        reason,
+			},
+		})
+	}
+
+	if payload.TargetUserID == client.UserID {
+		reject("cannot message yourself")
+		return
+	}
+	text, ok := chatText(payload.Text, config.Current().ChatMaxRunes)
+	if !ok {
+		return
+	}
+
+	h.mu.RLock()
+	space, exists := h.Spaces[client.SpaceID]
+	h.mu.RUnlock()
+	if !exists {
+		return
+	}
+
+	space.mu.RLock()
+	target, ok := space.Users[payload.TargetUserID]
+	space.mu.RUnlock()
+	if !ok {
+		reject("user is not in this space")
+		return
+	}
+
+	msg := messages.BaseMessage{
+		Type: messages.TypeDirectMessage,
+		Payload: messages.DirectMessagePayload{
+			UserID:       client.UserID,
+			TargetUserID: payload.TargetUserID,
+			Text:         text,
+			Timestamp:    time.Now().UnixMilli(),
+		},
+	}
+	target.SendJSON(msg)
+	client.SendJSON(msg)
+}
@@ -0,0 +1,172 @@
+package hub
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"world/internal/config"
+	"world/internal/messages"
+)
+
+// chatPayloads decodes the chat messages received by c
+func chatPayloads(t *testing.T, c *Client) []messages.ChatPayload {
+	t.Helper()
+	var out []messages.ChatPayload
+	for _, m := range messagesOfType(drainMessages(t, c), messages.TypeChat) {
+		var p messages.ChatPayload
+		if err := json.Unmarshal(m.Payload, &p); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+func TestChatBroadcastsToSpaceIncludingSender(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	other := newTestSpace(h, "s2")
+	a := addTestClient(h, space, "a", 100, 100)
+	b := addTestClient(h, space, "b", 900, 900)
+	c := addTestClient(h, other, "c", 100, 100)
+
+	h.handleChat(a, messages.IncomingPayload{Text: "  hello  ", TargetUserID: "b"})
+
+	for _, recipient := range []*Client{a, b} {
+		got := chatPayloads(t, recipient)
+		if len(got) != 1 || got[0].Text != "hello" || got[0].UserID != "a" || got[0].Timestamp == 0 {
+			t.Errorf("%s got %+v", recipient.UserID, got)
+		}
+	}
+	if got := chatPayloads(t, c); len(got) != 0 {
+		t.Errorf("other space got %+v", got)
+	}
+
+	h.handleChat(a, messages.IncomingPayload{Text: "   "})
+	if got := chatPayloads(t, b); len(got) != 0 {
+		t.Errorf("empty message was broadcast: %+v", got)
+	}
+}
+
+func TestChatTruncatesLongMessages(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.ChatMaxRunes = 5 })
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+
+	h.handleChat(a, messages.IncomingPayload{Text: strings.Repeat("é", 8)})
+	got := chatPayloads(t, a)
+	if len(got) != 1 || utf8.RuneCountInString(got[0].Text) != 5 || !utf8.ValidString(got[0].Text) {
+		t.Errorf("got %+v, want 5 valid runes", got)
+	}
+}
+
+func TestLocalChatReachesOnlyAudioNeighbors(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	near := addTestClient(h, space, "near", 200, 100)
+	far := addTestClient(h, space, "far", 1200, 900)
+	for _, c := range []*Client{a, near, far} {
+		h.recomputeProximity(space, c)
+	}
+	drainMessages(t, a)
+	drainMessages(t, near)
+	drainMessages(t, far)
+
+	if got := space.GetAudioNeighbors("a"); len(got) != 1 || got[0] != near {
+		t.Fatalf("audio neighbors of a = %v", got)
+	}
+
+	h.handleChat(a, messages.IncomingPayload{Text: "psst", Scope: messages.ChatScopeLocal})
+	for _, c := range []*Client{a, near} {
+		if got := chatPayloads(t, c); len(got) != 1 || got[0].Scope != messages.ChatScopeLocal {
+			t.Errorf("%s got %+v", c.UserID, got)
+		}
+	}
+	if got := chatPayloads(t, far); len(got) != 0 {
+		t.Errorf("far user heard local chat: %+v", got)
+	}
+
+	// An isolated sender only hears themselves
+	h.handleChat(far, messages.IncomingPayload{Text: "anyone?", Scope: messages.ChatScopeLocal})
+	if got := chatPayloads(t, far); len(got) != 1 {
+		t.Errorf("isolated sender got %+v, want their own echo", got)
+	}
+	if got := chatPayloads(t, a); len(got) != 0 {
+		t.Errorf("a heard an isolated sender: %+v", got)
+	}
+}
+
+func TestDirectMessageDeliveredToTargetAndEchoed(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	b := addTestClient(h, space, "b", 900, 900)
+	c := addTestClient(h, space, "c", 120, 100)
+
+	h.handleDirectMessage(a, messages.IncomingPayload{TargetUserID: "b", Text: " psst "})
+
+	for _, recipient := range []*Client{a, b} {
+		dms := messagesOfType(drainMessages(t, recipient), messages.TypeDirectMessage)
+		if len(dms) != 1 {
+			t.Fatalf("%s got %d dms, want 1", recipient.UserID, len(dms))
+		}
+		var p messages.DirectMessagePayload
+		if err := json.Unmarshal(dms[0].Payload, &p); err != nil {
+			t.Fatal(err)
+		}
+		if p.UserID != "a" || p.TargetUserID != "b" || p.Text != "psst" || p.Timestamp == 0 {
+			t.Errorf("%s got %+v", recipient.UserID, p)
+		}
+	}
+	if got := drainMessages(t, c); len(got) != 0 {
+		t.Errorf("bystander got %d messages, want 0", len(got))
+	}
+}
+
+func TestDirectMessageErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+	}{
+		{"missing target", "ghost"},
+		{"user in another space", "c"},
+		{"self", "a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestConfig(t)
+			h := NewHub()
+			a := addTestClient(h, newTestSpace(h, "s1"), "a", 100, 100)
+			c := addTestClient(h, newTestSpace(h, "s2"), "c", 100, 100)
+
+			h.handleDirectMessage(a, messages.IncomingPayload{TargetUserID: tt.target, Text: "hi"})
+
+			got := drainMessages(t, a)
+			if len(messagesOfType(got, messages.TypeDirectMessage)) != 0 {
+				t.Error("sender got an echo for an undelivered dm")
+			}
+			errs := messagesOfType(got, messages.TypeDMError)
+			if len(errs) != 1 {
+				t.Fatalf("got %d dm-errors, want 1", len(errs))
+			}
+			var p messages.DMErrorPayload
+			if err := json.Unmarshal(errs[0].Payload, &p); err != nil {
+				t.Fatal(err)
+			}
+			if p.TargetUserID != tt.target || p.Error == "" {
+				t.Errorf("dm-error = %+v", p)
+			}
+			if n := len(drainMessages(t, c)); n != 0 {
+				t.Errorf("other space got %d messages", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package hub
+
+import (
+	"log"
+
+	"world/internal/config"
+	"world/internal/messages"
+)
+
+// maxCustomEventBytes caps the opaque data relayed with a custom event
+const maxCustomEventBytes = 1024
+
+// handleCustomBroadcast relays an admin-defined event to the space, or only to users
+// within payload.Radius of the admin when a radius is given. Access is checked in ProcessMessage.
+func (h *Hub) handleCustomBroadcast(client *Client, payload messages.IncomingPayload) {
+	if client.SpaceID == "" {
+		return
+	}
+	if !isAllowedCustomSubtype(payload.Subtype) {
+		log.Printf("Custom broadcast ignored: subtype %q not allowed", payload.Subtype)
+		return
+	}
+	if len(payload.Data) > maxCustomEventBytes {
+		log.Printf("Custom broadcast ignored: %d bytes exceeds limit", len(payload.Data))
+		return
+	}
+
+	h.mu.RLock()
+	space, exists := h.Spaces[client.SpaceID]
+	h.mu.RUnlock()
+	if !exists {
+		return
+	}
+
+	msg := messages.BaseMessage{
+		Type: messages.TypeCustomEvent,
+		Payload: messages.CustomEventPayload{
+			Subtype: payload.Subtype,
+			From:    client.UserID,
+			Data:    payload.Data,
+		},
+	}
+
+	cx, cy := client.GetPosition()
+	for _, recipient := range space.GetAllUsers() {
+		if payload.Radius > 0 {
+			rx, ry := recipient.GetPosition()
+			if distance(cx, cy, rx, ry) > payload.Radius {
+				continue
+			}
+		}
+		recipient.SendJSON(msg)
+	}
+}
+
+func isAllowedCustomSubtype(subtype string) bool {
+	for _, allowed := range config.Current().CustomEventSubtypes {
+		if subtype == allowed {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package hub
+
+import (
+	"encoding/json"
+	"testing"
+
+	"world/internal/config"
+	"world/internal/messages"
+)
+
+func TestCustomBroadcastAllowlist(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.CustomEventSubtypes = []string{"trivia"} })
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	admin := addTestClient(h, space, "admin", 100, 100)
+	admin.Role = "admin"
+	user := addTestClient(h, space, "user", 900, 900)
+
+	h.handleCustomBroadcast(admin, messages.IncomingPayload{Subtype: "rickroll", Data: json.RawMessage(`{}`)})
+	if n := len(messagesOfType(drainMessages(t, user), messages.TypeCustomEvent)); n != 0 {
+		t.Errorf("disallowed subtype delivered %d events", n)
+	}
+
+	h.ProcessMessage(user, []byte(`{"type":"custom-broadcast","payload":{"subtype":"trivia"}}`))
+	if n := len(messagesOfType(drainMessages(t, admin), messages.TypeCustomEvent)); n != 0 {
+		t.Errorf("non-admin broadcast delivered %d events", n)
+	}
+
+	big := json.RawMessage(`"` + string(make([]byte, maxCustomEventBytes)) + `"`)
+	h.handleCustomBroadcast(admin, messages.IncomingPayload{Subtype: "trivia", Data: big})
+	if n := len(messagesOfType(drainMessages(t, user), messages.TypeCustomEvent)); n != 0 {
+		t.Errorf("oversized payload delivered %d events", n)
+	}
+
+	h.handleCustomBroadcast(admin, messages.IncomingPayload{Subtype: "trivia", Data: json.RawMessage(`{"q":1}`)})
+	events := messagesOfType(drainMessages(t, user), messages.TypeCustomEvent)
+	if len(events) != 1 {
+		t.Fatalf("got %d custom events, want 1", len(events))
+	}
+	var payload messages.CustomEventPayload
+	if err := json.Unmarshal(events[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Subtype != "trivia" || payload.From != "admin" || string(payload.Data) != `{"q":1}` {
+		t.Errorf("unexpected payload %+v", payload)
+	}
+}
+
+func TestCustomBroadcastZoneScope(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.CustomEventSubtypes = []string{"poll"} })
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	admin := addTestClient(h, space, "admin", 100, 100)
+	admin.Role = "admin"
+	near := addTestClient(h, space, "near", 150, 100)
+	far := addTestClient(h, space, "far", 900, 900)
+
+	h.handleCustomBroadcast(admin, messages.IncomingPayload{Subtype: "poll", Radius: 100})
+
+	for c, want := range map[*Client]int{admin: 1, near: 1, far: 0} {
+		if n := len(messagesOfType(drainMessages(t, c), messages.TypeCustomEvent)); n != want {
+			t.Errorf("%s got %d custom events, want %d", c.UserID, n, want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package hub
+
+import "world/internal/messages"
+
+// Emote display bounds. Clients that omit durationMs get the default.
+const (
+	minEmoteDurationMs     = 500
+	maxEmoteDurationMs     = 5000
+	defaultEmoteDurationMs = 2000
+)
+
+// allowedEmotes are the emote codes clients know how to render. Anything else is
+// rejected so the broadcast can't carry arbitrary text.
+var allowedEmotes = map[string]bool{
+	"wave":     true,
+	"heart":    true,
+	"laugh":    true,
+	"thumbsup": true,
+	"clap":     true,
+	"surprise": true,
+}
+
+// clampEmoteDuration keeps durationMs within the display bounds
+func clampEmoteDuration(durationMs int64) int64 {
+	switch {
+	case durationMs == 0:
+		return defaultEmoteDurationMs
+	case durationMs < minEmoteDurationMs:
+		return minEmoteDurationMs
+	case durationMs > maxEmoteDurationMs:
+		return maxEmoteDurationMs
+	}
+	return durationMs
+}
+
+// handleEmote broadcasts a reaction to everyone in the sender's space, sender included
+func (h *Hub) handleEmote(client *Client, payload messages.IncomingPayload) {
+	if client.SpaceID == "" {
+		return
+	}
+	if !allowedEmotes[payload.Emote] {
+		h.recordViolation(client, "unknown emote")
+		return
+	}
+
+	h.broadcastToSpace(client.SpaceID, messages.BaseMessage{
+		Type: messages.TypeEmote,
+		Payload: messages.EmotePayload{
+			UserID:     client.UserID,
+			Emote:      payload.Emote,
+			DurationMs: clampEmoteDuration(payload.DurationMs),
+		},
+	}, "")
+}
@@ -0,0 +1,80 @@
+package hub
+
+import (
+	"encoding/json"
+	"testing"
+
+	"world/internal/messages"
+)
+
+// emotePayloads decodes the emote messages received by c
+func emotePayloads(t *testing.T, c *Client) []messages.EmotePayload {
+	t.Helper()
+	var out []messages.EmotePayload
+	for _, m := range messagesOfType(drainMessages(t, c), messages.TypeEmote) {
+		var p messages.EmotePayload
+		if err := json.Unmarshal(m.Payload, &p); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+func TestEmoteBroadcastsToSpace(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	b := addTestClient(h, space, "b", 900, 900)
+	c := addTestClient(h, newTestSpace(h, "s2"), "c", 100, 100)
+
+	h.handleEmote(a, messages.IncomingPayload{Emote: "wave", DurationMs: 1500})
+
+	for _, recipient := range []*Client{a, b} {
+		got := emotePayloads(t, recipient)
+		want := messages.EmotePayload{UserID: "a", Emote: "wave", DurationMs: 1500}
+		if len(got) != 1 || got[0] != want {
+			t.Errorf("%s got %+v, want [%+v]", recipient.UserID, got, want)
+		}
+	}
+	if got := emotePayloads(t, c); len(got) != 0 {
+		t.Errorf("other space got %+v", got)
+	}
+}
+
+func TestEmoteRejectsUnknownCodes(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	b := addTestClient(h, space, "b", 900, 900)
+
+	for _, emote := range []string{"", "WAVE", "<script>", "wave "} {
+		h.handleEmote(a, messages.IncomingPayload{Emote: emote})
+	}
+	for _, c := range []*Client{a, b} {
+		if got := emotePayloads(t, c); len(got) != 0 {
+			t.Errorf("%s got %+v, want none", c.UserID, got)
+		}
+	}
+}
+
+func TestClampEmoteDuration(t *testing.T) {
+	tests := []struct {
+		in, want int64
+	}{
+		{0, defaultEmoteDurationMs},
+		{-100, minEmoteDurationMs},
+		{100, minEmoteDurationMs},
+		{500, 500},
+		{3000, 3000},
+		{5000, 5000},
+		{60000, maxEmoteDurationMs},
+	}
+	for _, tt := range tests {
+		if got := clampEmoteDuration(tt.in); got != tt.want {
+			t.Errorf("clampEmoteDuration(%d) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
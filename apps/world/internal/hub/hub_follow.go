@@ -0,0 +1,150 @@
+package hub
+
+import (
+	"log"
+	"math"
+	"sort"
+
+	"world/internal/messages"
+)
+
+// followDistance is how far behind their target a follower is placed
+const followDistance = joinNearDistance
+
+// Follow starts followerID following targetID. It returns false if they're the same
+// user, or either isn't in the space, or the target hides from the follower.
+func (s *Space) Follow(followerID, targetID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if followerID == targetID {
+		return false
+	}
+	target, ok := s.Users[targetID]
+	if _, present := s.Users[followerID]; !ok || !present || target.HidesFrom(followerID) {
+		return false
+	}
+	s.Following[followerID] = targetID
+	return true
+}
+
+// Unfollow stops followerID following anyone, returning who they followed ("" if nobody)
+func (s *Space) Unfollow(followerID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	targetID := s.Following[followerID]
+	delete(s.Following, followerID)
+	return targetID
+}
+
+// Followers returns the users following targetID, sorted
+func (s *Space) Followers(targetID string) []*Client {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	followers := make([]*Client, 0)
+	for followerID, id := range s.Following {
+		if id != targetID {
+			continue
+		}
+		if c, ok := s.Users[followerID]; ok {
+			followers = append(followers, c)
+		}
+	}
+	sort.Slice(followers, func(i, j int) bool { return followers[i].UserID < followers[j].UserID })
+	return followers
+}
+
+// handleFollow starts the sender following payload.TargetUserID in their space. They
+// get a follow back, or an unfollow with reason unavailable if the target can't be followed.
+func (h *Hub) handleFollow(client *Client, payload messages.IncomingPayload) {
+	if client.SpaceID == "" || payload.TargetUserID == "" {
+		return
+	}
+
+	h.mu.RLock()
+	space, exists := h.Spaces[client.SpaceID]
+	h.mu.RUnlock()
+	if !exists {
+		return
+	}
+
+	if !space.Follow(client.UserID, payload.TargetUserID) {
+		sendUnfollow(client, payload.TargetUserID, messages.FollowEndUnavailable)
+		return
+	}
+	log.Printf("Space %s: %s follows %s", space.ID, client.UserID, payload.TargetUserID)
+	client.SendJSON(messages.BaseMessage{
+		Type:    messages.TypeFollow,
+		Payload: messages.FollowPayload{TargetUserID: payload.TargetUserID},
+	})
+}
+
+// handleUnfollow stops the sender following anyone
+func (h *Hub) handleUnfollow(client *Client) {
+	if client.SpaceID == "" {
+		return
+	}
+
+	h.mu.RLock()
+	space, exists := h.Spaces[client.SpaceID]
+	h.mu.RUnlock()
+	if !exists {
+		return
+	}
+
+	if targetID := space.Unfollow(client.UserID); targetID != "" {
+		sendUnfollow(client, targetID, messages.FollowEndRequested)
+	}
+}
+
+// applyFollows runs after client moved itself from (oldX, oldY): a manual move ends
+// its own follow, and its followers are placed just behind it. Being placed doesn't
+// move a follower's own followers.
+func (h *Hub) applyFollows(space *Space, client *Client, oldX, oldY float64) {
+	if targetID := space.Unfollow(client.UserID); targetID != "" {
+		sendUnfollow(client, targetID, messages.FollowEndMoved)
+	}
+
+	followers := space.Followers(client.UserID)
+	if len(followers) == 0 {
+		return
+	}
+
+	// Behind is back along the direction of travel
+	x, y := client.GetPosition()
+	dx, dy := x-oldX, y-oldY
+	dist := math.Hypot(dx, dy)
+	if dist == 0 {
+		return
+	}
+	behindX, behindY := x-dx/dist*followDistance, y-dy/dist*followDistance
+
+	for _, follower := range followers {
+		fx, fy, ok := space.freeSpotAt(behindX, behindY, follower.UserID)
+		if !ok {
+			continue
+		}
+		if _, err := space.PlaceUser(follower.UserID, fx, fy); err != nil {
+			continue
+		}
+		h.announceRelocation(space, follower)
+	}
+}
+
+// endFollows drops userID's follows once they've left space, telling anyone who was
+// following them
+func (h *Hub) endFollows(space *Space, userID string) {
+	space.Unfollow(userID)
+	for _, follower := range space.Followers(userID) {
+		space.Unfollow(follower.UserID)
+		sendUnfollow(follower, userID, messages.FollowEndTargetLeft)
+	}
+}
+
+func sendUnfollow(client *Client, targetID, reason string) {
+	client.SendJSON(messages.BaseMessage{
+		Type:    messages.TypeUnfollow,
+		Payload: messages.FollowPayload{TargetUserID: targetID, Reason: reason},
+	})
+}
@@ -0,0 +1,119 @@
+package hub
+
+import (
+	"encoding/json"
+	"testing"
+
+	"world/internal/messages"
+)
+
+// unfollowReasons returns the reasons of the unfollow messages c has been sent
+func unfollowReasons(t *testing.T, c *Client) []string {
+	t.Helper()
+	var reasons []string
+	for _, m := range messagesOfType(drainMessages(t, c), messages.TypeUnfollow) {
+		var p messages.FollowPayload
+		if err := json.Unmarshal(m.Payload, &p); err != nil {
+			t.Fatal(err)
+		}
+		reasons = append(reasons, p.Reason)
+	}
+	return reasons
+}
+
+func TestFollowerPlacedBehindTarget(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	guide := addTestClient(h, space, "guide", 400, 400)
+	tourist := addTestClient(h, space, "tourist", 200, 200)
+	watcher := addTestClient(h, space, "watcher", 900, 900)
+
+	h.ProcessMessage(tourist, []byte(`{"type":"follow","payload":{"targetUserId":"guide"}}`))
+	if n := len(messagesOfType(drainMessages(t, tourist), messages.TypeFollow)); n != 1 {
+		t.Fatalf("tourist got %d follow confirmations, want 1", n)
+	}
+
+	// Walking right puts the follower followDistance to the guide's left
+	h.handleMovement(guide, messages.IncomingPayload{X: 420, Y: 400})
+	if x, y := tourist.GetPosition(); x != 420-followDistance || y != 400 {
+		t.Errorf("tourist at (%v, %v), want (%v, 400)", x, y, 420-followDistance)
+	}
+	for _, c := range []*Client{tourist, watcher} {
+		var moved bool
+		for _, m := range messagesOfType(drainMessages(t, c), messages.TypeMovement) {
+			var p messages.MovementPayload
+			if err := json.Unmarshal(m.Payload, &p); err != nil {
+				t.Fatal(err)
+			}
+			moved = moved || p.UserID == "tourist"
+		}
+		if !moved {
+			t.Errorf("%s wasn't told the tourist moved", c.UserID)
+		}
+	}
+
+	// A blocked spot behind is swapped for a free one nearby
+	space.Elements[posKey(440, 420-followDistance)] = true
+	h.handleMovement(guide, messages.IncomingPayload{X: 440, Y: 420})
+	if x, y := tourist.GetPosition(); space.IsColliding(x, y, "tourist") || distance(x, y, 440, 420) > 3*followDistance {
+		t.Errorf("tourist at (%v, %v), want a free spot near the guide", x, y)
+	}
+
+	// Moving on their own breaks the follow
+	x, y := tourist.GetPosition()
+	h.handleMovement(tourist, messages.IncomingPayload{X: x + 10, Y: y})
+	if got := unfollowReasons(t, tourist); len(got) != 1 || got[0] != messages.FollowEndMoved {
+		t.Errorf("unfollow reasons %v, want [moved]", got)
+	}
+	h.handleMovement(guide, messages.IncomingPayload{X: 460, Y: 420})
+	if nx, ny := tourist.GetPosition(); nx != x+10 || ny != y {
+		t.Errorf("tourist still dragged to (%v, %v) after breaking the follow", nx, ny)
+	}
+}
+
+func TestFollowEndsWhenTargetLeaves(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	guide := addTestClient(h, space, "guide", 400, 400)
+	a := addTestClient(h, space, "a", 200, 200)
+	b := addTestClient(h, space, "b", 250, 200)
+	for _, c := range []*Client{a, b} {
+		h.handleFollow(c, messages.IncomingPayload{TargetUserID: "guide"})
+		drainMessages(t, c)
+	}
+
+	h.removeFromSpace(space, guide)
+
+	for _, c := range []*Client{a, b} {
+		if got := unfollowReasons(t, c); len(got) != 1 || got[0] != messages.FollowEndTargetLeft {
+			t.Errorf("%s: unfollow reasons %v, want [target_left]", c.UserID, got)
+		}
+	}
+	if len(space.Following) != 0 {
+		t.Errorf("follows left behind: %v", space.Following)
+	}
+}
+
+func TestFollowUnavailableTarget(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 200, 200)
+	shy := addTestClient(h, space, "shy", 300, 300)
+	shy.SetHiddenFrom("a", true)
+
+	for _, target := range []string{"nobody", "a", "shy"} {
+		h.handleFollow(a, messages.IncomingPayload{TargetUserID: target})
+		if got := unfollowReasons(t, a); len(got) != 1 || got[0] != messages.FollowEndUnavailable {
+			t.Errorf("following %q: unfollow reasons %v, want [unavailable]", target, got)
+		}
+	}
+	if len(space.Following) != 0 {
+		t.Errorf("follows recorded: %v", space.Following)
+	}
+}
@@ -0,0 +1,122 @@
+package hub
+
+import (
+	"log"
+
+	"world/internal/messages"
+)
+
+// RaiseHand appends the user to the hand queue. Returns false if already queued.
+func (s *Space) RaiseHand(userID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range s.HandQueue {
+		if id == userID {
+			return false
+		}
+	}
+	s.HandQueue = append(s.HandQueue, userID)
+	return true
+}
+
+// LowerHand removes the user from the hand queue. Returns false if they weren't queued.
+func (s *Space) LowerHand(userID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, id := range s.HandQueue {
+		if id == userID {
+			s.HandQueue = append(s.HandQueue[:i], s.HandQueue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// AdvanceHands pops the user at the front of the queue
+func (s *Space) AdvanceHands() (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.HandQueue) == 0 {
+		return "", false
+	}
+	next := s.HandQueue[0]
+	s.HandQueue = s.HandQueue[1:]
+	return next, true
+}
+
+// ClearHands empties the queue
+func (s *Space) ClearHands() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.HandQueue) == 0 {
+		return false
+	}
+	s.HandQueue = nil
+	return true
+}
+
+// GetHandQueue returns a copy of the hand queue
+func (s *Space) GetHandQueue() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	queue := make([]string, len(s.HandQueue))
+	copy(queue, s.HandQueue)
+	return queue
+}
+
+// handleHand processes raise/lower/advance/clear requests. Anyone can raise or lower
+// their own hand; by default only an admin (the host) can advance, clear, or lower someone
+// else's (see permissions.go).
+func (h *Hub) handleHand(client *Client, msgType string, payload messages.IncomingPayload) {
+	if client.SpaceID == "" {
+		return
+	}
+
+	h.mu.RLock()
+	space, exists := h.Spaces[client.SpaceID]
+	h.mu.RUnlock()
+	if !exists {
+		return
+	}
+
+	changed := false
+
+	switch msgType {
+	case messages.TypeRaiseHand:
+		changed = space.RaiseHand(client.UserID)
+	case messages.TypeLowerHand:
+		target := client.UserID
+		if payload.TargetUserID != "" && payload.TargetUserID != client.UserID {
+			if !Can(client.Role, PermissionLowerOthersHand) {
+				log.Printf("Lower hand ignored: %s is not the host", client.UserID)
+				return
+			}
+			target = payload.TargetUserID
+		}
+		changed = space.LowerHand(target)
+	case messages.TypeAdvanceHand:
+		_, changed = space.AdvanceHands()
+	case messages.TypeClearHands:
+		changed = space.ClearHands()
+	}
+
+	if changed {
+		h.broadcastHandQueue(space)
+	}
+}
+
+// broadcastHandQueue sends the current hand queue to everyone in the space
+func (h *Hub) broadcastHandQueue(space *Space) {
+	msg := messages.BaseMessage{
+		Type:    messages.TypeHandQueue,
+		Payload: messages.HandQueuePayload{Queue: space.GetHandQueue()},
+	}
+	for _, client := range space.GetAllUsers() {
+		client.SendJSON(msg)
+	}
+}
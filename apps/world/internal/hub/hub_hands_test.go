@@ -0,0 +1,78 @@
+package hub
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"world/internal/messages"
+)
+
+// lastHandQueue returns the queue from the most recent hand-queue message
+func lastHandQueue(t *testing.T, c *Client) []string {
+	t.Helper()
+	msgs := messagesOfType(drainMessages(t, c), messages.TypeHandQueue)
+	if len(msgs) == 0 {
+		t.Fatalf("%s received no hand-queue message", c.UserID)
+	}
+	var payload messages.HandQueuePayload
+	if err := json.Unmarshal(msgs[len(msgs)-1].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	return payload.Queue
+}
+
+func TestHandQueueOrdering(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	host := addTestClient(h, space, "host", 100, 100)
+	host.Role = "admin"
+	a := addTestClient(h, space, "a", 200, 100)
+	b := addTestClient(h, space, "b", 300, 100)
+
+	h.handleHand(b, messages.TypeRaiseHand, messages.IncomingPayload{})
+	h.handleHand(a, messages.TypeRaiseHand, messages.IncomingPayload{})
+	h.handleHand(b, messages.TypeRaiseHand, messages.IncomingPayload{}) // duplicate, ignored
+
+	if got := lastHandQueue(t, host); !reflect.DeepEqual(got, []string{"b", "a"}) {
+		t.Errorf("queue = %v, want [b a]", got)
+	}
+
+	// Only the host may advance
+	h.ProcessMessage(a, []byte(`{"type":"advance-hand"}`))
+	if got := space.GetHandQueue(); !reflect.DeepEqual(got, []string{"b", "a"}) {
+		t.Errorf("non-host advanced the queue to %v", got)
+	}
+
+	h.handleHand(host, messages.TypeAdvanceHand, messages.IncomingPayload{})
+	if got := lastHandQueue(t, a); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("queue after advance = %v, want [a]", got)
+	}
+
+	h.handleHand(host, messages.TypeClearHands, messages.IncomingPayload{})
+	if got := lastHandQueue(t, b); len(got) != 0 {
+		t.Errorf("queue after clear = %v, want empty", got)
+	}
+}
+
+func TestHandQueueRemovesLeavingUser(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	b := addTestClient(h, space, "b", 200, 100)
+	c := addTestClient(h, space, "c", 300, 100)
+
+	for _, u := range []*Client{a, b, c} {
+		h.handleHand(u, messages.TypeRaiseHand, messages.IncomingPayload{})
+	}
+	drainMessages(t, a)
+
+	h.handleDisconnect(b)
+	if got := lastHandQueue(t, a); !reflect.DeepEqual(got, []string{"a", "c"}) {
+		t.Errorf("queue after b left = %v, want [a c]", got)
+	}
+}
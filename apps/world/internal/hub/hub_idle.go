@@ -0,0 +1,67 @@
+package hub
+
+import (
+	"time"
+
+	"world/internal/config"
+	"world/internal/messages"
+)
+
+// markIdle flags the client idle if it has sent no application message within timeout.
+// It reports whether the client just went idle, so each idle spell is announced once.
+func (c *Client) markIdle(now time.Time, timeout time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.idle || c.lastActivity.IsZero() || now.Sub(c.lastActivity) < timeout {
+		return false
+	}
+	c.idle = true
+	return true
+}
+
+// isIdle reports whether the client's space has been told it went idle
+func (c *Client) isIdle() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.idle
+}
+
+// markIdleClients tells each space which of its users have gone quiet for AwayTimeout.
+// ProcessMessage announces them active again on their next message.
+func (h *Hub) markIdleClients(now time.Time) {
+	timeout := config.Current().AwayTimeout
+	if timeout <= 0 {
+		return
+	}
+
+	h.mu.RLock()
+	clients := make([]*Client, 0, len(h.Clients))
+	for client := range h.Clients {
+		if client.SpaceID != "" {
+			clients = append(clients, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, client := range clients {
+		if client.markIdle(now, timeout) {
+			h.broadcastIdle(client, true)
+		}
+	}
+}
+
+// broadcastIdle tells everyone in the client's space, the client included, that it went
+// idle or came back
+func (h *Hub) broadcastIdle(client *Client, idle bool) {
+	if client.SpaceID == "" {
+		return
+	}
+	msgType := messages.TypeUserActive
+	if idle {
+		msgType = messages.TypeUserIdle
+	}
+	h.broadcastToSpace(client.SpaceID, messages.BaseMessage{
+		Type:    msgType,
+		Payload: messages.UserIdlePayload{UserID: client.UserID},
+	}, "")
+}
@@ -0,0 +1,120 @@
+package hub
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"world/internal/config"
+	"world/internal/messages"
+)
+
+// idleUpdates lists the user IDs in the client's queued messages of msgType
+func idleUpdates(t *testing.T, msgs []testMessage, msgType string) []string {
+	t.Helper()
+	var ids []string
+	for _, m := range messagesOfType(msgs, msgType) {
+		var payload messages.UserIdlePayload
+		if err := json.Unmarshal(m.Payload, &payload); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, payload.UserID)
+	}
+	return ids
+}
+
+func TestIdleAndActiveTransitions(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.AwayTimeout = time.Minute })
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	b := addTestClient(h, space, "b", 400, 400)
+
+	start := time.Now()
+	a.touch(start)
+	b.touch(start)
+
+	h.markIdleClients(start.Add(30 * time.Second))
+	if got := idleUpdates(t, drainMessages(t, b), messages.TypeUserIdle); len(got) != 0 {
+		t.Fatalf("idle before the timeout: %v", got)
+	}
+
+	b.touch(start.Add(50 * time.Second))
+	h.markIdleClients(start.Add(90 * time.Second))
+	if got := idleUpdates(t, drainMessages(t, b), messages.TypeUserIdle); len(got) != 1 || got[0] != "a" {
+		t.Fatalf("user-idle = %v, want only a", got)
+	}
+	if got := idleUpdates(t, drainMessages(t, a), messages.TypeUserIdle); len(got) != 1 || got[0] != "a" {
+		t.Errorf("a should be told it went idle, got %v", got)
+	}
+	if !a.isIdle() || b.isIdle() {
+		t.Errorf("idle = %v/%v, want a idle and b active", a.isIdle(), b.isIdle())
+	}
+
+	// Still idle on the next check: no repeat broadcast
+	h.markIdleClients(start.Add(100 * time.Second))
+	if got := idleUpdates(t, drainMessages(t, b), messages.TypeUserIdle); len(got) != 0 {
+		t.Errorf("idle rebroadcast: %v", got)
+	}
+
+	// Any application message brings a back
+	h.ProcessMessage(a, []byte(`{"type":"latency","payload":{"rttMs":40}}`))
+	if got := idleUpdates(t, drainMessages(t, b), messages.TypeUserActive); len(got) != 1 || got[0] != "a" {
+		t.Errorf("user-active = %v, want a", got)
+	}
+	if a.isIdle() {
+		t.Error("a should be active after sending a message")
+	}
+
+	// ...and only once
+	h.ProcessMessage(a, []byte(`{"type":"latency","payload":{"rttMs":40}}`))
+	if got := idleUpdates(t, drainMessages(t, b), messages.TypeUserActive); len(got) != 0 {
+		t.Errorf("user-active repeated: %v", got)
+	}
+}
+
+func TestIdleDisabled(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	a.touch(time.Now().Add(-time.Hour))
+
+	h.markIdleClients(time.Now())
+	if a.isIdle() {
+		t.Error("client marked idle with AwayTimeout disabled")
+	}
+}
+
+func TestSpaceJoinedListsIdleUsers(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.AwayTimeout = time.Minute })
+
+	h := NewHub()
+	away := joinTestClient(t, h, "s1", "away")
+	here := joinTestClient(t, h, "s1", "here")
+	now := time.Now()
+	away.touch(now.Add(-2 * time.Minute))
+	here.touch(now)
+	h.markIdleClients(now)
+
+	late := joinTestClient(t, h, "s1", "late")
+	joined := messagesOfType(drainMessages(t, late), messages.TypeSpaceJoined)
+	if len(joined) != 1 {
+		t.Fatalf("got %d space-joined messages", len(joined))
+	}
+	var payload messages.SpaceJoinedPayload
+	if err := json.Unmarshal(joined[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	idle := map[string]bool{}
+	for _, u := range payload.Users {
+		idle[u.UserID] = u.Idle
+	}
+	if len(idle) != 2 || !idle[away.UserID] || idle[here.UserID] {
+		t.Errorf("users idle = %v, want only away idle", idle)
+	}
+}
@@ -0,0 +1,96 @@
+package hub
+
+import (
+	"world/internal/config"
+	"world/internal/messages"
+)
+
+// maxMoveIntentDistance caps how far a single movement intent may travel
+const maxMoveIntentDistance = 320
+
+// handleMoveIntent processes a movement intent: the client names a target and the server
+// validates the whole path once, then broadcasts the target with a travel duration so peers
+// can interpolate. If the path is blocked the user stops at the last free point and is told so.
+func (h *Hub) handleMoveIntent(client *Client, payload messages.IncomingPayload) {
+	if client.SpaceID == "" {
+		return
+	}
+
+	h.mu.RLock()
+	space, exists := h.Spaces[client.SpaceID]
+	h.mu.RUnlock()
+	if !exists {
+		return
+	}
+
+	oldX, oldY := client.GetPosition()
+	targetX, targetY := NormalizeCoord(payload.X), NormalizeCoord(payload.Y)
+
+	if distance(oldX, oldY, targetX, targetY) > maxMoveIntentDistance {
+		h.recordViolation(client, "move intent too far")
+		client.SendJSON(messages.BaseMessage{
+			Type:    messages.TypeMovementRejected,
+			Payload: messages.MovementRejectedPayload{X: oldX, Y: oldY},
+		})
+		return
+	}
+
+	newX, newY, clear := space.SweepPath(oldX, oldY, targetX, targetY, client.UserID)
+	if !clear {
+		// Tell the mover where they actually ended up
+		client.SendJSON(messages.BaseMessage{
+			Type:    messages.TypeMovementRejected,
+			Payload: messages.MovementRejectedPayload{X: newX, Y: newY},
+		})
+		if newX == oldX && newY == oldY {
+			return
+		}
+	}
+
+	client.SetPosition(newX, newY)
+	anim := applyMoveAnim(client, payload.Anim)
+
+	h.proximityMoved(space, client)
+
+	moveMsg := messages.BaseMessage{
+		Type: messages.TypeMovement,
+		Payload: messages.MovementPayload{
+			X:          newX,
+			Y:          newY,
+			UserID:     client.UserID,
+			Anim:       anim,
+			DurationMs: durationMs(travelDurationMs(distance(oldX, oldY, newX, newY))),
+		},
+	}
+	h.broadcastMovement(space, client, moveMsg)
+	h.Events.Publish(Event{Type: EventUserMoved, SpaceID: space.ID, UserID: client.UserID, X: newX, Y: newY})
+	h.applyFollows(space, client, oldX, oldY)
+
+	if portal, ok := space.portalAt(newX, newY); ok {
+		h.usePortal(space, client, portal)
+	}
+}
+
+// travelDurationMs is how long walking dist units takes at the configured speed
+func travelDurationMs(dist float64) int64 {
+	speed := config.Current().MoveSpeed
+	if speed <= 0 {
+		return 0
+	}
+	return int64(dist / speed * 1000)
+}
+
+// moveDurationMs is how long a client-driven step of dist units takes at MaxMoveSpeed,
+// the fastest a client may sustain, falling back to the walking speed when it's uncapped
+func moveDurationMs(dist float64) int64 {
+	speed := config.Current().MaxMoveSpeed
+	if speed <= 0 {
+		return travelDurationMs(dist)
+	}
+	return int64(dist / speed * 1000)
+}
+
+// durationMs returns ms for MovementPayload.DurationMs, which is sent even when zero
+func durationMs(ms int64) *int64 {
+	return &ms
+}
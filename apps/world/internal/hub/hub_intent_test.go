@@ -0,0 +1,73 @@
+package hub
+
+import (
+	"encoding/json"
+	"testing"
+
+	"world/internal/config"
+	"world/internal/messages"
+)
+
+func TestMoveIntentClearPath(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) {
+		cfg.CoordinatePrecision = 1
+		cfg.MoveSpeed = 200
+	})
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	mover := addTestClient(h, space, "mover", 100, 100)
+	peer := addTestClient(h, space, "peer", 900, 900)
+
+	h.handleMoveIntent(mover, messages.IncomingPayload{X: 300, Y: 100})
+
+	if x, y := mover.GetPosition(); x != 300 || y != 100 {
+		t.Errorf("position = (%v, %v), want (300, 100)", x, y)
+	}
+	moves := messagesOfType(drainMessages(t, peer), messages.TypeMovement)
+	if len(moves) != 1 {
+		t.Fatalf("peer got %d movements, want 1", len(moves))
+	}
+	var payload messages.MovementPayload
+	if err := json.Unmarshal(moves[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.DurationMs == nil || *payload.DurationMs != 1000 {
+		t.Errorf("durationMs = %v, want 1000 for 200 units at 200 u/s", payload.DurationMs)
+	}
+	if n := len(messagesOfType(drainMessages(t, mover), messages.TypeMovementRejected)); n != 0 {
+		t.Errorf("clear path produced %d rejections", n)
+	}
+}
+
+func TestMoveIntentBlockedPath(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.CoordinatePrecision = 1 })
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	space.Elements[posKey(150, 100)] = true // wall halfway along the path
+	mover := addTestClient(h, space, "mover", 100, 100)
+	peer := addTestClient(h, space, "peer", 900, 900)
+
+	h.handleMoveIntent(mover, messages.IncomingPayload{X: 200, Y: 100})
+
+	if x, y := mover.GetPosition(); x != 149 || y != 100 {
+		t.Errorf("position = (%v, %v), want last free point (149, 100)", x, y)
+	}
+	rejected := messagesOfType(drainMessages(t, mover), messages.TypeMovementRejected)
+	if len(rejected) != 1 {
+		t.Fatalf("got %d rejections, want 1", len(rejected))
+	}
+	var payload messages.MovementRejectedPayload
+	if err := json.Unmarshal(rejected[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.X != 149 || payload.Y != 100 {
+		t.Errorf("rejection point = (%v, %v), want (149, 100)", payload.X, payload.Y)
+	}
+	if n := len(messagesOfType(drainMessages(t, peer), messages.TypeMovement)); n != 1 {
+		t.Errorf("peer got %d movements, want 1 to the last free point", n)
+	}
+}
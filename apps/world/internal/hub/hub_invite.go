@@ -0,0 +1,25 @@
+package hub
+
+import (
+	"time"
+
+	"world/internal/messages"
+)
+
+// handleMeetingInvite prompts the sender and payload.TargetUserID for a meeting right
+// away instead of waiting for a dwell. Both answer the usual meeting-prompt, and
+// handleMeetingResponse starts the meeting once both accept.
+func (h *Hub) handleMeetingInvite(client *Client, payload messages.IncomingPayload) {
+	if client.SpaceID == "" || payload.TargetUserID == "" || payload.TargetUserID == client.UserID {
+		return
+	}
+
+	h.mu.RLock()
+	space, exists := h.Spaces[client.SpaceID]
+	h.mu.RUnlock()
+	if !exists {
+		return
+	}
+
+	space.InviteToMeeting(client.UserID, payload.TargetUserID, time.Now())
+}
@@ -0,0 +1,17 @@
+package hub
+
+import (
+	"time"
+
+	"world/internal/messages"
+)
+
+// handleLatency stores the RTT the client measured with its app-level ping.
+// Movement validation uses it to tolerate moves that arrive batched.
+func (h *Hub) handleLatency(client *Client, payload messages.IncomingPayload) {
+	if payload.RTTMs < 0 {
+		h.recordViolation(client, "negative rtt")
+		return
+	}
+	client.SetRTT(time.Duration(payload.RTTMs) * time.Millisecond)
+}
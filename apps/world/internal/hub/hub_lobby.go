@@ -0,0 +1,88 @@
+package hub
+
+import (
+	"sort"
+	"time"
+
+	"world/internal/messages"
+)
+
+// maxLobbyChatRunes caps the length of a lobby chat message
+const maxLobbyChatRunes = 500
+
+// The lobby is every connected client that hasn't joined a space yet. It isn't a Space:
+// there is no movement or proximity, only the space list and a global chat.
+
+// lobbyClients returns connected clients that are not in a space
+func (h *Hub) lobbyClients() []*Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	clients := make([]*Client, 0)
+	for client := range h.Clients {
+		if client.SpaceID == "" {
+			clients = append(clients, client)
+		}
+	}
+	return clients
+}
+
+// SpaceSummaries returns the active spaces and their occupancy, sorted by ID
+func (h *Hub) SpaceSummaries() []messages.SpaceSummary {
+	h.mu.RLock()
+	summaries := make([]messages.SpaceSummary, 0, len(h.Spaces))
+	for id, space := range h.Spaces {
+		summaries = append(summaries, messages.SpaceSummary{SpaceID: id, UserCount: space.UserCount()})
+	}
+	h.mu.RUnlock()
+
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].SpaceID < summaries[j].SpaceID })
+	return summaries
+}
+
+// SpaceStats describes every active space for operators, sorted by ID. withUsers
+// includes each space's user IDs.
+func (h *Hub) SpaceStats(withUsers bool) []messages.SpaceStats {
+	h.mu.RLock()
+	stats := make([]messages.SpaceStats, 0, len(h.Spaces))
+	for id, space := range h.Spaces {
+		stats = append(stats, space.Stats(id, withUsers))
+	}
+	h.mu.RUnlock()
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].SpaceID < stats[j].SpaceID })
+	return stats
+}
+
+// sendSpaceList sends the active spaces to a client
+func (h *Hub) sendSpaceList(client *Client) {
+	client.SendJSON(messages.BaseMessage{
+		Type:    messages.TypeSpaceList,
+		Payload: messages.SpaceListPayload{Spaces: h.SpaceSummaries()},
+	})
+}
+
+// handleLobbyChat relays a chat message to everyone in the lobby, sender included.
+// Clients already in a space can't use lobby chat.
+func (h *Hub) handleLobbyChat(client *Client, payload messages.IncomingPayload) {
+	if client.SpaceID != "" {
+		return
+	}
+
+	text, ok := chatText(payload.Text, maxLobbyChatRunes)
+	if !ok {
+		return
+	}
+
+	msg := messages.BaseMessage{
+		Type: messages.TypeLobbyChat,
+		Payload: messages.LobbyChatPayload{
+			Name:      payload.Name,
+			Text:      text,
+			Timestamp: time.Now().UnixMilli(),
+		},
+	}
+	for _, recipient := range h.lobbyClients() {
+		recipient.SendJSON(msg)
+	}
+}
@@ -0,0 +1,72 @@
+package hub
+
+import (
+	"encoding/json"
+	"testing"
+
+	"world/internal/messages"
+)
+
+func TestLobbyChatStaysInLobby(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	inSpace := addTestClient(h, space, "joined", 100, 100)
+	lobbyA := &Client{Hub: h, Send: make(chan []byte, 16)}
+	lobbyB := &Client{Hub: h, Send: make(chan []byte, 16)}
+	h.Clients[lobbyA] = true
+	h.Clients[lobbyB] = true
+
+	h.handleLobbyChat(lobbyA, messages.IncomingPayload{Name: "Ann", Text: "  hi all  "})
+
+	for _, c := range []*Client{lobbyA, lobbyB} {
+		chats := messagesOfType(drainMessages(t, c), messages.TypeLobbyChat)
+		if len(chats) != 1 {
+			t.Fatalf("lobby client got %d chats, want 1", len(chats))
+		}
+		var payload messages.LobbyChatPayload
+		if err := json.Unmarshal(chats[0].Payload, &payload); err != nil {
+			t.Fatal(err)
+		}
+		if payload.Text != "hi all" || payload.Name != "Ann" {
+			t.Errorf("unexpected payload %+v", payload)
+		}
+	}
+	if n := len(drainMessages(t, inSpace)); n != 0 {
+		t.Errorf("client in a space received %d lobby messages", n)
+	}
+
+	// Joined clients can't talk into the lobby
+	h.handleLobbyChat(inSpace, messages.IncomingPayload{Text: "hello?"})
+	if n := len(drainMessages(t, lobbyA)); n != 0 {
+		t.Errorf("lobby received %d messages from a joined client", n)
+	}
+}
+
+func TestLobbySpaceList(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	s1 := newTestSpace(h, "s1")
+	s2 := newTestSpace(h, "s2")
+	addTestClient(h, s1, "a", 100, 100)
+	addTestClient(h, s1, "b", 200, 100)
+	addTestClient(h, s2, "c", 100, 100)
+
+	lobby := &Client{Hub: h, Send: make(chan []byte, 16)}
+	h.ProcessMessage(lobby, []byte(`{"type":"list-spaces"}`))
+
+	lists := messagesOfType(drainMessages(t, lobby), messages.TypeSpaceList)
+	if len(lists) != 1 {
+		t.Fatalf("got %d space lists, want 1", len(lists))
+	}
+	var payload messages.SpaceListPayload
+	if err := json.Unmarshal(lists[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	want := []messages.SpaceSummary{{SpaceID: "s1", UserCount: 2}, {SpaceID: "s2", UserCount: 1}}
+	if len(payload.Spaces) != 2 || payload.Spaces[0] != want[0] || payload.Spaces[1] != want[1] {
+		t.Errorf("spaces = %+v, want %+v", payload.Spaces, want)
+	}
+}
@@ -0,0 +1,165 @@
+package hub
+
+import (
+	"log"
+	"math"
+	"sort"
+	"strings"
+
+	"world/internal/messages"
+)
+
+// maxObjectTypeLen caps an object type's length; types are short codes like "chair"
+const maxObjectTypeLen = 32
+
+// WorldObject is an object a builder placed at runtime. It fills the single tile at
+// (X, Y), which is blocked like a map element.
+type WorldObject struct {
+	Type     string  `json:"type"`
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+	PlacedBy string  `json:"placedBy"`
+}
+
+// validObjectType reports whether t is a short code of letters, digits, '-' and '_'
+func validObjectType(t string) bool {
+	if t == "" || len(t) > maxObjectTypeLen {
+		return false
+	}
+	for _, r := range t {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// PlaceObject puts obj on the tile containing (obj.X, obj.Y), snapping it there. It
+// returns the placed object, or an ObjectError code if the tile is out of bounds,
+// already blocked, or under a user's avatar.
+func (s *Space) PlaceObject(obj WorldObject) (WorldObject, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	obj.X, obj.Y = math.Floor(obj.X), math.Floor(obj.Y)
+	if !s.IsValidPosition(obj.X, obj.Y) {
+		return obj, messages.ObjectErrorOutOfBounds
+	}
+	key := posKey(obj.X, obj.Y)
+	if s.Elements[key] {
+		return obj, messages.ObjectErrorOccupied
+	}
+	w, h := avatarSize()
+	for _, user := range s.Users {
+		ux, uy := user.GetPosition()
+		if ux < obj.X+1 && obj.X < ux+w && uy < obj.Y+1 && obj.Y < uy+h {
+			return obj, messages.ObjectErrorOccupied
+		}
+	}
+
+	s.Elements[key] = true
+	s.Objects[key] = obj
+	return obj, ""
+}
+
+// RemoveObject removes the placed object on the tile containing (x, y). Map elements
+// and users are never touched; ok is false if there is no placed object there.
+func (s *Space) RemoveObject(x, y float64) (WorldObject, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := posKey(math.Floor(x), math.Floor(y))
+	obj, ok := s.Objects[key]
+	if !ok {
+		return WorldObject{}, false
+	}
+	delete(s.Objects, key)
+	delete(s.Elements, key)
+	return obj, true
+}
+
+// ObjectPayloads lists the space's placed objects for space-joined, sorted by position
+func (s *Space) ObjectPayloads() []messages.ObjectPayload {
+	s.mu.RLock()
+	objects := make([]messages.ObjectPayload, 0, len(s.Objects))
+	for _, obj := range s.Objects {
+		objects = append(objects, objectPayload(obj))
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(objects, func(i, j int) bool {
+		if objects[i].Y != objects[j].Y {
+			return objects[i].Y < objects[j].Y
+		}
+		return objects[i].X < objects[j].X
+	})
+	return objects
+}
+
+func objectPayload(obj WorldObject) messages.ObjectPayload {
+	return messages.ObjectPayload{UserID: obj.PlacedBy, ObjectType: obj.Type, X: obj.X, Y: obj.Y}
+}
+
+// handlePlaceObject places an object for a builder and broadcasts it to the space,
+// sender included; the builder gets object-rejected if it can't go there. Access is
+// checked in ProcessMessage.
+func (h *Hub) handlePlaceObject(client *Client, payload messages.IncomingPayload) {
+	if client.SpaceID == "" {
+		return
+	}
+
+	h.mu.RLock()
+	space, exists := h.Spaces[client.SpaceID]
+	h.mu.RUnlock()
+	if !exists {
+		return
+	}
+
+	objectType := strings.TrimSpace(payload.ObjectType)
+	if !validObjectType(objectType) {
+		sendObjectRejected(client, payload.X, payload.Y, messages.ObjectErrorInvalidType)
+		return
+	}
+	obj, code := space.PlaceObject(WorldObject{Type: objectType, X: payload.X, Y: payload.Y, PlacedBy: client.UserID})
+	if code != "" {
+		sendObjectRejected(client, obj.X, obj.Y, code)
+		return
+	}
+	log.Printf("Space %s: %s placed %s at (%v, %v)", space.ID, client.UserID, obj.Type, obj.X, obj.Y)
+
+	h.broadcastToSpace(space.ID, messages.BaseMessage{Type: messages.TypePlaceObject, Payload: objectPayload(obj)}, "")
+}
+
+// handleRemoveObject removes a placed object for a builder and broadcasts the removal
+// to the space, sender included. Access is checked in ProcessMessage.
+func (h *Hub) handleRemoveObject(client *Client, payload messages.IncomingPayload) {
+	if client.SpaceID == "" {
+		return
+	}
+
+	h.mu.RLock()
+	space, exists := h.Spaces[client.SpaceID]
+	h.mu.RUnlock()
+	if !exists {
+		return
+	}
+
+	obj, ok := space.RemoveObject(payload.X, payload.Y)
+	if !ok {
+		sendObjectRejected(client, payload.X, payload.Y, messages.ObjectErrorNoObject)
+		return
+	}
+	log.Printf("Space %s: %s removed %s at (%v, %v)", space.ID, client.UserID, obj.Type, obj.X, obj.Y)
+
+	h.broadcastToSpace(space.ID, messages.BaseMessage{
+		Type:    messages.TypeRemoveObject,
+		Payload: messages.ObjectPayload{UserID: client.UserID, ObjectType: obj.Type, X: obj.X, Y: obj.Y},
+	}, "")
+}
+
+func sendObjectRejected(client *Client, x, y float64, code string) {
+	client.SendJSON(messages.BaseMessage{
+		Type:    messages.TypeObjectRejected,
+		Payload: messages.ObjectRejectedPayload{X: x, Y: y, Code: code},
+	})
+}
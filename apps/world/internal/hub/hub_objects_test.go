@@ -0,0 +1,139 @@
+package hub
+
+import (
+	"encoding/json"
+	"testing"
+
+	"world/internal/messages"
+)
+
+// objectRejections returns the codes of the object-rejected messages c has been sent
+func objectRejections(t *testing.T, c *Client) []string {
+	t.Helper()
+	var codes []string
+	for _, m := range messagesOfType(drainMessages(t, c), messages.TypeObjectRejected) {
+		var p messages.ObjectRejectedPayload
+		if err := json.Unmarshal(m.Payload, &p); err != nil {
+			t.Fatal(err)
+		}
+		codes = append(codes, p.Code)
+	}
+	return codes
+}
+
+func TestPlaceObjectBroadcastsAndBlocks(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	builder := addTestClient(h, space, "builder", 100, 100)
+	builder.Role = RoleBuilder
+	watcher := addTestClient(h, space, "watcher", 300, 300)
+
+	h.ProcessMessage(builder, []byte(`{"type":"place-object","payload":{"objectType":"chair","x":200.7,"y":150.2}}`))
+
+	for _, c := range []*Client{builder, watcher} {
+		got := messagesOfType(drainMessages(t, c), messages.TypePlaceObject)
+		if len(got) != 1 {
+			t.Fatalf("%s got %d place-object messages, want 1", c.UserID, len(got))
+		}
+		var p messages.ObjectPayload
+		if err := json.Unmarshal(got[0].Payload, &p); err != nil {
+			t.Fatal(err)
+		}
+		if p != (messages.ObjectPayload{UserID: "builder", ObjectType: "chair", X: 200, Y: 150}) {
+			t.Errorf("%s: payload %+v", c.UserID, p)
+		}
+	}
+	if !space.IsColliding(200, 150, "") {
+		t.Error("placed object doesn't block its tile")
+	}
+
+	// Later joiners are told about it
+	joiner := joinTestClient(t, h, "s1", "joiner")
+	joined := messagesOfType(drainMessages(t, joiner), messages.TypeSpaceJoined)
+	var p messages.SpaceJoinedPayload
+	if len(joined) != 1 || json.Unmarshal(joined[0].Payload, &p) != nil {
+		t.Fatalf("joiner got %d space-joined messages", len(joined))
+	}
+	if len(p.Objects) != 1 || p.Objects[0].ObjectType != "chair" {
+		t.Errorf("space-joined objects = %+v", p.Objects)
+	}
+
+	h.ProcessMessage(builder, []byte(`{"type":"remove-object","payload":{"x":200,"y":150}}`))
+	if n := len(messagesOfType(drainMessages(t, watcher), messages.TypeRemoveObject)); n != 1 {
+		t.Errorf("watcher got %d remove-object messages, want 1", n)
+	}
+	if space.IsColliding(200, 150, "") {
+		t.Error("removed object still blocks its tile")
+	}
+}
+
+func TestPlaceObjectRejected(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	space.Elements[posKey(50, 50)] = true
+	admin := addTestClient(h, space, "admin", 100, 100)
+	admin.Role = RoleAdmin
+	addTestClient(h, space, "other", 300, 300)
+
+	tests := []struct {
+		name    string
+		payload messages.IncomingPayload
+		want    string
+	}{
+		{"map element", messages.IncomingPayload{ObjectType: "chair", X: 50, Y: 50}, messages.ObjectErrorOccupied},
+		{"under a user", messages.IncomingPayload{ObjectType: "chair", X: 300, Y: 300}, messages.ObjectErrorOccupied},
+		{"out of bounds", messages.IncomingPayload{ObjectType: "chair", X: 5000, Y: 50}, messages.ObjectErrorOutOfBounds},
+		{"bad type", messages.IncomingPayload{ObjectType: "<script>", X: 60, Y: 60}, messages.ObjectErrorInvalidType},
+	}
+	for _, tt := range tests {
+		h.handlePlaceObject(admin, tt.payload)
+		if got := objectRejections(t, admin); len(got) != 1 || got[0] != tt.want {
+			t.Errorf("%s: rejections %v, want [%s]", tt.name, got, tt.want)
+		}
+	}
+
+	h.handlePlaceObject(admin, messages.IncomingPayload{ObjectType: "plant", X: 60, Y: 60})
+	h.handlePlaceObject(admin, messages.IncomingPayload{ObjectType: "plant", X: 60.5, Y: 60})
+	if got := objectRejections(t, admin); len(got) != 1 || got[0] != messages.ObjectErrorOccupied {
+		t.Errorf("placing on an object: rejections %v, want [occupied]", got)
+	}
+
+	// Removal only takes placed objects: not map elements, and not users
+	for _, pos := range [][2]float64{{50, 50}, {300, 300}} {
+		h.handleRemoveObject(admin, messages.IncomingPayload{X: pos[0], Y: pos[1]})
+		if got := objectRejections(t, admin); len(got) != 1 || got[0] != messages.ObjectErrorNoObject {
+			t.Errorf("remove at %v: rejections %v, want [no_object]", pos, got)
+		}
+	}
+	if !space.IsColliding(50, 50, "") {
+		t.Error("map element was cleared")
+	}
+	if _, ok := space.Users["other"]; !ok {
+		t.Error("user was cleared")
+	}
+}
+
+func TestPlaceObjectRequiresBuilder(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	user := addTestClient(h, space, "user", 100, 100)
+	watcher := addTestClient(h, space, "watcher", 300, 300)
+
+	h.ProcessMessage(user, []byte(`{"type":"place-object","payload":{"objectType":"chair","x":200,"y":150}}`))
+
+	if n := len(messagesOfType(drainMessages(t, user), messages.TypeForbidden)); n != 1 {
+		t.Errorf("user got %d forbidden messages, want 1", n)
+	}
+	if n := len(messagesOfType(drainMessages(t, watcher), messages.TypePlaceObject)); n != 0 {
+		t.Errorf("forbidden placement broadcast %d times", n)
+	}
+	if space.IsColliding(200, 150, "") || len(space.Objects) != 0 {
+		t.Error("forbidden placement changed the space")
+	}
+}
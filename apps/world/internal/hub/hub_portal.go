@@ -0,0 +1,129 @@
+package hub
+
+import (
+	"log"
+	"time"
+
+	"world/internal/config"
+	"world/internal/messages"
+)
+
+// portalAt returns the portal whose rectangle contains (x, y)
+func (s *Space) portalAt(x, y float64) (Portal, bool) {
+	for _, p := range s.Portals {
+		if p.Contains(x, y) {
+			return p, true
+		}
+	}
+	return Portal{}, false
+}
+
+// freeSpotAt returns (x, y) if userID could stand there, else the first free spot
+// around it
+func (s *Space) freeSpotAt(x, y float64, userID string) (float64, float64, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	x, y = NormalizeCoord(x), NormalizeCoord(y)
+	if !s.isCollidingLocked(x, y, userID) {
+		return x, y, true
+	}
+	return s.freeSpotNearLocked(x, y, userID)
+}
+
+// usePortal moves a client that stepped onto portal to its destination: within the
+// space like an admin move, or into another space without dropping the connection
+func (h *Hub) usePortal(space *Space, client *Client, portal Portal) {
+	if portal.ToSpace != "" && portal.ToSpace != space.ID {
+		h.transferThroughPortal(space, client, portal)
+		return
+	}
+
+	x, y, ok := space.freeSpotAt(portal.To.X, portal.To.Y, client.UserID)
+	if !ok {
+		log.Printf("Portal %q in space %s: no free spot for %s at its destination", portal.Name, space.ID, client.UserID)
+		return
+	}
+	if _, err := space.PlaceUser(client.UserID, x, y); err != nil {
+		log.Printf("Portal %q in space %s: could not move %s: %v", portal.Name, space.ID, client.UserID, err)
+		return
+	}
+	h.announceRelocation(space, client)
+}
+
+// transferThroughPortal moves client from space into portal.ToSpace, creating it if
+// needed. The old space sees the user leave and the new one sees them join, and the
+// client gets a space-joined for the new space. Access is checked as for a join; a
+// client that may not enter gets a join-error and stays where it is.
+func (h *Hub) transferThroughPortal(from *Space, client *Client, portal Portal) {
+	toID := portal.ToSpace
+	if code, ok := h.portalAccess(client, toID); !ok {
+		log.Printf("Portal %q in space %s: %s may not enter space %s (%s)", portal.Name, from.ID, client.UserID, toID, code)
+		client.SendJSON(messages.BaseMessage{
+			Type:    messages.TypeJoinError,
+			Payload: messages.JoinErrorPayload{Error: "cannot enter " + toID, Code: code},
+		})
+		return
+	}
+
+	// The capacity check, leaving the old space and taking the place in the new one all
+	// share h.mu, so a full destination leaves the client where it was
+	h.mu.Lock()
+	dest, exists := h.Spaces[toID]
+	if exists && !dest.hasRoomFor(client.UserID, config.Current().MaxUsersPerSpace) {
+		h.mu.Unlock()
+		log.Printf("Portal %q in space %s: space %s is full for %s", portal.Name, from.ID, toID, client.UserID)
+		client.SendJSON(messages.BaseMessage{
+			Type:    messages.TypeJoinError,
+			Payload: messages.JoinErrorPayload{Error: "space full", Code: messages.JoinErrorSpaceFull},
+		})
+		return
+	}
+	removed, proximityEvents := from.RemoveUserAndCollectProximityLeaves(client)
+	if !exists {
+		dest = h.createSpaceLocked(toID)
+	}
+	client.SpaceID = toID
+	x, y, ok := dest.freeSpotAt(portal.To.X, portal.To.Y, client.UserID)
+	spawnFallback := false
+	if !ok {
+		x, y, spawnFallback = h.spawnInZoneLocked(dest, client.UserID)
+	}
+	client.SetPosition(x, y)
+	dest.AddUser(client)
+	h.mu.Unlock()
+
+	client.setTyping(false, "", time.Now())
+	if removed {
+		h.finishRemoval(from, client, proximityEvents)
+	}
+	h.announceArrival(dest, client, spawnFallback)
+	log.Printf("User %s took portal %q from space %s to %s at (%v, %v)", client.UserID, portal.Name, from.ID, toID, x, y)
+}
+
+// portalAccess checks client may enter spaceID the way handleJoin would, returning
+// the join-error code if not. Capacity is left to transferThroughPortal, which checks it
+// under h.mu.
+func (h *Hub) portalAccess(client *Client, spaceID string) (string, bool) {
+	client.mu.Lock()
+	claims := client.claims
+	client.mu.Unlock()
+	if claims == nil {
+		return messages.JoinErrorNotAuthorized, false
+	}
+
+	allowed, err := h.authorizeJoin(claims, spaceID)
+	if err != nil {
+		return messages.JoinErrorAuthUnavailable, false
+	}
+	if !allowed {
+		return messages.JoinErrorNotAuthorized, false
+	}
+	banned, err := h.isBanned(spaceID, client.UserID)
+	if err != nil {
+		return messages.JoinErrorAuthUnavailable, false
+	}
+	if banned {
+		return messages.JoinErrorBanned, false
+	}
+	return "", true
+}
@@ -0,0 +1,198 @@
+package hub
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"world/internal/config"
+	"world/internal/messages"
+)
+
+// inSpace reports whether userID is among space's users
+func inSpace(space *Space, userID string) bool {
+	space.mu.RLock()
+	defer space.mu.RUnlock()
+	_, ok := space.Users[userID]
+	return ok
+}
+
+func TestPortalRelocatesWithinSpace(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	space.Portals = []Portal{{
+		Name:    "stairs",
+		MapRect: MapRect{X: 110, Y: 90, Width: 10, Height: 20},
+		To:      MapPoint{X: 800, Y: 600},
+	}}
+	mover := addTestClient(h, space, "mover", 100, 100)
+	observer := addTestClient(h, space, "observer", 500, 500)
+
+	h.handleMovement(mover, messages.IncomingPayload{X: 115, Y: 100})
+
+	if x, y := mover.GetPosition(); x != 800 || y != 600 {
+		t.Fatalf("mover at (%v, %v), want the portal's destination (800, 600)", x, y)
+	}
+	for _, c := range []*Client{mover, observer} {
+		moves := messagesOfType(drainMessages(t, c), messages.TypeMovement)
+		if len(moves) == 0 {
+			t.Fatalf("%s got no movement", c.UserID)
+		}
+		var last messages.MovementPayload
+		if err := json.Unmarshal(moves[len(moves)-1].Payload, &last); err != nil {
+			t.Fatal(err)
+		}
+		if last.UserID != "mover" || last.X != 800 || last.Y != 600 {
+			t.Errorf("%s: last movement %+v, want mover at (800, 600)", c.UserID, last)
+		}
+	}
+}
+
+func TestPortalTransfersBetweenSpaces(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.MapDir = t.TempDir() })
+	writeMap(t, config.Current().MapDir, "lobby", `{
+		"portals": [{"name": "garden gate", "x": 110, "y": 90, "width": 10, "height": 20,
+			"to": {"x": 300, "y": 300}, "toSpace": "garden"}]
+	}`)
+	h := NewHub()
+
+	mover := joinTestClient(t, h, "lobby", "mover")
+	follower := joinTestClient(t, h, "lobby", "follower")
+	lobby := h.Spaces["lobby"]
+	for _, c := range []*Client{mover, follower} {
+		if _, err := lobby.PlaceUser(c.UserID, 100, 100+float64(len(c.UserID))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	drainMessages(t, mover)
+	drainMessages(t, follower)
+
+	// The garden doesn't exist yet: the portal creates it
+	h.handleMovement(mover, messages.IncomingPayload{X: 115, Y: 105})
+
+	garden, ok := h.Spaces["garden"]
+	if !ok {
+		t.Fatal("destination space was not created")
+	}
+	if mover.SpaceID != "garden" || !inSpace(garden, "mover") || inSpace(lobby, "mover") {
+		t.Fatalf("mover in space %q (garden has it: %v, lobby has it: %v)", mover.SpaceID, inSpace(garden, "mover"), inSpace(lobby, "mover"))
+	}
+	if x, y := mover.GetPosition(); x != 300 || y != 300 {
+		t.Errorf("mover at (%v, %v), want (300, 300)", x, y)
+	}
+	joined := messagesOfType(drainMessages(t, mover), messages.TypeSpaceJoined)
+	if len(joined) != 1 {
+		t.Fatalf("mover got %d space-joined messages, want 1", len(joined))
+	}
+	var p messages.SpaceJoinedPayload
+	if err := json.Unmarshal(joined[0].Payload, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.SpaceID != "garden" || p.Spawn != (messages.Position{X: 300, Y: 300}) {
+		t.Errorf("space-joined = %+v", p)
+	}
+	if got := userIDsOf(t, messagesOfType(drainMessages(t, follower), messages.TypeUserLeft)); len(got) != 1 || got[0] != "mover" {
+		t.Errorf("lobby saw %v leave, want [mover]", got)
+	}
+
+	// The connection is kept: still registered, not closed, and nothing unregistered it
+	if !h.Clients[mover] || mover.closedByServer() {
+		t.Error("mover's connection was dropped")
+	}
+	select {
+	case got := <-h.Unregister:
+		t.Fatalf("%s was unregistered", got.UserID)
+	default:
+	}
+
+	// Following into the existing garden is a join there
+	h.handleMovement(follower, messages.IncomingPayload{X: 115, Y: 108})
+	if follower.SpaceID != "garden" {
+		t.Fatalf("follower in space %q, want garden", follower.SpaceID)
+	}
+	if x, y := follower.GetPosition(); x == 300 && y == 300 {
+		t.Error("follower landed on top of mover")
+	}
+	if got := userIDsOf(t, messagesOfType(drainMessages(t, mover), messages.TypeUserJoin)); len(got) != 1 || got[0] != "follower" {
+		t.Errorf("garden saw %v join, want [follower]", got)
+	}
+	if _, ok := h.Spaces["lobby"]; ok {
+		t.Error("empty lobby was not removed")
+	}
+}
+
+func TestPortalRefusesBannedUser(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	lobby := newTestSpace(h, "lobby")
+	lobby.Portals = []Portal{{MapRect: MapRect{X: 110, Y: 90, Width: 10, Height: 20}, To: MapPoint{X: 300, Y: 300}, ToSpace: "garden"}}
+	mover := joinTestClient(t, h, "lobby", "mover")
+	if _, err := lobby.PlaceUser("mover", 100, 100); err != nil {
+		t.Fatal(err)
+	}
+	drainMessages(t, mover)
+	if err := h.Bans.Ban("garden", "mover", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	h.handleMovement(mover, messages.IncomingPayload{X: 115, Y: 100})
+
+	if code := joinErrorCode(t, mover); code != messages.JoinErrorBanned {
+		t.Errorf("join-error code = %q, want %q", code, messages.JoinErrorBanned)
+	}
+	if mover.SpaceID != "lobby" || !inSpace(lobby, "mover") {
+		t.Errorf("banned user left the lobby for %q", mover.SpaceID)
+	}
+	if _, ok := h.Spaces["garden"]; ok {
+		t.Error("refused portal still created the destination space")
+	}
+}
+
+// fillingStore admits everyone, but calls fill the first time it's asked, to fill a
+// space between the access checks and the move
+type fillingStore struct {
+	fill func()
+}
+
+func (s *fillingStore) CanJoinSpace(userID, spaceID string) (bool, error) {
+	if fill := s.fill; fill != nil {
+		s.fill = nil
+		fill()
+	}
+	return true, nil
+}
+
+func TestPortalIntoSpaceThatFillsUp(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.MaxUsersPerSpace = 2 })
+
+	h := NewHub()
+	lobby := newTestSpace(h, "lobby")
+	lobby.Portals = []Portal{{MapRect: MapRect{X: 110, Y: 90, Width: 10, Height: 20}, To: MapPoint{X: 300, Y: 300}, ToSpace: "garden"}}
+	mover := joinTestClient(t, h, "lobby", "mover")
+	if _, err := lobby.PlaceUser("mover", 100, 100); err != nil {
+		t.Fatal(err)
+	}
+	peer := addTestClient(h, lobby, "peer", 500, 500)
+	garden := newTestSpace(h, "garden")
+	addTestClient(h, garden, "g1", 600, 600)
+	drainMessages(t, mover)
+
+	// The garden's last place goes while the mover's access is being checked
+	h.Store = &fillingStore{fill: func() { addTestClient(h, garden, "g2", 700, 700) }}
+	h.handleMovement(mover, messages.IncomingPayload{X: 115, Y: 100})
+
+	if code := joinErrorCode(t, mover); code != messages.JoinErrorSpaceFull {
+		t.Errorf("join-error code = %q, want %q", code, messages.JoinErrorSpaceFull)
+	}
+	if mover.SpaceID != "lobby" || !inSpace(lobby, "mover") || inSpace(garden, "mover") {
+		t.Errorf("mover in space %q (lobby has it: %v, garden has it: %v), want still in the lobby", mover.SpaceID, inSpace(lobby, "mover"), inSpace(garden, "mover"))
+	}
+	if n := len(messagesOfType(drainMessages(t, peer), messages.TypeUserLeft)); n != 0 {
+		t.Errorf("lobby saw the mover leave %d times", n)
+	}
+}
@@ -0,0 +1,98 @@
+package hub
+
+import (
+	"errors"
+	"log"
+
+	"world/internal/messages"
+)
+
+// Errors returned by the admin position API
+var (
+	ErrUserNotFound    = errors.New("user not found in space")
+	ErrInvalidPosition = errors.New("position is out of bounds or blocked")
+)
+
+// PlaceUser moves userID to (x, y) if the spot is in bounds and free. The check and
+// the move happen under the space lock, so no other user can take the spot in between.
+func (s *Space) PlaceUser(userID string, x, y float64) (*Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	client, ok := s.Users[userID]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	if s.isCollidingLocked(x, y, userID) {
+		return nil, ErrInvalidPosition
+	}
+	client.SetPosition(x, y)
+	return client, nil
+}
+
+// lookupSpaceUser finds userID in spaceID
+func (h *Hub) lookupSpaceUser(spaceID, userID string) (*Space, *Client, error) {
+	h.mu.RLock()
+	space, exists := h.Spaces[spaceID]
+	h.mu.RUnlock()
+	if !exists {
+		return nil, nil, ErrUserNotFound
+	}
+
+	space.mu.RLock()
+	client, ok := space.Users[userID]
+	space.mu.RUnlock()
+	if !ok {
+		return nil, nil, ErrUserNotFound
+	}
+	return space, client, nil
+}
+
+// UserPosition returns the current position of userID in spaceID
+func (h *Hub) UserPosition(spaceID, userID string) (x, y float64, err error) {
+	_, client, err := h.lookupSpaceUser(spaceID, userID)
+	if err != nil {
+		return 0, 0, err
+	}
+	x, y = client.GetPosition()
+	return x, y, nil
+}
+
+// SetUserPosition teleports userID to (x, y) on behalf of an operator. Everyone in
+// the space, including the moved user, gets the movement and proximity is recomputed.
+func (h *Hub) SetUserPosition(spaceID, userID string, x, y float64) error {
+	space, _, err := h.lookupSpaceUser(spaceID, userID)
+	if err != nil {
+		return err
+	}
+
+	x, y = NormalizeCoord(x), NormalizeCoord(y)
+	client, err := space.PlaceUser(userID, x, y)
+	if err != nil {
+		return err
+	}
+	log.Printf("Admin moved %s in space %s to (%v, %v)", userID, spaceID, x, y)
+
+	h.announceRelocation(space, client)
+	return nil
+}
+
+// announceRelocation tells the space, the client included, that the server moved
+// client, and recomputes its proximity
+func (h *Hub) announceRelocation(space *Space, client *Client) {
+	x, y := client.GetPosition()
+	h.recomputeProximity(space, client)
+	msg := messages.BaseMessage{
+		Type: messages.TypeMovement,
+		Payload: messages.MovementPayload{
+			X:          x,
+			Y:          y,
+			UserID:     client.UserID,
+			Anim:       client.GetAnim(),
+			DurationMs: durationMs(0),
+		},
+	}
+	h.broadcastMovement(space, client, msg)
+	client.SendJSON(msg)
+	h.Events.Publish(Event{Type: EventUserMoved, SpaceID: space.ID, UserID: client.UserID, X: x, Y: y})
+}
@@ -0,0 +1,120 @@
+package hub
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"world/internal/messages"
+
+	"github.com/gorilla/mux"
+)
+
+// positionRequest calls ServeUserPosition for userID in space s1 with the given role
+func positionRequest(t *testing.T, h *Hub, method, userID, role, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, "/admin/spaces/s1/users/"+userID+"/position", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testToken(t, "ops", role))
+	req = mux.SetURLVars(req, map[string]string{"spaceId": "s1", "userId": userID})
+	rec := httptest.NewRecorder()
+	h.ServeUserPosition(rec, req)
+	return rec
+}
+
+func decodePosition(t *testing.T, rec *httptest.ResponseRecorder) messages.Position {
+	t.Helper()
+	var pos messages.Position
+	if err := json.NewDecoder(rec.Body).Decode(&pos); err != nil {
+		t.Fatal(err)
+	}
+	return pos
+}
+
+func TestServeUserPositionGet(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	addTestClient(h, space, "a", 40, 50)
+
+	rec := positionRequest(t, h, http.MethodGet, "a", RoleAdmin, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if pos := decodePosition(t, rec); pos.X != 40 || pos.Y != 50 {
+		t.Errorf("position = %+v, want (40, 50)", pos)
+	}
+
+	if rec := positionRequest(t, h, http.MethodGet, "ghost", RoleAdmin, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown user status = %d, want 404", rec.Code)
+	}
+	if rec := positionRequest(t, h, http.MethodGet, "a", "user", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("non-admin status = %d, want 403", rec.Code)
+	}
+}
+
+func TestServeUserPositionSet(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 40, 50)
+	b := addTestClient(h, space, "b", 500, 500)
+
+	rec := positionRequest(t, h, http.MethodPut, "a", RoleAdmin, `{"x":510,"y":500}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if pos := decodePosition(t, rec); pos.X != 510 || pos.Y != 500 {
+		t.Errorf("response position = %+v", pos)
+	}
+	if x, y := a.GetPosition(); x != 510 || y != 500 {
+		t.Errorf("a at (%v, %v), want (510, 500)", x, y)
+	}
+
+	// Both the moved user and the rest of the space see the movement
+	for _, c := range []*Client{a, b} {
+		if len(messagesOfType(drainMessages(t, c), messages.TypeMovement)) != 1 {
+			t.Errorf("%s did not get the movement", c.UserID)
+		}
+	}
+
+	// Proximity is recomputed: a is now within audio range of b
+	space.mu.RLock()
+	inRange := space.Proximity["audio"]["a"]["b"]
+	space.mu.RUnlock()
+	if !inRange {
+		t.Error("proximity not recomputed after the admin move")
+	}
+}
+
+func TestServeUserPositionRejected(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 40, 50)
+	addTestClient(h, space, "b", 500, 500)
+
+	for name, body := range map[string]string{
+		"out of bounds": `{"x":-10,"y":50}`,
+		"occupied":      `{"x":500,"y":500}`,
+	} {
+		rec := positionRequest(t, h, http.MethodPut, "a", RoleAdmin, body)
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: status = %d, want 422", name, rec.Code)
+		}
+	}
+	if x, y := a.GetPosition(); x != 40 || y != 50 {
+		t.Errorf("a moved to (%v, %v) after rejected sets", x, y)
+	}
+	if len(drainMessages(t, a)) != 0 {
+		t.Error("rejected set broadcast a movement")
+	}
+
+	if rec := positionRequest(t, h, http.MethodPut, "ghost", RoleAdmin, `{"x":60,"y":60}`); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown user status = %d, want 404", rec.Code)
+	}
+	if rec := positionRequest(t, h, http.MethodPut, "a", RoleAdmin, `not json`); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad body status = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,73 @@
+package hub
+
+import "world/internal/messages"
+
+// proximityBatch collects what one batching recipient is owed from a round of
+// proximity events
+type proximityBatch struct {
+	client  *Client
+	updates []messages.ProximityUpdatePayload
+	volumes []messages.ProximityVolumePayload
+}
+
+// proximityBatches tracks the batching recipients seen while handling one round of
+// proximity events, in the order they were first seen
+type proximityBatches struct {
+	hub   *Hub
+	seen  map[string]*proximityBatch
+	order []*proximityBatch
+}
+
+func newProximityBatches(h *Hub) *proximityBatches {
+	return &proximityBatches{hub: h, seen: make(map[string]*proximityBatch)}
+}
+
+// get returns the batch for userID in spaceID, or nil if that user isn't in the space
+// or didn't ask for batching
+func (b *proximityBatches) get(spaceID, userID string) *proximityBatch {
+	key := spaceID + "/" + userID
+	if batch, ok := b.seen[key]; ok {
+		return batch
+	}
+
+	var batch *proximityBatch
+	if client := b.hub.userInSpace(spaceID, userID); client != nil && client.BatchProximity {
+		batch = &proximityBatch{client: client}
+		b.order = append(b.order, batch)
+	}
+	b.seen[key] = batch
+	return batch
+}
+
+// flush sends each batch as one proximity-batch message, followed by any volume
+// changes for the same recipient
+func (b *proximityBatches) flush() {
+	for _, batch := range b.order {
+		if len(batch.updates) > 0 {
+			batch.client.SendJSON(messages.BaseMessage{
+				Type:    messages.TypeProximityBatch,
+				Payload: messages.ProximityBatchPayload{Updates: batch.updates},
+			})
+		}
+		for _, volume := range batch.volumes {
+			batch.client.SendJSON(messages.BaseMessage{
+				Type:    messages.TypeProximityVolume,
+				Payload: volume,
+			})
+		}
+	}
+}
+
+// userInSpace returns the client for userID in spaceID, or nil
+func (h *Hub) userInSpace(spaceID, userID string) *Client {
+	h.mu.RLock()
+	space, ok := h.Spaces[spaceID]
+	h.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	space.mu.RLock()
+	defer space.mu.RUnlock()
+	return space.Users[userID]
+}
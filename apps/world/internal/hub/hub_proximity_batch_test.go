@@ -0,0 +1,86 @@
+package hub
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"world/internal/messages"
+)
+
+// proximityUpdatesFor places a newcomer among three neighbours and returns the
+// proximity updates the newcomer received, and how many messages carried them
+func proximityUpdatesFor(t *testing.T, batch bool) ([]messages.ProximityUpdatePayload, int) {
+	t.Helper()
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	neighbours := []*Client{
+		addTestClient(h, space, "a", 100, 100),
+		addTestClient(h, space, "b", 160, 100),
+		addTestClient(h, space, "c", 100, 160),
+	}
+	newcomer := addTestClient(h, space, "n", 120, 120)
+	newcomer.BatchProximity = batch
+
+	h.recomputeProximity(space, newcomer)
+
+	// Neighbours didn't ask for batching and keep getting one message per update
+	for _, c := range neighbours {
+		msgs := drainMessages(t, c)
+		if len(messagesOfType(msgs, messages.TypeProximityBatch)) != 0 || len(messagesOfType(msgs, messages.TypeProximityUpdate)) == 0 {
+			t.Errorf("%s: got %+v, want unbatched proximity-update messages", c.UserID, msgs)
+		}
+	}
+
+	var updates []messages.ProximityUpdatePayload
+	msgs := drainMessages(t, newcomer)
+	for _, m := range messagesOfType(msgs, messages.TypeProximityUpdate) {
+		var payload messages.ProximityUpdatePayload
+		if err := json.Unmarshal(m.Payload, &payload); err != nil {
+			t.Fatal(err)
+		}
+		updates = append(updates, payload)
+	}
+	batches := messagesOfType(msgs, messages.TypeProximityBatch)
+	for _, m := range batches {
+		var payload messages.ProximityBatchPayload
+		if err := json.Unmarshal(m.Payload, &payload); err != nil {
+			t.Fatal(err)
+		}
+		updates = append(updates, payload.Updates...)
+	}
+	return updates, len(messagesOfType(msgs, messages.TypeProximityUpdate)) + len(batches)
+}
+
+func TestProximityBatchMatchesUnbatched(t *testing.T) {
+	setupTestConfig(t)
+
+	unbatched, unbatchedMsgs := proximityUpdatesFor(t, false)
+	batched, batchedMsgs := proximityUpdatesFor(t, true)
+
+	if len(unbatched) < 3 || unbatchedMsgs != len(unbatched) {
+		t.Fatalf("unbatched: %d updates in %d messages, want one message per update", len(unbatched), unbatchedMsgs)
+	}
+	if batchedMsgs != 1 {
+		t.Errorf("batched updates arrived in %d messages, want 1", batchedMsgs)
+	}
+
+	// Neighbours are visited in map order, so compare regardless of order
+	key := func(u messages.ProximityUpdatePayload) string {
+		data, _ := json.Marshal(u)
+		return string(data)
+	}
+	sortUpdates := func(updates []messages.ProximityUpdatePayload) {
+		sort.Slice(updates, func(i, j int) bool { return key(updates[i]) < key(updates[j]) })
+	}
+	sortUpdates(unbatched)
+	sortUpdates(batched)
+	if len(batched) != len(unbatched) {
+		t.Fatalf("batched %d updates, unbatched %d", len(batched), len(unbatched))
+	}
+	for i := range batched {
+		if key(batched[i]) != key(unbatched[i]) {
+			t.Errorf("update %d: batched %s, unbatched %s", i, key(batched[i]), key(unbatched[i]))
+		}
+	}
+}
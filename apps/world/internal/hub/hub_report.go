@@ -0,0 +1,184 @@
+package hub
+
+import (
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"world/internal/config"
+	"world/internal/messages"
+)
+
+// maxReportDetailsRunes caps the free-text part of a report
+const maxReportDetailsRunes = 1000
+
+// Report is a user's complaint about another user, with the context moderators need
+type Report struct {
+	ReporterID   string
+	TargetUserID string
+	SpaceID      string
+	Reason       string
+	Details      string
+	ReporterPos  messages.Position
+	TargetPos    messages.Position
+	// MeetingID and MeetingPeerID describe the reporter's active meeting, if any
+	MeetingID     string
+	MeetingPeerID string
+	At            time.Time
+}
+
+// ReportSink is where reports go for moderation. The hub only does intake;
+// acting on a report is up to the sink's owner.
+type ReportSink interface {
+	SubmitReport(report Report) error
+}
+
+// nopReportSink drops reports; it's the default until a real sink is wired in
+type nopReportSink struct{}
+
+func (nopReportSink) SubmitReport(Report) error { return nil }
+
+// reportWindow counts the reports a user has filed since start
+type reportWindow struct {
+	start time.Time
+	count int
+}
+
+// handleReport validates a report and forwards it to the moderation sink
+func (h *Hub) handleReport(client *Client, payload messages.IncomingPayload) {
+	if client.SpaceID == "" {
+		return
+	}
+
+	reject := func(reason string) {
+		client.SendJSON(messages.BaseMessage{
+			Type:    messages.TypeReportResult,
+			Payload: messages.ReportResultPayload{Error: reason},
+		})
+	}
+
+	if !isAllowedReportReason(payload.Reason) {
+		reject("unknown reason")
+		return
+	}
+	details := strings.TrimSpace(payload.Details)
+	if utf8.RuneCountInString(details) > maxReportDetailsRunes {
+		reject("details too long")
+		return
+	}
+	if payload.TargetUserID == client.UserID {
+		reject("cannot report yourself")
+		return
+	}
+
+	h.mu.RLock()
+	space, exists := h.Spaces[client.SpaceID]
+	h.mu.RUnlock()
+	if !exists {
+		return
+	}
+
+	space.mu.RLock()
+	target, ok := space.Users[payload.TargetUserID]
+	space.mu.RUnlock()
+	if !ok {
+		reject("user not in space")
+		return
+	}
+
+	now := time.Now()
+	if !h.allowReport(client.UserID, now) {
+		reject("too many reports, please wait")
+		return
+	}
+
+	reporterX, reporterY := client.GetPosition()
+	targetX, targetY := target.GetPosition()
+	report := Report{
+		ReporterID:   client.UserID,
+		TargetUserID: target.UserID,
+		SpaceID:      space.ID,
+		Reason:       payload.Reason,
+		Details:      details,
+		ReporterPos:  messages.Position{X: reporterX, Y: reporterY},
+		TargetPos:    messages.Position{X: targetX, Y: targetY},
+		At:           now,
+	}
+	if meeting, ok := space.activeMeetingFor(client.UserID); ok {
+		report.MeetingID = meeting.MeetingID
+		// Prefer the reported user when they're in the meeting, else the first peer
+		report.MeetingPeerID = meeting.peersOf(client.UserID)[0]
+		if meeting.hasParticipant(target.UserID) && target.UserID != client.UserID {
+			report.MeetingPeerID = target.UserID
+		}
+	}
+
+	h.mu.RLock()
+	sink := h.Reports
+	h.mu.RUnlock()
+
+	if err := sink.SubmitReport(report); err != nil {
+		log.Printf("Report from %s about %s failed: %v", client.UserID, target.UserID, err)
+		reject("report could not be submitted")
+		return
+	}
+
+	log.Printf("Report from %s about %s in %s: %s", client.UserID, target.UserID, space.ID, payload.Reason)
+	client.SendJSON(messages.BaseMessage{
+		Type:    messages.TypeReportResult,
+		Payload: messages.ReportResultPayload{Accepted: true},
+	})
+}
+
+// allowReport enforces the per-user report limit, counting the report if allowed
+func (h *Hub) allowReport(userID string, now time.Time) bool {
+	limit := config.Current().ReportLimit
+	window := config.Current().ReportWindow
+	if limit <= 0 || window <= 0 {
+		return true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Keep the map from growing without bound
+	if len(h.reportWindows) > 1024 {
+		for id, w := range h.reportWindows {
+			if now.Sub(w.start) >= window {
+				delete(h.reportWindows, id)
+			}
+		}
+	}
+
+	w, ok := h.reportWindows[userID]
+	if !ok || now.Sub(w.start) >= window {
+		w = &reportWindow{start: now}
+		h.reportWindows[userID] = w
+	}
+	if w.count >= limit {
+		return false
+	}
+	w.count++
+	return true
+}
+
+func isAllowedReportReason(reason string) bool {
+	for _, allowed := range config.Current().ReportReasons {
+		if reason == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// activeMeetingFor returns a copy of userID's active meeting, if any
+func (s *Space) activeMeetingFor(userID string) (MeetingState, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, state, ok := s.activeMeetingForLocked(userID); ok {
+		return *state, true
+	}
+	return MeetingState{}, false
+}
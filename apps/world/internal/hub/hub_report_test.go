@@ -0,0 +1,142 @@
+package hub
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"world/internal/config"
+	"world/internal/messages"
+)
+
+// recordingSink keeps every report it receives
+type recordingSink struct {
+	reports []Report
+	err     error
+}
+
+func (s *recordingSink) SubmitReport(report Report) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.reports = append(s.reports, report)
+	return nil
+}
+
+// setupReportTest returns a hub with a recording sink and two users in one space
+func setupReportTest(t *testing.T) (*Hub, *Space, *recordingSink, *Client) {
+	t.Helper()
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) {
+		cfg.ReportReasons = []string{"harassment", "spam"}
+		cfg.ReportLimit = 2
+		cfg.ReportWindow = time.Minute
+	})
+
+	h := NewHub()
+	sink := &recordingSink{}
+	h.Reports = sink
+	space := newTestSpace(h, "s1")
+	reporter := addTestClient(h, space, "reporter", 100, 100)
+	addTestClient(h, space, "target", 150, 120)
+	return h, space, sink, reporter
+}
+
+// reportResult decodes the last report-result sent to c
+func reportResult(t *testing.T, c *Client) messages.ReportResultPayload {
+	t.Helper()
+	results := messagesOfType(drainMessages(t, c), messages.TypeReportResult)
+	if len(results) == 0 {
+		t.Fatal("no report-result sent")
+	}
+	var p messages.ReportResultPayload
+	if err := json.Unmarshal(results[len(results)-1].Payload, &p); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestReportValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload messages.IncomingPayload
+	}{
+		{"unknown reason", messages.IncomingPayload{TargetUserID: "target", Reason: "vibes"}},
+		{"target not in space", messages.IncomingPayload{TargetUserID: "ghost", Reason: "spam"}},
+		{"self report", messages.IncomingPayload{TargetUserID: "reporter", Reason: "spam"}},
+		{"details too long", messages.IncomingPayload{TargetUserID: "target", Reason: "spam", Details: strings.Repeat("x", maxReportDetailsRunes+1)}},
+	}
+	for _, tt := range tests {
+		h, _, sink, reporter := setupReportTest(t)
+		h.handleReport(reporter, tt.payload)
+
+		if p := reportResult(t, reporter); p.Accepted || p.Error == "" {
+			t.Errorf("%s: result = %+v, want a rejection", tt.name, p)
+		}
+		if len(sink.reports) != 0 {
+			t.Errorf("%s: sink received %d reports, want 0", tt.name, len(sink.reports))
+		}
+	}
+}
+
+func TestReportDeliveredToSink(t *testing.T) {
+	h, space, sink, reporter := setupReportTest(t)
+	startTestMeeting(space, "reporter", "target")
+
+	h.handleReport(reporter, messages.IncomingPayload{TargetUserID: "target", Reason: "harassment", Details: "  rude  "})
+
+	if p := reportResult(t, reporter); !p.Accepted {
+		t.Fatalf("report rejected: %s", p.Error)
+	}
+	if len(sink.reports) != 1 {
+		t.Fatalf("sink received %d reports, want 1", len(sink.reports))
+	}
+	r := sink.reports[0]
+	if r.ReporterID != "reporter" || r.TargetUserID != "target" || r.SpaceID != "s1" || r.Reason != "harassment" || r.Details != "rude" {
+		t.Errorf("report = %+v", r)
+	}
+	if r.ReporterPos != (messages.Position{X: 100, Y: 100}) || r.TargetPos != (messages.Position{X: 150, Y: 120}) {
+		t.Errorf("positions = %+v / %+v", r.ReporterPos, r.TargetPos)
+	}
+	if r.MeetingID != "reporter-target" || r.MeetingPeerID != "target" {
+		t.Errorf("meeting context = (%q, %q)", r.MeetingID, r.MeetingPeerID)
+	}
+}
+
+func TestReportSinkFailure(t *testing.T) {
+	h, _, sink, reporter := setupReportTest(t)
+	sink.err = errors.New("sink down")
+
+	h.handleReport(reporter, messages.IncomingPayload{TargetUserID: "target", Reason: "spam"})
+	if p := reportResult(t, reporter); p.Accepted {
+		t.Error("report accepted although the sink failed")
+	}
+}
+
+func TestReportRateLimit(t *testing.T) {
+	h, _, sink, reporter := setupReportTest(t)
+	report := messages.IncomingPayload{TargetUserID: "target", Reason: "spam"}
+
+	for i := 0; i < 2; i++ {
+		h.handleReport(reporter, report)
+		if p := reportResult(t, reporter); !p.Accepted {
+			t.Fatalf("report %d rejected: %s", i, p.Error)
+		}
+	}
+	h.handleReport(reporter, report)
+	if p := reportResult(t, reporter); p.Accepted {
+		t.Error("report over the limit was accepted")
+	}
+
+	// A new window allows reporting again
+	h.reportWindows["reporter"].start = time.Now().Add(-2 * time.Minute)
+	h.handleReport(reporter, report)
+	if p := reportResult(t, reporter); !p.Accepted {
+		t.Errorf("report after the window reset rejected: %s", p.Error)
+	}
+	if len(sink.reports) != 3 {
+		t.Errorf("sink received %d reports, want 3", len(sink.reports))
+	}
+}
@@ -0,0 +1,196 @@
+package hub
+
+import (
+	"log"
+	"time"
+
+	"world/internal/auth"
+	"world/internal/config"
+	"world/internal/messages"
+)
+
+// suspendedSession is a dropped user kept in their space until deadline, so a
+// reconnect (a page refresh or network blip) can resume where they left off
+type suspendedSession struct {
+	client   *Client
+	space    *Space
+	deadline time.Time
+}
+
+// suspendSession keeps a dropped client in its space for the reconnect grace instead
+// of removing it. Peers see no leave: position, proximity and meetings are retained.
+// Server-initiated disconnects (kicks, idle reaps, ...) are never suspended.
+func (h *Hub) suspendSession(space *Space, client *Client, now time.Time) bool {
+	grace := config.Current().ReconnectGrace
+	if grace <= 0 || client.closedByServer() {
+		return false
+	}
+
+	space.mu.RLock()
+	current := space.Users[client.UserID] == client
+	space.mu.RUnlock()
+	if !current {
+		return false
+	}
+
+	h.mu.Lock()
+	h.pendingReconnect[client.UserID] = &suspendedSession{client: client, space: space, deadline: now.Add(grace)}
+	h.mu.Unlock()
+
+	log.Printf("User %s dropped from space %s; holding their session for %v", client.UserID, space.ID, grace)
+	return true
+}
+
+// expireSuspendedSessions removes users whose reconnect grace ran out, as a normal leave
+func (h *Hub) expireSuspendedSessions(now time.Time) {
+	h.mu.Lock()
+	expired := make([]*suspendedSession, 0)
+	for userID, session := range h.pendingReconnect {
+		if !now.Before(session.deadline) {
+			expired = append(expired, session)
+			delete(h.pendingReconnect, userID)
+		}
+	}
+	h.mu.Unlock()
+
+	for _, session := range expired {
+		log.Printf("User %s did not reconnect to space %s", session.client.UserID, session.space.ID)
+		h.removeFromSpace(session.space, session.client)
+	}
+}
+
+// resumeSession hands a suspended session for the token's user to client if it is
+// rejoining the same space within the grace. The user keeps their position, peers see
+// no leave or join, and proximity and active meetings are re-sent so the new page can
+// rebuild its media connections. A join to a different space ends the old session.
+func (h *Hub) resumeSession(client *Client, claims *auth.Claims, payload messages.IncomingPayload, now time.Time) bool {
+	h.mu.Lock()
+	session, ok := h.pendingReconnect[claims.UserID]
+	if !ok {
+		h.mu.Unlock()
+		return false
+	}
+	delete(h.pendingReconnect, claims.UserID)
+
+	space := session.space
+	resumable := space.ID == payload.SpaceID && h.Spaces[space.ID] == space && now.Before(session.deadline)
+	if resumable {
+		client.UserID = claims.UserID
+		client.Role = claims.Role
+		client.setTokenExpiry(claims)
+		client.SpaceID = space.ID
+		client.joined = true
+		resumable = space.ReplaceUser(session.client, client, payload.Name, payload.AvatarName)
+	}
+	h.mu.Unlock()
+
+	if !resumable {
+		h.removeFromSpace(space, session.client)
+		return false
+	}
+
+	x, y := client.GetPosition()
+	users := visibleUserInfos(space, client.UserID)
+	if interestEnabled() {
+		// The pairs in view were kept with the session
+		users = userInfosIn(users, space.InViewUsers(client.UserID))
+	}
+	client.SendJSON(messages.BaseMessage{
+		Type: messages.TypeSpaceJoined,
+		Payload: messages.SpaceJoinedPayload{
+			SessionID: client.UserID,
+			SpaceID:   space.ID,
+			Spawn:     messages.Position{X: x, Y: y},
+			Users:     users,
+			Resumed:   true,
+			Objects:   space.ObjectPayloads(),
+		},
+	})
+
+	h.handleProximityEvents(space.ProximityEntersFor(client.UserID))
+	for _, state := range space.ActiveMeetingsFor(client.UserID) {
+		for _, id := range state.participants() {
+			h.sendToUser(space.ID, id, messages.BaseMessage{
+				Type:    messages.TypeMeetingStart,
+				Payload: meetingStartPayload(&state, id),
+			})
+		}
+	}
+
+	log.Printf("User %s resumed their session in space %s at (%f, %f)", client.UserID, space.ID, x, y)
+	return true
+}
+
+// ReplaceUser swaps a suspended client for the connection resuming its session,
+// carrying over its position and visibility. name and avatarName override the saved
+// ones if set. It fails if old is no longer the user's client in the space.
+func (s *Space) ReplaceUser(old, client *Client, name, avatarName string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Users[old.UserID] != old {
+		return false
+	}
+
+	old.mu.Lock()
+	x, y, anim, hiddenFrom := old.X, old.Y, old.Anim, old.hiddenFrom
+	if name == "" {
+		name = old.Name
+	}
+	if avatarName == "" {
+		avatarName = old.AvatarName
+	}
+	old.mu.Unlock()
+
+	client.mu.Lock()
+	client.X, client.Y, client.Anim, client.hiddenFrom = x, y, anim, hiddenFrom
+	client.Name, client.AvatarName = name, avatarName
+	client.mu.Unlock()
+
+	s.Users[client.UserID] = client
+	if s.grid != nil {
+		old.setGrid(nil)
+		client.setGrid(s.grid)
+		s.grid.update(client)
+	}
+	return true
+}
+
+// ProximityEntersFor returns an enter event for every peer currently in range of
+// userID, across all media
+func (s *Space) ProximityEntersFor(userID string) []ProximityEvent {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	events := make([]ProximityEvent, 0)
+	for media, proximity := range s.Proximity {
+		for peerID := range proximity[userID] {
+			event := ProximityEvent{
+				Type:    ProximityEnter,
+				UserA:   userID,
+				UserB:   peerID,
+				SpaceID: s.ID,
+				Media:   media,
+			}
+			if media == config.MediaAudio {
+				event.Volume = s.audioVolumeLocked(userID, peerID)
+			}
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// ActiveMeetingsFor returns copies of the active meetings userID is part of
+func (s *Space) ActiveMeetingsFor(userID string) []MeetingState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	meetings := make([]MeetingState, 0)
+	for _, state := range s.MeetingStates {
+		if state.Status == MeetingStatusActive && state.hasParticipant(userID) {
+			meetings = append(meetings, *state)
+		}
+	}
+	return meetings
+}
@@ -0,0 +1,166 @@
+package hub
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"world/internal/config"
+	"world/internal/messages"
+)
+
+// setupResumeTest joins a and b next to each other in s1 with an active meeting
+func setupResumeTest(t *testing.T) (*Hub, *Space, *Client, *Client) {
+	t.Helper()
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.ReconnectGrace = 10 * time.Second })
+
+	h := NewHub()
+	a := joinTestClient(t, h, "s1", "a")
+	b := joinTestClient(t, h, "s1", "b")
+	space := h.Spaces["s1"]
+	a.SetPosition(400, 400)
+	b.SetPosition(450, 400)
+	h.recomputeProximity(space, a)
+	startTestMeeting(space, "a", "b")
+	drainMessages(t, a)
+	drainMessages(t, b)
+	return h, space, a, b
+}
+
+func TestRefreshDuringMeetingResumesSession(t *testing.T) {
+	h, space, a, b := setupResumeTest(t)
+
+	// The page refreshes: the old socket drops and a new one joins with the same token
+	h.handleDisconnect(a)
+	if _, ok := <-a.Send; ok {
+		t.Error("old connection's Send was left open")
+	}
+	a2 := joinTestClient(t, h, "s1", "a")
+
+	msgs := drainMessages(t, a2)
+	joined := messagesOfType(msgs, messages.TypeSpaceJoined)
+	if len(joined) != 1 {
+		t.Fatalf("got %d space-joined messages", len(joined))
+	}
+	var payload messages.SpaceJoinedPayload
+	if err := json.Unmarshal(joined[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if !payload.Resumed || payload.Spawn != (messages.Position{X: 400, Y: 400}) {
+		t.Errorf("space-joined = %+v, want resumed at (400, 400)", payload)
+	}
+	if len(payload.Users) != 1 || payload.Users[0].UserID != "b" {
+		t.Errorf("users = %+v, want just b", payload.Users)
+	}
+	if len(messagesOfType(msgs, messages.TypeProximityUpdate)) == 0 {
+		t.Error("resumed client was not re-sent its proximity")
+	}
+	if len(messagesOfType(msgs, messages.TypeMeetingStart)) != 1 {
+		t.Error("resumed client was not re-sent its meeting")
+	}
+
+	// The peer saw no churn, only the meeting being re-established
+	peerMsgs := drainMessages(t, b)
+	for _, churn := range []string{messages.TypeUserLeft, messages.TypeUserJoin, messages.TypeMeetingEnd, messages.TypeUserCount} {
+		if n := len(messagesOfType(peerMsgs, churn)); n != 0 {
+			t.Errorf("peer got %d %s messages", n, churn)
+		}
+	}
+	if len(messagesOfType(peerMsgs, messages.TypeMeetingStart)) != 1 {
+		t.Error("peer was not told to re-establish the meeting")
+	}
+
+	space.mu.RLock()
+	current := space.Users["a"]
+	_, meeting := space.MeetingStates[dwellKey("a", "b")]
+	space.mu.RUnlock()
+	if current != a2 || !meeting {
+		t.Errorf("after resume: current client is new = %v, meeting kept = %v", current == a2, meeting)
+	}
+
+	// The resumed session isn't pending any more
+	h.expireSuspendedSessions(time.Now().Add(time.Minute))
+	if n := len(messagesOfType(drainMessages(t, b), messages.TypeUserLeft)); n != 0 {
+		t.Error("resumed user was removed when the grace ran out")
+	}
+}
+
+func TestSuspendedSessionExpires(t *testing.T) {
+	h, space, a, b := setupResumeTest(t)
+
+	h.handleDisconnect(a)
+	h.expireSuspendedSessions(time.Now())
+	if n := len(messagesOfType(drainMessages(t, b), messages.TypeUserLeft)); n != 0 {
+		t.Fatal("user removed before the grace ran out")
+	}
+
+	h.expireSuspendedSessions(time.Now().Add(11 * time.Second))
+	msgs := drainMessages(t, b)
+	if len(messagesOfType(msgs, messages.TypeUserLeft)) != 1 || len(messagesOfType(msgs, messages.TypeMeetingEnd)) != 1 {
+		t.Errorf("expected user-left and meeting-end after the grace, got %+v", msgs)
+	}
+	if space.UserCount() != 1 {
+		t.Errorf("user count = %d, want 1", space.UserCount())
+	}
+
+	// A late reconnect is an ordinary join
+	a2 := joinTestClient(t, h, "s1", "a")
+	if len(messagesOfType(drainMessages(t, b), messages.TypeUserJoin)) != 1 {
+		t.Error("late reconnect was not announced as a join")
+	}
+	drainMessages(t, a2)
+}
+
+func TestJoinOtherSpaceEndsSuspendedSession(t *testing.T) {
+	h, _, a, b := setupResumeTest(t)
+
+	h.handleDisconnect(a)
+	joinTestClient(t, h, "s2", "a")
+
+	if len(messagesOfType(drainMessages(t, b), messages.TypeUserLeft)) != 1 {
+		t.Error("old space was not told the user left")
+	}
+}
+
+func TestServerDisconnectIsNotSuspended(t *testing.T) {
+	h, _, a, b := setupResumeTest(t)
+
+	a.Disconnect(CloseKicked)
+	h.handleDisconnect(<-h.Unregister)
+
+	if len(messagesOfType(drainMessages(t, b), messages.TypeUserLeft)) != 1 {
+		t.Error("kicked user was held for reconnect")
+	}
+}
+
+func TestReconnectAfterGraceBeforeSweepIsFreshJoin(t *testing.T) {
+	h, space, a, b := setupResumeTest(t)
+
+	h.handleDisconnect(a)
+	// The grace has run out but the sweep hasn't removed the session yet
+	h.mu.Lock()
+	h.pendingReconnect["a"].deadline = time.Now().Add(-time.Second)
+	h.mu.Unlock()
+
+	a2 := joinTestClient(t, h, "s1", "a")
+	joined := messagesOfType(drainMessages(t, a2), messages.TypeSpaceJoined)
+	if len(joined) != 1 {
+		t.Fatalf("got %d space-joined messages", len(joined))
+	}
+	var payload messages.SpaceJoinedPayload
+	if err := json.Unmarshal(joined[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Resumed {
+		t.Error("expired session was resumed")
+	}
+
+	msgs := drainMessages(t, b)
+	if len(messagesOfType(msgs, messages.TypeUserLeft)) != 1 || len(messagesOfType(msgs, messages.TypeUserJoin)) != 1 {
+		t.Errorf("expected user-left then user-join, got %+v", msgs)
+	}
+	if space.UserCount() != 2 {
+		t.Errorf("user count = %d, want 2", space.UserCount())
+	}
+}
@@ -0,0 +1,102 @@
+package hub
+
+import (
+	"log"
+	"time"
+
+	"world/internal/auth"
+	"world/internal/config"
+	"world/internal/messages"
+)
+
+// setTokenExpiry records the client's token claims and when the token expires,
+// re-arming the warning
+func (c *Client) setTokenExpiry(claims *auth.Claims) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.claims = claims
+	c.tokenExpiresAt = time.Time{}
+	if claims.ExpiresAt != nil {
+		c.tokenExpiresAt = claims.ExpiresAt.Time
+	}
+	c.expiryWarned = false
+}
+
+// checkSessionExpiry warns clients whose token expires within SessionExpiryWarning
+// with a session-expiring, once per token, and disconnects those whose token has
+// expired after a session-expired
+func (h *Hub) checkSessionExpiry(now time.Time) {
+	lead := config.Current().SessionExpiryWarning
+
+	h.mu.RLock()
+	clients := make([]*Client, 0, len(h.Clients))
+	for client := range h.Clients {
+		clients = append(clients, client)
+	}
+	h.mu.RUnlock()
+
+	for _, client := range clients {
+		client.mu.Lock()
+		expiresAt := client.tokenExpiresAt
+		warn := lead > 0 && !client.expiryWarned && !expiresAt.IsZero() && expiresAt.Sub(now) <= lead
+		if warn {
+			client.expiryWarned = true
+		}
+		client.mu.Unlock()
+
+		switch {
+		case expiresAt.IsZero():
+		case !now.Before(expiresAt):
+			log.Printf("Disconnecting %s: token expired", client.UserID)
+			client.SendJSON(messages.BaseMessage{Type: messages.TypeSessionExpired})
+			client.Disconnect(CloseSessionExpired)
+		case warn:
+			client.SendJSON(messages.BaseMessage{
+				Type:    messages.TypeSessionExpiring,
+				Payload: messages.SessionExpiringPayload{ExpiresAt: expiresAt.UnixMilli()},
+			})
+		}
+	}
+}
+
+// handleAuthRefresh swaps in a new token for a joined client without reconnecting.
+// The token must be valid and for the same user; its role and expiry replace the
+// old ones and the expiry warning is re-armed.
+func (h *Hub) handleAuthRefresh(client *Client, payload messages.IncomingPayload) {
+	if client.UserID == "" {
+		h.sendAuthRefreshError(client, "Not joined")
+		return
+	}
+
+	claims, err := auth.ValidateToken(payload.Token)
+	if err != nil {
+		log.Printf("Auth refresh rejected for %s: %v", client.UserID, err)
+		h.sendAuthRefreshError(client, "Invalid or expired token")
+		return
+	}
+	if claims.UserID != client.UserID {
+		log.Printf("Auth refresh rejected: %s presented a token for %s", client.UserID, claims.UserID)
+		h.sendAuthRefreshError(client, "Token is for a different user")
+		return
+	}
+
+	client.Role = claims.Role
+	client.setTokenExpiry(claims)
+
+	var expiresAt int64
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.UnixMilli()
+	}
+	client.SendJSON(messages.BaseMessage{
+		Type:    messages.TypeAuthRefreshed,
+		Payload: messages.AuthRefreshedPayload{ExpiresAt: expiresAt},
+	})
+}
+
+// sendAuthRefreshError tells client its auth-refresh was rejected
+func (h *Hub) sendAuthRefreshError(client *Client, reason string) {
+	client.SendJSON(messages.BaseMessage{
+		Type:    messages.TypeAuthRefreshError,
+		Payload: messages.AuthRefreshErrorPayload{Error: reason},
+	})
+}
@@ -0,0 +1,177 @@
+package hub
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"world/internal/auth"
+	"world/internal/config"
+	"world/internal/messages"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// expiringToken signs a token for userID that expires at expiresAt
+func expiringToken(t *testing.T, userID, role string, expiresAt time.Time) string {
+	t.Helper()
+	claims := &auth.Claims{
+		UserID: userID,
+		Role:   role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(config.Current().JWTSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// joinWithExpiry joins userID to spaceID with a token expiring at expiresAt
+func joinWithExpiry(t *testing.T, h *Hub, spaceID, userID string, expiresAt time.Time) *Client {
+	t.Helper()
+	c := &Client{Hub: h, Send: make(chan []byte, 256)}
+	h.Clients[c] = true
+	h.handleJoin(c, messages.IncomingPayload{SpaceID: spaceID, Token: expiringToken(t, userID, "user", expiresAt)})
+	return c
+}
+
+func TestSessionExpiryWarningFires(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.SessionExpiryWarning = time.Minute })
+	h := NewHub()
+
+	now := time.Now()
+	expiresAt := now.Add(30 * time.Second)
+	near := joinWithExpiry(t, h, "s1", "near", expiresAt)
+	far := joinWithExpiry(t, h, "s1", "far", now.Add(time.Hour))
+	drainMessages(t, near)
+	drainMessages(t, far)
+
+	h.checkSessionExpiry(now)
+	warnings := messagesOfType(drainMessages(t, near), messages.TypeSessionExpiring)
+	if len(warnings) != 1 {
+		t.Fatalf("got %d session-expiring messages, want 1", len(warnings))
+	}
+	var payload messages.SessionExpiringPayload
+	if err := json.Unmarshal(warnings[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	// The token's exp claim has second precision
+	if payload.ExpiresAt != expiresAt.Truncate(time.Second).UnixMilli() {
+		t.Errorf("expiresAt = %d, want %d", payload.ExpiresAt, expiresAt.Truncate(time.Second).UnixMilli())
+	}
+	if n := len(messagesOfType(drainMessages(t, far), messages.TypeSessionExpiring)); n != 0 {
+		t.Error("client with a long-lived token was warned")
+	}
+
+	// Warned once per token
+	h.checkSessionExpiry(now.Add(time.Second))
+	if n := len(messagesOfType(drainMessages(t, near), messages.TypeSessionExpiring)); n != 0 {
+		t.Errorf("warning repeated %d times", n)
+	}
+}
+
+func TestExpiredSessionIsDisconnected(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) {
+		cfg.SessionExpiryWarning = time.Minute
+		cfg.ReconnectGrace = 10 * time.Second
+	})
+	h := NewHub()
+
+	now := time.Now()
+	a := joinWithExpiry(t, h, "s1", "a", now.Add(30*time.Second))
+	b := joinTestClient(t, h, "s1", "b")
+	drainMessages(t, a)
+	drainMessages(t, b)
+
+	h.checkSessionExpiry(now.Add(31 * time.Second))
+	if n := len(messagesOfType(drainMessages(t, a), messages.TypeSessionExpired)); n != 1 {
+		t.Fatalf("got %d session-expired messages, want 1", n)
+	}
+	if reason := a.closeReason; reason != CloseSessionExpired {
+		t.Errorf("close reason = %+v, want %+v", reason, CloseSessionExpired)
+	}
+
+	// Not held for reconnect: the expired token couldn't resume it anyway
+	h.handleDisconnect(<-h.Unregister)
+	if n := len(messagesOfType(drainMessages(t, b), messages.TypeUserLeft)); n != 1 {
+		t.Errorf("peer got %d user-left messages, want 1", n)
+	}
+}
+
+func TestAuthRefreshExtendsSession(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.SessionExpiryWarning = time.Minute })
+	h := NewHub()
+
+	now := time.Now()
+	a := joinWithExpiry(t, h, "s1", "a", now.Add(30*time.Second))
+	a.SetPosition(500, 500)
+	h.checkSessionExpiry(now)
+	drainMessages(t, a)
+
+	renewed := now.Add(time.Hour)
+	h.handleAuthRefresh(a, messages.IncomingPayload{Token: expiringToken(t, "a", "admin", renewed)})
+	msgs := drainMessages(t, a)
+	refreshed := messagesOfType(msgs, messages.TypeAuthRefreshed)
+	if len(refreshed) != 1 {
+		t.Fatalf("got %d auth-refreshed messages, want 1", len(refreshed))
+	}
+	var payload messages.AuthRefreshedPayload
+	if err := json.Unmarshal(refreshed[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.ExpiresAt != renewed.Truncate(time.Second).UnixMilli() {
+		t.Errorf("expiresAt = %d, want %d", payload.ExpiresAt, renewed.Truncate(time.Second).UnixMilli())
+	}
+	if a.Role != "admin" {
+		t.Errorf("role = %q, want the refreshed token's admin", a.Role)
+	}
+	if x, y := a.GetPosition(); x != 500 || y != 500 {
+		t.Errorf("position = (%v, %v), want unchanged (500, 500)", x, y)
+	}
+
+	// The old expiry no longer applies, and the warning is re-armed for the new one
+	h.checkSessionExpiry(now.Add(31 * time.Second))
+	if n := len(drainMessages(t, a)); n != 0 {
+		t.Errorf("got %d messages at the old expiry, want none", n)
+	}
+	h.checkSessionExpiry(renewed.Add(-30 * time.Second))
+	if n := len(messagesOfType(drainMessages(t, a), messages.TypeSessionExpiring)); n != 1 {
+		t.Errorf("got %d warnings for the refreshed token, want 1", n)
+	}
+}
+
+func TestAuthRefreshRejectsOtherUser(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+
+	now := time.Now()
+	a := joinWithExpiry(t, h, "s1", "a", now.Add(30*time.Second))
+	drainMessages(t, a)
+
+	for name, token := range map[string]string{
+		"other user": expiringToken(t, "b", "admin", now.Add(time.Hour)),
+		"expired":    expiringToken(t, "a", "user", now.Add(-time.Hour)),
+	} {
+		h.handleAuthRefresh(a, messages.IncomingPayload{Token: token})
+		msgs := drainMessages(t, a)
+		if len(messagesOfType(msgs, messages.TypeAuthRefreshError)) != 1 || len(messagesOfType(msgs, messages.TypeAuthRefreshed)) != 0 {
+			t.Errorf("%s: got %+v, want one auth-refresh-error", name, msgs)
+		}
+	}
+	if a.UserID != "a" || a.Role != "user" {
+		t.Errorf("session changed to %s/%s", a.UserID, a.Role)
+	}
+
+	// The original expiry still stands
+	h.checkSessionExpiry(now.Add(31 * time.Second))
+	if n := len(messagesOfType(drainMessages(t, a), messages.TypeSessionExpired)); n != 1 {
+		t.Errorf("got %d session-expired messages, want 1", n)
+	}
+	<-h.Unregister
+}
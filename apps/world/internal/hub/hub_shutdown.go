@@ -0,0 +1,52 @@
+package hub
+
+import (
+	"context"
+	"log"
+
+	"world/internal/messages"
+)
+
+// unregister hands client to Run for disconnect handling. After Shutdown nobody is
+// receiving, so it gives up instead of blocking forever.
+func (h *Hub) unregister(client *Client) {
+	select {
+	case h.Unregister <- client:
+	case <-h.done:
+	}
+}
+
+// Shutdown stops the hub: Run and the dwell checker exit, and every connected client
+// gets a server-shutdown message followed by a close frame with CloseShutdown, so
+// frontends can show a reconnect banner. It waits until each connection's write pump
+// has flushed or ctx expires. Calling it again is harmless.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.shutdownOnce.Do(func() { close(h.done) })
+
+	h.mu.RLock()
+	clients := make([]*Client, 0, len(h.Clients))
+	for client := range h.Clients {
+		clients = append(clients, client)
+	}
+	h.mu.RUnlock()
+	log.Printf("Shutting down: closing %d connections", len(clients))
+
+	notice := messages.BaseMessage{Type: messages.TypeServerShutdown}
+	for _, client := range clients {
+		client.SendJSON(notice)
+		client.setCloseReason(CloseShutdown)
+		client.closeSend()
+	}
+
+	for _, client := range clients {
+		if client.writerDone == nil {
+			continue
+		}
+		select {
+		case <-client.writerDone:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
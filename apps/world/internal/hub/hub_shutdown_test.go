@@ -0,0 +1,74 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"world/internal/messages"
+)
+
+func TestShutdownNotifiesAndClosesClients(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	stopped := make(chan struct{})
+	go func() {
+		h.Run()
+		close(stopped)
+	}()
+
+	transport := newFakeTransport()
+	c := NewClient(h, transport, false)
+	h.Register <- c
+	go c.WritePump()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := h.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after Shutdown")
+	}
+
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+	var types []string
+	for _, data := range transport.written {
+		var msg testMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatal(err)
+		}
+		types = append(types, msg.Type)
+	}
+	if len(types) == 0 || types[len(types)-1] != messages.TypeServerShutdown {
+		t.Errorf("messages = %v, want server-shutdown last", types)
+	}
+	if len(transport.closeFrames) != 1 {
+		t.Fatalf("wrote %d close frames, want 1", len(transport.closeFrames))
+	}
+	frame := transport.closeFrames[0]
+	if code := int(frame[0])<<8 | int(frame[1]); code != CloseShutdown.Code {
+		t.Errorf("close code = %d, want %d", code, CloseShutdown.Code)
+	}
+
+	// Late unregisters don't block once the hub has stopped
+	done := make(chan struct{})
+	go func() {
+		c.Disconnect(CloseServerError)
+		h.unregister(c)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("unregister blocked after Shutdown")
+	}
+	if err := h.Shutdown(ctx); err != nil {
+		t.Errorf("second Shutdown: %v", err)
+	}
+}
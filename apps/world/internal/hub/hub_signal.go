@@ -0,0 +1,95 @@
+package hub
+
+import (
+	"log"
+
+	"world/internal/messages"
+)
+
+// maxSignalBytes caps opaque SDP/ICE blobs relayed between meeting peers
+const maxSignalBytes = 16 * 1024
+
+// activeMeetingPeer returns the peer client if userID and peerID share an active meeting
+func (h *Hub) activeMeetingPeer(spaceID, userID, peerID string) (*Client, bool) {
+	if spaceID == "" || peerID == "" || peerID == userID {
+		return nil, false
+	}
+
+	h.mu.RLock()
+	space, exists := h.Spaces[spaceID]
+	h.mu.RUnlock()
+	if !exists {
+		return nil, false
+	}
+
+	space.mu.RLock()
+	defer space.mu.RUnlock()
+
+	_, state, ok := space.meetingBetweenLocked(userID, peerID)
+	if !ok || state.Status != MeetingStatusActive {
+		return nil, false
+	}
+	peer, ok := space.Users[peerID]
+	return peer, ok
+}
+
+// handleRenegotiate relays a renegotiation request to a peer in the same active meeting.
+// The blob is forwarded untouched.
+func (h *Hub) handleRenegotiate(client *Client, payload messages.IncomingPayload) {
+	if len(payload.Data) > maxSignalBytes {
+		log.Printf("Renegotiate from %s dropped: %d bytes exceeds limit", client.UserID, len(payload.Data))
+		return
+	}
+
+	peer, ok := h.activeMeetingPeer(client.SpaceID, client.UserID, payload.TargetUserID)
+	if !ok {
+		log.Printf("Renegotiate from %s dropped: %s is not a meeting peer", client.UserID, payload.TargetUserID)
+		return
+	}
+
+	peer.SendJSON(messages.BaseMessage{
+		Type: messages.TypeRenegotiate,
+		Payload: messages.RenegotiatePayload{
+			PeerID: client.UserID,
+			Data:   payload.Data,
+		},
+	})
+}
+
+// handleSignal relays a WebRTC signaling message to a co-participant of an active meeting.
+// Targets outside the sender's meeting are rejected with a signal-error.
+func (h *Hub) handleSignal(client *Client, payload messages.IncomingPayload) {
+	reject := func(reason string) {
+		client.SendJSON(messages.BaseMessage{
+			Type: messages.TypeSignalError,
+			Payload: messages.SignalErrorPayload{
+				TargetUserID: payload.TargetUserID,
+				Error:        reason,
+			},
+		})
+	}
+
+	if payload.SignalType == "" {
+		reject("missing signal type")
+		return
+	}
+	if len(payload.Data) > maxSignalBytes {
+		reject("signal too large")
+		return
+	}
+
+	peer, ok := h.activeMeetingPeer(client.SpaceID, client.UserID, payload.TargetUserID)
+	if !ok {
+		reject("target is not in your meeting")
+		return
+	}
+
+	peer.SendJSON(messages.BaseMessage{
+		Type: messages.TypeSignal,
+		Payload: messages.SignalPayload{
+			PeerID:     client.UserID,
+			SignalType: payload.SignalType,
+			Data:       payload.Data,
+		},
+	})
+}
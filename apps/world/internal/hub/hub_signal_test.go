@@ -0,0 +1,210 @@
+package hub
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"world/internal/messages"
+
+	"github.com/gorilla/websocket"
+)
+
+// testSDPOffer builds an SDP offer the size a browser sends: audio and video sections
+// with their codecs, header extensions and a handful of ICE candidates each
+func testSDPOffer() string {
+	var b strings.Builder
+	b.WriteString("v=0\r\no=- 4611731400430051336 2 IN IP4 127.0.0.1\r\ns=-\r\nt=0 0\r\n")
+	b.WriteString("a=group:BUNDLE 0 1\r\na=extmap-allow-mixed\r\na=msid-semantic: WMS stream\r\n")
+	sections := []struct {
+		kind     string
+		payloads []int
+		codecs   []string
+	}{
+		{"audio", []int{111, 63, 9, 0, 8, 13, 110, 126}, []string{"opus/48000/2", "red/48000/2", "G722/8000", "PCMU/8000", "PCMA/8000", "CN/8000", "telephone-event/48000", "telephone-event/8000"}},
+		{"video", []int{96, 97, 98, 99, 100, 101, 102, 103, 104, 105, 106, 107}, []string{"VP8/90000", "rtx/90000", "VP9/90000", "rtx/90000", "H264/90000", "rtx/90000", "H264/90000", "rtx/90000", "AV1/90000", "rtx/90000", "red/90000", "ulpfec/90000"}},
+	}
+	for mid, m := range sections {
+		fmt.Fprintf(&b, "m=%s 9 UDP/TLS/RTP/SAVPF", m.kind)
+		for _, pt := range m.payloads {
+			fmt.Fprintf(&b, " %d", pt)
+		}
+		b.WriteString("\r\nc=IN IP4 0.0.0.0\r\na=rtcp:9 IN IP4 0.0.0.0\r\n")
+		for i := 0; i < 4; i++ {
+			fmt.Fprintf(&b, "a=candidate:%d 1 udp %d 192.168.1.%d %d typ host generation 0 network-id 1\r\n", 1467250027+i, 2122260223-i, 10+i, 54321+i)
+		}
+		b.WriteString("a=ice-ufrag:Fx3p\r\na=ice-pwd:4sK2kUbXz5dQ7cR9vLmN0pYt\r\na=ice-options:trickle\r\n")
+		b.WriteString("a=fingerprint:sha-256 7B:8B:F0:65:5F:78:E2:51:3B:AC:6F:F3:3F:46:1B:35:DC:B8:5F:64:1A:24:C2:43:F0:A1:58:D0:A1:2C:19:08\r\n")
+		fmt.Fprintf(&b, "a=setup:actpass\r\na=mid:%d\r\n", mid)
+		for i, ext := range []string{"urn:ietf:params:rtp-hdrext:ssrc-audio-level", "http://www.webrtc.org/experiments/rtp-hdrext/abs-send-time", "http://www.ietf.org/id/draft-holmer-rmcat-transport-wide-cc-extensions-01", "urn:ietf:params:rtp-hdrext:sdes:mid"} {
+			fmt.Fprintf(&b, "a=extmap:%d %s\r\n", i+1, ext)
+		}
+		fmt.Fprintf(&b, "a=sendrecv\r\na=msid:stream %s-track\r\na=rtcp-mux\r\na=rtcp-rsize\r\n", m.kind)
+		for i, pt := range m.payloads {
+			fmt.Fprintf(&b, "a=rtpmap:%d %s\r\n", pt, m.codecs[i])
+			if m.kind == "video" {
+				fmt.Fprintf(&b, "a=rtcp-fb:%d goog-remb\r\na=rtcp-fb:%d transport-cc\r\na=rtcp-fb:%d ccm fir\r\na=rtcp-fb:%d nack\r\na=rtcp-fb:%d nack pli\r\n", pt, pt, pt, pt, pt)
+			}
+		}
+		fmt.Fprintf(&b, "a=fmtp:%d minptime=10;useinbandfec=1\r\n", m.payloads[0])
+		fmt.Fprintf(&b, "a=ssrc-group:FID 3735928559 3405691582\r\na=ssrc:3735928559 cname:4TOk42mSjXCkVIa6\r\na=ssrc:3735928559 msid:stream %s-track\r\n", m.kind)
+	}
+	return b.String()
+}
+
+// startTestMeeting marks two users as being in an active meeting
+func startTestMeeting(space *Space, userA, userB string) *MeetingState {
+	state := &MeetingState{
+		MeetingID: userA + "-" + userB,
+		UserA:     userA,
+		UserB:     userB,
+		Status:    MeetingStatusActive,
+	}
+	space.MeetingStates[dwellKey(userA, userB)] = state
+	return state
+}
+
+func TestRenegotiateRequiresSameMeeting(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	b := addTestClient(h, space, "b", 150, 100)
+	c := addTestClient(h, space, "c", 200, 100)
+	startTestMeeting(space, "a", "b")
+
+	blob := json.RawMessage(`{"sdp":"v=0"}`)
+
+	h.handleRenegotiate(a, messages.IncomingPayload{TargetUserID: "c", Data: blob})
+	if n := len(messagesOfType(drainMessages(t, c), messages.TypeRenegotiate)); n != 0 {
+		t.Errorf("non-participant received %d renegotiate messages", n)
+	}
+
+	h.handleRenegotiate(a, messages.IncomingPayload{TargetUserID: "b", Data: blob})
+	got := messagesOfType(drainMessages(t, b), messages.TypeRenegotiate)
+	if len(got) != 1 {
+		t.Fatalf("meeting peer got %d renegotiate messages, want 1", len(got))
+	}
+	var payload messages.RenegotiatePayload
+	if err := json.Unmarshal(got[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.PeerID != "a" || string(payload.Data) != string(blob) {
+		t.Errorf("unexpected payload %+v", payload)
+	}
+
+	// A prompted (not yet active) meeting does not qualify
+	space.MeetingStates[dwellKey("a", "b")].Status = MeetingStatusPrompted
+	h.handleRenegotiate(a, messages.IncomingPayload{TargetUserID: "b", Data: blob})
+	if n := len(messagesOfType(drainMessages(t, b), messages.TypeRenegotiate)); n != 0 {
+		t.Errorf("renegotiate relayed for a non-active meeting")
+	}
+}
+
+func TestSignalRelayScopedToMeeting(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	b := addTestClient(h, space, "b", 150, 100)
+	c := addTestClient(h, space, "c", 200, 100)
+	d := addTestClient(h, space, "d", 250, 100)
+	startTestMeeting(space, "a", "b")
+	startTestMeeting(space, "c", "d")
+
+	offer := messages.IncomingPayload{SignalType: "offer", Data: json.RawMessage(`{"sdp":"v=0"}`)}
+
+	// Cross-meeting target is rejected
+	offer.TargetUserID = "c"
+	h.handleSignal(a, offer)
+	if n := len(messagesOfType(drainMessages(t, c), messages.TypeSignal)); n != 0 {
+		t.Errorf("cross-meeting target received %d signals", n)
+	}
+	if n := len(messagesOfType(drainMessages(t, a), messages.TypeSignalError)); n != 1 {
+		t.Errorf("sender got %d signal-errors, want 1", n)
+	}
+
+	// Co-participant receives it
+	offer.TargetUserID = "b"
+	h.handleSignal(a, offer)
+	got := messagesOfType(drainMessages(t, b), messages.TypeSignal)
+	if len(got) != 1 {
+		t.Fatalf("co-participant got %d signals, want 1", len(got))
+	}
+	var payload messages.SignalPayload
+	if err := json.Unmarshal(got[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.PeerID != "a" || payload.SignalType != "offer" {
+		t.Errorf("unexpected payload %+v", payload)
+	}
+	if n := len(drainMessages(t, d)); n != 0 {
+		t.Errorf("unrelated meeting received %d messages", n)
+	}
+}
+
+func TestSignalOfferThroughReadPump(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	b := addTestClient(h, space, "b", 150, 100)
+	startTestMeeting(space, "a", "b")
+
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		a := addTestClient(h, space, "a", 100, 100)
+		a.Conn = conn
+		go a.ReadPump()
+	}))
+	defer srv.Close()
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	sdp := testSDPOffer()
+	data, _ := json.Marshal(map[string]string{"type": "offer", "sdp": sdp})
+	msg, _ := json.Marshal(messages.BaseMessage{
+		Type:    messages.TypeSignal,
+		Payload: map[string]interface{}{"targetUserId": "b", "signalType": "offer", "data": json.RawMessage(data)},
+	})
+	if len(msg) < 4096 {
+		t.Fatalf("offer is only %d bytes; browsers send several KB", len(msg))
+	}
+	if err := ws.WriteMessage(websocket.TextMessage, msg); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case raw := <-b.Send:
+		var got struct {
+			Type    string                 `json:"type"`
+			Payload messages.SignalPayload `json:"payload"`
+		}
+		if err := json.Unmarshal(raw, &got); err != nil {
+			t.Fatal(err)
+		}
+		var offer map[string]string
+		if err := json.Unmarshal(got.Payload.Data, &offer); err != nil {
+			t.Fatal(err)
+		}
+		if got.Type != messages.TypeSignal || offer["sdp"] != sdp {
+			t.Errorf("relayed %s with a %d-byte sdp, want the %d-byte offer", got.Type, len(offer["sdp"]), len(sdp))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("offer was not relayed")
+	}
+}
@@ -0,0 +1,221 @@
+package hub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SpaceSnapshot is the persistent layout of a space: its size, static elements, placed
+// objects, spawn zones and portals. Users and meetings are transient and left out.
+type SpaceSnapshot struct {
+	ID     string `json:"id"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	// Elements are the blocked points as "x,y" keys, sorted
+	Elements []string `json:"elements"`
+	// Objects are the placed objects; their tiles are in Elements too
+	Objects    []WorldObject `json:"objects,omitempty"`
+	SpawnZones []SpawnZone   `json:"spawnZones,omitempty"`
+	Portals    []Portal      `json:"portals,omitempty"`
+}
+
+// Snapshot serializes the space's layout to JSON
+func (s *Space) Snapshot() ([]byte, error) {
+	s.mu.RLock()
+	snap := SpaceSnapshot{
+		ID:         s.ID,
+		Width:      s.Width,
+		Height:     s.Height,
+		Elements:   make([]string, 0, len(s.Elements)),
+		SpawnZones: s.SpawnZones,
+		Portals:    s.Portals,
+	}
+	for key, blocked := range s.Elements {
+		if blocked {
+			snap.Elements = append(snap.Elements, key)
+		}
+	}
+	for _, obj := range s.Objects {
+		snap.Objects = append(snap.Objects, obj)
+	}
+	s.mu.RUnlock()
+
+	sort.Strings(snap.Elements)
+	sort.Slice(snap.Objects, func(i, j int) bool {
+		if snap.Objects[i].Y != snap.Objects[j].Y {
+			return snap.Objects[i].Y < snap.Objects[j].Y
+		}
+		return snap.Objects[i].X < snap.Objects[j].X
+	})
+	return json.Marshal(snap)
+}
+
+// LoadSpaceSnapshot reads a snapshot written from Space.Snapshot into a new, empty
+// space. The caller wires it up to a hub.
+func LoadSpaceSnapshot(path string) (*Space, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var snap SpaceSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, err
+	}
+	if snap.ID == "" || snap.Width <= 0 || snap.Height <= 0 {
+		return nil, fmt.Errorf("snapshot needs an id and positive dimensions")
+	}
+	m := SpaceMap{SpawnZones: snap.SpawnZones, Portals: snap.Portals}
+	if err := m.validateAreas(); err != nil {
+		return nil, err
+	}
+
+	space := NewSpace(snap.ID, snap.Width, snap.Height)
+	for _, key := range snap.Elements {
+		space.Elements[key] = true
+	}
+	for _, obj := range snap.Objects {
+		key := posKey(obj.X, obj.Y)
+		space.Elements[key] = true
+		space.Objects[key] = obj
+	}
+	space.SpawnZones, space.Portals = snap.SpawnZones, snap.Portals
+	return space, nil
+}
+
+// loadSnapshotFor loads spaceID's snapshot from dir, or returns nil if there is none
+// (or dir is ""). A broken snapshot is logged and treated as missing.
+func loadSnapshotFor(dir, spaceID string) *Space {
+	if dir == "" {
+		return nil
+	}
+	path, ok := snapshotPath(dir, spaceID)
+	if !ok {
+		return nil
+	}
+	space, err := LoadSpaceSnapshot(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err == nil && space.ID != spaceID {
+		err = fmt.Errorf("it holds space %q", space.ID)
+	}
+	if err != nil {
+		log.Printf("WARNING: ignoring space snapshot %s: %v", path, err)
+		return nil
+	}
+	return space
+}
+
+// snapshotPath is where spaceID's snapshot lives in dir. ok is false for IDs that
+// can't name a file there.
+func snapshotPath(dir, spaceID string) (string, bool) {
+	if spaceID == "" || spaceID != filepath.Base(spaceID) || strings.HasPrefix(spaceID, ".") {
+		return "", false
+	}
+	return filepath.Join(dir, spaceID+".json"), true
+}
+
+// SaveSnapshots writes a snapshot of every live space into dir, replacing each file
+// atomically. Snapshots of spaces that have since emptied are kept.
+func (h *Hub) SaveSnapshots(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	h.mu.RLock()
+	spaces := make([]*Space, 0, len(h.Spaces))
+	for _, space := range h.Spaces {
+		spaces = append(spaces, space)
+	}
+	h.mu.RUnlock()
+
+	var errs []error
+	for _, space := range spaces {
+		path, ok := snapshotPath(dir, space.ID)
+		if !ok {
+			continue
+		}
+		data, err := space.Snapshot()
+		if err == nil {
+			err = writeFileAtomic(path, data)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("space %s: %w", space.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it into
+// place, so a crash never leaves a half-written snapshot
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// RestoreSpaces loads every snapshot in dir into the hub, skipping spaces that
+// already exist. A broken snapshot is logged and skipped. Returns how many were restored.
+func (h *Hub) RestoreSpaces(dir string) (int, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return 0, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	restored := 0
+	for _, path := range paths {
+		space, err := LoadSpaceSnapshot(path)
+		if err != nil {
+			log.Printf("WARNING: ignoring space snapshot %s: %v", path, err)
+			continue
+		}
+		if want, _ := snapshotPath(dir, space.ID); want != path {
+			log.Printf("WARNING: ignoring space snapshot %s: it holds space %q", path, space.ID)
+			continue
+		}
+		if _, exists := h.Spaces[space.ID]; exists {
+			continue
+		}
+		h.addSpaceLocked(space)
+		restored++
+	}
+	return restored, nil
+}
+
+// RunSnapshots saves snapshots into dir every interval until the hub shuts down
+func (h *Hub) RunSnapshots(dir string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.done:
+			return
+		case <-ticker.C:
+		}
+		if err := h.SaveSnapshots(dir); err != nil {
+			log.Printf("Saving space snapshots: %v", err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package hub
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"world/internal/config"
+)
+
+func TestSpaceSnapshotRoundTrip(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	space := newTestSpace(h, "office")
+	space.Elements[posKey(10, 20)] = true
+	space.Elements[posKey(11, 20)] = true
+	space.SpawnZones = []SpawnZone{{Name: "default", MapRect: MapRect{X: 100, Y: 100, Width: 50, Height: 50}}}
+	space.Portals = []Portal{{Name: "gate", MapRect: MapRect{X: 0, Y: 0, Width: 5, Height: 5}, To: MapPoint{X: 1, Y: 2}, ToSpace: "garden"}}
+	if _, code := space.PlaceObject(WorldObject{Type: "chair", X: 30, Y: 40, PlacedBy: "builder"}); code != "" {
+		t.Fatal(code)
+	}
+	addTestClient(h, space, "visitor", 400, 400)
+
+	data, err := space.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "visitor") {
+		t.Errorf("snapshot persisted a user: %s", data)
+	}
+	path := filepath.Join(t.TempDir(), "office.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadSpaceSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.ID != "office" || loaded.Width != 1280 || loaded.Height != 960 {
+		t.Errorf("loaded %s %dx%d, want office 1280x960", loaded.ID, loaded.Width, loaded.Height)
+	}
+	if !reflect.DeepEqual(loaded.Elements, space.Elements) {
+		t.Errorf("elements = %v, want %v", loaded.Elements, space.Elements)
+	}
+	if !reflect.DeepEqual(loaded.Objects, space.Objects) {
+		t.Errorf("objects = %v, want %v", loaded.Objects, space.Objects)
+	}
+	if !reflect.DeepEqual(loaded.SpawnZones, space.SpawnZones) || !reflect.DeepEqual(loaded.Portals, space.Portals) {
+		t.Errorf("zones %+v and portals %+v not restored", loaded.SpawnZones, loaded.Portals)
+	}
+	if len(loaded.Users) != 0 {
+		t.Errorf("loaded space has %d users", len(loaded.Users))
+	}
+}
+
+func TestSaveAndRestoreSpaces(t *testing.T) {
+	setupTestConfig(t)
+	dir := t.TempDir()
+
+	h := NewHub()
+	newTestSpace(h, "office").Elements[posKey(705, 500)] = true
+	newTestSpace(h, "garden")
+	if err := h.SaveSnapshots(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{"id": "broken"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := NewHub()
+	n, err := restarted.RestoreSpaces(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("restored %d spaces, want 2", n)
+	}
+	office, ok := restarted.Spaces["office"]
+	if !ok || !office.Elements[posKey(705, 500)] {
+		t.Fatal("office layout not restored")
+	}
+	if office.Events != restarted.Events || office.ProximityStrategy == nil {
+		t.Error("restored space not wired up to the hub")
+	}
+	if _, ok := restarted.Spaces["broken"]; ok {
+		t.Error("broken snapshot was restored")
+	}
+
+	// Joining a restored space uses its layout instead of creating a fresh one
+	c := joinTestClient(t, restarted, "office", "u1")
+	if restarted.Spaces["office"] != office {
+		t.Error("join replaced the restored space")
+	}
+	if x, y := c.GetPosition(); office.IsColliding(x, y, "u1") {
+		t.Errorf("joined onto a restored element at (%v, %v)", x, y)
+	}
+}
+
+func TestNewSpaceStartsFromSnapshot(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.SnapshotDir = t.TempDir() })
+
+	h := NewHub()
+	newTestSpace(h, "office").Elements[posKey(1, 1)] = true
+	if err := h.SaveSnapshots(config.Current().SnapshotDir); err != nil {
+		t.Fatal(err)
+	}
+	delete(h.Spaces, "office")
+
+	joinTestClient(t, h, "office", "u1")
+	if !h.Spaces["office"].Elements[posKey(1, 1)] {
+		t.Error("space recreated without its snapshot layout")
+	}
+}
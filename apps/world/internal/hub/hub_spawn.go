@@ -0,0 +1,216 @@
+package hub
+
+import (
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"world/internal/config"
+	"world/internal/messages"
+)
+
+// joinNearDistance is the spacing between a friend and the spots tried around them
+const joinNearDistance = 32.0
+
+// joinNearOffsets are the directions tried around a point, closest ring first
+var joinNearOffsets = [][2]float64{
+	{1, 0}, {-1, 0}, {0, 1}, {0, -1},
+	{1, 1}, {-1, 1}, {1, -1}, {-1, -1},
+	{2, 0}, {-2, 0}, {0, 2}, {0, -2},
+}
+
+// spawnNearUser picks a free spot next to friendID for joinerID.
+// ok is false if the friend isn't in the space (or is hidden from the joiner)
+// or every spot around them is taken.
+func (s *Space) spawnNearUser(friendID, joinerID string) (x, y float64, ok bool) {
+	if friendID == "" || friendID == joinerID {
+		return 0, 0, false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	friend, present := s.Users[friendID]
+	if !present || friend.HidesFrom(joinerID) {
+		return 0, 0, false
+	}
+
+	fx, fy := friend.GetPosition()
+	return s.freeSpotNearLocked(fx, fy, joinerID)
+}
+
+// freeSpotNearLocked returns the first free spot around (x, y), trying joinNearOffsets in order
+func (s *Space) freeSpotNearLocked(x, y float64, excludeUserID string) (float64, float64, bool) {
+	for _, offset := range joinNearOffsets {
+		nx := NormalizeCoord(x + offset[0]*joinNearDistance)
+		ny := NormalizeCoord(y + offset[1]*joinNearDistance)
+		if !s.isCollidingLocked(nx, ny, excludeUserID) {
+			return nx, ny, true
+		}
+	}
+	return 0, 0, false
+}
+
+// spawnRandLocked returns the hub's spawn RNG, seeding it from SPAWN_SEED (or the clock)
+// on first use. Callers hold h.mu.
+func (h *Hub) spawnRandLocked() *rand.Rand {
+	if h.SpawnRand == nil {
+		seed := config.Current().SpawnSeed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		h.SpawnRand = rand.New(rand.NewSource(seed))
+	}
+	return h.SpawnRand
+}
+
+// defaultSpawnHalfWidth is how far, in grid tiles, the default spawn zone reaches from
+// the space's center
+const defaultSpawnHalfWidth = 50
+
+// spawnZone returns the zone new arrivals are placed in: the map's zone named
+// "default", else its first zone, else a square around the grid point (705, 500).
+func (s *Space) spawnZone() SpawnZone {
+	for _, z := range s.SpawnZones {
+		if z.Name == "default" {
+			return z
+		}
+	}
+	if len(s.SpawnZones) > 0 {
+		return s.SpawnZones[0]
+	}
+	x, y := s.ToClientCoords(705-defaultSpawnHalfWidth, 500-defaultSpawnHalfWidth)
+	size := (2*defaultSpawnHalfWidth + 1) * s.Coords.Scale
+	return SpawnZone{Name: "default", MapRect: MapRect{X: x, Y: y, Width: size, Height: size}}
+}
+
+// FindFreeSpawn searches outward from (centerX, centerY), one grid tile at a time in
+// square rings, for the first tile an avatar can stand on. A center outside the space
+// starts from the nearest tile inside it. ok is false only if every tile is taken.
+func (s *Space) FindFreeSpawn(centerX, centerY float64) (x, y float64, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.findFreeTileLocked(centerX, centerY, 0, 0, s.Width, s.Height, nil)
+}
+
+// FindFreeSpawnIn is FindFreeSpawn limited to the tiles inside zone, searching
+// outward from its center
+func (s *Space) FindFreeSpawnIn(zone SpawnZone) (x, y float64, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c := s.Coords
+	x0 := int(math.Ceil((zone.X - c.OriginX) / c.Scale))
+	y0 := int(math.Ceil((zone.Y - c.OriginY) / c.Scale))
+	x1 := int(math.Ceil((zone.X + zone.Width - c.OriginX) / c.Scale))
+	y1 := int(math.Ceil((zone.Y + zone.Height - c.OriginY) / c.Scale))
+	return s.findFreeTileLocked(zone.X+zone.Width/2, zone.Y+zone.Height/2,
+		max(x0, 0), max(y0, 0), min(x1, s.Width), min(y1, s.Height), zone.Contains)
+}
+
+// findFreeTileLocked walks the tiles in [x0, x1) x [y0, y1) in square rings around the
+// tile nearest (centerX, centerY) and returns the first free one that within accepts
+// (nil accepts any). Callers hold s.mu.
+func (s *Space) findFreeTileLocked(centerX, centerY float64, x0, y0, x1, y1 int, within func(x, y float64) bool) (x, y float64, ok bool) {
+	if x0 >= x1 || y0 >= y1 {
+		return 0, 0, false
+	}
+	clampTile := func(v float64, lo, hi int) int {
+		return max(lo, min(hi-1, int(math.Round(v))))
+	}
+	gx := clampTile((centerX-s.Coords.OriginX)/s.Coords.Scale, x0, x1)
+	gy := clampTile((centerY-s.Coords.OriginY)/s.Coords.Scale, y0, y1)
+
+	for r := 0; r < max(x1-x0, y1-y0); r++ {
+		for dy := -r; dy <= r; dy++ {
+			// Top and bottom rows of the ring are walked in full, the rows between only at their ends
+			step := 1
+			if dy != -r && dy != r {
+				step = 2 * r
+			}
+			for dx := -r; dx <= r; dx += step {
+				tx, ty := gx+dx, gy+dy
+				if tx < x0 || tx >= x1 || ty < y0 || ty >= y1 {
+					continue
+				}
+				cx, cy := s.ToClientCoords(float64(tx), float64(ty))
+				cx, cy = NormalizeCoord(cx), NormalizeCoord(cy)
+				if within != nil && !within(cx, cy) {
+					continue
+				}
+				if !s.isCollidingLocked(cx, cy, "") {
+					return cx, cy, true
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// ResolveOverlaps pushes apart users whose avatars overlap. The user with the lowest
+// ID stays put; the others move to free spots nearby. Returns the users that were moved.
+func (s *Space) ResolveOverlaps() []*Client {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0, len(s.Users))
+	for id := range s.Users {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	type spot struct{ x, y float64 }
+	placed := make([]spot, 0, len(ids))
+	overlapsPlaced := func(x, y float64) bool {
+		for _, p := range placed {
+			if footprintsOverlap(x, y, p.x, p.y) {
+				return true
+			}
+		}
+		return false
+	}
+	moved := make([]*Client, 0)
+	for _, id := range ids {
+		client := s.Users[id]
+		x, y := client.GetPosition()
+		if !overlapsPlaced(x, y) {
+			placed = append(placed, spot{x, y})
+			continue
+		}
+		nx, ny, ok := s.freeSpotNearLocked(x, y, id)
+		if !ok {
+			log.Printf("Space %s: no free spot to push %s off (%v, %v)", s.ID, id, x, y)
+			continue
+		}
+		client.SetPosition(nx, ny)
+		placed = append(placed, spot{nx, ny})
+		moved = append(moved, client)
+	}
+	return moved
+}
+
+// resolveOverlaps separates overlapping users in space and broadcasts the corrections.
+// Moved users get the broadcast too, so their client snaps to the new position.
+func (h *Hub) resolveOverlaps(space *Space) {
+	for _, client := range space.ResolveOverlaps() {
+		x, y := client.GetPosition()
+		log.Printf("Space %s: pushed %s to (%v, %v) to resolve an overlap", space.ID, client.UserID, x, y)
+
+		h.recomputeProximity(space, client)
+		msg := messages.BaseMessage{
+			Type: messages.TypeMovement,
+			Payload: messages.MovementPayload{
+				X:          x,
+				Y:          y,
+				UserID:     client.UserID,
+				Anim:       client.GetAnim(),
+				DurationMs: durationMs(0),
+			},
+		}
+		h.broadcastMovement(space, client, msg)
+		client.SendJSON(msg)
+		h.Events.Publish(Event{Type: EventUserMoved, SpaceID: space.ID, UserID: client.UserID, X: x, Y: y})
+	}
+}
@@ -0,0 +1,250 @@
+package hub
+
+import (
+	"encoding/json"
+	"math/rand"
+	"testing"
+
+	"world/internal/messages"
+)
+
+// joinNear runs userID through handleJoin asking to spawn next to friendID
+func joinNear(t *testing.T, h *Hub, spaceID, userID, friendID string) *Client {
+	t.Helper()
+	c := &Client{Hub: h, Send: make(chan []byte, 256)}
+	h.Clients[c] = true
+	h.handleJoin(c, messages.IncomingPayload{
+		SpaceID:        spaceID,
+		Token:          testToken(t, userID, "user"),
+		JoinNearUserID: friendID,
+	})
+	return c
+}
+
+func TestJoinSpawnsNearPresentFriend(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	addTestClient(h, space, "friend", 200, 200)
+	// The first spot around the friend is blocked
+	space.Elements[posKey(200+joinNearDistance, 200)] = true
+
+	c := joinNear(t, h, "s1", "joiner", "friend")
+
+	x, y := c.GetPosition()
+	if x != 200-joinNearDistance || y != 200 {
+		t.Errorf("spawned at (%v, %v), want (%v, 200)", x, y, 200-joinNearDistance)
+	}
+}
+
+func TestJoinNearFallsBackToDefaultSpawn(t *testing.T) {
+	tests := []struct {
+		name   string
+		friend string
+		setup  func(space *Space)
+	}{
+		{"friend not in space", "ghost", func(*Space) {}},
+		{"friend hidden from joiner", "friend", func(space *Space) {
+			space.Users["friend"].SetHiddenFrom("joiner", true)
+		}},
+		{"no free spot around friend", "friend", func(space *Space) {
+			for _, offset := range joinNearOffsets {
+				space.Elements[posKey(200+offset[0]*joinNearDistance, 200+offset[1]*joinNearDistance)] = true
+			}
+		}},
+	}
+	for _, tt := range tests {
+		setupTestConfig(t)
+		h := NewHub()
+		space := newTestSpace(h, "s1")
+		addTestClient(h, space, "friend", 200, 200)
+		tt.setup(space)
+
+		c := joinNear(t, h, "s1", "joiner", tt.friend)
+
+		x, y := c.GetPosition()
+		if x < 655 || x > 755 || y < 450 || y > 550 {
+			t.Errorf("%s: spawned at (%v, %v), want the default spawn area", tt.name, x, y)
+		}
+		if len(messagesOfType(drainMessages(t, c), messages.TypeSpaceJoined)) != 1 {
+			t.Errorf("%s: join did not complete", tt.name)
+		}
+	}
+}
+
+func TestResolveOverlapsSeparatesUsers(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 200, 200)
+	b := addTestClient(h, space, "b", 200, 200)
+	observer := addTestClient(h, space, "observer", 600, 600)
+	// The first spot to the right is a wall, so b is pushed left
+	space.Elements[posKey(200+joinNearDistance, 200)] = true
+
+	h.resolveOverlaps(space)
+
+	if x, y := a.GetPosition(); x != 200 || y != 200 {
+		t.Errorf("a moved to (%v, %v), want it to stay at (200, 200)", x, y)
+	}
+	if x, y := b.GetPosition(); x != 200-joinNearDistance || y != 200 {
+		t.Errorf("b at (%v, %v), want (%v, 200)", x, y, 200-joinNearDistance)
+	}
+
+	// Everyone, including b itself, hears about the correction
+	for _, c := range []*Client{b, observer} {
+		moves := messagesOfType(drainMessages(t, c), messages.TypeMovement)
+		if len(moves) != 1 {
+			t.Fatalf("%s received %d movement messages, want 1", c.UserID, len(moves))
+		}
+		var p messages.MovementPayload
+		if err := json.Unmarshal(moves[0].Payload, &p); err != nil {
+			t.Fatal(err)
+		}
+		if p.UserID != "b" || p.X != 200-joinNearDistance {
+			t.Errorf("%s got correction %+v", c.UserID, p)
+		}
+	}
+
+	// Nothing left to resolve
+	if moved := space.ResolveOverlaps(); len(moved) != 0 {
+		t.Errorf("second pass moved %d users, want 0", len(moved))
+	}
+}
+
+func TestJoinReportsSpawnFallback(t *testing.T) {
+	setupTestConfig(t)
+
+	// A space walled in tile by tile has no free spawn at all
+	h := NewHub()
+	tiny := NewSpace("tiny", 10, 10)
+	for x := 0; x < 10; x++ {
+		for y := 0; y < 10; y++ {
+			tiny.Elements[posKey(float64(x), float64(y))] = true
+		}
+	}
+	h.Spaces["tiny"] = tiny
+	newTestSpace(h, "roomy")
+	before := SpawnFallbacks()
+
+	for _, tt := range []struct {
+		spaceID string
+		want    bool
+	}{{"tiny", true}, {"roomy", false}} {
+		c := joinTestClient(t, h, tt.spaceID, "u-"+tt.spaceID)
+		joined := messagesOfType(drainMessages(t, c), messages.TypeSpaceJoined)
+		if len(joined) != 1 {
+			t.Fatalf("%s: got %d space-joined messages", tt.spaceID, len(joined))
+		}
+		var p messages.SpaceJoinedPayload
+		if err := json.Unmarshal(joined[0].Payload, &p); err != nil {
+			t.Fatal(err)
+		}
+		if p.SpawnFallback != tt.want {
+			t.Errorf("%s: spawnFallback = %v, want %v", tt.spaceID, p.SpawnFallback, tt.want)
+		}
+	}
+
+	if d := SpawnFallbacks() - before; d != 1 {
+		t.Errorf("spawn fallback counter +%d, want +1", d)
+	}
+}
+
+func TestJoinFindsFreeTileAroundCrowdedCenter(t *testing.T) {
+	setupTestConfig(t)
+
+	// Wall off the whole random spawn area, so only the outward search can place the user
+	h := NewHub()
+	space := newTestSpace(h, "crowded")
+	for x := 705 - 60; x <= 705+60; x++ {
+		for y := 500 - 60; y <= 500+60; y++ {
+			space.Elements[posKey(float64(x), float64(y))] = true
+		}
+	}
+
+	c := joinTestClient(t, h, "crowded", "u1")
+	joined := messagesOfType(drainMessages(t, c), messages.TypeSpaceJoined)
+	if len(joined) != 1 {
+		t.Fatalf("got %d space-joined messages", len(joined))
+	}
+	var p messages.SpaceJoinedPayload
+	if err := json.Unmarshal(joined[0].Payload, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.SpawnFallback {
+		t.Error("spawnFallback set although free tiles remain")
+	}
+	x, y := c.GetPosition()
+	if space.IsColliding(x, y, "u1") {
+		t.Errorf("spawned on a blocked tile at (%v, %v)", x, y)
+	}
+	if x > 705-60 && x < 705+60 && y > 500-60 && y < 500+60 {
+		t.Errorf("spawned inside the walled area at (%v, %v)", x, y)
+	}
+}
+
+func TestFindFreeSpawnSearchesOutward(t *testing.T) {
+	setupTestConfig(t)
+
+	space := NewSpace("s1", 100, 100)
+	if x, y, ok := space.FindFreeSpawn(50, 50); !ok || x != 50 || y != 50 {
+		t.Errorf("free center: got (%v, %v, %v), want (50, 50, true)", x, y, ok)
+	}
+
+	// Block the 3x3 block around the center: the nearest free tile is two away
+	for x := 49; x <= 51; x++ {
+		for y := 49; y <= 51; y++ {
+			space.Elements[posKey(float64(x), float64(y))] = true
+		}
+	}
+	x, y, ok := space.FindFreeSpawn(50, 50)
+	if !ok || space.IsColliding(x, y, "") {
+		t.Fatalf("got (%v, %v, %v), want a free tile", x, y, ok)
+	}
+	if max(abs(x-50), abs(y-50)) != 2 {
+		t.Errorf("got (%v, %v), want a tile on the ring two away from the center", x, y)
+	}
+
+	// A center outside the space starts from its nearest edge tile
+	if x, y, ok := space.FindFreeSpawn(-500, 500); !ok || x != 0 || y != 99 {
+		t.Errorf("outside center: got (%v, %v, %v), want (0, 99, true)", x, y, ok)
+	}
+}
+
+func TestSeededSpawnIsReproducible(t *testing.T) {
+	setupTestConfig(t)
+
+	spawn := func() (float64, float64) {
+		h := NewHub()
+		h.SpawnRand = rand.New(rand.NewSource(42))
+		newTestSpace(h, "s1")
+		return joinTestClient(t, h, "s1", "u1").GetPosition()
+	}
+	x1, y1 := spawn()
+	x2, y2 := spawn()
+	if x1 != x2 || y1 != y2 {
+		t.Errorf("same seed spawned at (%v, %v) and (%v, %v)", x1, y1, x2, y2)
+	}
+}
+
+func TestFindFreeSpawnInStaysInZone(t *testing.T) {
+	setupTestConfig(t)
+
+	space := NewSpace("s1", 100, 100)
+	zone := SpawnZone{Name: "corner", MapRect: MapRect{X: 10, Y: 10, Width: 3, Height: 3}}
+	for x := 10; x < 13; x++ {
+		for y := 10; y < 13; y++ {
+			if x != 12 || y != 12 {
+				space.Elements[posKey(float64(x), float64(y))] = true
+			}
+		}
+	}
+	if x, y, ok := space.FindFreeSpawnIn(zone); !ok || x != 12 || y != 12 {
+		t.Errorf("got (%v, %v, %v), want the zone's last free tile (12, 12)", x, y, ok)
+	}
+
+	space.Elements[posKey(12, 12)] = true
+	if x, y, ok := space.FindFreeSpawnIn(zone); ok {
+		t.Errorf("full zone gave (%v, %v)", x, y)
+	}
+}
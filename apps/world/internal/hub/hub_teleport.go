@@ -0,0 +1,46 @@
+package hub
+
+import (
+	"time"
+
+	"world/internal/config"
+)
+
+// portalLanding is how far from a portal's destination a teleport through it may land,
+// the same as one movement step
+const portalLanding = 20
+
+// TeleportAllowed reports whether userID may teleport to (x, y): into video range of a
+// peer in their active or pending meeting, or next to the destination of a portal
+// they're standing in.
+func (s *Space) TeleportAllowed(userID string, x, y float64, now time.Time) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	client, ok := s.Users[userID]
+	if !ok {
+		return false
+	}
+
+	for _, state := range s.MeetingStates {
+		if !state.hasParticipant(userID) || (state.Status != MeetingStatusActive && !state.isPending(now)) {
+			continue
+		}
+		for _, peerID := range state.peersOf(userID) {
+			if peer, ok := s.Users[peerID]; ok {
+				px, py := peer.GetPosition()
+				if distance(x, y, px, py) <= config.Current().VideoRadius {
+					return true
+				}
+			}
+		}
+	}
+
+	cx, cy := client.GetPosition()
+	for _, p := range s.Portals {
+		if p.Contains(cx, cy) && (p.ToSpace == "" || p.ToSpace == s.ID) && distance(x, y, p.To.X, p.To.Y) <= portalLanding {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,203 @@
+package hub
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"world/internal/config"
+	"world/internal/messages"
+)
+
+func TestTeleportToMeetingPeer(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	addTestClient(h, space, "b", 900, 700)
+	observer := addTestClient(h, space, "c", 500, 500)
+	startTestMeeting(space, "a", "b")
+
+	h.handleTeleport(a, messages.IncomingPayload{X: 860, Y: 700})
+
+	if x, y := a.GetPosition(); x != 860 || y != 700 {
+		t.Errorf("a at (%v, %v), want (860, 700)", x, y)
+	}
+	if n := len(messagesOfType(drainMessages(t, observer), messages.TypeMovement)); n != 1 {
+		t.Errorf("observer got %d movements, want 1", n)
+	}
+	if n := len(messagesOfType(drainMessages(t, a), messages.TypeMovementRejected)); n != 0 {
+		t.Errorf("meeting teleport rejected %d times", n)
+	}
+}
+
+func TestTeleportSnaps(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.MaxMoveSpeed = 200 })
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	addTestClient(h, space, "b", 900, 700)
+	observer := addTestClient(h, space, "c", 500, 500)
+	startTestMeeting(space, "a", "b")
+
+	h.handleTeleport(a, messages.IncomingPayload{X: 860, Y: 700})
+
+	moves := messagesOfType(drainMessages(t, observer), messages.TypeMovement)
+	if len(moves) != 1 {
+		t.Fatalf("observer got %d movements, want 1", len(moves))
+	}
+	var payload messages.MovementPayload
+	if err := json.Unmarshal(moves[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.DurationMs == nil || *payload.DurationMs != 0 {
+		t.Errorf("teleport durationMs = %v, want an explicit 0", payload.DurationMs)
+	}
+}
+
+func TestArbitraryTeleportRejected(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) {
+		cfg.ProtocolViolationLimit = 10
+		cfg.ProtocolViolationWindow = time.Minute
+	})
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	addTestClient(h, space, "b", 900, 700)
+	observer := addTestClient(h, space, "c", 500, 500)
+
+	// Not in a meeting with b, so b's side of the map is off limits
+	h.handleTeleport(a, messages.IncomingPayload{X: 860, Y: 700})
+	// In a meeting, but the destination is nowhere near the peer
+	startTestMeeting(space, "a", "b")
+	h.handleTeleport(a, messages.IncomingPayload{X: 1200, Y: 100})
+
+	if x, y := a.GetPosition(); x != 100 || y != 100 {
+		t.Errorf("a warped to (%v, %v)", x, y)
+	}
+	if n := len(messagesOfType(drainMessages(t, a), messages.TypeMovementRejected)); n != 2 {
+		t.Errorf("got %d movement rejections, want 2", n)
+	}
+	if n := len(messagesOfType(drainMessages(t, observer), messages.TypeMovement)); n != 0 {
+		t.Errorf("observer saw %d movements of a rejected teleport", n)
+	}
+	if a.violations != 0 {
+		t.Errorf("violations = %d, want 0", a.violations)
+	}
+}
+
+func TestTeleportPathStepsAreMoves(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) {
+		cfg.ProtocolViolationLimit = 3
+		cfg.ProtocolViolationWindow = time.Minute
+	})
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	observer := addTestClient(h, space, "c", 500, 500)
+
+	// Walking to a meeting sends every path node as a teleport, well before the meeting
+	// peer is in range; none of them may count against the client
+	for i := 1; i <= 10; i++ {
+		h.handleTeleport(a, messages.IncomingPayload{X: 100 + float64(i*16), Y: 100})
+	}
+
+	if x, y := a.GetPosition(); x != 260 || y != 100 {
+		t.Errorf("a at (%v, %v), want (260, 100)", x, y)
+	}
+	if n := len(messagesOfType(drainMessages(t, observer), messages.TypeMovement)); n != 10 {
+		t.Errorf("observer got %d movements, want 10", n)
+	}
+	if n := len(messagesOfType(drainMessages(t, a), messages.TypeMovementRejected)); n != 0 {
+		t.Errorf("path steps rejected %d times", n)
+	}
+	if a.violations != 0 {
+		t.Errorf("violations = %d, want 0", a.violations)
+	}
+}
+
+func TestTeleportThroughPortal(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	space.Portals = []Portal{{
+		Name:    "lift",
+		MapRect: MapRect{X: 90, Y: 90, Width: 20, Height: 20},
+		To:      MapPoint{X: 1000, Y: 800},
+	}}
+	a := addTestClient(h, space, "a", 100, 100)
+	b := addTestClient(h, space, "b", 300, 300)
+
+	h.handleTeleport(a, messages.IncomingPayload{X: 1010, Y: 800})
+	if x, y := a.GetPosition(); x != 1010 || y != 800 {
+		t.Errorf("a at (%v, %v), want through the portal at (1010, 800)", x, y)
+	}
+
+	// Only from inside the door, and only to its destination
+	h.handleTeleport(b, messages.IncomingPayload{X: 1000, Y: 800})
+	h.handleTeleport(a, messages.IncomingPayload{X: 100, Y: 100})
+	if x, y := b.GetPosition(); x != 300 || y != 300 {
+		t.Errorf("b teleported from outside the portal to (%v, %v)", x, y)
+	}
+	if x, y := a.GetPosition(); x != 1010 || y != 800 {
+		t.Errorf("a teleported back without a portal to (%v, %v)", x, y)
+	}
+}
+
+func TestMeetingChairWalkLikeGameClient(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) {
+		cfg.MaxMoveSpeed = 300
+		cfg.MoveTickInterval = 100 * time.Millisecond
+		cfg.MoveTickBudget = 40
+		cfg.MovementRateHz = config.DefaultMovementRateHz
+		cfg.ProtocolViolationLimit = 20
+		cfg.ProtocolViolationWindow = time.Minute
+	})
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 130, 110)
+	addTestClient(h, space, "b", 200, 170)
+	observer := addTestClient(h, space, "c", 900, 900)
+	startTestMeeting(space, "a", "b")
+
+	// Game.ts walks the A* path of tile centers to the chair at (220, 88) at 150px/s,
+	// with the last node swapped for the spot in front of the chair, and teleports to
+	// each node as its tween completes. Hops of a whole tile are real teleports; the
+	// shorter first and last ones go through the movement checks.
+	path := []struct{ x, y float64 }{{144, 112}, {176, 112}, {208, 112}, {220, 104}}
+	x, y := a.GetPosition()
+	for _, node := range path {
+		time.Sleep(time.Duration(distance(x, y, node.x, node.y) / 150 * float64(time.Second)))
+		h.handleTeleport(a, messages.IncomingPayload{X: node.x, Y: node.y, Anim: "ginny_run_right"})
+		x, y = node.x, node.y
+	}
+	// The final sync, then sitting down
+	h.handleTeleport(a, messages.IncomingPayload{X: 220, Y: 104, Anim: "ginny_idle_down"})
+	if got := a.GetAnim(); got != "ginny_idle_down" {
+		t.Errorf("anim after the final sync = %q, want ginny_idle_down", got)
+	}
+	h.handleMovement(a, messages.IncomingPayload{X: 220, Y: 91, Anim: "ginny_sit_down"})
+
+	if x, y := a.GetPosition(); x != 220 || y != 91 || a.GetAnim() != "ginny_sit_down" {
+		t.Errorf("a at (%v, %v) playing %q, want seated at (220, 91)", x, y, a.GetAnim())
+	}
+	if n := len(messagesOfType(drainMessages(t, a), messages.TypeMovementRejected)); n != 0 {
+		t.Errorf("walk to the chair rejected %d times", n)
+	}
+	if n := len(messagesOfType(drainMessages(t, observer), messages.TypeMovement)); n != len(path)+2 {
+		t.Errorf("observer got %d movements, want %d", n, len(path)+2)
+	}
+	if a.violations != 0 {
+		t.Errorf("violations = %d, want 0", a.violations)
+	}
+}
@@ -0,0 +1,1090 @@
+package hub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"world/internal/auth"
+	"world/internal/config"
+	"world/internal/messages"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
+)
+
+// testMessage mirrors BaseMessage with a raw payload for decoding in tests
+type testMessage struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// setupTestConfig installs a config with the default radii and no movement budget
+func setupTestConfig(t *testing.T) {
+	t.Helper()
+	config.Set(&config.Config{
+		JWTSecret:   "test-secret",
+		AudioRadius: 300,
+		VideoRadius: 120,
+	})
+}
+
+// updateTestConfig swaps in a copy of the current config with update applied, the way a
+// reload does, rather than changing the config other goroutines may be reading
+func updateTestConfig(update func(cfg *config.Config)) {
+	next := *config.Current()
+	update(&next)
+	config.Set(&next)
+}
+
+// testToken signs a token for userID with the test secret
+func testToken(t *testing.T, userID, role string) string {
+	t.Helper()
+	claims := &auth.Claims{
+		UserID: userID,
+		Role:   role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(config.Current().JWTSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// joinTestClient connects a new client and runs it through handleJoin
+func joinTestClient(t *testing.T, h *Hub, spaceID, userID string) *Client {
+	t.Helper()
+	c := &Client{Hub: h, Send: make(chan []byte, 256)}
+	h.Clients[c] = true
+	h.handleJoin(c, messages.IncomingPayload{SpaceID: spaceID, Token: testToken(t, userID, "user")})
+	return c
+}
+
+// newTestSpace registers a fresh space on the hub
+func newTestSpace(h *Hub, spaceID string) *Space {
+	space := NewSpace(spaceID, 1280, 960)
+	h.Spaces[spaceID] = space
+	return space
+}
+
+// addTestClient places a client with a buffered Send channel directly into a space
+func addTestClient(h *Hub, space *Space, userID string, x, y float64) *Client {
+	c := &Client{
+		Hub:     h,
+		Send:    make(chan []byte, 256),
+		UserID:  userID,
+		SpaceID: space.ID,
+		X:       x,
+		Y:       y,
+	}
+	h.Clients[c] = true
+	space.AddUser(c)
+	return c
+}
+
+// drainMessages returns every message currently queued on the client's Send channel
+func drainMessages(t *testing.T, c *Client) []testMessage {
+	t.Helper()
+	var out []testMessage
+	for {
+		select {
+		case data, ok := <-c.Send:
+			if !ok {
+				return out
+			}
+			var msg testMessage
+			if err := json.Unmarshal(data, &msg); err != nil {
+				t.Fatalf("failed to decode message %s: %v", data, err)
+			}
+			out = append(out, msg)
+		default:
+			return out
+		}
+	}
+}
+
+// messagesOfType filters drained messages by type
+func messagesOfType(msgs []testMessage, msgType string) []testMessage {
+	var out []testMessage
+	for _, m := range msgs {
+		if m.Type == msgType {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func TestHandleMovementTickBudget(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) {
+		cfg.MoveTickInterval = time.Minute // keep every move inside one window
+		cfg.MoveTickBudget = 40
+	})
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	mover := addTestClient(h, space, "mover", 100, 100)
+
+	// Five rapid 10-unit steps each pass IsValidMove, but only four fit in the budget
+	for i := 1; i <= 5; i++ {
+		h.handleMovement(mover, messages.IncomingPayload{X: 100 + float64(i*10), Y: 100})
+	}
+
+	x, _ := mover.GetPosition()
+	if x != 140 {
+		t.Errorf("position after burst = %v, want 140", x)
+	}
+	rejected := messagesOfType(drainMessages(t, mover), messages.TypeMovementRejected)
+	if len(rejected) != 1 {
+		t.Errorf("got %d movement-rejected messages, want 1", len(rejected))
+	}
+}
+
+func TestConsumeMoveBudget(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) {
+		cfg.MoveTickInterval = 100 * time.Millisecond
+		cfg.MoveTickBudget = 40
+	})
+
+	c := &Client{}
+	now := time.Now()
+
+	if !c.consumeMoveBudget(20, now) || !c.consumeMoveBudget(20, now.Add(10*time.Millisecond)) {
+		t.Fatal("moves within budget were rejected")
+	}
+	if c.consumeMoveBudget(1, now.Add(20*time.Millisecond)) {
+		t.Error("move exceeding budget within the same tick was accepted")
+	}
+	if !c.consumeMoveBudget(20, now.Add(150*time.Millisecond)) {
+		t.Error("move in a new tick window was rejected")
+	}
+
+	updateTestConfig(func(cfg *config.Config) { cfg.MoveTickBudget = 0 })
+	if !c.consumeMoveBudget(1000, now.Add(160*time.Millisecond)) {
+		t.Error("zero budget should disable reconciliation")
+	}
+}
+
+func TestMoveSpeedCap(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.MaxMoveSpeed = 200 })
+
+	// feed accepts each step of dist at interval while the client stays under the cap,
+	// and returns how many passed
+	feed := func(c *Client, start time.Time, dist float64, interval time.Duration, steps int) int {
+		accepted := 0
+		for i := 0; i < steps; i++ {
+			now := start.Add(time.Duration(i) * interval)
+			if c.withinMoveSpeed(dist, now) {
+				c.acceptMove(dist, now)
+				accepted++
+			}
+		}
+		return accepted
+	}
+	start := time.Now()
+
+	// 10 units every 50ms is exactly 200 units/s
+	if got := feed(&Client{}, start, 10, 50*time.Millisecond, 40); got != 40 {
+		t.Errorf("legal speed: %d of 40 moves accepted", got)
+	}
+	// 20 units every 50ms is 400 units/s: the banked burst runs out and about half pass
+	if got := feed(&Client{}, start, 20, 50*time.Millisecond, 40); got > 25 {
+		t.Errorf("double speed: %d of 40 moves accepted", got)
+	}
+
+	// A full step after standing still always passes, even right after a burst
+	c := &Client{}
+	feed(c, start, 20, time.Millisecond, 5)
+	if !c.withinMoveSpeed(maxStepDistance, start.Add(time.Minute)) {
+		t.Error("first move after idling was rejected")
+	}
+}
+
+func TestHandleMovementSpeedCap(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.MaxMoveSpeed = 200 })
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	mover := addTestClient(h, space, "mover", 100, 100)
+
+	// Back-to-back 20-unit hops: the 50-unit burst (250ms at 200 units/s) covers two
+	for i := 1; i <= 4; i++ {
+		h.handleMovement(mover, messages.IncomingPayload{X: 100 + float64(i*20), Y: 100})
+	}
+
+	x, _ := mover.GetPosition()
+	if x != 140 {
+		t.Errorf("position after hops = %v, want 140", x)
+	}
+	rejected := messagesOfType(drainMessages(t, mover), messages.TypeMovementRejected)
+	if len(rejected) != 2 {
+		t.Errorf("got %d movement-rejected messages, want 2", len(rejected))
+	}
+}
+
+func TestMovementDurationScalesWithDistance(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.MaxMoveSpeed = 200 })
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	mover := addTestClient(h, space, "mover", 100, 100)
+	observer := addTestClient(h, space, "observer", 400, 400)
+
+	h.handleMovement(mover, messages.IncomingPayload{X: 110, Y: 100})
+	h.handleMovement(mover, messages.IncomingPayload{X: 110, Y: 120})
+
+	var durations []int64
+	for _, m := range messagesOfType(drainMessages(t, observer), messages.TypeMovement) {
+		var payload messages.MovementPayload
+		if err := json.Unmarshal(m.Payload, &payload); err != nil {
+			t.Fatal(err)
+		}
+		if payload.DurationMs == nil {
+			t.Fatal("movement without durationMs")
+		}
+		durations = append(durations, *payload.DurationMs)
+	}
+	// 10 and 20 units at 200 units/s
+	if len(durations) != 2 || durations[0] != 50 || durations[1] != 100 {
+		t.Errorf("durations = %v, want [50 100]", durations)
+	}
+}
+
+func TestHandleMovementRateLimit(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.MovementRateHz = 30 })
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	mover := addTestClient(h, space, "mover", 100, 100)
+	observer := addTestClient(h, space, "observer", 900, 900)
+
+	// A burst arriving all at once: only the burst allowance gets through
+	for i := 1; i <= 10; i++ {
+		h.handleMovement(mover, messages.IncomingPayload{X: 100 + float64(i), Y: 100})
+	}
+
+	if x, _ := mover.GetPosition(); x != 100+moveRateBurst {
+		t.Errorf("position after burst = %v, want %v", x, 100+moveRateBurst)
+	}
+	if n := len(messagesOfType(drainMessages(t, observer), messages.TypeMovement)); n != moveRateBurst {
+		t.Errorf("observer saw %d moves, want %d", n, moveRateBurst)
+	}
+	if n := len(messagesOfType(drainMessages(t, mover), messages.TypeMovementRejected)); n != 0 {
+		t.Errorf("dropped moves sent %d rejections", n)
+	}
+}
+
+// walkAt60Hz sends frames moves 2.5px apart (150px/s) at a 60fps client's pace, then
+// the stop the client sends when the walk ends
+func walkAt60Hz(h *Hub, c *Client, frames int) {
+	x, y := c.GetPosition()
+	for i := 0; i < frames; i++ {
+		x += 2.5
+		h.handleMovement(c, messages.IncomingPayload{X: x, Y: y, Anim: "adam_run_right"})
+		time.Sleep(time.Second / 60)
+	}
+	h.handleMovement(c, messages.IncomingPayload{X: x, Y: y, Anim: "adam_idle_right"})
+}
+
+func TestSixtyHzClientStops(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.MovementRateHz = config.DefaultMovementRateHz })
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	mover := addTestClient(h, space, "mover", 100, 100)
+	observer := addTestClient(h, space, "observer", 900, 900)
+
+	walkAt60Hz(h, mover, 30)
+
+	if x, _ := mover.GetPosition(); x != 175 {
+		t.Errorf("walk ended at x=%v, want 175", x)
+	}
+	if mover.Anim != "adam_idle_right" {
+		t.Errorf("anim = %q after stopping, want adam_idle_right", mover.Anim)
+	}
+	if n := len(messagesOfType(drainMessages(t, observer), messages.TypeMovement)); n != 31 {
+		t.Errorf("observer saw %d of 31 moves at the default rate", n)
+	}
+
+	// Even under a rate below the client's, the stop gets through
+	updateTestConfig(func(cfg *config.Config) { cfg.MovementRateHz = 20 })
+	walkAt60Hz(h, mover, 30)
+	if mover.Anim != "adam_idle_right" {
+		t.Errorf("anim = %q after stopping at 20Hz, want adam_idle_right", mover.Anim)
+	}
+	moves := messagesOfType(drainMessages(t, observer), messages.TypeMovement)
+	var last messages.MovementPayload
+	if err := json.Unmarshal(moves[len(moves)-1].Payload, &last); err != nil {
+		t.Fatal(err)
+	}
+	if last.Anim != "adam_idle_right" || last.X != 250 {
+		t.Errorf("observer's last move = %+v, want the stop at x=250", last)
+	}
+}
+
+func TestAllowMoveRate(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.MovementRateHz = 10 })
+
+	c := &Client{}
+	now := time.Now()
+	allowed := 0
+	// 100 moves over one second at 100Hz: the burst plus ~10 refills
+	for i := 0; i < 100; i++ {
+		if c.allowMoveRate(now.Add(time.Duration(i) * 10 * time.Millisecond)) {
+			allowed++
+		}
+	}
+	if allowed < 10 || allowed > 10+moveRateBurst {
+		t.Errorf("allowed %d moves in a second at 10Hz", allowed)
+	}
+
+	// Moves at the configured rate are never dropped
+	c = &Client{}
+	for i := 0; i < 50; i++ {
+		if !c.allowMoveRate(now.Add(time.Duration(i) * 100 * time.Millisecond)) {
+			t.Fatalf("move %d at the allowed rate was dropped", i)
+		}
+	}
+
+	updateTestConfig(func(cfg *config.Config) { cfg.MovementRateHz = 0 })
+	for i := 0; i < 100; i++ {
+		if !c.allowMoveRate(now) {
+			t.Fatal("zero rate should disable the limit")
+		}
+	}
+}
+
+func TestHandleJoinServerMisconfigured(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.JWTSecret = "" })
+
+	h := NewHub()
+	c := &Client{Hub: h, Send: make(chan []byte, 8)}
+	h.handleJoin(c, messages.IncomingPayload{SpaceID: "s1", Token: "whatever"})
+
+	errs := messagesOfType(drainMessages(t, c), messages.TypeJoinError)
+	if len(errs) != 1 {
+		t.Fatalf("got %d join-error messages, want 1", len(errs))
+	}
+	var payload messages.JoinErrorPayload
+	if err := json.Unmarshal(errs[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Code != messages.JoinErrorServerMisconfigured {
+		t.Errorf("join-error code = %q, want %q", payload.Code, messages.JoinErrorServerMisconfigured)
+	}
+	if _, ok := h.Spaces["s1"]; ok {
+		t.Error("space should not be created when the server is misconfigured")
+	}
+}
+
+func TestReloadProximityRadiiRecomputesExistingUsers(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	b := addTestClient(h, space, "b", 300, 100)
+
+	h.RecomputeAllProximity()
+	if !space.Proximity["audio"]["a"]["b"] {
+		t.Fatal("users 200 apart should be in audio proximity with radius 300")
+	}
+	drainMessages(t, a)
+	drainMessages(t, b)
+
+	t.Setenv("AUDIO_RADIUS", "150")
+	h.ReloadProximityRadii()
+
+	if config.Current().AudioRadius != 150 {
+		t.Fatalf("AudioRadius = %v, want 150", config.Current().AudioRadius)
+	}
+	if space.Proximity["audio"]["a"]["b"] || space.Proximity["audio"]["b"]["a"] {
+		t.Error("audio proximity should be cleared after shrinking the radius")
+	}
+	for _, c := range []*Client{a, b} {
+		if n := len(messagesOfType(drainMessages(t, c), messages.TypeProximityUpdate)); n != 1 {
+			t.Errorf("%s got %d proximity updates after reload, want 1", c.UserID, n)
+		}
+	}
+}
+
+func TestReloadProximityRadiiDuringMoves(t *testing.T) {
+	setupTestConfig(t)
+	t.Setenv("AUDIO_RADIUS", "150")
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	b := addTestClient(h, space, "b", 300, 100)
+
+	// Run under -race: reloads swap the config while moves read it
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			h.ReloadProximityRadii()
+		}
+	}()
+	clients := []*Client{a, b}
+	for i := 0; i < 200; i++ {
+		// Each user shuffles a unit right and back
+		c := clients[i%2]
+		dx := 1.0
+		if i%4 >= 2 {
+			dx = -1
+		}
+		x, y := c.GetPosition()
+		h.handleMovement(c, messages.IncomingPayload{X: x + dx, Y: y})
+	}
+	<-done
+
+	if r := config.Current().AudioRadius; r != 150 {
+		t.Errorf("AudioRadius = %v, want 150", r)
+	}
+}
+
+func TestProtocolViolationsDisconnect(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) {
+		cfg.ProtocolViolationLimit = 3
+		cfg.ProtocolViolationWindow = time.Minute
+	})
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	c := addTestClient(h, space, "u1", 100, 100)
+
+	// Three violations are tolerated
+	h.ProcessMessage(c, []byte("{not json"))
+	h.ProcessMessage(c, []byte(`{"type":"bogus"}`))
+	h.ProcessMessage(c, []byte(`{"type":"movement","payload":{"x":900,"y":900}}`))
+
+	select {
+	case <-h.Unregister:
+		t.Fatal("client disconnected before exceeding the limit")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	h.ProcessMessage(c, []byte(`{"type":"bogus"}`))
+	select {
+	case got := <-h.Unregister:
+		if got != c {
+			t.Fatal("unexpected client unregistered")
+		}
+		if got.closeReason != CloseProtocolViolations {
+			t.Errorf("close reason = %v, want %v", got.closeReason, CloseProtocolViolations)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("client was not disconnected after exceeding the limit")
+	}
+}
+
+func TestAddViolationResetsAfterWindow(t *testing.T) {
+	c := &Client{}
+	now := time.Now()
+
+	for i := 0; i < 5; i++ {
+		c.addViolation(now, time.Minute)
+	}
+	if n := c.addViolation(now.Add(2*time.Minute), time.Minute); n != 1 {
+		t.Errorf("violation count after window = %d, want 1", n)
+	}
+}
+
+// lastUserCount returns the count from the most recent user-count message, or -1
+func lastUserCount(t *testing.T, c *Client) int {
+	t.Helper()
+	counts := messagesOfType(drainMessages(t, c), messages.TypeUserCount)
+	if len(counts) == 0 {
+		return -1
+	}
+	var payload messages.UserCountPayload
+	if err := json.Unmarshal(counts[len(counts)-1].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	return payload.Count
+}
+
+func TestUserCountBroadcastOnJoinAndLeave(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+
+	a := joinTestClient(t, h, "s1", "a")
+	if n := lastUserCount(t, a); n != 1 {
+		t.Errorf("count after first join = %d, want 1", n)
+	}
+
+	b := joinTestClient(t, h, "s1", "b")
+	if n := lastUserCount(t, a); n != 2 {
+		t.Errorf("a saw count %d after second join, want 2", n)
+	}
+	if n := lastUserCount(t, b); n != 2 {
+		t.Errorf("b saw count %d after joining, want 2", n)
+	}
+
+	h.handleDisconnect(b)
+	if n := lastUserCount(t, a); n != 1 {
+		t.Errorf("count after leave = %d, want 1", n)
+	}
+}
+
+func TestJoinCooldown(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.JoinCooldown = time.Minute })
+
+	h := NewHub()
+	first := joinTestClient(t, h, "s1", "u1")
+	if n := len(messagesOfType(drainMessages(t, first), messages.TypeSpaceJoined)); n != 1 {
+		t.Fatalf("first join got %d space-joined, want 1", n)
+	}
+
+	// Immediate rejoin (e.g. a client reconnect loop) is rejected
+	h.handleDisconnect(first)
+	second := joinTestClient(t, h, "s2", "u1")
+	errs := messagesOfType(drainMessages(t, second), messages.TypeJoinError)
+	if len(errs) != 1 {
+		t.Fatalf("rapid rejoin got %d join-errors, want 1", len(errs))
+	}
+	var payload messages.JoinErrorPayload
+	if err := json.Unmarshal(errs[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Code != messages.JoinErrorTooFrequent {
+		t.Errorf("code = %q, want %q", payload.Code, messages.JoinErrorTooFrequent)
+	}
+	if second.SpaceID != "" {
+		t.Error("rejected client should not be assigned a space")
+	}
+
+	// Other users are unaffected, and a normal-paced rejoin succeeds
+	other := joinTestClient(t, h, "s1", "u2")
+	if n := len(messagesOfType(drainMessages(t, other), messages.TypeSpaceJoined)); n != 1 {
+		t.Errorf("other user's join got %d space-joined, want 1", n)
+	}
+	if !h.allowJoin("u1", time.Now().Add(2*time.Minute)) {
+		t.Error("join after the cooldown should be allowed")
+	}
+}
+
+func TestConsumeMoveBudgetLatencyTolerance(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) {
+		cfg.MoveTickInterval = 100 * time.Millisecond
+		cfg.MoveTickBudget = 40
+		cfg.MaxLatencyCompensation = 300 * time.Millisecond
+	})
+
+	now := time.Now()
+
+	// 100ms of RTT doubles the budget for a 100ms window
+	c := &Client{}
+	if c.consumeMoveBudget(60, now) {
+		t.Fatal("move beyond the base budget was accepted without latency")
+	}
+	c.SetRTT(100 * time.Millisecond)
+	if !c.consumeMoveBudget(60, now) {
+		t.Error("high reported RTT should allow a batched move")
+	}
+
+	// An absurd RTT is capped at 300ms, i.e. at most 4x the budget
+	c = &Client{}
+	c.SetRTT(time.Hour)
+	if !c.consumeMoveBudget(160, now) {
+		t.Error("move within the capped tolerance was rejected")
+	}
+	if c.consumeMoveBudget(1, now) {
+		t.Error("capped RTT should not allow moves beyond 4x the budget")
+	}
+}
+
+func TestHandleLatencyStoresRTT(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	c := &Client{Hub: h, Send: make(chan []byte, 8)}
+
+	h.ProcessMessage(c, []byte(`{"type":"latency","payload":{"rttMs":250}}`))
+	if got := c.RTT(); got != 250*time.Millisecond {
+		t.Errorf("RTT = %v, want 250ms", got)
+	}
+}
+
+func TestConcurrentJoinAndLastLeaveKeepsSpace(t *testing.T) {
+	setupTestConfig(t)
+
+	for i := 0; i < 300; i++ {
+		h := NewHub()
+		space := newTestSpace(h, "s1")
+
+		// Leavers aren't registered as hub clients so their Send channels stay open
+		// for concurrent broadcasts; this test is about space bookkeeping only
+		var leavers []*Client
+		for _, id := range []string{"a", "c", "d", "e"} {
+			c := &Client{Hub: h, Send: make(chan []byte, 256), UserID: id, SpaceID: space.ID}
+			space.AddUser(c)
+			leavers = append(leavers, c)
+		}
+		joiner := &Client{Hub: h, Send: make(chan []byte, 256)}
+		token := testToken(t, "b", "user")
+
+		var wg sync.WaitGroup
+		for _, c := range leavers {
+			wg.Add(1)
+			go func(c *Client) {
+				defer wg.Done()
+				h.handleDisconnect(c)
+			}(c)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.handleJoin(joiner, messages.IncomingPayload{SpaceID: "s1", Token: token})
+		}()
+		wg.Wait()
+
+		current, ok := h.Spaces["s1"]
+		if !ok {
+			t.Fatalf("iteration %d: space deleted while %s was in it", i, joiner.UserID)
+		}
+		if users := current.GetUsers(""); len(users) != 1 || users[0] != joiner {
+			t.Fatalf("iteration %d: registered space holds %d users, want only the joiner", i, len(users))
+		}
+	}
+}
+
+func TestRemoveSpaceIfEmptyIgnoresReplacedSpace(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	stale := newTestSpace(h, "s1")
+
+	// The space was removed and recreated under the same ID before the stale disconnect ran
+	current := newTestSpace(h, "s1")
+	addTestClient(h, current, "b", 100, 100)
+
+	h.removeSpaceIfEmpty(stale)
+	if h.Spaces["s1"] != current {
+		t.Fatal("stale removal deleted the newer space")
+	}
+
+	h.handleDisconnect(current.GetUsers("")[0])
+	if _, ok := h.Spaces["s1"]; ok {
+		t.Error("empty space was not removed after its last user left")
+	}
+}
+
+func TestMovementAcceptedConfirmation(t *testing.T) {
+	for _, confirm := range []bool{false, true} {
+		setupTestConfig(t)
+		h := NewHub()
+		space := newTestSpace(h, "s1")
+		mover := addTestClient(h, space, "mover", 100, 100)
+		other := addTestClient(h, space, "other", 600, 600)
+		mover.ConfirmMoves = confirm
+
+		h.handleMovement(mover, messages.IncomingPayload{X: 110, Y: 100})
+
+		accepted := messagesOfType(drainMessages(t, mover), messages.TypeMovementAccepted)
+		if !confirm {
+			if len(accepted) != 0 {
+				t.Errorf("default mode sent %d movement-accepted messages, want 0", len(accepted))
+			}
+		} else {
+			if len(accepted) != 1 {
+				t.Fatalf("confirm mode sent %d movement-accepted messages, want 1", len(accepted))
+			}
+			var p messages.MovementAcceptedPayload
+			if err := json.Unmarshal(accepted[0].Payload, &p); err != nil {
+				t.Fatal(err)
+			}
+			if p.X != 110 || p.Y != 100 {
+				t.Errorf("accepted position = (%v, %v), want (110, 100)", p.X, p.Y)
+			}
+		}
+
+		// Others still get the broadcast either way
+		if got := len(messagesOfType(drainMessages(t, other), messages.TypeMovement)); got != 1 {
+			t.Errorf("confirm=%v: other received %d movement broadcasts, want 1", confirm, got)
+		}
+	}
+}
+
+func TestRejectedMoveIsNotConfirmed(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	mover := addTestClient(h, space, "mover", 100, 100)
+	mover.ConfirmMoves = true
+
+	h.handleMovement(mover, messages.IncomingPayload{X: 400, Y: 100})
+
+	msgs := drainMessages(t, mover)
+	if len(messagesOfType(msgs, messages.TypeMovementAccepted)) != 0 {
+		t.Error("rejected move was confirmed")
+	}
+	if len(messagesOfType(msgs, messages.TypeMovementRejected)) != 1 {
+		t.Error("rejected move did not send movement-rejected")
+	}
+}
+
+func TestWallPushingIsThrottled(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.CollisionCooldown = time.Second })
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	space.Elements[posKey(110, 100)] = true
+	mover := addTestClient(h, space, "mover", 100, 100)
+
+	rejections := func() int {
+		return len(messagesOfType(drainMessages(t, mover), messages.TypeMovementRejected))
+	}
+
+	for i := 0; i < 5; i++ {
+		h.handleMovement(mover, messages.IncomingPayload{X: 110, Y: 100})
+	}
+	if got := rejections(); got != 1 {
+		t.Fatalf("repeated wall pushes sent %d rejections, want 1", got)
+	}
+
+	// Once the cooldown has passed the attempt is checked (and rejected) again
+	mover.setRejectedTarget(110, 100, time.Now().Add(-2*time.Second))
+	h.handleMovement(mover, messages.IncomingPayload{X: 110, Y: 100})
+	if got := rejections(); got != 1 {
+		t.Errorf("push after cooldown sent %d rejections, want 1", got)
+	}
+
+	// Moving elsewhere clears the throttle
+	h.handleMovement(mover, messages.IncomingPayload{X: 105, Y: 100})
+	h.handleMovement(mover, messages.IncomingPayload{X: 110, Y: 100})
+	if got := rejections(); got != 1 {
+		t.Errorf("push after moving sent %d rejections, want 1", got)
+	}
+}
+
+func TestAppIdleClientIsReaped(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.AppIdleTimeout = time.Minute })
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	idle := addTestClient(h, space, "idle", 100, 100)
+	active := addTestClient(h, space, "active", 300, 300)
+	lobby := &Client{Hub: h, Send: make(chan []byte, 8)}
+	h.Clients[lobby] = true
+
+	now := time.Now()
+	idle.touch(now.Add(-2 * time.Minute))
+	lobby.touch(now.Add(-2 * time.Minute))
+	// Any application message counts as activity
+	h.ProcessMessage(active, []byte(`{"type":"latency","payload":{"rttMs":40}}`))
+
+	h.reapIdleClients(now)
+
+	select {
+	case got := <-h.Unregister:
+		if got != idle {
+			t.Fatalf("unregistered %q, want the idle client", got.UserID)
+		}
+		if got.closeReason != CloseIdle {
+			t.Errorf("close reason = %v, want %v", got.closeReason, CloseIdle)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("idle client was not disconnected")
+	}
+
+	select {
+	case got := <-h.Unregister:
+		t.Errorf("unexpectedly disconnected %q", got.UserID)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestUnjoinedClientIsReaped(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.JoinTimeout = 15 * time.Second })
+
+	h := NewHub()
+	start := time.Now()
+	ghost := &Client{Hub: h, Send: make(chan []byte, 8)}
+	h.registerClient(ghost, start)
+	joined := &Client{Hub: h, Send: make(chan []byte, 256)}
+	h.registerClient(joined, start)
+	h.handleJoin(joined, messages.IncomingPayload{SpaceID: "s1", Token: testToken(t, "joined", "user")})
+
+	h.reapUnjoinedClients(start.Add(10 * time.Second))
+	if ghost.closedByServer() {
+		t.Fatal("client disconnected before the join deadline")
+	}
+
+	h.reapUnjoinedClients(start.Add(20 * time.Second))
+	select {
+	case got := <-h.Unregister:
+		if got != ghost {
+			t.Fatalf("unregistered %q, want the client that never joined", got.UserID)
+		}
+		if got.closeReason != CloseJoinTimeout {
+			t.Errorf("close reason = %v, want %v", got.closeReason, CloseJoinTimeout)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("client that never joined was not disconnected")
+	}
+	if len(messagesOfType(drainMessages(t, ghost), messages.TypeJoinTimeout)) != 1 {
+		t.Error("no join-timeout sent before disconnecting")
+	}
+
+	select {
+	case got := <-h.Unregister:
+		t.Errorf("unexpectedly disconnected %q", got.UserID)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestDeclineSetsCooldown(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	b := addTestClient(h, space, "b", 150, 100)
+	key := dwellKey("a", "b")
+
+	space.VideoDwellStart[key] = time.Now().Add(-time.Minute)
+	space.CheckVideoDwellTimers()
+	state := space.MeetingStates[key]
+	if state == nil {
+		t.Fatal("pair was not prompted")
+	}
+	requestID := state.RequestID
+	drainMessages(t, a)
+
+	// Both decline at nearly the same time
+	h.handleMeetingResponse(a, messages.IncomingPayload{PeerID: "b", RequestID: requestID})
+	h.handleMeetingResponse(b, messages.IncomingPayload{PeerID: "a", RequestID: requestID})
+
+	if state.CooldownUntil.Before(time.Now()) {
+		t.Fatal("decline did not start a cooldown")
+	}
+
+	// Re-approaching straight away completes a dwell but must not re-prompt
+	space.VideoDwellStart[key] = time.Now().Add(-time.Minute)
+	space.CheckVideoDwellTimers()
+	if n := len(messagesOfType(drainMessages(t, a), messages.TypeMeetingPrompt)); n != 0 {
+		t.Errorf("got %d prompts during the decline cooldown, want 0", n)
+	}
+
+	// Once the cooldown is over the pair can be prompted again
+	state.CooldownUntil = time.Now().Add(-time.Second)
+	space.CheckVideoDwellTimers()
+	space.VideoDwellStart[key] = time.Now().Add(-time.Minute)
+	space.CheckVideoDwellTimers()
+	if n := len(messagesOfType(drainMessages(t, a), messages.TypeMeetingPrompt)); n != 1 {
+		t.Errorf("got %d prompts after the cooldown, want 1", n)
+	}
+}
+
+func TestDetachClient(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	c := addTestClient(h, space, "u1", 100, 100)
+
+	got, registered := h.detachClient(c)
+	if got != space || !registered {
+		t.Fatalf("detachClient = (%v, %v), want the client's space and registered", got, registered)
+	}
+	if _, ok := h.Clients[c]; ok {
+		t.Error("client still registered with the hub")
+	}
+	// Detaching doesn't touch the space; that's the next step
+	if space.UserCount() != 1 {
+		t.Error("detachClient removed the user from the space")
+	}
+
+	if _, registered := h.detachClient(c); registered {
+		t.Error("second detach reported the client as registered")
+	}
+}
+
+func TestAnnounceDeparture(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	stayer := addTestClient(h, space, "stayer", 100, 100)
+	leaver := addTestClient(h, space, "leaver", 600, 600)
+	space.RaiseHand("leaver")
+	space.RemoveUserAndCollectProximityLeaves(leaver)
+
+	h.announceDeparture(space, "leaver", messages.UserLeftClosed)
+
+	msgs := drainMessages(t, stayer)
+	if n := len(messagesOfType(msgs, messages.TypeUserLeft)); n != 1 {
+		t.Errorf("got %d user-left messages, want 1", n)
+	}
+	if n := len(messagesOfType(msgs, messages.TypeUserCount)); n != 1 {
+		t.Errorf("got %d user-count messages, want 1", n)
+	}
+	if n := len(messagesOfType(msgs, messages.TypeHandQueue)); n != 1 {
+		t.Errorf("got %d hand-queue messages, want 1", n)
+	}
+	if n := len(drainMessages(t, leaver)); n != 0 {
+		t.Errorf("leaver received %d messages after leaving", n)
+	}
+}
+
+func TestDisconnectClosesTransportLast(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	transport := newFakeTransport()
+	leaver := NewClient(h, transport, false)
+	leaver.UserID, leaver.SpaceID = "leaver", "s1"
+	h.Clients[leaver] = true
+	space.AddUser(leaver)
+	peer := addTestClient(h, space, "peer", 150, 100)
+	startTestMeeting(space, "leaver", "peer")
+
+	leaver.Disconnect(CloseKicked)
+	h.handleDisconnect(<-h.Unregister)
+	leaver.WritePump()
+
+	// The peer learns the meeting ended and the user left; the space survives
+	msgs := drainMessages(t, peer)
+	if len(messagesOfType(msgs, messages.TypeMeetingEnd)) != 1 || len(messagesOfType(msgs, messages.TypeUserLeft)) != 1 {
+		t.Errorf("peer messages = %+v", msgs)
+	}
+	if _, ok := h.Spaces["s1"]; !ok {
+		t.Error("space with a remaining user was removed")
+	}
+
+	// The transport ends with the close frame carrying the reason
+	if len(transport.controls) == 0 || transport.controls[len(transport.controls)-1] != websocket.CloseMessage {
+		t.Fatalf("controls = %v, want the close frame last", transport.controls)
+	}
+
+	// Late sends are dropped instead of panicking on the closed channel
+	if err := leaver.SendJSON(messages.BaseMessage{Type: messages.TypeUserCount}); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("SendJSON after disconnect = %v, want ErrClientClosed", err)
+	}
+}
+
+func TestBroadcastDuringDisconnect(t *testing.T) {
+	setupTestConfig(t)
+
+	for i := 0; i < 100; i++ {
+		h := NewHub()
+		space := newTestSpace(h, "s1")
+		leaver := addTestClient(h, space, "leaver", 100, 100)
+		talker := addTestClient(h, space, "talker", 600, 600)
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			h.handleDisconnect(leaver)
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				h.broadcastToSpace("s1", messages.BaseMessage{Type: messages.TypeMovement}, talker.UserID)
+			}
+		}()
+		wg.Wait()
+	}
+}
+
+func TestJoinRejectedWhenSpaceFull(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.MaxUsersPerSpace = 3 })
+	h := NewHub()
+
+	members := []*Client{
+		joinTestClient(t, h, "s1", "a"),
+		joinTestClient(t, h, "s1", "b"),
+		joinTestClient(t, h, "s1", "c"),
+	}
+	for _, c := range members {
+		drainMessages(t, c)
+	}
+
+	late := joinTestClient(t, h, "s1", "d")
+	msgs := drainMessages(t, late)
+	errs := messagesOfType(msgs, messages.TypeJoinError)
+	if len(errs) != 1 || len(messagesOfType(msgs, messages.TypeSpaceJoined)) != 0 {
+		t.Fatalf("late join got %+v, want one join-error", msgs)
+	}
+	var payload messages.JoinErrorPayload
+	if err := json.Unmarshal(errs[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Code != messages.JoinErrorSpaceFull || payload.Error != "space full" {
+		t.Errorf("join-error = %+v, want space full", payload)
+	}
+	if late.SpaceID != "" {
+		t.Errorf("rejected client has SpaceID %q", late.SpaceID)
+	}
+
+	space := h.Spaces["s1"]
+	if space.UserCount() != 3 {
+		t.Errorf("user count = %d, want 3", space.UserCount())
+	}
+	for _, c := range members {
+		if n := len(drainMessages(t, c)); n != 0 {
+			t.Errorf("%s got %d messages from the rejected join", c.UserID, n)
+		}
+	}
+
+	// Other spaces aren't affected, and a member can still rejoin their own space
+	if code := joinErrorCode(t, joinTestClient(t, h, "s2", "d")); code != "" {
+		t.Errorf("join to another space rejected with %q", code)
+	}
+	updateTestConfig(func(cfg *config.Config) { cfg.JoinCooldown = 0 })
+	if code := joinErrorCode(t, joinTestClient(t, h, "s1", "a")); code != "" {
+		t.Errorf("member rejoin rejected with %q", code)
+	}
+	if space.UserCount() != 3 {
+		t.Errorf("user count after rejoin = %d, want 3", space.UserCount())
+	}
+}
+
+func TestConcurrentJoinsRespectCapacity(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.MaxUsersPerSpace = 5 })
+	h := NewHub()
+	newTestSpace(h, "s1")
+
+	tokens := make([]string, 20)
+	for i := range tokens {
+		tokens[i] = testToken(t, fmt.Sprintf("u%d", i), "user")
+	}
+	clients := make([]*Client, len(tokens))
+	for i := range clients {
+		clients[i] = &Client{Hub: h, Send: make(chan []byte, 256)}
+		h.Clients[clients[i]] = true
+	}
+
+	var wg sync.WaitGroup
+	for i, c := range clients {
+		wg.Add(1)
+		go func(c *Client, token string) {
+			defer wg.Done()
+			h.handleJoin(c, messages.IncomingPayload{SpaceID: "s1", Token: token})
+		}(c, tokens[i])
+	}
+	wg.Wait()
+
+	if n := h.Spaces["s1"].UserCount(); n != 5 {
+		t.Errorf("user count = %d, want 5", n)
+	}
+}
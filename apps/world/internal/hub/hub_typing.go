@@ -0,0 +1,107 @@
+package hub
+
+import (
+	"time"
+
+	"world/internal/messages"
+)
+
+// typingTimeout clears a typing indicator whose stop message never arrived. Clients
+// resend isTyping while the user keeps typing.
+const typingTimeout = 5 * time.Second
+
+// setTyping records the client's typing state. It returns the scope to tell that the
+// client stopped typing and the scope to tell that it started; either is "" when
+// nothing changed there, so repeated isTyping=true messages forward nothing.
+func (c *Client) setTyping(typing bool, scope string, now time.Time) (stopped, started string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !typing {
+		stopped, c.typingScope = c.typingScope, ""
+		return stopped, ""
+	}
+	c.typingAt = now
+	if c.typingScope == scope {
+		return "", ""
+	}
+	stopped, c.typingScope = c.typingScope, scope
+	return stopped, scope
+}
+
+// handleTyping forwards changes to the sender's typing state to the users who would
+// see their chat in the same scope. The sender is never a recipient.
+func (h *Hub) handleTyping(client *Client, payload messages.IncomingPayload) {
+	if client.SpaceID == "" {
+		return
+	}
+
+	scope := payload.Scope
+	if scope == "" {
+		scope = messages.ChatScopeSpace
+	}
+	if scope != messages.ChatScopeSpace && scope != messages.ChatScopeLocal {
+		h.recordViolation(client, "unknown chat scope")
+		return
+	}
+
+	stopped, started := client.setTyping(payload.IsTyping, scope, time.Now())
+	if stopped != "" {
+		h.sendTyping(client, stopped, false)
+	}
+	if started != "" {
+		h.sendTyping(client, started, true)
+	}
+}
+
+// sendTyping tells the users in scope whether client is typing
+func (h *Hub) sendTyping(client *Client, scope string, typing bool) {
+	msg := messages.BaseMessage{
+		Type: messages.TypeTyping,
+		Payload: messages.TypingPayload{
+			UserID:   client.UserID,
+			IsTyping: typing,
+			Scope:    scope,
+		},
+	}
+	if scope == messages.ChatScopeSpace {
+		h.broadcastToSpace(client.SpaceID, msg, client.UserID)
+		return
+	}
+
+	h.mu.RLock()
+	space, exists := h.Spaces[client.SpaceID]
+	h.mu.RUnlock()
+	if !exists {
+		return
+	}
+	for _, recipient := range space.GetAudioNeighbors(client.UserID) {
+		recipient.SendJSON(msg)
+	}
+}
+
+// clearStaleTyping stops the typing indicator of clients that haven't said they were
+// typing within typingTimeout
+func (h *Hub) clearStaleTyping(now time.Time) {
+	h.mu.RLock()
+	clients := make([]*Client, 0, len(h.Clients))
+	for client := range h.Clients {
+		clients = append(clients, client)
+	}
+	h.mu.RUnlock()
+
+	for _, client := range clients {
+		client.mu.Lock()
+		scope := client.typingScope
+		if scope != "" && now.Sub(client.typingAt) >= typingTimeout {
+			client.typingScope = ""
+		} else {
+			scope = ""
+		}
+		client.mu.Unlock()
+
+		if scope != "" {
+			h.sendTyping(client, scope, false)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package hub
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"world/internal/messages"
+)
+
+// typingStates decodes the typing messages c received
+func typingStates(t *testing.T, c *Client) []messages.TypingPayload {
+	t.Helper()
+	var states []messages.TypingPayload
+	for _, m := range messagesOfType(drainMessages(t, c), messages.TypeTyping) {
+		var payload messages.TypingPayload
+		if err := json.Unmarshal(m.Payload, &payload); err != nil {
+			t.Fatal(err)
+		}
+		states = append(states, payload)
+	}
+	return states
+}
+
+func TestTypingForwardsOnlyStateChanges(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	near := addTestClient(h, space, "near", 150, 100)
+	far := addTestClient(h, space, "far", 900, 700)
+	h.recomputeProximity(space, a)
+	drainMessages(t, near)
+
+	for i := 0; i < 3; i++ {
+		h.ProcessMessage(a, []byte(`{"type":"typing","payload":{"isTyping":true,"userId":"spoofed"}}`))
+	}
+	got := typingStates(t, far)
+	if len(got) != 1 || !got[0].IsTyping || got[0].UserID != "a" || got[0].Scope != messages.ChatScopeSpace {
+		t.Fatalf("far got %+v, want a single start from a", got)
+	}
+	if n := len(typingStates(t, a)); n != 0 {
+		t.Errorf("sender got %d of its own typing messages", n)
+	}
+
+	h.handleTyping(a, messages.IncomingPayload{IsTyping: false})
+	h.handleTyping(a, messages.IncomingPayload{IsTyping: false})
+	if got := typingStates(t, far); len(got) != 1 || got[0].IsTyping {
+		t.Errorf("far got %+v, want a single stop", got)
+	}
+	drainMessages(t, near)
+
+	// Local typing only reaches users in audio range
+	h.handleTyping(a, messages.IncomingPayload{IsTyping: true, Scope: messages.ChatScopeLocal})
+	if got := typingStates(t, near); len(got) != 1 || !got[0].IsTyping || got[0].Scope != messages.ChatScopeLocal {
+		t.Errorf("near got %+v, want a single local start", got)
+	}
+	if n := len(typingStates(t, far)); n != 0 {
+		t.Errorf("far got %d local typing messages", n)
+	}
+}
+
+func TestTypingAutoClears(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	b := addTestClient(h, space, "b", 150, 100)
+
+	start := time.Now()
+	h.handleTyping(a, messages.IncomingPayload{IsTyping: true})
+	drainMessages(t, b)
+
+	h.clearStaleTyping(start.Add(typingTimeout / 2))
+	if n := len(typingStates(t, b)); n != 0 {
+		t.Fatalf("typing cleared after %v, want it kept", typingTimeout/2)
+	}
+
+	h.clearStaleTyping(start.Add(typingTimeout + time.Second))
+	got := typingStates(t, b)
+	if len(got) != 1 || got[0].IsTyping || got[0].UserID != "a" {
+		t.Fatalf("b got %+v, want a single stop for a", got)
+	}
+
+	// Cleared once; a late stop from the client is a no-op
+	h.clearStaleTyping(start.Add(2 * typingTimeout))
+	h.handleTyping(a, messages.IncomingPayload{IsTyping: false})
+	if n := len(typingStates(t, b)); n != 0 {
+		t.Errorf("got %d more typing messages after the auto-clear", n)
+	}
+}
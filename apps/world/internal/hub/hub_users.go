@@ -0,0 +1,58 @@
+package hub
+
+import (
+	"sort"
+
+	"world/internal/messages"
+)
+
+// Roster lists the users in the space that viewerID can see, viewerID included, sorted
+// by user ID. It's read in one pass under the space lock, so it's a consistent snapshot.
+func (s *Space) Roster(viewerID string) []messages.UserInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]messages.UserInfo, 0, len(s.Users))
+	for _, u := range s.Users {
+		if u.UserID != viewerID && u.HidesFrom(viewerID) {
+			continue
+		}
+		x, y := u.GetPosition()
+		users = append(users, messages.UserInfo{
+			UserID:     u.UserID,
+			X:          x,
+			Y:          y,
+			Name:       u.Name,
+			AvatarName: u.AvatarName,
+			Anim:       u.GetAnim(),
+			Idle:       u.isIdle(),
+		})
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].UserID < users[j].UserID })
+	return users
+}
+
+// handleGetUsers sends the client its space's current roster, so a client that missed
+// space-joined or user-join/user-left updates can resync. With interest management it
+// lists the users in view, as space-joined does.
+func (h *Hub) handleGetUsers(client *Client) {
+	if client.SpaceID == "" {
+		return
+	}
+
+	h.mu.RLock()
+	space, exists := h.Spaces[client.SpaceID]
+	h.mu.RUnlock()
+	if !exists {
+		return
+	}
+
+	users := space.Roster(client.UserID)
+	if interestEnabled() {
+		users = userInfosIn(users, append(space.InViewUsers(client.UserID), client))
+	}
+	client.SendJSON(messages.BaseMessage{
+		Type:    messages.TypeUserList,
+		Payload: messages.UserListPayload{Users: users},
+	})
+}
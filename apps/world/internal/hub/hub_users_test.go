@@ -0,0 +1,65 @@
+package hub
+
+import (
+	"encoding/json"
+	"testing"
+
+	"world/internal/messages"
+)
+
+// requestUsers sends get-users as client and returns the roster it gets back
+func requestUsers(t *testing.T, h *Hub, client *Client) []messages.UserInfo {
+	t.Helper()
+	drainMessages(t, client)
+	h.ProcessMessage(client, []byte(`{"type":"get-users"}`))
+	lists := messagesOfType(drainMessages(t, client), messages.TypeUserList)
+	if len(lists) != 1 {
+		t.Fatalf("got %d user-list replies, want 1", len(lists))
+	}
+	var payload messages.UserListPayload
+	if err := json.Unmarshal(lists[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	return payload.Users
+}
+
+func TestGetUsersReturnsFullRoster(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	a := joinTestClient(t, h, "s1", "a")
+	b := joinTestClient(t, h, "s1", "b")
+	bx, by := b.GetPosition()
+	h.handleMovement(b, messages.IncomingPayload{X: bx + 10, Y: by, Anim: "ginny_run_right"})
+	c := joinTestClient(t, h, "s1", "c")
+
+	users := requestUsers(t, h, c)
+	if len(users) != 3 {
+		t.Fatalf("roster = %+v, want a, b and c", users)
+	}
+	for i, want := range []*Client{a, b, c} {
+		x, y := want.GetPosition()
+		if got := users[i]; got.UserID != want.UserID || got.X != x || got.Y != y {
+			t.Errorf("users[%d] = %+v, want %s at (%v, %v)", i, got, want.UserID, x, y)
+		}
+	}
+	if users[1].Anim != "ginny_run_right" {
+		t.Errorf("b's anim = %q, want ginny_run_right", users[1].Anim)
+	}
+}
+
+func TestGetUsersRespectsHiding(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	joinTestClient(t, h, "s1", "a")
+	hider := joinTestClient(t, h, "s1", "hider")
+	hider.SetHiddenFrom("a", true)
+	viewer := joinTestClient(t, h, "s1", "viewer")
+	a := h.Spaces["s1"].Users["a"]
+
+	if users := requestUsers(t, h, a); len(users) != 2 || users[0].UserID != "a" || users[1].UserID != "viewer" {
+		t.Errorf("a's roster = %+v, want a and viewer", users)
+	}
+	if users := requestUsers(t, h, viewer); len(users) != 3 {
+		t.Errorf("viewer's roster = %+v, want all three", users)
+	}
+}
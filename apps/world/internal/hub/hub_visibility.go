@@ -0,0 +1,47 @@
+package hub
+
+import (
+	"world/internal/messages"
+)
+
+// handleVisibility hides the client from (or reveals them to) a specific user.
+// Visibility is one-way: the target stays visible to the client.
+func (h *Hub) handleVisibility(client *Client, payload messages.IncomingPayload, hide bool) {
+	if client.SpaceID == "" || payload.TargetUserID == "" || payload.TargetUserID == client.UserID {
+		return
+	}
+
+	h.mu.RLock()
+	space, exists := h.Spaces[client.SpaceID]
+	h.mu.RUnlock()
+	if !exists {
+		return
+	}
+
+	if client.HidesFrom(payload.TargetUserID) == hide {
+		return
+	}
+	client.SetHiddenFrom(payload.TargetUserID, hide)
+
+	space.mu.RLock()
+	target, ok := space.Users[payload.TargetUserID]
+	space.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	// Make the target's client add or remove our avatar
+	if hide {
+		target.SendJSON(messages.BaseMessage{
+			Type:    messages.TypeUserLeft,
+			Payload: messages.UserLeftPayload{UserID: client.UserID},
+		})
+		return
+	}
+
+	// Out of the target's view there's no avatar to reveal yet; it spawns on entering view
+	if interestEnabled() && !space.InViewOf(client.UserID, target.UserID) {
+		return
+	}
+	target.SendJSON(userJoinMessage(client))
+}
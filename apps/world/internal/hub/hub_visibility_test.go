@@ -0,0 +1,71 @@
+package hub
+
+import (
+	"encoding/json"
+	"testing"
+
+	"world/internal/messages"
+)
+
+func TestHiddenUserExcludedFromBroadcasts(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	b := addTestClient(h, space, "b", 600, 600)
+	c := addTestClient(h, space, "c", 900, 600)
+
+	h.handleVisibility(a, messages.IncomingPayload{TargetUserID: "b"}, true)
+	if left := messagesOfType(drainMessages(t, b), messages.TypeUserLeft); len(left) != 1 {
+		t.Fatalf("b got %d user-left messages on hide, want 1", len(left))
+	}
+
+	h.handleMovement(a, messages.IncomingPayload{X: 105, Y: 100})
+	if moves := messagesOfType(drainMessages(t, b), messages.TypeMovement); len(moves) != 0 {
+		t.Errorf("b received %d movements from a hidden user", len(moves))
+	}
+	if moves := messagesOfType(drainMessages(t, c), messages.TypeMovement); len(moves) != 1 {
+		t.Errorf("c got %d movements, want 1", len(moves))
+	}
+
+	// Hiding is one-way: a still sees b
+	h.handleMovement(b, messages.IncomingPayload{X: 605, Y: 600})
+	if moves := messagesOfType(drainMessages(t, a), messages.TypeMovement); len(moves) != 1 {
+		t.Errorf("a got %d movements from b, want 1", len(moves))
+	}
+
+	h.handleVisibility(a, messages.IncomingPayload{TargetUserID: "b"}, false)
+	if joins := messagesOfType(drainMessages(t, b), messages.TypeUserJoin); len(joins) != 1 {
+		t.Errorf("b got %d user-join messages on unhide, want 1", len(joins))
+	}
+}
+
+func TestHiddenUserExcludedFromJoinSnapshot(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	addTestClient(h, space, "c", 900, 600)
+
+	h.handleVisibility(a, messages.IncomingPayload{TargetUserID: "b"}, true)
+
+	b := joinTestClient(t, h, "s1", "b")
+	joined := messagesOfType(drainMessages(t, b), messages.TypeSpaceJoined)
+	if len(joined) != 1 {
+		t.Fatalf("got %d space-joined messages, want 1", len(joined))
+	}
+	var payload messages.SpaceJoinedPayload
+	if err := json.Unmarshal(joined[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if len(payload.Users) != 1 || payload.Users[0].UserID != "c" {
+		t.Errorf("snapshot users = %+v, want only c", payload.Users)
+	}
+
+	// a still learns that b joined
+	if joins := messagesOfType(drainMessages(t, a), messages.TypeUserJoin); len(joins) != 1 {
+		t.Errorf("a got %d user-join messages, want 1", len(joins))
+	}
+}
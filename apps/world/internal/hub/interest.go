@@ -0,0 +1,180 @@
+package hub
+
+import (
+	"world/internal/config"
+	"world/internal/messages"
+)
+
+// Area of interest: with VIEW_RADIUS set, a user only has the avatars of users within
+// that radius of them. Space.InView records which pairs have each other's avatars; a
+// pair entering view gets a synthetic user-join each way and one leaving it a user-left,
+// and movement is only sent within the pair set. Real leaves and user counts still go
+// to the whole space.
+
+// interestEnabled reports whether movement is limited to the view radius
+func interestEnabled() bool {
+	return config.Current().ViewRadius > 0
+}
+
+// UpdateInterest recomputes which users are within the view radius of client, using
+// the leave margin so pairs at the edge don't flap. Returns the users that came into
+// view and those that went out of it.
+func (s *Space) UpdateInterest(client *Client) (entered, left []*Client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	radius := config.Current().ViewRadius
+	x, y := client.GetPosition()
+	current := s.InView[client.UserID]
+
+	candidates := s.Users
+	if s.grid != nil {
+		candidates = s.grid.near(x, y, leaveRadius(radius))
+	}
+	inView := make(map[string]bool)
+	for id, other := range candidates {
+		if id == client.UserID {
+			continue
+		}
+		ox, oy := other.GetPosition()
+		d := distance(x, y, ox, oy)
+		if d <= radius || (current[id] && d <= leaveRadius(radius)) {
+			inView[id] = true
+			if !current[id] {
+				entered = append(entered, other)
+			}
+		}
+	}
+	for id := range current {
+		if !inView[id] {
+			if other, ok := s.Users[id]; ok {
+				left = append(left, other)
+			}
+		}
+	}
+
+	s.removeInterestLocked(client.UserID)
+	for id := range inView {
+		s.setInViewLocked(client.UserID, id)
+	}
+	return entered, left
+}
+
+// setInViewLocked records that a and b have each other's avatars
+func (s *Space) setInViewLocked(a, b string) {
+	for _, pair := range [][2]string{{a, b}, {b, a}} {
+		set, ok := s.InView[pair[0]]
+		if !ok {
+			set = make(map[string]bool)
+			s.InView[pair[0]] = set
+		}
+		set[pair[1]] = true
+	}
+}
+
+// removeInterestLocked forgets every pair userID is part of
+func (s *Space) removeInterestLocked(userID string) {
+	for id := range s.InView[userID] {
+		delete(s.InView[id], userID)
+		if len(s.InView[id]) == 0 {
+			delete(s.InView, id)
+		}
+	}
+	delete(s.InView, userID)
+}
+
+// InViewOf reports whether a and b are within each other's view
+func (s *Space) InViewOf(a, b string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.InView[a][b]
+}
+
+// InViewUsers returns the users userID has in view
+func (s *Space) InViewUsers(userID string) []*Client {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]*Client, 0, len(s.InView[userID]))
+	for id := range s.InView[userID] {
+		if client, ok := s.Users[id]; ok {
+			users = append(users, client)
+		}
+	}
+	return users
+}
+
+// ViewersOf returns the users with userID in view that userID isn't hidden from
+func (s *Space) ViewersOf(userID string) []*Client {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	subject := s.Users[userID]
+	viewers := make([]*Client, 0, len(s.InView[userID]))
+	for id := range s.InView[userID] {
+		client, ok := s.Users[id]
+		if !ok || (subject != nil && subject.HidesFrom(id)) {
+			continue
+		}
+		viewers = append(viewers, client)
+	}
+	return viewers
+}
+
+// userJoinMessage is the user-join (spawn) for client at its current position
+func userJoinMessage(client *Client) messages.BaseMessage {
+	x, y := client.GetPosition()
+	return messages.BaseMessage{
+		Type: messages.TypeUserJoin,
+		Payload: messages.UserJoinPayload{
+			UserID:     client.UserID,
+			X:          x,
+			Y:          y,
+			Name:       client.Name,
+			AvatarName: client.AvatarName,
+			Anim:       client.GetAnim(),
+		},
+	}
+}
+
+// sendInterestChanges spawns and despawns avatars for the pairs client's move brought
+// into or out of view. Hidden users are neither spawned nor despawned.
+func (h *Hub) sendInterestChanges(client *Client, entered, left []*Client) {
+	for _, other := range entered {
+		if !client.HidesFrom(other.UserID) {
+			other.SendJSON(userJoinMessage(client))
+		}
+		if !other.HidesFrom(client.UserID) {
+			client.SendJSON(userJoinMessage(other))
+		}
+	}
+	for _, other := range left {
+		if !client.HidesFrom(other.UserID) {
+			other.SendJSON(messages.BaseMessage{
+				Type:    messages.TypeUserLeft,
+				Payload: messages.UserLeftPayload{UserID: client.UserID},
+			})
+		}
+		if !other.HidesFrom(client.UserID) {
+			client.SendJSON(messages.BaseMessage{
+				Type:    messages.TypeUserLeft,
+				Payload: messages.UserLeftPayload{UserID: other.UserID},
+			})
+		}
+	}
+}
+
+// broadcastMovement sends client's movement to the rest of the space, or with an area of
+// interest only to the users in view, after spawning and despawning for the new position
+func (h *Hub) broadcastMovement(space *Space, client *Client, msg messages.BaseMessage) {
+	if !interestEnabled() {
+		h.broadcastToSpace(space.ID, msg, client.UserID)
+		return
+	}
+
+	entered, left := space.UpdateInterest(client)
+	h.sendInterestChanges(client, entered, left)
+	for _, viewer := range space.ViewersOf(client.UserID) {
+		viewer.SendJSON(msg)
+	}
+}
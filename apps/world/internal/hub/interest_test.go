@@ -0,0 +1,156 @@
+package hub
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"world/internal/config"
+	"world/internal/messages"
+)
+
+// setupInterestTest places mover at (100, 100), near at (400, 100) and far at (1100, 100)
+// with a 500 view radius, each having the users in view
+func setupInterestTest(t *testing.T) (*Hub, *Space, *Client, *Client, *Client) {
+	t.Helper()
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.ViewRadius = 500 })
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	mover := addTestClient(h, space, "mover", 100, 100)
+	near := addTestClient(h, space, "near", 400, 100)
+	far := addTestClient(h, space, "far", 1100, 100)
+	for _, c := range []*Client{mover, near, far} {
+		space.UpdateInterest(c)
+	}
+	return h, space, mover, near, far
+}
+
+// userIDsOf returns the sorted userIds of msgs
+func userIDsOf(t *testing.T, msgs []testMessage) []string {
+	t.Helper()
+	ids := make([]string, 0, len(msgs))
+	for _, m := range msgs {
+		var payload struct {
+			UserID string `json:"userId"`
+		}
+		if err := json.Unmarshal(m.Payload, &payload); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, payload.UserID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestMovementOnlyReachesUsersInView(t *testing.T) {
+	h, _, mover, near, far := setupInterestTest(t)
+
+	h.handleMovement(mover, messages.IncomingPayload{X: 110, Y: 100})
+
+	if n := len(messagesOfType(drainMessages(t, near), messages.TypeMovement)); n != 1 {
+		t.Errorf("near observer got %d movements, want 1", n)
+	}
+	if msgs := drainMessages(t, far); len(msgs) != 0 {
+		t.Errorf("far observer got %+v, want nothing", msgs)
+	}
+
+	// Without a view radius everyone gets it, as before
+	updateTestConfig(func(cfg *config.Config) { cfg.ViewRadius = 0 })
+	h.handleMovement(mover, messages.IncomingPayload{X: 120, Y: 100})
+	if n := len(messagesOfType(drainMessages(t, far), messages.TypeMovement)); n != 1 {
+		t.Errorf("far observer without a view radius got %d movements, want 1", n)
+	}
+}
+
+func TestCrossingViewRadiusSpawnsAndDespawns(t *testing.T) {
+	h, space, mover, near, far := setupInterestTest(t)
+	space.Portals = []Portal{
+		{MapRect: MapRect{X: 100, Y: 100, Width: 1, Height: 1}, To: MapPoint{X: 1000, Y: 100}},
+		{MapRect: MapRect{X: 1000, Y: 100, Width: 1, Height: 1}, To: MapPoint{X: 550, Y: 100}},
+	}
+
+	// Jump next to far: far comes into view, near goes out of it
+	h.handleTeleport(mover, messages.IncomingPayload{X: 1000, Y: 100})
+
+	moverMsgs := drainMessages(t, mover)
+	if got := userIDsOf(t, messagesOfType(moverMsgs, messages.TypeUserJoin)); len(got) != 1 || got[0] != "far" {
+		t.Errorf("mover spawned %v, want [far]", got)
+	}
+	if got := userIDsOf(t, messagesOfType(moverMsgs, messages.TypeUserLeft)); len(got) != 1 || got[0] != "near" {
+		t.Errorf("mover despawned %v, want [near]", got)
+	}
+
+	farMsgs := drainMessages(t, far)
+	if got := userIDsOf(t, messagesOfType(farMsgs, messages.TypeUserJoin)); len(got) != 1 || got[0] != "mover" {
+		t.Errorf("far spawned %v, want [mover]", got)
+	}
+	if n := len(messagesOfType(farMsgs, messages.TypeMovement)); n != 1 {
+		t.Errorf("far got %d movements, want 1", n)
+	}
+
+	nearMsgs := drainMessages(t, near)
+	if got := userIDsOf(t, messagesOfType(nearMsgs, messages.TypeUserLeft)); len(got) != 1 || got[0] != "mover" {
+		t.Errorf("near despawned %v, want [mover]", got)
+	}
+	if n := len(messagesOfType(nearMsgs, messages.TypeMovement)); n != 0 {
+		t.Errorf("near got %d movements after the mover left view", n)
+	}
+
+	if space.InViewOf("mover", "near") || !space.InViewOf("mover", "far") || !space.InViewOf("far", "mover") {
+		t.Error("InView pairs not updated")
+	}
+
+	// Just past the radius but inside the leave margin keeps the pair
+	updateTestConfig(func(cfg *config.Config) { cfg.ProximityLeaveMargin = 0.15 })
+	h.handleTeleport(mover, messages.IncomingPayload{X: 550, Y: 100})
+	if n := len(messagesOfType(drainMessages(t, far), messages.TypeUserLeft)); n != 0 {
+		t.Error("pair inside the leave margin was despawned")
+	}
+}
+
+func TestJoinOnlyShowsUsersInView(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.ViewRadius = 500 })
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	// Joins spawn near (705, 500)
+	near := addTestClient(h, space, "near", 705, 300)
+	far := addTestClient(h, space, "far", 50, 50)
+
+	joiner := joinTestClient(t, h, "s1", "joiner")
+
+	joined := messagesOfType(drainMessages(t, joiner), messages.TypeSpaceJoined)
+	if len(joined) != 1 {
+		t.Fatalf("got %d space-joined messages", len(joined))
+	}
+	var payload messages.SpaceJoinedPayload
+	if err := json.Unmarshal(joined[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if len(payload.Users) != 1 || payload.Users[0].UserID != "near" {
+		t.Errorf("space-joined users = %+v, want just near", payload.Users)
+	}
+
+	nearMsgs := drainMessages(t, near)
+	farMsgs := drainMessages(t, far)
+	if n := len(messagesOfType(nearMsgs, messages.TypeUserJoin)); n != 1 {
+		t.Errorf("near got %d user-joins, want 1", n)
+	}
+	if n := len(messagesOfType(farMsgs, messages.TypeUserJoin)); n != 0 {
+		t.Errorf("far got %d user-joins, want none", n)
+	}
+	// Space-wide system events still reach everyone
+	if len(messagesOfType(farMsgs, messages.TypeUserCount)) == 0 {
+		t.Error("far did not get the user count")
+	}
+
+	h.handleDisconnect(joiner)
+	if n := len(messagesOfType(drainMessages(t, far), messages.TypeUserLeft)); n != 1 {
+		t.Errorf("far got %d user-lefts for a real leave, want 1", n)
+	}
+	if space.InViewOf("near", "joiner") {
+		t.Error("leaver still in view")
+	}
+}
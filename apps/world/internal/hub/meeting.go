@@ -0,0 +1,438 @@
+package hub
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"world/internal/config"
+	"world/internal/messages"
+)
+
+// meetingKey is the MeetingStates key for a set of participants: their sorted IDs joined
+// by ":". For two users it's the same as dwellKey.
+func meetingKey(userIDs []string) string {
+	sorted := append([]string(nil), userIDs...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ":")
+}
+
+// participants returns everyone in the meeting. States built before group meetings
+// existed only set UserA and UserB.
+func (m *MeetingState) participants() []string {
+	if len(m.Participants) > 0 {
+		return m.Participants
+	}
+	return []string{m.UserA, m.UserB}
+}
+
+// hasParticipant reports whether userID is part of the meeting
+func (m *MeetingState) hasParticipant(userID string) bool {
+	for _, id := range m.participants() {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// peersOf returns the participants other than userID
+func (m *MeetingState) peersOf(userID string) []string {
+	peers := make([]string, 0, len(m.participants()))
+	for _, id := range m.participants() {
+		if id != userID {
+			peers = append(peers, id)
+		}
+	}
+	return peers
+}
+
+// isGroup reports whether the meeting has more than two participants
+func (m *MeetingState) isGroup() bool {
+	return len(m.participants()) > 2
+}
+
+// allAccepted reports whether every participant has accepted the prompt
+func (m *MeetingState) allAccepted() bool {
+	for _, id := range m.participants() {
+		if !m.Accepted[id] {
+			return false
+		}
+	}
+	return true
+}
+
+// isPending reports whether the meeting has a prompt awaiting responses
+func (m *MeetingState) isPending(now time.Time) bool {
+	return m.Status == MeetingStatusPrompted && m.RequestID != "" && m.ExpiresAt.After(now)
+}
+
+// meetingBetweenLocked finds the meeting state userID and peerID are both part of.
+// A pair's cooldown stub can sit under their pair key while they're both in a group
+// meeting, so the most current state wins: active, then prompted, then cooling down.
+func (s *Space) meetingBetweenLocked(userID, peerID string) (string, *MeetingState, bool) {
+	pairKey := dwellKey(userID, peerID)
+	if state, ok := s.MeetingStates[pairKey]; ok && state.Status == MeetingStatusActive {
+		return pairKey, state, true
+	}
+
+	var bestKey string
+	var best *MeetingState
+	for key, state := range s.MeetingStates {
+		if !state.hasParticipant(userID) || !state.hasParticipant(peerID) {
+			continue
+		}
+		if best == nil || meetingRank(state) > meetingRank(best) ||
+			(meetingRank(state) == meetingRank(best) && key == pairKey) {
+			bestKey, best = key, state
+		}
+	}
+	return bestKey, best, best != nil
+}
+
+// meetingRank orders meeting states by how current they are
+func meetingRank(m *MeetingState) int {
+	switch {
+	case m.Status == MeetingStatusActive:
+		return 2
+	case m.RequestID != "":
+		return 1
+	}
+	return 0
+}
+
+// meetingForResponseLocked finds the meeting a meeting-response refers to: the one
+// shared with peerID if set, otherwise the one userID was prompted for with requestID
+func (s *Space) meetingForResponseLocked(userID, peerID, requestID string) (string, *MeetingState, bool) {
+	if peerID != "" {
+		return s.meetingBetweenLocked(userID, peerID)
+	}
+	for key, state := range s.MeetingStates {
+		if state.RequestID == requestID && state.hasParticipant(userID) {
+			return key, state, true
+		}
+	}
+	return "", nil, false
+}
+
+// activeMeetingForLocked finds userID's active meeting
+func (s *Space) activeMeetingForLocked(userID string) (string, *MeetingState, bool) {
+	for key, state := range s.MeetingStates {
+		if state.Status == MeetingStatusActive && state.hasParticipant(userID) {
+			return key, state, true
+		}
+	}
+	return "", nil, false
+}
+
+// busyUsersLocked maps each user in an active meeting or with a pending prompt to
+// that meeting's key. Busy users aren't prompted again until it resolves.
+func (s *Space) busyUsersLocked(now time.Time) map[string]string {
+	busy := make(map[string]string)
+	for key, state := range s.MeetingStates {
+		if state.Status == MeetingStatusActive || state.isPending(now) {
+			for _, id := range state.participants() {
+				busy[id] = key
+			}
+		}
+	}
+	return busy
+}
+
+// coolingDownLocked reports whether the meeting state under key is in cooldown
+func (s *Space) coolingDownLocked(key string, now time.Time) bool {
+	state, ok := s.MeetingStates[key]
+	return ok && state.Status != MeetingStatusActive && now.Before(state.CooldownUntil)
+}
+
+// rekeyMeetingLocked stores state under the key for its current participants
+func (s *Space) rekeyMeetingLocked(oldKey string, state *MeetingState) string {
+	newKey := meetingKey(state.participants())
+	if newKey != oldKey {
+		delete(s.MeetingStates, oldKey)
+		s.MeetingStates[newKey] = state
+	}
+	return newKey
+}
+
+// meetingComponents groups the users joined by edges into connected components.
+// Each component and the list of components are sorted for deterministic prompts.
+func meetingComponents(edges map[string][]string) [][]string {
+	nodes := make([]string, 0, len(edges))
+	for id := range edges {
+		nodes = append(nodes, id)
+	}
+	sort.Strings(nodes)
+
+	seen := make(map[string]bool)
+	components := make([][]string, 0)
+	for _, start := range nodes {
+		if seen[start] {
+			continue
+		}
+		seen[start] = true
+		component := []string{}
+		queue := []string{start}
+		for len(queue) > 0 {
+			id := queue[0]
+			queue = queue[1:]
+			component = append(component, id)
+			for _, next := range edges[id] {
+				if !seen[next] {
+					seen[next] = true
+					queue = append(queue, next)
+				}
+			}
+		}
+		sort.Strings(component)
+		components = append(components, component)
+	}
+	return components
+}
+
+// promptMeetingLocked creates a prompted meeting for participants and sends each of them
+// a meeting-prompt. Pairs keep the original payload with a single peerId; groups get
+// peerIds instead.
+func (s *Space) promptMeetingLocked(key string, participants []string, now time.Time) *MeetingState {
+	requestID := fmt.Sprintf("%d-%s", now.UnixNano(), strings.Join(participants, "-"))
+	meetingID := fmt.Sprintf("%s-%d", strings.Join(participants, "-"), now.Unix())
+	state := &MeetingState{
+		MeetingID:    meetingID,
+		RequestID:    requestID,
+		UserA:        participants[0],
+		UserB:        participants[1],
+		Participants: participants,
+		ExpiresAt:    now.Add(MeetingTimeout),
+		Status:       MeetingStatusPrompted,
+	}
+	s.MeetingStates[key] = state
+	meetingFunnel.PromptsSent.Add(1)
+
+	log.Printf("Space %s: Sending meeting prompt to %s (reqID: %s)", s.ID, strings.Join(participants, ", "), requestID)
+
+	for _, id := range participants {
+		client, ok := s.Users[id]
+		if !ok {
+			continue
+		}
+		payload := map[string]interface{}{
+			"requestId": requestID,
+			"meetingId": meetingID,
+			"expiresAt": state.ExpiresAt.UnixMilli(),
+		}
+		if state.isGroup() {
+			payload["peerIds"] = state.peersOf(id)
+		} else {
+			payload["peerId"] = state.peersOf(id)[0]
+		}
+		client.SendJSON(map[string]interface{}{
+			"type":    messages.TypeMeetingPrompt,
+			"payload": payload,
+		})
+	}
+	return state
+}
+
+// meetingStartPayload is the meeting-start payload for recipientID
+func meetingStartPayload(state *MeetingState, recipientID string) interface{} {
+	if state.isGroup() {
+		return map[string]interface{}{
+			"peerIds":   state.peersOf(recipientID),
+			"meetingId": state.MeetingID,
+		}
+	}
+	return map[string]string{
+		"peerId":    state.peersOf(recipientID)[0],
+		"meetingId": state.MeetingID,
+	}
+}
+
+// joinMeetingLocked adds userID to the active meeting under key and tells every
+// participant, the newcomer included, with a meeting-join
+func (s *Space) joinMeetingLocked(key string, state *MeetingState, userID string) {
+	state.Participants = append(append([]string(nil), state.participants()...), userID)
+	sort.Strings(state.Participants)
+	s.rekeyMeetingLocked(key, state)
+
+	log.Printf("Space %s: %s joined meeting %s", s.ID, userID, state.MeetingID)
+	msg := messages.BaseMessage{
+		Type: messages.TypeMeetingJoin,
+		Payload: messages.MeetingJoinPayload{
+			MeetingID:    state.MeetingID,
+			UserID:       userID,
+			Participants: state.Participants,
+		},
+	}
+	for _, id := range state.Participants {
+		if client, ok := s.Users[id]; ok {
+			client.SendJSON(msg)
+		}
+	}
+}
+
+// leaveMeetingLocked takes userID out of the meeting under key, telling the other
+// participants with a meeting-end for reason. A group meeting carries on while at least
+// two remain; otherwise the meeting is over.
+func (s *Space) leaveMeetingLocked(key string, state *MeetingState, userID, reason string) {
+	for _, peerID := range state.peersOf(userID) {
+		if peer, ok := s.Users[peerID]; ok {
+			peer.SendJSON(messages.BaseMessage{
+				Type: messages.TypeMeetingEnd,
+				Payload: map[string]string{
+					"peerId":    userID,
+					"meetingId": state.MeetingID,
+					"reason":    reason,
+				},
+			})
+		}
+	}
+
+	if state.Status == MeetingStatusActive && state.isGroup() {
+		state.Participants = state.peersOf(userID)
+		state.UserA, state.UserB = state.Participants[0], state.Participants[1]
+		s.rekeyMeetingLocked(key, state)
+		return
+	}
+
+	if state.Status == MeetingStatusActive {
+		meetingFunnel.MeetingEnded(reason)
+		s.publishMeetingEnded(state, reason)
+	}
+	delete(s.MeetingStates, key)
+}
+
+// coolDownPairLocked keeps userID and peerID from being prompted or rejoined together
+// until the meeting cooldown has passed
+func (s *Space) coolDownPairLocked(userID, peerID string, now time.Time) {
+	key := dwellKey(userID, peerID)
+	participants := []string{userID, peerID}
+	sort.Strings(participants)
+	s.MeetingStates[key] = &MeetingState{
+		UserA:         participants[0],
+		UserB:         participants[1],
+		Participants:  participants,
+		Status:        MeetingStatusPrompted,
+		CooldownUntil: now.Add(MeetingCooldown + pairJitter(key)),
+	}
+}
+
+// CheckActiveMeetingProximity takes participants out of active meetings once they've
+// been out of video range (past the leave margin) of everyone else in it for
+// WalkAwayGrace. Stepping back in range within the grace keeps the call. A pair
+// meeting ends outright; a group carries on without the one who walked away.
+func (s *Space) CheckActiveMeetingProximity(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dwellPaused {
+		return
+	}
+
+	radius := leaveRadius(config.Current().VideoRadius)
+	// Leaving a group rekeys its state, so don't range over the map while changing it
+	keys := make([]string, 0, len(s.MeetingStates))
+	for key := range s.MeetingStates {
+		keys = append(keys, key)
+	}
+	for _, key := range keys {
+		state := s.MeetingStates[key]
+		if state.Status != MeetingStatusActive {
+			continue
+		}
+		for _, userID := range state.participants() {
+			if s.inRangeOfMeetingLocked(state, userID, radius) {
+				delete(state.awaySince, userID)
+				continue
+			}
+			since, ok := state.awaySince[userID]
+			if !ok {
+				if state.awaySince == nil {
+					state.awaySince = make(map[string]time.Time)
+				}
+				state.awaySince[userID] = now
+				continue
+			}
+			if now.Sub(since) < WalkAwayGrace {
+				continue
+			}
+
+			// The walker is told about each peer; leaveMeetingLocked tells the peers
+			log.Printf("Space %s: %s walked away from meeting %s", s.ID, userID, state.MeetingID)
+			if walker, ok := s.Users[userID]; ok {
+				for _, peerID := range state.peersOf(userID) {
+					walker.SendJSON(messages.BaseMessage{
+						Type: messages.TypeMeetingEnd,
+						Payload: map[string]string{
+							"peerId":    peerID,
+							"meetingId": state.MeetingID,
+							"reason":    MeetingEndWalkedAway,
+						},
+					})
+				}
+			}
+			delete(state.awaySince, userID)
+			s.leaveMeetingLocked(key, state, userID, MeetingEndWalkedAway)
+			// One departure per meeting per tick; the rest are re-measured next tick
+			break
+		}
+	}
+}
+
+// inRangeOfMeetingLocked reports whether userID is within radius of any other participant
+func (s *Space) inRangeOfMeetingLocked(state *MeetingState, userID string, radius float64) bool {
+	client, ok := s.Users[userID]
+	if !ok {
+		return false
+	}
+	x, y := client.GetPosition()
+	for _, peerID := range state.peersOf(userID) {
+		if peer, ok := s.Users[peerID]; ok {
+			px, py := peer.GetPosition()
+			if distance(x, y, px, py) <= radius {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// InviteToMeeting prompts userID and targetID for a meeting without waiting for a dwell,
+// going through the same meeting-prompt and accept flow. It does nothing and returns
+// false if either is busy, the pair is cooling down or out of prompts, or the target
+// isn't in the space (or is hidden from the inviter).
+func (s *Space) InviteToMeeting(userID, targetID string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dwellPaused {
+		return false
+	}
+	target, ok := s.Users[targetID]
+	if _, inviterOK := s.Users[userID]; !ok || !inviterOK || target.HidesFrom(userID) {
+		log.Printf("Space %s: invite from %s ignored: %s is not in the space", s.ID, userID, targetID)
+		return false
+	}
+
+	busy := s.busyUsersLocked(now)
+	key := dwellKey(userID, targetID)
+	switch {
+	case busy[userID] != "" || busy[targetID] != "":
+		log.Printf("Space %s: invite from %s to %s ignored: already in or prompted for a meeting", s.ID, userID, targetID)
+		return false
+	case s.coolingDownLocked(key, now):
+		log.Printf("Space %s: invite from %s to %s ignored: pair is cooling down", s.ID, userID, targetID)
+		return false
+	case !s.allowPromptLocked(key, now):
+		log.Printf("Space %s: invite from %s to %s ignored: prompt limit reached", s.ID, userID, targetID)
+		return false
+	}
+
+	participants := []string{userID, targetID}
+	sort.Strings(participants)
+	s.promptMeetingLocked(key, participants, now)
+	// A dwell already under way would only prompt them again
+	delete(s.VideoDwellStart, key)
+	return true
+}
@@ -0,0 +1,471 @@
+package hub
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"world/internal/messages"
+)
+
+// addTriangle places a, b and c within video range of each other with completed dwells
+func addTriangle(t *testing.T, h *Hub, space *Space) (a, b, c *Client) {
+	t.Helper()
+	a = addTestClient(h, space, "a", 100, 100)
+	b = addTestClient(h, space, "b", 150, 100)
+	c = addTestClient(h, space, "c", 125, 140)
+	for _, key := range []string{dwellKey("a", "b"), dwellKey("a", "c"), dwellKey("b", "c")} {
+		space.VideoDwellStart[key] = time.Now().Add(-time.Minute)
+	}
+	return a, b, c
+}
+
+// meetingPayload decodes the single message of type from c into a generic map
+func meetingPayload(t *testing.T, c *Client, typ string) map[string]interface{} {
+	t.Helper()
+	msgs := messagesOfType(drainMessages(t, c), typ)
+	if len(msgs) != 1 {
+		t.Fatalf("%s got %d %s messages, want 1", c.UserID, len(msgs), typ)
+	}
+	var p map[string]interface{}
+	if err := json.Unmarshal(msgs[0].Payload, &p); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func peerIDs(p map[string]interface{}) []string {
+	ids := make([]string, 0)
+	list, _ := p["peerIds"].([]interface{})
+	for _, id := range list {
+		ids = append(ids, id.(string))
+	}
+	return ids
+}
+
+func TestTriangleFormsOneGroupMeeting(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a, b, c := addTriangle(t, h, space)
+
+	space.CheckVideoDwellTimers()
+
+	if len(space.MeetingStates) != 1 {
+		t.Fatalf("got %d meeting states, want 1 group", len(space.MeetingStates))
+	}
+	state, ok := space.MeetingStates["a:b:c"]
+	if !ok || !reflect.DeepEqual(state.Participants, []string{"a", "b", "c"}) {
+		t.Fatalf("group state = %+v", state)
+	}
+	if len(space.VideoDwellStart) != 0 {
+		t.Errorf("dwell timers left after prompting: %v", space.VideoDwellStart)
+	}
+
+	wantPeers := map[*Client][]string{a: {"b", "c"}, b: {"a", "c"}, c: {"a", "b"}}
+	for client, want := range wantPeers {
+		p := meetingPayload(t, client, messages.TypeMeetingPrompt)
+		if got := peerIDs(p); !reflect.DeepEqual(got, want) || p["requestId"] != state.RequestID {
+			t.Errorf("%s prompt = %v, want peerIds %v", client.UserID, p, want)
+		}
+	}
+
+	// The meeting starts once everyone has accepted; responses don't need a peerId
+	h.handleMeetingResponse(a, messages.IncomingPayload{RequestID: state.RequestID, Accept: true})
+	h.handleMeetingResponse(b, messages.IncomingPayload{PeerID: "c", RequestID: state.RequestID, Accept: true})
+	if state.Status == MeetingStatusActive {
+		t.Fatal("meeting started before everyone accepted")
+	}
+	h.handleMeetingResponse(c, messages.IncomingPayload{RequestID: state.RequestID, Accept: true})
+	if state.Status != MeetingStatusActive {
+		t.Fatal("meeting did not start after everyone accepted")
+	}
+	for client, want := range wantPeers {
+		p := meetingPayload(t, client, messages.TypeMeetingStart)
+		if got := peerIDs(p); !reflect.DeepEqual(got, want) || p["meetingId"] != state.MeetingID {
+			t.Errorf("%s meeting-start = %v, want peerIds %v", client.UserID, p, want)
+		}
+	}
+
+	// Every pair in the group can signal
+	if _, ok := h.activeMeetingPeer("s1", "a", "c"); !ok {
+		t.Error("a and c should share the active meeting")
+	}
+}
+
+func TestGroupDeclineCoolsDownWholeGroup(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a, b, c := addTriangle(t, h, space)
+	space.CheckVideoDwellTimers()
+	state := space.MeetingStates["a:b:c"]
+
+	h.handleMeetingResponse(b, messages.IncomingPayload{RequestID: state.RequestID, Accept: false})
+	if state.CooldownUntil.IsZero() || state.RequestID != "" {
+		t.Fatalf("decline did not cool down the group: %+v", state)
+	}
+	for _, client := range []*Client{a, b, c} {
+		drainMessages(t, client)
+	}
+
+	// Staying together doesn't re-prompt during the cooldown
+	for _, key := range []string{dwellKey("a", "b"), dwellKey("a", "c"), dwellKey("b", "c")} {
+		space.VideoDwellStart[key] = time.Now().Add(-time.Minute)
+	}
+	space.CheckVideoDwellTimers()
+	if n := len(messagesOfType(drainMessages(t, a), messages.TypeMeetingPrompt)); n != 0 {
+		t.Errorf("got %d prompts during the group cooldown, want 0", n)
+	}
+}
+
+func TestWalkingIntoActiveMeetingJoinsIt(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	b := addTestClient(h, space, "b", 150, 100)
+	c := addTestClient(h, space, "c", 125, 140)
+	startTestMeeting(space, "a", "b")
+
+	space.VideoDwellStart[dwellKey("a", "c")] = time.Now().Add(-time.Minute)
+	space.CheckVideoDwellTimers()
+
+	for _, client := range []*Client{a, b, c} {
+		msgs := drainMessages(t, client)
+		if n := len(messagesOfType(msgs, messages.TypeMeetingPrompt)); n != 0 {
+			t.Errorf("%s got %d prompts, want a meeting-join instead", client.UserID, n)
+		}
+		joins := messagesOfType(msgs, messages.TypeMeetingJoin)
+		if len(joins) != 1 {
+			t.Fatalf("%s got %d meeting-joins, want 1", client.UserID, len(joins))
+		}
+		var p messages.MeetingJoinPayload
+		if err := json.Unmarshal(joins[0].Payload, &p); err != nil {
+			t.Fatal(err)
+		}
+		want := messages.MeetingJoinPayload{MeetingID: "a-b", UserID: "c", Participants: []string{"a", "b", "c"}}
+		if !reflect.DeepEqual(p, want) {
+			t.Errorf("%s meeting-join = %+v, want %+v", client.UserID, p, want)
+		}
+	}
+	if _, ok := space.MeetingStates["a:b:c"]; !ok || len(space.MeetingStates) != 1 {
+		t.Errorf("meeting states = %v, want the group under a:b:c", space.MeetingStates)
+	}
+}
+
+func TestLeavingGroupMeetingKeepsTheRest(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a, b, c := addTriangle(t, h, space)
+	space.CheckVideoDwellTimers()
+	state := space.MeetingStates["a:b:c"]
+	for _, client := range []*Client{a, b, c} {
+		h.handleMeetingResponse(client, messages.IncomingPayload{RequestID: state.RequestID, Accept: true})
+		drainMessages(t, client)
+	}
+	before := MeetingFunnelStats()
+
+	h.handleMeetingEnd(c, messages.IncomingPayload{PeerID: "a"})
+
+	for _, client := range []*Client{a, b} {
+		p := meetingPayload(t, client, messages.TypeMeetingEnd)
+		if p["peerId"] != "c" {
+			t.Errorf("%s meeting-end = %v, want peerId c", client.UserID, p)
+		}
+	}
+	pair, ok := space.MeetingStates[dwellKey("a", "b")]
+	if !ok || pair != state || state.Status != MeetingStatusActive || state.hasParticipant("c") {
+		t.Fatalf("a and b should carry on as a pair: %v", space.MeetingStates)
+	}
+	if d := MeetingFunnelStats().MeetingsEnded[MeetingEndUserEnded] - before.MeetingsEnded[MeetingEndUserEnded]; d != 0 {
+		t.Errorf("meetings ended +%d while two remained, want +0", d)
+	}
+
+	// Down to the last two, leaving ends the meeting
+	h.handleDisconnect(b)
+	if len(space.MeetingStates) != 0 {
+		t.Errorf("meeting states = %v, want none", space.MeetingStates)
+	}
+	if p := meetingPayload(t, a, messages.TypeMeetingEnd); p["peerId"] != "b" || p["reason"] != MeetingEndUserLeft {
+		t.Errorf("a meeting-end = %v", p)
+	}
+}
+
+func TestGroupMeetingWinsOverPairCooldown(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a, b, c := addTriangle(t, h, space)
+	space.CheckVideoDwellTimers()
+	state := space.MeetingStates["a:b:c"]
+	for _, client := range []*Client{a, b, c} {
+		h.handleMeetingResponse(client, messages.IncomingPayload{RequestID: state.RequestID, Accept: true})
+		drainMessages(t, client)
+	}
+	// Left over from an earlier meeting between a and b
+	space.coolDownPairLocked("a", "b", time.Now())
+
+	h.handleSignal(a, messages.IncomingPayload{TargetUserID: "b", SignalType: "offer", Data: json.RawMessage(`{"sdp":"v=0"}`)})
+	if n := len(messagesOfType(drainMessages(t, b), messages.TypeSignal)); n != 1 {
+		t.Errorf("b got %d signals from a in their group meeting, want 1", n)
+	}
+
+	h.handleMeetingEnd(a, messages.IncomingPayload{PeerID: "b"})
+	for _, client := range []*Client{b, c} {
+		if p := meetingPayload(t, client, messages.TypeMeetingEnd); p["peerId"] != "a" {
+			t.Errorf("%s meeting-end = %v, want peerId a", client.UserID, p)
+		}
+	}
+	if state.Status != MeetingStatusActive || state.hasParticipant("a") || !state.hasParticipant("c") {
+		t.Errorf("a should have left the group, leaving b and c: %+v", state)
+	}
+}
+
+func TestMeetingComponents(t *testing.T) {
+	edges := map[string][]string{
+		"a": {"b"}, "b": {"a", "c"}, "c": {"b"},
+		"x": {"y"}, "y": {"x"},
+	}
+	got := meetingComponents(edges)
+	want := [][]string{{"a", "b", "c"}, {"x", "y"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("components = %v, want %v", got, want)
+	}
+}
+
+func TestMeetingLeaveByEitherParticipant(t *testing.T) {
+	for _, leaver := range []string{"a", "b"} {
+		t.Run(leaver, func(t *testing.T) {
+			setupTestConfig(t)
+			h := NewHub()
+			space := newTestSpace(h, "s1")
+			clients := map[string]*Client{
+				"a": addTestClient(h, space, "a", 100, 100),
+				"b": addTestClient(h, space, "b", 150, 100),
+			}
+			startTestMeeting(space, "a", "b")
+			peer := clients["b"]
+			if leaver == "b" {
+				peer = clients["a"]
+			}
+			before := MeetingFunnelStats()
+
+			h.handleMeetingLeave(clients[leaver], messages.IncomingPayload{})
+
+			p := meetingPayload(t, peer, messages.TypeMeetingEnd)
+			if p["peerId"] != leaver || p["reason"] != MeetingEndUserLeftMeeting || p["meetingId"] != "a-b" {
+				t.Errorf("peer meeting-end = %v", p)
+			}
+			if n := len(drainMessages(t, clients[leaver])); n != 0 {
+				t.Errorf("leaver got %d messages, want 0", n)
+			}
+			if _, _, ok := space.activeMeetingForLocked("a"); ok {
+				t.Error("meeting is still active")
+			}
+			if d := MeetingFunnelStats().MeetingsEnded[MeetingEndUserLeftMeeting] - before.MeetingsEnded[MeetingEndUserLeftMeeting]; d != 1 {
+				t.Errorf("meetings ended (user_left_meeting) +%d, want +1", d)
+			}
+
+			// Staying close doesn't re-prompt during the cooldown
+			space.VideoDwellStart[dwellKey("a", "b")] = time.Now().Add(-time.Minute)
+			space.CheckVideoDwellTimers()
+			if n := len(messagesOfType(drainMessages(t, peer), messages.TypeMeetingPrompt)); n != 0 {
+				t.Errorf("got %d prompts during the cooldown, want 0", n)
+			}
+		})
+	}
+}
+
+func TestMeetingLeaveIgnoresNonParticipants(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	b := addTestClient(h, space, "b", 150, 100)
+	c := addTestClient(h, space, "c", 400, 400)
+	state := startTestMeeting(space, "a", "b")
+
+	h.handleMeetingLeave(c, messages.IncomingPayload{MeetingID: state.MeetingID})
+	// A participant naming some other meeting is ignored too
+	h.handleMeetingLeave(a, messages.IncomingPayload{MeetingID: "x-y"})
+
+	if state.Status != MeetingStatusActive || space.MeetingStates[dwellKey("a", "b")] != state {
+		t.Error("meeting changed after invalid leaves")
+	}
+	for _, client := range []*Client{a, b, c} {
+		if n := len(drainMessages(t, client)); n != 0 {
+			t.Errorf("%s got %d messages, want 0", client.UserID, n)
+		}
+	}
+}
+
+func TestMeetingLeaveFromGroupCoolsDownLeaver(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a, b, c := addTriangle(t, h, space)
+	space.CheckVideoDwellTimers()
+	state := space.MeetingStates["a:b:c"]
+	for _, client := range []*Client{a, b, c} {
+		h.handleMeetingResponse(client, messages.IncomingPayload{RequestID: state.RequestID, Accept: true})
+		drainMessages(t, client)
+	}
+
+	h.handleMeetingLeave(c, messages.IncomingPayload{MeetingID: state.MeetingID})
+	if state.Status != MeetingStatusActive || state.hasParticipant("c") {
+		t.Fatalf("a and b should carry on: %+v", state)
+	}
+
+	// c is still next to them but doesn't walk straight back in
+	space.VideoDwellStart[dwellKey("a", "c")] = time.Now().Add(-time.Minute)
+	space.CheckVideoDwellTimers()
+	if n := len(messagesOfType(drainMessages(t, c), messages.TypeMeetingJoin)); n != 0 {
+		t.Errorf("c rejoined during the cooldown (%d meeting-joins)", n)
+	}
+}
+
+func TestWalkingApartEndsMeetingAfterGrace(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	b := addTestClient(h, space, "b", 150, 100)
+	state := startTestMeeting(space, "a", "b")
+	before := MeetingFunnelStats()
+	now := time.Now()
+
+	// Stepping out briefly and back keeps the call
+	b.SetPosition(400, 100)
+	space.CheckActiveMeetingProximity(now)
+	space.CheckActiveMeetingProximity(now.Add(WalkAwayGrace / 2))
+	b.SetPosition(150, 100)
+	space.CheckActiveMeetingProximity(now.Add(WalkAwayGrace))
+	if state.Status != MeetingStatusActive || len(space.MeetingStates) != 1 {
+		t.Fatal("a brief step out of range ended the meeting")
+	}
+
+	// Staying apart past the grace ends it for both
+	b.SetPosition(900, 700)
+	later := now.Add(2 * WalkAwayGrace)
+	space.CheckActiveMeetingProximity(later)
+	space.CheckActiveMeetingProximity(later.Add(WalkAwayGrace - time.Millisecond))
+	if len(space.MeetingStates) != 1 {
+		t.Fatal("meeting ended before the grace passed")
+	}
+	space.CheckActiveMeetingProximity(later.Add(WalkAwayGrace))
+	if len(space.MeetingStates) != 0 {
+		t.Fatalf("meeting states = %v, want none", space.MeetingStates)
+	}
+	for client, peer := range map[*Client]string{a: "b", b: "a"} {
+		p := meetingPayload(t, client, messages.TypeMeetingEnd)
+		if p["peerId"] != peer || p["reason"] != MeetingEndWalkedAway {
+			t.Errorf("%s meeting-end = %v", client.UserID, p)
+		}
+	}
+	if d := MeetingFunnelStats().MeetingsEnded[MeetingEndWalkedAway] - before.MeetingsEnded[MeetingEndWalkedAway]; d != 1 {
+		t.Errorf("meetings ended (walked_away) +%d, want +1", d)
+	}
+}
+
+func TestWalkingAwayFromGroupKeepsTheRest(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a, b, c := addTriangle(t, h, space)
+	space.CheckVideoDwellTimers()
+	state := space.MeetingStates["a:b:c"]
+	for _, client := range []*Client{a, b, c} {
+		h.handleMeetingResponse(client, messages.IncomingPayload{RequestID: state.RequestID, Accept: true})
+		drainMessages(t, client)
+	}
+
+	now := time.Now()
+	c.SetPosition(900, 700)
+	space.CheckActiveMeetingProximity(now)
+	space.CheckActiveMeetingProximity(now.Add(WalkAwayGrace))
+
+	if state.Status != MeetingStatusActive || state.hasParticipant("c") || space.MeetingStates[dwellKey("a", "b")] != state {
+		t.Fatalf("a and b should carry on without c: %v", space.MeetingStates)
+	}
+	if got := len(messagesOfType(drainMessages(t, c), messages.TypeMeetingEnd)); got != 2 {
+		t.Errorf("c got %d meeting-ends, want one per peer", got)
+	}
+	for _, client := range []*Client{a, b} {
+		if p := meetingPayload(t, client, messages.TypeMeetingEnd); p["peerId"] != "c" || p["reason"] != MeetingEndWalkedAway {
+			t.Errorf("%s meeting-end = %v", client.UserID, p)
+		}
+	}
+}
+
+func TestMeetingInviteCreatesPrompt(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	// Far outside video range: no dwell could ever prompt them
+	a := addTestClient(h, space, "a", 100, 100)
+	b := addTestClient(h, space, "b", 900, 700)
+
+	h.ProcessMessage(a, []byte(`{"type":"meeting-invite","payload":{"targetUserId":"b"}}`))
+
+	promptA := meetingPayload(t, a, messages.TypeMeetingPrompt)
+	promptB := meetingPayload(t, b, messages.TypeMeetingPrompt)
+	if promptA["peerId"] != "b" || promptB["peerId"] != "a" || promptA["requestId"] != promptB["requestId"] {
+		t.Fatalf("unexpected prompts %v / %v", promptA, promptB)
+	}
+	state := space.MeetingStates[dwellKey("a", "b")]
+	if state == nil || state.Status != MeetingStatusPrompted {
+		t.Fatalf("invite did not create a prompted meeting: %+v", state)
+	}
+
+	// The normal accept flow starts it
+	requestID := promptA["requestId"].(string)
+	h.handleMeetingResponse(a, messages.IncomingPayload{PeerID: "b", RequestID: requestID, Accept: true})
+	h.handleMeetingResponse(b, messages.IncomingPayload{PeerID: "a", RequestID: requestID, Accept: true})
+	if state.Status != MeetingStatusActive {
+		t.Fatalf("meeting status = %q after both accepted, want active", state.Status)
+	}
+	if p := meetingPayload(t, b, messages.TypeMeetingStart); p["peerId"] != "a" {
+		t.Errorf("meeting-start payload = %v", p)
+	}
+
+	// Already meeting: a second invite is ignored
+	h.handleMeetingInvite(b, messages.IncomingPayload{TargetUserID: "a"})
+	if n := len(messagesOfType(drainMessages(t, a), messages.TypeMeetingPrompt)); n != 0 {
+		t.Errorf("got %d prompts while already meeting, want 0", n)
+	}
+}
+
+func TestMeetingInviteIgnoredDuringCooldown(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	b := addTestClient(h, space, "b", 150, 100)
+
+	h.handleMeetingInvite(a, messages.IncomingPayload{TargetUserID: "b"})
+	state := space.MeetingStates[dwellKey("a", "b")]
+	h.handleMeetingResponse(b, messages.IncomingPayload{PeerID: "a", RequestID: state.RequestID, Accept: false})
+	drainMessages(t, a)
+	drainMessages(t, b)
+
+	h.handleMeetingInvite(a, messages.IncomingPayload{TargetUserID: "b"})
+	if n := len(messagesOfType(drainMessages(t, b), messages.TypeMeetingPrompt)); n != 0 {
+		t.Errorf("got %d prompts during the cooldown, want 0", n)
+	}
+
+	// Nor can a user outside the space be invited
+	h.handleMeetingInvite(a, messages.IncomingPayload{TargetUserID: "ghost"})
+	if n := len(messagesOfType(drainMessages(t, a), messages.TypeMeetingPrompt)); n != 0 {
+		t.Errorf("got %d prompts for an absent target, want 0", n)
+	}
+
+	// Once the cooldown is over the invite goes through
+	state.CooldownUntil = time.Now().Add(-time.Second)
+	h.handleMeetingInvite(a, messages.IncomingPayload{TargetUserID: "b"})
+	if n := len(messagesOfType(drainMessages(t, b), messages.TypeMeetingPrompt)); n != 1 {
+		t.Errorf("got %d prompts after the cooldown, want 1", n)
+	}
+}
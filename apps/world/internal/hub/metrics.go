@@ -0,0 +1,91 @@
+package hub
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// Reasons a meeting ends, as sent in meeting-end and counted in the funnel
+const (
+	MeetingEndUserEnded = "user_ended"
+	MeetingEndUserLeft  = "user_left"
+	// MeetingEndUserLeftMeeting is a participant leaving with meeting-leave while staying in the space
+	MeetingEndUserLeftMeeting = "user_left_meeting"
+	// MeetingEndWalkedAway is a participant staying out of video range past WalkAwayGrace
+	MeetingEndWalkedAway = "walked_away"
+)
+
+// MeetingFunnel counts transitions through the meeting lifecycle:
+// dwell → prompt → accept/decline/expire → start → end
+type MeetingFunnel struct {
+	PromptsSent     atomic.Int64
+	PromptsAccepted atomic.Int64 // individual accept responses; both are needed to start
+	PromptsDeclined atomic.Int64
+	PromptsExpired  atomic.Int64
+	MeetingsStarted atomic.Int64
+
+	mu    sync.Mutex
+	ended map[string]int64 // reason -> count
+}
+
+// MeetingFunnelSnapshot is a point-in-time copy of the funnel counters
+type MeetingFunnelSnapshot struct {
+	PromptsSent     int64            `json:"promptsSent"`
+	PromptsAccepted int64            `json:"promptsAccepted"`
+	PromptsDeclined int64            `json:"promptsDeclined"`
+	PromptsExpired  int64            `json:"promptsExpired"`
+	MeetingsStarted int64            `json:"meetingsStarted"`
+	MeetingsEnded   map[string]int64 `json:"meetingsEnded"`
+}
+
+// spawnFallbacks counts joins where no free spawn was found, a sign of an overcrowded space
+var spawnFallbacks atomic.Int64
+
+// SpawnFallbacks returns how many joins fell back to a possibly-overlapping spawn
+func SpawnFallbacks() int64 {
+	return spawnFallbacks.Load()
+}
+
+// meetingFunnel is process-wide; spaces come and go but the funnel spans them all
+var meetingFunnel = &MeetingFunnel{ended: make(map[string]int64)}
+
+// MeetingEnded counts a meeting ending for reason
+func (f *MeetingFunnel) MeetingEnded(reason string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ended[reason]++
+}
+
+// Snapshot copies the current counters
+func (f *MeetingFunnel) Snapshot() MeetingFunnelSnapshot {
+	f.mu.Lock()
+	ended := make(map[string]int64, len(f.ended))
+	for reason, n := range f.ended {
+		ended[reason] = n
+	}
+	f.mu.Unlock()
+
+	return MeetingFunnelSnapshot{
+		PromptsSent:     f.PromptsSent.Load(),
+		PromptsAccepted: f.PromptsAccepted.Load(),
+		PromptsDeclined: f.PromptsDeclined.Load(),
+		PromptsExpired:  f.PromptsExpired.Load(),
+		MeetingsStarted: f.MeetingsStarted.Load(),
+		MeetingsEnded:   ended,
+	}
+}
+
+// MeetingFunnelStats returns the process-wide meeting funnel counters
+func MeetingFunnelStats() MeetingFunnelSnapshot {
+	return meetingFunnel.Snapshot()
+}
+
+// ServeMeetingMetrics reports the meeting funnel counters as JSON to a bearer allowed
+// PermissionAdminMetrics
+func ServeMeetingMetrics(w http.ResponseWriter, r *http.Request) {
+	if _, ok := authorize(w, r, PermissionAdminMetrics); !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, MeetingFunnelStats())
+}
@@ -0,0 +1,128 @@
+package hub
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"world/internal/messages"
+)
+
+// promptPair puts a and b next to each other and runs the dwell checker until they're prompted
+func promptPair(t *testing.T, h *Hub, space *Space) (a, b *Client, state *MeetingState) {
+	t.Helper()
+	a = addTestClient(h, space, "a", 100, 100)
+	b = addTestClient(h, space, "b", 150, 100)
+	key := dwellKey("a", "b")
+	space.VideoDwellStart[key] = time.Now().Add(-time.Minute)
+	space.CheckVideoDwellTimers()
+
+	state, ok := space.MeetingStates[key]
+	if !ok {
+		t.Fatal("pair was not prompted")
+	}
+	return a, b, state
+}
+
+func TestMeetingFunnelAcceptAndEnd(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	before := MeetingFunnelStats()
+
+	a, b, state := promptPair(t, h, space)
+	h.handleMeetingResponse(a, messages.IncomingPayload{PeerID: "b", RequestID: state.RequestID, Accept: true})
+	h.handleMeetingResponse(a, messages.IncomingPayload{PeerID: "b", RequestID: state.RequestID, Accept: true}) // repeat doesn't count
+	h.handleMeetingResponse(b, messages.IncomingPayload{PeerID: "a", RequestID: state.RequestID, Accept: true})
+	h.handleMeetingEnd(a, messages.IncomingPayload{PeerID: "b"})
+
+	after := MeetingFunnelStats()
+	if d := after.PromptsSent - before.PromptsSent; d != 1 {
+		t.Errorf("prompts sent +%d, want +1", d)
+	}
+	if d := after.PromptsAccepted - before.PromptsAccepted; d != 2 {
+		t.Errorf("prompts accepted +%d, want +2", d)
+	}
+	if d := after.MeetingsStarted - before.MeetingsStarted; d != 1 {
+		t.Errorf("meetings started +%d, want +1", d)
+	}
+	if d := after.MeetingsEnded[MeetingEndUserEnded] - before.MeetingsEnded[MeetingEndUserEnded]; d != 1 {
+		t.Errorf("meetings ended (user_ended) +%d, want +1", d)
+	}
+}
+
+func TestMeetingFunnelDeclineExpireAndLeave(t *testing.T) {
+	setupTestConfig(t)
+	before := MeetingFunnelStats()
+
+	// Declined
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a, _, state := promptPair(t, h, space)
+	h.handleMeetingResponse(a, messages.IncomingPayload{PeerID: "b", RequestID: state.RequestID, Accept: false})
+
+	// Expired
+	h = NewHub()
+	space = newTestSpace(h, "s1")
+	_, _, state = promptPair(t, h, space)
+	state.ExpiresAt = time.Now().Add(-time.Second)
+	space.CheckVideoDwellTimers()
+
+	// Active meeting ended by a user leaving; a pending prompt doesn't count as a meeting
+	h = NewHub()
+	space = newTestSpace(h, "s1")
+	a, _, _ = promptPair(t, h, space)
+	startTestMeeting(space, "a", "b")
+	h.handleDisconnect(a)
+
+	after := MeetingFunnelStats()
+	if d := after.PromptsSent - before.PromptsSent; d != 3 {
+		t.Errorf("prompts sent +%d, want +3", d)
+	}
+	if d := after.PromptsDeclined - before.PromptsDeclined; d != 1 {
+		t.Errorf("prompts declined +%d, want +1", d)
+	}
+	if d := after.PromptsExpired - before.PromptsExpired; d != 1 {
+		t.Errorf("prompts expired +%d, want +1", d)
+	}
+	if d := after.MeetingsEnded[MeetingEndUserLeft] - before.MeetingsEnded[MeetingEndUserLeft]; d != 1 {
+		t.Errorf("meetings ended (user_left) +%d, want +1", d)
+	}
+}
+
+// meetingMetricsRequest calls ServeMeetingMetrics with a token for role, or none if role is empty
+func meetingMetricsRequest(t *testing.T, role string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/metrics/meetings", nil)
+	if role != "" {
+		req.Header.Set("Authorization", "Bearer "+testToken(t, "ops", role))
+	}
+	rec := httptest.NewRecorder()
+	ServeMeetingMetrics(rec, req)
+	return rec
+}
+
+func TestServeMeetingMetrics(t *testing.T) {
+	setupTestConfig(t)
+
+	if rec := meetingMetricsRequest(t, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("no token status = %d, want 401", rec.Code)
+	}
+	if rec := meetingMetricsRequest(t, "user"); rec.Code != http.StatusForbidden {
+		t.Errorf("user token status = %d, want 403", rec.Code)
+	}
+
+	rec := meetingMetricsRequest(t, RoleAdmin)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var body MeetingFunnelSnapshot
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.MeetingsEnded == nil {
+		t.Error("meetingsEnded missing from response")
+	}
+}
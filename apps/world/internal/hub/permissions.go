@@ -0,0 +1,63 @@
+package hub
+
+import (
+	"world/internal/config"
+	"world/internal/messages"
+)
+
+// Roles carried in the JWT role claim
+const (
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+	RoleBuilder   = "builder"
+)
+
+// PermissionLowerOthersHand is an action rather than a message type: lowering
+// someone else's hand with a lower-hand message
+const PermissionLowerOthersHand = "lower-hand:others"
+
+// PermissionAdminPosition guards the admin HTTP API for reading and setting user positions
+const PermissionAdminPosition = "admin:position"
+
+// PermissionAdminMetrics guards the meeting funnel counters at /metrics/meetings
+const PermissionAdminMetrics = "admin:metrics"
+
+// defaultPermissions maps a message type (or action) to the roles allowed to use it.
+// Types that aren't listed are open to everyone. ROLE_PERMISSIONS overrides entries.
+var defaultPermissions = map[string][]string{
+	messages.TypePauseDwell:      {RoleAdmin},
+	messages.TypeCustomBroadcast: {RoleAdmin},
+	messages.TypeAdvanceHand:     {RoleAdmin},
+	messages.TypeClearHands:      {RoleAdmin},
+	messages.TypeKickUser:        {RoleAdmin},
+	messages.TypeAnnouncement:    {RoleAdmin},
+	messages.TypeBan:             {RoleAdmin},
+	messages.TypeUnban:           {RoleAdmin},
+	messages.TypePlaceObject:     {RoleAdmin, RoleBuilder},
+	messages.TypeRemoveObject:    {RoleAdmin, RoleBuilder},
+	PermissionLowerOthersHand:    {RoleAdmin},
+	PermissionAdminPosition:      {RoleAdmin},
+	PermissionAdminMetrics:       {RoleAdmin},
+}
+
+// allowedRoles returns the roles permitted to perform action; nil means unrestricted
+func allowedRoles(action string) []string {
+	if roles, ok := config.Current().RolePermissions[action]; ok {
+		return roles
+	}
+	return defaultPermissions[action]
+}
+
+// Can reports whether role may perform action (a message type or permission name)
+func Can(role, action string) bool {
+	roles := allowedRoles(action)
+	if roles == nil {
+		return true
+	}
+	for _, allowed := range roles {
+		if allowed == role || allowed == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package hub
+
+import (
+	"encoding/json"
+	"testing"
+
+	"world/internal/config"
+	"world/internal/messages"
+)
+
+func TestRestrictedMessageForbidden(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	user := addTestClient(h, space, "user", 100, 100)
+	user.Role = "user"
+
+	h.ProcessMessage(user, []byte(`{"type":"clear-hands"}`))
+
+	forbidden := messagesOfType(drainMessages(t, user), messages.TypeForbidden)
+	if len(forbidden) != 1 {
+		t.Fatalf("got %d forbidden messages, want 1", len(forbidden))
+	}
+	var p messages.ForbiddenPayload
+	if err := json.Unmarshal(forbidden[0].Payload, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Type != messages.TypeClearHands {
+		t.Errorf("forbidden type = %q, want %q", p.Type, messages.TypeClearHands)
+	}
+}
+
+func TestAllowedMessagesPass(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	admin := addTestClient(h, space, "admin", 100, 100)
+	admin.Role = RoleAdmin
+	user := addTestClient(h, space, "user", 200, 100)
+	user.Role = "user"
+
+	// Unrestricted types are open to everyone
+	h.ProcessMessage(user, []byte(`{"type":"raise-hand"}`))
+	if got := space.GetHandQueue(); len(got) != 1 {
+		t.Fatalf("raise-hand by a user: queue = %v", got)
+	}
+
+	// Restricted types work for the allowed role
+	h.ProcessMessage(admin, []byte(`{"type":"clear-hands"}`))
+	if got := space.GetHandQueue(); len(got) != 0 {
+		t.Errorf("clear-hands by an admin left queue = %v", got)
+	}
+	if n := len(messagesOfType(drainMessages(t, admin), messages.TypeForbidden)); n != 0 {
+		t.Errorf("admin got %d forbidden messages", n)
+	}
+}
+
+func TestRolePermissionOverrides(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) {
+		cfg.RolePermissions = map[string][]string{
+			messages.TypeClearHands:  {RoleAdmin, RoleModerator},
+			messages.TypeRaiseHand:   {RoleAdmin},
+			messages.TypeAdvanceHand: {"*"},
+		}
+	})
+
+	if !Can(RoleModerator, messages.TypeClearHands) {
+		t.Error("moderator should be allowed to clear hands")
+	}
+	if Can("user", messages.TypeRaiseHand) {
+		t.Error("override should restrict raise-hand to admins")
+	}
+	if !Can("user", messages.TypeAdvanceHand) {
+		t.Error("* should open advance-hand to everyone")
+	}
+	if Can("user", messages.TypePauseDwell) {
+		t.Error("types without an override keep their default")
+	}
+}
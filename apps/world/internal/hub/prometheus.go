@@ -0,0 +1,89 @@
+package hub
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Process-wide Prometheus counters. They carry no user or space labels so the
+// series count stays fixed no matter how many spaces come and go.
+var (
+	joinsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "world_joins_total",
+		Help: "Users that joined a space.",
+	})
+	leavesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "world_leaves_total",
+		Help: "Users that left a space.",
+	})
+	movementsRejectedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "world_movements_rejected_total",
+		Help: "Movement requests rejected as invalid or colliding.",
+	})
+	messagesDroppedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "world_messages_dropped_total",
+		Help: "Outgoing messages dropped because a client's send buffer was full.",
+	})
+	pongLatencySeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "world_pong_latency_seconds",
+		Help:    "Round trip from a WebSocket ping to its pong.",
+		Buckets: []float64{.01, .025, .05, .1, .25, .5, 1, 2.5},
+	})
+)
+
+// NewMetricsRegistry returns a registry with the process counters and gauges
+// that read h's live state on every scrape
+func (h *Hub) NewMetricsRegistry() *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		joinsTotal,
+		leavesTotal,
+		movementsRejectedTotal,
+		messagesDroppedTotal,
+		pongLatencySeconds,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "world_connected_clients",
+			Help: "Open WebSocket connections, in a space or not.",
+		}, func() float64 {
+			h.mu.RLock()
+			defer h.mu.RUnlock()
+			return float64(len(h.Clients))
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "world_users_in_spaces",
+			Help: "Users currently in a space.",
+		}, func() float64 {
+			users := 0
+			for _, stats := range h.SpaceStats(false) {
+				users += stats.UserCount
+			}
+			return float64(users)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "world_spaces",
+			Help: "Spaces with at least one user.",
+		}, func() float64 {
+			h.mu.RLock()
+			defer h.mu.RUnlock()
+			return float64(len(h.Spaces))
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "world_active_meetings",
+			Help: "Meetings currently in progress across all spaces.",
+		}, func() float64 {
+			meetings := 0
+			for _, stats := range h.SpaceStats(false) {
+				meetings += stats.ActiveMeetings
+			}
+			return float64(meetings)
+		}),
+	)
+	return reg
+}
+
+// MetricsHandler serves h's metrics in the Prometheus exposition format
+func (h *Hub) MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(h.NewMetricsRegistry(), promhttp.HandlerOpts{})
+}
@@ -0,0 +1,98 @@
+package hub
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"world/internal/messages"
+)
+
+// scrapeMetrics gathers h's registry into name -> value. Histograms report their sample count.
+func scrapeMetrics(t *testing.T, h *Hub) map[string]float64 {
+	t.Helper()
+	families, err := h.NewMetricsRegistry().Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+	values := make(map[string]float64)
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			switch {
+			case m.GetCounter() != nil:
+				values[family.GetName()] = m.GetCounter().GetValue()
+			case m.GetGauge() != nil:
+				values[family.GetName()] = m.GetGauge().GetValue()
+			case m.GetHistogram() != nil:
+				values[family.GetName()] = float64(m.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+	return values
+}
+
+func TestMetricsAfterJoins(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	// The counters are process-wide, so compare against what other tests left behind
+	before := scrapeMetrics(t, h)
+
+	a := joinTestClient(t, h, "lobby", "a")
+	joinTestClient(t, h, "lobby", "b")
+	joinTestClient(t, h, "arena", "c")
+	startTestMeeting(h.Spaces["lobby"], "a", "b")
+
+	// Straight into the map edge: out of bounds, so rejected
+	drainMessages(t, a)
+	h.handleMovement(a, messages.IncomingPayload{X: -1000, Y: -1000})
+
+	after := scrapeMetrics(t, h)
+	if got := after["world_joins_total"] - before["world_joins_total"]; got != 3 {
+		t.Errorf("joins delta = %v, want 3", got)
+	}
+	if got := after["world_movements_rejected_total"] - before["world_movements_rejected_total"]; got != 1 {
+		t.Errorf("rejected moves delta = %v, want 1", got)
+	}
+	gauges := map[string]float64{
+		"world_connected_clients": 3,
+		"world_users_in_spaces":   3,
+		"world_spaces":            2,
+		"world_active_meetings":   1,
+	}
+	for name, want := range gauges {
+		if after[name] != want {
+			t.Errorf("%s = %v, want %v", name, after[name], want)
+		}
+	}
+
+	h.handleDisconnect(a)
+	final := scrapeMetrics(t, h)
+	if got := final["world_leaves_total"] - after["world_leaves_total"]; got != 1 {
+		t.Errorf("leaves delta = %v, want 1", got)
+	}
+	if final["world_users_in_spaces"] != 2 {
+		t.Errorf("users in spaces after leave = %v, want 2", final["world_users_in_spaces"])
+	}
+}
+
+func TestMetricsHandlerServesExpositionFormat(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	joinTestClient(t, h, "lobby", "a")
+
+	rec := httptest.NewRecorder()
+	h.MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"world_joins_total", "world_spaces 1", "world_connected_clients 1"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output missing %q", want)
+		}
+	}
+	if strings.Contains(body, `userId`) || strings.Contains(body, `spaceId`) {
+		t.Error("metrics must not carry user or space labels")
+	}
+}
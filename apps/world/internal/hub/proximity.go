@@ -3,20 +3,59 @@ package hub
 import (
 	"log"
 	"math"
+	"strings"
 	"time"
+
+	"world/internal/config"
 )
 
 const (
-	ProximityEnter = "enter"
-	ProximityLeave = "leave"
+	ProximityEnter  = "enter"
+	ProximityLeave  = "leave"
+	ProximityVolume = "volume" // audio volume changed for a pair already in range
 )
 
+// leaveRadius is the distance a pair already in range must exceed to leave it. The gap
+// between radius and leaveRadius is a dead band that stops enter/leave flapping.
+func leaveRadius(radius float64) float64 {
+	return radius * (1 + max(config.Current().ProximityLeaveMargin, 0))
+}
+
+// volumeThreshold is how far a pair's audio volume must move before it's resent
+const volumeThreshold = 0.05
+
+// proximityTick is the window over which ProximityEventsPerTick is enforced
+const proximityTick = 500 * time.Millisecond
+
 type ProximityEvent struct {
 	Type    string `json:"type"`
 	UserA   string `json:"userA"`
 	UserB   string `json:"userB"`
 	SpaceID string `json:"spaceId,omitempty"`
 	Media   string `json:"media,omitempty"`
+	// Volume is the audio volume for the pair, 1 at distance 0 down to 0 at the radius
+	Volume float64 `json:"volume,omitempty"`
+}
+
+// proximityVolume falls off linearly from 1 at distance 0 to 0 at radius
+func proximityVolume(d, radius float64) float64 {
+	if radius <= 0 || d >= radius {
+		return 0
+	}
+	return 1 - d/radius
+}
+
+// audioVolumeLocked computes the current audio volume between two users in the space
+func (s *Space) audioVolumeLocked(userA, userB string) float64 {
+	a, okA := s.Users[userA]
+	b, okB := s.Users[userB]
+	if !okA || !okB {
+		return 0
+	}
+	def, _ := config.Current().Media(config.MediaAudio)
+	xA, yA := a.GetPosition()
+	xB, yB := b.GetPosition()
+	return proximityVolume(distance(xA, yA, xB, yB), def.Radius)
 }
 
 
@@ -30,6 +69,8 @@ func (s *Space) UpdateProximityForUser(
 		return nil
 	}
 
+	def, _ := config.Current().Media(media)
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -44,17 +85,24 @@ func (s *Space) UpdateProximityForUser(
 	userX, userY := user.GetPosition()
 	now := time.Now()
 
-	for otherID, other := range s.Users {
+	for otherID, other := range s.proximityCandidatesLocked(user.UserID, userX, userY, radius, media) {
 		if otherID == user.UserID {
 			continue
 		}
 
 		otherX, otherY := other.GetPosition()
-		inRange := distance(userX, userY, otherX, otherY) <= radius
+		d := distance(userX, userY, otherX, otherY)
 		wasInRange := userSet[otherID]
+		// Pairs already in range (for video, already dwelling) only leave past leaveRadius
+		tracked := wasInRange
+		if def.Meeting {
+			_, dwelling := s.VideoDwellStart[dwellKey(user.UserID, otherID)]
+			tracked = tracked || dwelling
+		}
+		inRange := d <= radius || (tracked && d <= leaveRadius(radius))
 
 		if inRange {
-			if media == "video" {
+			if def.Meeting {
 				// For video, we ONLY manage the dwell start time here.
 				// The actual meeting prompt emission is handled by CheckVideoDwellTimers logic (in space.go).
 				// We do NOT emit ProximityEnter for video here.
@@ -77,21 +125,37 @@ func (s *Space) UpdateProximityForUser(
 				
 				// We do NOT update userSet (VideoProximity) here. Meeting logic handles that state.
 			} else if !wasInRange {
-				// For AUDIO, emit enter immediately
-				userSet[otherID] = true
-				otherSet, ok := proximity[otherID]
-				if !ok {
-					otherSet = make(map[string]bool)
-					proximity[otherID] = otherSet
+				// For AUDIO (and other plain media), emit enter immediately unless the media has a
+				// dwell, in which case CheckPendingEnters emits it once the pair has stayed close.
+				if def.Dwell > 0 {
+					key := dwellKey(user.UserID, otherID)
+					pending := s.getPendingEnterLocked(media)
+					start, ok := pending[key]
+					if !ok {
+						pending[key] = now
+						continue
+					}
+					if now.Sub(start) < def.Dwell {
+						continue
+					}
+					delete(pending, key)
+				}
+				events = append(events, s.enterProximityLocked(proximity, user.UserID, otherID, media))
+			} else if media == config.MediaAudio {
+				// Still in range: resend the volume only once it has moved noticeably
+				key := dwellKey(user.UserID, otherID)
+				volume := proximityVolume(d, radius)
+				if math.Abs(volume-s.AudioVolume[key]) > volumeThreshold {
+					s.setAudioVolumeLocked(key, volume)
+					events = append(events, ProximityEvent{
+						Type:    ProximityVolume,
+						UserA:   user.UserID,
+						UserB:   otherID,
+						SpaceID: s.ID,
+						Media:   media,
+						Volume:  volume,
+					})
 				}
-				otherSet[user.UserID] = true
-				events = append(events, ProximityEvent{
-					Type:    ProximityEnter,
-					UserA:   user.UserID,
-					UserB:   otherID,
-					SpaceID: s.ID,
-					Media:   media,
-				})
 			}
 		}
 
@@ -102,6 +166,7 @@ func (s *Space) UpdateProximityForUser(
 				if otherSet, ok := proximity[otherID]; ok {
 					delete(otherSet, user.UserID)
 				}
+				delete(s.AudioVolume, dwellKey(user.UserID, otherID))
 				events = append(events, ProximityEvent{
 					Type:    ProximityLeave,
 					UserA:   user.UserID,
@@ -111,7 +176,10 @@ func (s *Space) UpdateProximityForUser(
 				})
 			}
 			
-			if media == "video" {
+			// Leaving before the dwell completes cancels the pending enter
+			delete(s.PendingEnter[media], dwellKey(user.UserID, otherID))
+
+			if def.Meeting {
 				// Clear dwell timer if they leave range
 				key := dwellKey(user.UserID, otherID)
 				delete(s.VideoDwellStart, key)
@@ -122,11 +190,203 @@ func (s *Space) UpdateProximityForUser(
 	return events
 }
 
+// proximityCandidatesLocked returns the users UpdateProximityForUser must look at: those
+// in grid cells within radius, plus anyone with proximity state to clear (previously in
+// range, a pending enter or a video dwell) wherever they are now. Without a grid it's
+// every user in the space.
+func (s *Space) proximityCandidatesLocked(userID string, x, y, radius float64, media string) map[string]*Client {
+	if s.grid == nil {
+		return s.Users
+	}
+
+	candidates := s.grid.near(x, y, radius)
+	addPeer := func(peerID string) {
+		if client, ok := s.Users[peerID]; ok {
+			candidates[peerID] = client
+		}
+	}
+	for peerID := range s.Proximity[media][userID] {
+		addPeer(peerID)
+	}
+	for _, pairs := range []map[string]time.Time{s.PendingEnter[media], s.VideoDwellStart} {
+		for key := range pairs {
+			if a, b, _ := strings.Cut(key, ":"); a == userID {
+				addPeer(b)
+			} else if b == userID {
+				addPeer(a)
+			}
+		}
+	}
+	return candidates
+}
+
+// enterProximityLocked marks two users as mutually in proximity and returns the enter event
+func (s *Space) enterProximityLocked(proximity map[string]map[string]bool, userID, otherID, media string) ProximityEvent {
+	for _, pair := range [][2]string{{userID, otherID}, {otherID, userID}} {
+		set, ok := proximity[pair[0]]
+		if !ok {
+			set = make(map[string]bool)
+			proximity[pair[0]] = set
+		}
+		set[pair[1]] = true
+	}
+	event := ProximityEvent{
+		Type:    ProximityEnter,
+		UserA:   userID,
+		UserB:   otherID,
+		SpaceID: s.ID,
+		Media:   media,
+	}
+	if media == config.MediaAudio {
+		event.Volume = s.audioVolumeLocked(userID, otherID)
+		s.setAudioVolumeLocked(dwellKey(userID, otherID), event.Volume)
+	}
+	return event
+}
+
+func (s *Space) setAudioVolumeLocked(key string, volume float64) {
+	if s.AudioVolume == nil {
+		s.AudioVolume = make(map[string]float64)
+	}
+	s.AudioVolume[key] = volume
+}
+
+// CheckPendingEnters emits "enter" events for pairs that have stayed in range of a
+// dwell-gated media long enough, and drops pending enters for pairs that drifted apart.
+func (s *Space) CheckPendingEnters() []ProximityEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	events := make([]ProximityEvent, 0)
+
+	for media, pending := range s.PendingEnter {
+		def, _ := config.Current().Media(media)
+		proximity := s.getProximityMapLocked(media)
+
+		for key, start := range pending {
+			userA, userB, _ := strings.Cut(key, ":")
+			clientA, okA := s.Users[userA]
+			clientB, okB := s.Users[userB]
+			if !okA || !okB {
+				delete(pending, key)
+				continue
+			}
+
+			xA, yA := clientA.GetPosition()
+			xB, yB := clientB.GetPosition()
+			if distance(xA, yA, xB, yB) > def.Radius {
+				delete(pending, key)
+				continue
+			}
+
+			if now.Sub(start) >= def.Dwell {
+				delete(pending, key)
+				if !proximity[userA][userB] {
+					events = append(events, s.enterProximityLocked(proximity, userA, userB, media))
+				}
+			}
+		}
+	}
+
+	return events
+}
+
+// ShedProximityEvents applies the per-tick event cap. Volume changes pass through; enters are always delivered (and count
+// toward the cap); leaves beyond the cap are deferred to later ticks. A deferred leave that is
+// followed by an enter for the same pair cancels out, since the peers never saw them separate.
+func (s *Space) ShedProximityEvents(events []ProximityEvent) []ProximityEvent {
+	limit := config.Current().ProximityEventsPerTick
+	if limit <= 0 || len(events) == 0 {
+		return events
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rollProximityTickLocked(time.Now())
+
+	out := make([]ProximityEvent, 0, len(events))
+	for _, event := range events {
+		// Volume changes don't renegotiate media, so they're neither capped nor counted
+		if event.Type == ProximityVolume {
+			out = append(out, event)
+			continue
+		}
+		if event.Type == ProximityEnter {
+			if s.cancelDeferredLeaveLocked(event) {
+				continue
+			}
+			out = append(out, event)
+			s.proximityEmitted++
+			continue
+		}
+		if s.proximityEmitted < limit {
+			out = append(out, event)
+			s.proximityEmitted++
+		} else {
+			s.deferredLeaves = append(s.deferredLeaves, event)
+		}
+	}
+	return out
+}
+
+// FlushDeferredProximityEvents releases deferred leaves that fit in the current tick's budget
+func (s *Space) FlushDeferredProximityEvents() []ProximityEvent {
+	limit := config.Current().ProximityEventsPerTick
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.deferredLeaves) == 0 {
+		return nil
+	}
+	s.rollProximityTickLocked(time.Now())
+
+	n := len(s.deferredLeaves)
+	if limit > 0 && n > limit-s.proximityEmitted {
+		n = max(limit-s.proximityEmitted, 0)
+	}
+	out := s.deferredLeaves[:n:n]
+	s.deferredLeaves = s.deferredLeaves[n:]
+	s.proximityEmitted += n
+	return out
+}
+
+func (s *Space) rollProximityTickLocked(now time.Time) {
+	if now.Sub(s.proximityTickStart) >= proximityTick {
+		s.proximityTickStart = now
+		s.proximityEmitted = 0
+	}
+}
+
+// cancelDeferredLeaveLocked drops a pending leave for the same pair and media as event
+func (s *Space) cancelDeferredLeaveLocked(event ProximityEvent) bool {
+	key := dwellKey(event.UserA, event.UserB)
+	for i, pending := range s.deferredLeaves {
+		if pending.Media == event.Media && dwellKey(pending.UserA, pending.UserB) == key {
+			s.deferredLeaves = append(s.deferredLeaves[:i], s.deferredLeaves[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
 func (s *Space) getProximityMapLocked(media string) map[string]map[string]bool {
-	if media == "video" {
-		return s.VideoProximity
+	proximity, ok := s.Proximity[media]
+	if !ok {
+		proximity = make(map[string]map[string]bool)
+		s.Proximity[media] = proximity
+	}
+	return proximity
+}
+
+func (s *Space) getPendingEnterLocked(media string) map[string]time.Time {
+	pending, ok := s.PendingEnter[media]
+	if !ok {
+		pending = make(map[string]time.Time)
+		s.PendingEnter[media] = pending
 	}
-	return s.AudioProximity
+	return pending
 }
 
 func distance(x1, y1, x2, y2 float64) float64 {
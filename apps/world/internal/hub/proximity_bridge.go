@@ -0,0 +1,153 @@
+package hub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"world/internal/config"
+)
+
+// Proximity bridge payload formats (PROXIMITY_BRIDGE_FORMAT)
+const (
+	BridgeFormatTRPC  = "trpc"
+	BridgeFormatPlain = "plain"
+	BridgeFormatOff   = "off"
+)
+
+// bridgeTimeout bounds each proximity bridge request
+const bridgeTimeout = 5 * time.Second
+
+// ProximityReporter forwards proximity changes to the media backend
+type ProximityReporter interface {
+	ReportProximity(ctx context.Context, events []ProximityEvent) error
+}
+
+// TRPCProximityReporter posts events as a tRPC batch call:
+// {"0": {"json": {"events": [...], "secret": "..."}}}
+type TRPCProximityReporter struct {
+	URL    string
+	Secret string
+	Client *http.Client
+}
+
+// ReportProximity implements ProximityReporter
+func (r *TRPCProximityReporter) ReportProximity(ctx context.Context, events []ProximityEvent) error {
+	type input struct {
+		Events []ProximityEvent `json:"events"`
+		Secret string           `json:"secret,omitempty"`
+	}
+	body := map[string]map[string]input{
+		"0": {"json": {Events: events, Secret: r.Secret}},
+	}
+	return postJSON(ctx, r.Client, r.URL, nil, body)
+}
+
+// PlainProximityReporter posts events as {"events": [...]}, with the secret in WorldSecretHeader
+type PlainProximityReporter struct {
+	URL    string
+	Secret string
+	Client *http.Client
+}
+
+// ReportProximity implements ProximityReporter
+func (r *PlainProximityReporter) ReportProximity(ctx context.Context, events []ProximityEvent) error {
+	var header http.Header
+	if r.Secret != "" {
+		header = http.Header{WorldSecretHeader: {r.Secret}}
+	}
+	body := struct {
+		Events []ProximityEvent `json:"events"`
+	}{events}
+	return postJSON(ctx, r.Client, r.URL, header, body)
+}
+
+// postJSON posts v to url and fails on any non-2xx response
+func postJSON(ctx context.Context, client *http.Client, url string, header http.Header, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("proximity bridge returned %s", resp.Status)
+	}
+	return nil
+}
+
+// NewProximityReporter builds the reporter selected by cfg. It returns nil when the
+// bridge is off or has no URL, and an error for an unknown format.
+func NewProximityReporter(cfg *config.Config) (ProximityReporter, error) {
+	if cfg.ProximityBridgeURL == "" {
+		return nil, nil
+	}
+	switch cfg.ProximityBridgeFormat {
+	case BridgeFormatOff:
+		return nil, nil
+	case BridgeFormatTRPC, "":
+		return &TRPCProximityReporter{URL: cfg.ProximityBridgeURL, Secret: cfg.WorldServerSecret}, nil
+	case BridgeFormatPlain:
+		return &PlainProximityReporter{URL: cfg.ProximityBridgeURL, Secret: cfg.WorldServerSecret}, nil
+	}
+	return nil, fmt.Errorf("unknown proximity bridge format %q", cfg.ProximityBridgeFormat)
+}
+
+// StartProximityBridge forwards audio and video proximity changes on the hub's event bus
+// to reporter, in order and off the hot path. Changes that queue up while a report is in
+// flight go out together in the next one. Other media (screen, whisper, PROXIMITY_MEDIA)
+// aren't forwarded, since the media backend only handles audio and video. Failed reports
+// are logged and dropped.
+func (h *Hub) StartProximityBridge(reporter ProximityReporter) *Subscription {
+	return h.Events.SubscribeBatch(func(batch []Event) {
+		var events []ProximityEvent
+		for _, e := range batch {
+			if e.Type != EventProximityChanged || !bridgedMedia(e.Media) {
+				continue
+			}
+			events = append(events, ProximityEvent{
+				Type:    e.Change,
+				UserA:   e.UserID,
+				UserB:   e.PeerID,
+				SpaceID: e.SpaceID,
+				Media:   e.Media,
+			})
+		}
+		if len(events) > 0 {
+			h.notifyProximityChanges(reporter, events)
+		}
+	}, defaultEventBuffer)
+}
+
+// bridgedMedia reports whether the media backend accepts proximity changes for media
+func bridgedMedia(media string) bool {
+	return media == config.MediaAudio || media == config.MediaVideo
+}
+
+// notifyProximityChanges sends events through reporter
+func (h *Hub) notifyProximityChanges(reporter ProximityReporter, events []ProximityEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), bridgeTimeout)
+	defer cancel()
+	if err := reporter.ReportProximity(ctx, events); err != nil {
+		log.Printf("Proximity bridge: failed to report %d events: %v", len(events), err)
+	}
+}
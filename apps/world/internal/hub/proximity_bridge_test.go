@@ -0,0 +1,194 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"world/internal/config"
+)
+
+// bridgeServer records the requests and bodies it receives, answering with status
+func bridgeServer(t *testing.T, status int) (*httptest.Server, <-chan *http.Request, <-chan []byte) {
+	t.Helper()
+	reqs := make(chan *http.Request, 8)
+	bodies := make(chan []byte, 8)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		reqs <- r
+		bodies <- body
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, reqs, bodies
+}
+
+var bridgeEvents = []ProximityEvent{{Type: "enter", UserA: "a", UserB: "b", SpaceID: "s1", Media: "audio"}}
+
+func TestTRPCProximityReporter(t *testing.T) {
+	srv, reqs, bodies := bridgeServer(t, http.StatusOK)
+	r := &TRPCProximityReporter{URL: srv.URL + "/mediasoup.proximityUpdate?batch=1", Secret: "s3cret"}
+
+	if err := r.ReportProximity(context.Background(), bridgeEvents); err != nil {
+		t.Fatal(err)
+	}
+	if req := <-reqs; req.URL.Query().Get("batch") != "1" || req.Method != http.MethodPost {
+		t.Errorf("request = %s %s", req.Method, req.URL)
+	}
+
+	var got map[string]struct {
+		JSON struct {
+			Events []ProximityEvent `json:"events"`
+			Secret string           `json:"secret"`
+		} `json:"json"`
+	}
+	if err := json.Unmarshal(<-bodies, &got); err != nil {
+		t.Fatal(err)
+	}
+	input := got["0"].JSON
+	if input.Secret != "s3cret" || len(input.Events) != 1 || input.Events[0] != bridgeEvents[0] {
+		t.Errorf("tRPC input = %+v", input)
+	}
+}
+
+func TestPlainProximityReporter(t *testing.T) {
+	srv, reqs, bodies := bridgeServer(t, http.StatusNoContent)
+	r := &PlainProximityReporter{URL: srv.URL, Secret: "s3cret"}
+
+	if err := r.ReportProximity(context.Background(), bridgeEvents); err != nil {
+		t.Fatal(err)
+	}
+	if req := <-reqs; req.Header.Get(WorldSecretHeader) != "s3cret" {
+		t.Errorf("secret header = %q", req.Header.Get(WorldSecretHeader))
+	}
+
+	var got map[string]json.RawMessage
+	if err := json.Unmarshal(<-bodies, &got); err != nil {
+		t.Fatal(err)
+	}
+	var events []ProximityEvent
+	if err := json.Unmarshal(got["events"], &events); err != nil || len(got) != 1 {
+		t.Fatalf("plain body = %v (%v)", got, err)
+	}
+	if len(events) != 1 || events[0] != bridgeEvents[0] {
+		t.Errorf("events = %+v", events)
+	}
+}
+
+func TestProximityReporterErrorStatus(t *testing.T) {
+	srv, _, _ := bridgeServer(t, http.StatusUnauthorized)
+	for _, r := range []ProximityReporter{
+		&TRPCProximityReporter{URL: srv.URL},
+		&PlainProximityReporter{URL: srv.URL},
+	} {
+		if err := r.ReportProximity(context.Background(), bridgeEvents); err == nil {
+			t.Errorf("%T: expected an error for a 401", r)
+		}
+	}
+}
+
+func TestNewProximityReporter(t *testing.T) {
+	cases := []struct {
+		url, format string
+		want        string
+		wantErr     bool
+	}{
+		{"http://backend", "", "*hub.TRPCProximityReporter", false},
+		{"http://backend", BridgeFormatTRPC, "*hub.TRPCProximityReporter", false},
+		{"http://backend", BridgeFormatPlain, "*hub.PlainProximityReporter", false},
+		{"http://backend", BridgeFormatOff, "<nil>", false},
+		{"", BridgeFormatPlain, "<nil>", false},
+		{"http://backend", "xml", "<nil>", true},
+	}
+	for _, tc := range cases {
+		r, err := NewProximityReporter(&config.Config{ProximityBridgeURL: tc.url, ProximityBridgeFormat: tc.format})
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%q/%q: err = %v", tc.url, tc.format, err)
+		}
+		if got := fmt.Sprintf("%T", r); got != tc.want {
+			t.Errorf("%q/%q: got %s, want %s", tc.url, tc.format, got, tc.want)
+		}
+	}
+}
+
+// recordingReporter captures reported proximity events
+type recordingReporter chan []ProximityEvent
+
+func (r recordingReporter) ReportProximity(_ context.Context, events []ProximityEvent) error {
+	r <- events
+	return nil
+}
+
+func TestStartProximityBridgeForwardsChanges(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	reporter := make(recordingReporter, 4)
+	sub := h.StartProximityBridge(reporter)
+	defer sub.Unsubscribe()
+
+	h.Events.Publish(Event{Type: EventUserMoved, UserID: "a"})
+	// The media backend only takes audio and video
+	h.handleProximityEvents([]ProximityEvent{{Type: "enter", UserA: "a", UserB: "b", SpaceID: "s1", Media: config.MediaScreen}})
+	h.handleProximityEvents(bridgeEvents)
+
+	select {
+	case got := <-reporter:
+		if len(got) != 1 || got[0] != bridgeEvents[0] {
+			t.Errorf("reported %+v, want %+v", got, bridgeEvents)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("proximity change was not reported")
+	}
+	select {
+	case got := <-reporter:
+		t.Errorf("unexpected report %+v", got)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+// blockingReporter records reports but holds each one until released
+type blockingReporter struct {
+	reports chan []ProximityEvent
+	release chan struct{}
+}
+
+func (r *blockingReporter) ReportProximity(_ context.Context, events []ProximityEvent) error {
+	r.reports <- events
+	<-r.release
+	return nil
+}
+
+func TestProximityBridgeBatchesQueuedChanges(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	reporter := &blockingReporter{reports: make(chan []ProximityEvent, 4), release: make(chan struct{})}
+	sub := h.StartProximityBridge(reporter)
+	defer sub.Unsubscribe()
+
+	h.handleProximityEvents(bridgeEvents)
+	select {
+	case <-reporter.reports:
+	case <-time.After(time.Second):
+		t.Fatal("first change was not reported")
+	}
+
+	// Changes arriving while that request is in flight share the next one
+	for _, peer := range []string{"c", "d", "e"} {
+		h.handleProximityEvents([]ProximityEvent{{Type: "enter", UserA: "a", UserB: peer, SpaceID: "s1", Media: config.MediaVideo}})
+	}
+	close(reporter.release)
+
+	select {
+	case got := <-reporter.reports:
+		if len(got) != 3 || got[0].UserB != "c" || got[2].UserB != "e" {
+			t.Errorf("reported %+v, want the three queued changes in one batch", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("queued changes were not reported")
+	}
+}
@@ -0,0 +1,76 @@
+package hub
+
+import (
+	"sync"
+
+	"world/internal/config"
+)
+
+// ProximityStrategy decides when a space recomputes proximity for users who move
+type ProximityStrategy interface {
+	// Moved is called after client moved and returns the events to emit now
+	Moved(space *Space, client *Client) []ProximityEvent
+	// Tick is called on every dwell tick and returns events for deferred recomputes
+	Tick(space *Space) []ProximityEvent
+}
+
+// newProximityStrategy returns the strategy configured for spaceID
+func newProximityStrategy(spaceID string) ProximityStrategy {
+	if config.Current().ProximityStrategy(spaceID) == config.ProximityPolled {
+		return &polledProximity{}
+	}
+	return eventedProximity{}
+}
+
+// eventedProximity recomputes a mover's proximity on every move
+type eventedProximity struct{}
+
+func (eventedProximity) Moved(space *Space, client *Client) []ProximityEvent {
+	return space.updateProximityAllMedia(client)
+}
+
+func (eventedProximity) Tick(*Space) []ProximityEvent { return nil }
+
+// polledProximity remembers who moved and recomputes them together on the next tick,
+// so a user sending many moves between ticks costs one proximity pass
+type polledProximity struct {
+	mu    sync.Mutex
+	moved map[string]bool
+}
+
+func (p *polledProximity) Moved(_ *Space, client *Client) []ProximityEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.moved == nil {
+		p.moved = make(map[string]bool)
+	}
+	p.moved[client.UserID] = true
+	return nil
+}
+
+func (p *polledProximity) Tick(space *Space) []ProximityEvent {
+	p.mu.Lock()
+	moved := p.moved
+	p.moved = nil
+	p.mu.Unlock()
+
+	events := make([]ProximityEvent, 0)
+	for userID := range moved {
+		space.mu.RLock()
+		client, ok := space.Users[userID]
+		space.mu.RUnlock()
+		if ok {
+			events = append(events, space.updateProximityAllMedia(client)...)
+		}
+	}
+	return events
+}
+
+// updateProximityAllMedia re-evaluates client's proximity for every configured media
+func (s *Space) updateProximityAllMedia(client *Client) []ProximityEvent {
+	events := make([]ProximityEvent, 0)
+	for _, media := range config.Current().ProximityMedia() {
+		events = append(events, s.UpdateProximityForUser(client, media.Radius, media.Name)...)
+	}
+	return events
+}
@@ -0,0 +1,104 @@
+package hub
+
+import (
+	"reflect"
+	"testing"
+
+	"world/internal/config"
+)
+
+// audioNeighbors snapshots the audio proximity sets of space
+func audioNeighbors(space *Space) map[string]map[string]bool {
+	space.mu.RLock()
+	defer space.mu.RUnlock()
+
+	out := make(map[string]map[string]bool)
+	for userID, peers := range space.Proximity[config.MediaAudio] {
+		if len(peers) == 0 {
+			continue
+		}
+		out[userID] = make(map[string]bool)
+		for peerID := range peers {
+			out[userID][peerID] = true
+		}
+	}
+	return out
+}
+
+func TestProximityStrategiesConverge(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) {
+		cfg.SpaceProximityStrategies = map[string]string{"polled": config.ProximityPolled}
+	})
+
+	h := NewHub()
+	moves := []struct {
+		user string
+		x, y float64
+	}{
+		{"a", 100, 100}, {"b", 1000, 800}, {"c", 200, 100},
+		{"a", 300, 300}, {"a", 600, 500}, {"b", 700, 500},
+		{"c", 1200, 900}, {"a", 650, 500}, {"c", 800, 600},
+	}
+
+	spaces := make([]*Space, 0, 2)
+	for _, id := range []string{"evented", "polled"} {
+		space := newTestSpace(h, id)
+		space.ProximityStrategy = newProximityStrategy(id)
+		clients := map[string]*Client{
+			"a": addTestClient(h, space, "a", 0, 0),
+			"b": addTestClient(h, space, "b", 1270, 950),
+			"c": addTestClient(h, space, "c", 0, 950),
+		}
+		for _, m := range moves {
+			clients[m.user].SetPosition(m.x, m.y)
+			h.proximityMoved(space, clients[m.user])
+		}
+		spaces = append(spaces, space)
+	}
+	evented, polled := spaces[0], spaces[1]
+
+	if _, ok := polled.ProximityStrategy.(*polledProximity); !ok {
+		t.Fatalf("polled space uses %T", polled.ProximityStrategy)
+	}
+	if got := audioNeighbors(polled); len(got) != 0 {
+		t.Errorf("polled space updated proximity before its tick: %v", got)
+	}
+
+	for _, space := range spaces {
+		h.handleProximityEvents(space.ProximityStrategy.Tick(space))
+	}
+
+	want := audioNeighbors(evented)
+	if len(want) == 0 {
+		t.Fatal("moves produced no proximity; test is vacuous")
+	}
+	if got := audioNeighbors(polled); !reflect.DeepEqual(got, want) {
+		t.Errorf("polled proximity = %v, evented = %v", got, want)
+	}
+}
+
+func TestPolledProximityCoalescesMoves(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	space.ProximityStrategy = &polledProximity{}
+	a := addTestClient(h, space, "a", 100, 100)
+	addTestClient(h, space, "b", 150, 100)
+
+	for x := 1000.0; x < 1010; x++ {
+		a.SetPosition(x, 100)
+		h.proximityMoved(space, a)
+	}
+	a.SetPosition(120, 100)
+	h.proximityMoved(space, a)
+
+	// a ended up where it started relative to b: one pass sees a single enter
+	events := space.ProximityStrategy.Tick(space)
+	if len(events) != 1 || events[0].Type != ProximityEnter {
+		t.Errorf("tick events = %+v, want one enter", events)
+	}
+	if events := space.ProximityStrategy.Tick(space); len(events) != 0 {
+		t.Errorf("second tick recomputed again: %+v", events)
+	}
+}
@@ -0,0 +1,490 @@
+package hub
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"world/internal/config"
+	"world/internal/messages"
+)
+
+func TestAudioDwellPassThroughEmitsNoEnter(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.AudioDwell = time.Second })
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	addTestClient(h, space, "a", 100, 100)
+	b := addTestClient(h, space, "b", 600, 100)
+
+	// b brushes past a and keeps walking
+	b.SetPosition(200, 100)
+	if events := space.UpdateProximityForUser(b, config.Current().AudioRadius, "audio"); len(events) != 0 {
+		t.Fatalf("got %d events while grace pending, want 0", len(events))
+	}
+	if _, pending := space.PendingEnter["audio"][dwellKey("a", "b")]; !pending {
+		t.Fatal("expected a pending audio grace")
+	}
+
+	b.SetPosition(600, 100)
+	if events := space.UpdateProximityForUser(b, config.Current().AudioRadius, "audio"); len(events) != 0 {
+		t.Errorf("got %d events after leaving during grace, want 0", len(events))
+	}
+	if len(space.PendingEnter["audio"]) != 0 {
+		t.Error("leaving range should cancel the pending grace")
+	}
+	if events := space.CheckPendingEnters(); len(events) != 0 {
+		t.Errorf("checker emitted %d events for a cancelled grace", len(events))
+	}
+}
+
+func TestAudioDwellEmitsEnterAfterGrace(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.AudioDwell = time.Second })
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	addTestClient(h, space, "a", 100, 100)
+	b := addTestClient(h, space, "b", 200, 100)
+
+	space.UpdateProximityForUser(b, config.Current().AudioRadius, "audio")
+	if events := space.CheckPendingEnters(); len(events) != 0 {
+		t.Fatalf("checker emitted %d events before the grace elapsed", len(events))
+	}
+
+	space.PendingEnter["audio"][dwellKey("a", "b")] = time.Now().Add(-2 * time.Second)
+	events := space.CheckPendingEnters()
+	if len(events) != 1 || events[0].Type != ProximityEnter || events[0].Media != "audio" {
+		t.Fatalf("got %+v, want a single audio enter", events)
+	}
+	if !space.Proximity["audio"]["a"]["b"] || !space.Proximity["audio"]["b"]["a"] {
+		t.Error("pair should be in audio proximity after the grace")
+	}
+}
+
+func TestAudioDwellDisabledByDefault(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	addTestClient(h, space, "a", 100, 100)
+	b := addTestClient(h, space, "b", 200, 100)
+
+	if events := space.UpdateProximityForUser(b, config.Current().AudioRadius, "audio"); len(events) != 1 {
+		t.Errorf("got %d events with no grace, want 1 immediate enter", len(events))
+	}
+}
+
+func TestScreenProximityImmediateEnter(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.ScreenRadius = 180 })
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	b := addTestClient(h, space, "b", 600, 100)
+
+	// 150 apart: inside screen (180) and audio, outside video (120)
+	b.SetPosition(250, 100)
+	events := space.UpdateProximityForUser(b, config.Current().ScreenRadius, config.MediaScreen)
+	if len(events) != 1 || events[0].Type != ProximityEnter || events[0].Media != config.MediaScreen || events[0].Volume != 0 {
+		t.Fatalf("got %+v, want a single screen enter", events)
+	}
+	if !space.Proximity[config.MediaScreen]["a"]["b"] || !space.Proximity[config.MediaScreen]["b"]["a"] {
+		t.Error("pair should be in screen proximity")
+	}
+
+	// Moving is recomputed for screen alongside audio and video
+	for x := 270.0; x <= 450; x += 20 {
+		h.handleMovement(b, messages.IncomingPayload{X: x, Y: 100})
+	}
+	var screen []messages.ProximityUpdatePayload
+	for _, m := range messagesOfType(drainMessages(t, a), messages.TypeProximityUpdate) {
+		var payload messages.ProximityUpdatePayload
+		if err := json.Unmarshal(m.Payload, &payload); err != nil {
+			t.Fatal(err)
+		}
+		if payload.Media == config.MediaScreen {
+			screen = append(screen, payload)
+		}
+	}
+	if len(screen) != 1 || screen[0].Type != ProximityLeave || screen[0].PeerID != "b" {
+		t.Errorf("screen updates after walking away = %+v, want a single leave", screen)
+	}
+}
+
+func TestWhisperEntersInsideAudio(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.WhisperRadius = 60 })
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	b := addTestClient(h, space, "b", 500, 100)
+	drainMessages(t, a)
+
+	// Walk b towards a; audio (300) should open long before whisper (60)
+	audioActive := false
+	whispered := false
+	for x := 480.0; x >= 140; x -= 20 {
+		h.handleMovement(b, messages.IncomingPayload{X: x, Y: 100})
+		for _, m := range messagesOfType(drainMessages(t, a), messages.TypeProximityUpdate) {
+			var payload messages.ProximityUpdatePayload
+			if err := json.Unmarshal(m.Payload, &payload); err != nil {
+				t.Fatal(err)
+			}
+			if payload.Type != ProximityEnter {
+				continue
+			}
+			switch payload.Media {
+			case config.MediaAudio:
+				audioActive = true
+			case config.MediaWhisper:
+				if !audioActive {
+					t.Fatalf("whisper entered at distance %v before audio", x-100)
+				}
+				if x-100 > config.Current().WhisperRadius {
+					t.Fatalf("whisper entered at distance %v, outside the whisper radius", x-100)
+				}
+				whispered = true
+			}
+		}
+		if !whispered && space.Proximity[config.MediaWhisper]["a"]["b"] {
+			t.Fatalf("pair tracked in whisper proximity at distance %v without an event", x-100)
+		}
+	}
+	if !whispered {
+		t.Fatal("no whisper enter once inside the whisper radius")
+	}
+	if !space.Proximity[config.MediaAudio]["a"]["b"] {
+		t.Error("audio proximity should stay active alongside whisper")
+	}
+}
+
+func TestProximityEventShedding(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	for i := 0; i < 20; i++ {
+		addTestClient(h, space, fmt.Sprintf("u%02d", i), 150+float64(i), 150)
+	}
+	space.UpdateProximityForUser(a, config.Current().AudioRadius, "audio")
+
+	// Crowd surge: a walks away from everyone at once
+	updateTestConfig(func(cfg *config.Config) { cfg.ProximityEventsPerTick = 5 })
+	a.SetPosition(1200, 900)
+	leaves := space.UpdateProximityForUser(a, config.Current().AudioRadius, "audio")
+	if len(leaves) != 20 {
+		t.Fatalf("got %d leave events, want 20", len(leaves))
+	}
+
+	if out := space.ShedProximityEvents(leaves); len(out) != 5 {
+		t.Errorf("first tick emitted %d events, want 5", len(out))
+	}
+	if out := space.FlushDeferredProximityEvents(); len(out) != 0 {
+		t.Errorf("flush within an exhausted tick emitted %d events", len(out))
+	}
+
+	total := 5
+	for tick := 0; tick < 10 && total < 20; tick++ {
+		space.proximityTickStart = time.Now().Add(-proximityTick)
+		out := space.FlushDeferredProximityEvents()
+		if len(out) > 5 {
+			t.Fatalf("tick emitted %d events, cap is 5", len(out))
+		}
+		total += len(out)
+	}
+	if total != 20 {
+		t.Errorf("delivered %d leave events in total, want 20", total)
+	}
+}
+
+func TestProximityShedEnterCancelsDeferredLeave(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.ProximityEventsPerTick = 1 })
+
+	space := NewSpace("s1", 1280, 960)
+	leave := func(b string) ProximityEvent {
+		return ProximityEvent{Type: ProximityLeave, UserA: "a", UserB: b, Media: "audio"}
+	}
+
+	space.ShedProximityEvents([]ProximityEvent{leave("b"), leave("c")})
+	if len(space.deferredLeaves) != 1 {
+		t.Fatalf("deferred %d leaves, want 1", len(space.deferredLeaves))
+	}
+
+	// c comes back before its leave was ever delivered
+	out := space.ShedProximityEvents([]ProximityEvent{{Type: ProximityEnter, UserA: "c", UserB: "a", Media: "audio"}})
+	if len(out) != 0 || len(space.deferredLeaves) != 0 {
+		t.Errorf("enter should cancel the deferred leave, got out=%v deferred=%v", out, space.deferredLeaves)
+	}
+}
+
+func TestCustomProximityMedia(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) {
+		cfg.ExtraMedia = []config.ProximityMedia{
+			{Name: "presence", Radius: 500},
+			{Name: "slow", Radius: 500, Dwell: time.Second},
+		}
+	})
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	b := addTestClient(h, space, "b", 500, 100) // 400 apart: outside audio (300), inside presence (500)
+
+	h.recomputeProximity(space, b)
+
+	updates := messagesOfType(drainMessages(t, a), messages.TypeProximityUpdate)
+	if len(updates) != 1 {
+		t.Fatalf("a got %d proximity updates, want 1", len(updates))
+	}
+	var payload map[string]string
+	if err := json.Unmarshal(updates[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload["media"] != "presence" || payload["type"] != ProximityEnter {
+		t.Errorf("unexpected update %v", payload)
+	}
+	if space.Proximity["audio"]["a"]["b"] {
+		t.Error("audio proximity should not be set at 400 units")
+	}
+	if _, pending := space.PendingEnter["slow"][dwellKey("a", "b")]; !pending {
+		t.Error("dwell-gated custom media should be pending")
+	}
+
+	// Leaving removes the custom media state like any other
+	_, leaves := space.RemoveUserAndCollectProximityLeaves(b)
+	if len(leaves) != 1 || leaves[0].Media != "presence" {
+		t.Errorf("leave events = %+v, want a single presence leave", leaves)
+	}
+}
+
+// proximityPeers returns the peerIds from proximity updates received by c
+func proximityPeers(t *testing.T, c *Client) []string {
+	t.Helper()
+	var peers []string
+	for _, m := range messagesOfType(drainMessages(t, c), messages.TypeProximityUpdate) {
+		var payload messages.ProximityUpdatePayload
+		if err := json.Unmarshal(m.Payload, &payload); err != nil {
+			t.Fatal(err)
+		}
+		peers = append(peers, payload.PeerID)
+	}
+	return peers
+}
+
+func TestProximityUpdatePeerIDsWhenUserBMoves(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	b := addTestClient(h, space, "b", 900, 100)
+
+	// b moves into a's radius; for the dwell checker's key order a is UserA, so
+	// exercise both the mover-generated and key-generated orderings.
+	b.SetPosition(300, 100)
+	h.recomputeProximity(space, b)
+
+	if peers := proximityPeers(t, a); len(peers) != 1 || peers[0] != "b" {
+		t.Errorf("a got peerIds %v, want [b]", peers)
+	}
+	if peers := proximityPeers(t, b); len(peers) != 1 || peers[0] != "a" {
+		t.Errorf("b got peerIds %v, want [a]", peers)
+	}
+
+	event := ProximityEvent{Type: ProximityEnter, UserA: "a", UserB: "b", SpaceID: "s1", Media: "audio"}
+	h.handleProximityEvents([]ProximityEvent{event})
+	if peers := proximityPeers(t, a); len(peers) != 1 || peers[0] != "b" {
+		t.Errorf("a got peerIds %v, want [b]", peers)
+	}
+	if peers := proximityPeers(t, b); len(peers) != 1 || peers[0] != "a" {
+		t.Errorf("b got peerIds %v, want [a]", peers)
+	}
+}
+
+func TestProximityVolumeCurve(t *testing.T) {
+	tests := []struct {
+		d, want float64
+	}{
+		{0, 1},
+		{75, 0.75},
+		{150, 0.5},
+		{240, 0.2},
+		{300, 0},
+		{450, 0},
+	}
+	for _, tt := range tests {
+		if got := proximityVolume(tt.d, 300); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("proximityVolume(%v, 300) = %v, want %v", tt.d, got, tt.want)
+		}
+	}
+}
+
+// proximityUpdates decodes the proximity-update messages received by c
+func proximityUpdates(t *testing.T, c *Client) []messages.ProximityUpdatePayload {
+	t.Helper()
+	var out []messages.ProximityUpdatePayload
+	for _, m := range messagesOfType(drainMessages(t, c), messages.TypeProximityUpdate) {
+		var p messages.ProximityUpdatePayload
+		if err := json.Unmarshal(m.Payload, &p); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+func TestProximityVolumeFollowsDistance(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	b := addTestClient(h, space, "b", 900, 100)
+
+	// Entering at 150 of 300 carries half volume
+	b.SetPosition(250, 100)
+	h.recomputeProximity(space, b)
+	got := proximityUpdates(t, a)
+	if len(got) != 1 || got[0].Type != ProximityEnter || got[0].Volume == nil || math.Abs(*got[0].Volume-0.5) > 1e-9 {
+		t.Fatalf("enter = %+v, want volume 0.5", got)
+	}
+	drainMessages(t, b)
+
+	// A small step stays under the threshold and sends nothing
+	b.SetPosition(240, 100)
+	h.recomputeProximity(space, b)
+	if msgs := drainMessages(t, a); len(msgs) != 0 {
+		t.Errorf("got %d messages for a sub-threshold change, want 0", len(msgs))
+	}
+
+	// Walking in close raises the volume for both sides
+	b.SetPosition(130, 100)
+	h.recomputeProximity(space, b)
+	for _, c := range []*Client{a, b} {
+		msgs := messagesOfType(drainMessages(t, c), messages.TypeProximityVolume)
+		if len(msgs) != 1 {
+			t.Fatalf("%s got %d volume updates, want 1", c.UserID, len(msgs))
+		}
+		var p messages.ProximityVolumePayload
+		if err := json.Unmarshal(msgs[0].Payload, &p); err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(p.Volume-0.9) > 1e-9 || p.PeerID == c.UserID {
+			t.Errorf("%s got %+v, want volume 0.9 from the other side", c.UserID, p)
+		}
+	}
+
+	// Leaving sends volume 0
+	b.SetPosition(900, 100)
+	h.recomputeProximity(space, b)
+	got = proximityUpdates(t, a)
+	if len(got) != 1 || got[0].Type != ProximityLeave || got[0].Volume == nil || *got[0].Volume != 0 {
+		t.Fatalf("leave = %+v, want volume 0", got)
+	}
+	if _, ok := space.AudioVolume[dwellKey("a", "b")]; ok {
+		t.Error("leaving range should forget the pair's volume")
+	}
+}
+
+func TestProximityVolumeBypassesShedding(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.ProximityEventsPerTick = 1 })
+
+	space := NewSpace("s1", 1280, 960)
+	events := []ProximityEvent{
+		{Type: ProximityEnter, UserA: "a", UserB: "b", Media: "audio"},
+		{Type: ProximityVolume, UserA: "a", UserB: "c", Media: "audio", Volume: 0.4},
+		{Type: ProximityLeave, UserA: "a", UserB: "d", Media: "audio"},
+	}
+	out := space.ShedProximityEvents(events)
+	if len(out) != 2 || out[1].Type != ProximityVolume {
+		t.Errorf("shed = %+v, want the enter and the volume change", out)
+	}
+}
+
+// walkAcross moves b along y=100 through xs, recomputing proximity after each step,
+// and returns the proximity-update types a received
+func walkAcross(t *testing.T, h *Hub, space *Space, a, b *Client, xs []float64) []string {
+	t.Helper()
+	var types []string
+	for _, x := range xs {
+		b.SetPosition(x, 100)
+		h.recomputeProximity(space, b)
+		for _, p := range proximityUpdates(t, a) {
+			types = append(types, p.Type)
+		}
+	}
+	return types
+}
+
+func TestProximityHysteresisStopsFlapping(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.ProximityLeaveMargin = 0.15 })
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	b := addTestClient(h, space, "b", 500, 100)
+
+	// Hover back and forth across the 300 audio radius (a is at x=100), then walk
+	// out past the 345 leave radius
+	xs := []float64{410, 398, 402, 396, 404, 400, 420, 440, 444, 446, 450}
+	got := walkAcross(t, h, space, a, b, xs)
+	if fmt.Sprint(got) != "[enter leave]" {
+		t.Errorf("updates = %v, want [enter leave]", got)
+	}
+}
+
+func TestProximityWithoutMarginFlaps(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	b := addTestClient(h, space, "b", 500, 100)
+
+	got := walkAcross(t, h, space, a, b, []float64{398, 402, 396, 404})
+	if len(got) != 4 {
+		t.Errorf("updates = %v, want 4 without a dead band", got)
+	}
+}
+
+func TestVideoDwellHysteresis(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.ProximityLeaveMargin = 0.15 })
+
+	space := NewSpace("s1", 1280, 960)
+	a := &Client{UserID: "a", X: 100, Y: 100}
+	b := &Client{UserID: "b", X: 500, Y: 100}
+	space.AddUser(a)
+	space.AddUser(b)
+	key := dwellKey("a", "b")
+
+	// Video radius is 120: enter at 120, only break the dwell past 138
+	for _, step := range []struct {
+		x     float64
+		dwell bool
+	}{
+		{230, false},
+		{220, true},
+		{230, true},
+		{238, true},
+		{239, false},
+		{225, false},
+	} {
+		b.SetPosition(step.x, 100)
+		space.UpdateProximityForUser(b, config.Current().VideoRadius, config.MediaVideo)
+		if _, ok := space.VideoDwellStart[key]; ok != step.dwell {
+			t.Errorf("at x=%v dwelling = %v, want %v", step.x, ok, step.dwell)
+		}
+	}
+}
@@ -2,9 +2,15 @@ package hub
 
 import (
 	"fmt"
-	"log"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
 	"sync"
 	"time"
+
+	"world/internal/config"
+	"world/internal/messages"
 )
 
 // Space represents a virtual space with users
@@ -12,20 +18,66 @@ type Space struct {
 	ID      string
 	Width   int
 	Height  int
+	// Coords maps the Width x Height grid onto client coordinates
+	Coords  config.SpaceCoordinates
+	// Events is the hub's event bus (nil publishes nothing)
+	Events  *EventBus
+	// ProximityStrategy decides when movers' proximity is recomputed
+	ProximityStrategy ProximityStrategy
 	Users    map[string]*Client // userID -> Client
+	// grid indexes Users by position for proximity queries (nil scans every user)
+	grid     *spatialGrid
 	Elements map[string]bool    // "x,y" -> true if occupied by static element
-	AudioProximity map[string]map[string]bool
-	VideoProximity map[string]map[string]bool
+	// Objects are placed by builders at runtime, by the same keys; each also blocks its
+	// tile in Elements
+	Objects map[string]WorldObject
+	// SpawnZones come from the space's map; without any, users spawn around the center
+	SpawnZones []SpawnZone
+	// Portals come from the space's map too; they're the only way to teleport outside a meeting
+	Portals []Portal
+	// Proximity maps media -> userID -> set of userIDs currently in range
+	Proximity map[string]map[string]map[string]bool
 	// VideoDwellStart tracks when each user pair entered video proximity.
 	// Key format: "userA:userB" (sorted alphabetically).
 	VideoDwellStart map[string]time.Time
+	// PendingEnter tracks, per media, pairs waiting out a dwell before "enter" fires.
+	// Same key format as VideoDwellStart.
+	PendingEnter map[string]map[string]time.Time
+	// AudioVolume is the volume last sent for each pair in audio range (same key format)
+	AudioVolume map[string]float64
+	// InView maps userID -> set of userIDs whose avatars it has, when VIEW_RADIUS is set
+	InView map[string]map[string]bool
 	
 	// MeetingStates tracks active meeting negotiations and sessions
 	MeetingStates map[string]*MeetingState
+
+	// PromptWindows counts auto-prompts per pair (same key format) for the long-term limit
+	PromptWindows map[string]*PromptWindow
+
+	// HandQueue lists user IDs with raised hands, oldest first
+	HandQueue []string
+
+	// Following maps a follower's userID to the userID they follow
+	Following map[string]string
+
+	// dwellPaused freezes the dwell/meeting state machine (see SetDwellPaused)
+	dwellPaused bool
+	pausedAt    time.Time
+
+	// Proximity event shedding state for the current tick
+	proximityTickStart time.Time
+	proximityEmitted   int
+	deferredLeaves     []ProximityEvent
 	
 	mu       sync.RWMutex
 }
 
+// PromptWindow counts the meeting prompts a pair has received since Start
+type PromptWindow struct {
+	Start time.Time
+	Count int
+}
+
 type MeetingStatus int
 
 const (
@@ -36,10 +88,15 @@ const (
 type MeetingState struct {
 	MeetingID     string // Unique ID for this specific meeting instance
 	RequestID     string
+	// UserA and UserB are the pair in a two-person meeting (the first two participants of a group)
 	UserA         string
 	UserB         string
-	AcceptA       bool
-	AcceptB       bool
+	// Participants lists everyone in the meeting, sorted (see participants())
+	Participants  []string
+	// Accepted records who has accepted the current prompt
+	Accepted      map[string]bool
+	// awaySince records when each participant of an active meeting walked out of range
+	awaySince     map[string]time.Time
 	ExpiresAt     time.Time
 	Status        MeetingStatus
 	CooldownUntil time.Time
@@ -50,6 +107,8 @@ const (
 	MeetingTimeout  = 15 * time.Second
 	MeetingCooldown = 10 * time.Second
 	VideoDwellDuration = 3 * time.Second
+	// WalkAwayGrace is how long a participant may stay out of range before leaving the meeting
+	WalkAwayGrace = 2 * time.Second
 )
 
 
@@ -59,12 +118,20 @@ func NewSpace(id string, width, height int) *Space {
 		ID:       id,
 		Width:    width,
 		Height:   height,
+		Coords:   config.DefaultSpaceCoordinates,
+		ProximityStrategy: eventedProximity{},
 		Users:    make(map[string]*Client),
+		grid:     newSpatialGrid(defaultGridCell),
 		Elements: make(map[string]bool),
-		AudioProximity: make(map[string]map[string]bool),
-		VideoProximity: make(map[string]map[string]bool),
+		Objects:  make(map[string]WorldObject),
+		Proximity: make(map[string]map[string]map[string]bool),
 		VideoDwellStart: make(map[string]time.Time),
+		PendingEnter:    make(map[string]map[string]time.Time),
+		AudioVolume:     make(map[string]float64),
+		InView:          make(map[string]map[string]bool),
 		MeetingStates:   make(map[string]*MeetingState),
+		PromptWindows:   make(map[string]*PromptWindow),
+		Following:       make(map[string]string),
 	}
 }
 
@@ -74,6 +141,10 @@ func (s *Space) AddUser(client *Client) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.Users[client.UserID] = client
+	if s.grid != nil {
+		client.setGrid(s.grid)
+		s.grid.update(client)
+	}
 }
 
 // RemoveUserAndCollectProximityLeaves removes the user and returns proximity leave events.
@@ -87,15 +158,18 @@ func (s *Space) RemoveUserAndCollectProximityLeaves(client *Client) (bool, []Pro
 		s.cleanupMeetingsForUserLocked(client.UserID)
 
 		leaveEvents := make([]ProximityEvent, 0)
-		leaveEvents = append(
-			leaveEvents,
-			s.collectProximityLeavesLocked(client.UserID, "audio")...,
-		)
-		leaveEvents = append(
-			leaveEvents,
-			s.collectProximityLeavesLocked(client.UserID, "video")...,
-		)
+		for media := range s.Proximity {
+			leaveEvents = append(
+				leaveEvents,
+				s.collectProximityLeavesLocked(client.UserID, media)...,
+			)
+		}
+		s.removeInterestLocked(client.UserID)
 		delete(s.Users, client.UserID)
+		if s.grid != nil {
+			s.grid.remove(client.UserID)
+			client.setGrid(nil)
+		}
 		return true, leaveEvents
 	}
 
@@ -104,34 +178,25 @@ func (s *Space) RemoveUserAndCollectProximityLeaves(client *Client) (bool, []Pro
 
 func (s *Space) cleanupMeetingsForUserLocked(userID string) {
 	for key, state := range s.MeetingStates {
-		if state.UserA == userID || state.UserB == userID {
-			// Notify the other user if meeting was active
-			var otherID string
-			if state.UserA == userID {
-				otherID = state.UserB
-			} else {
-				otherID = state.UserA
-			}
-
-			if otherClient, ok := s.Users[otherID]; ok {
-				// Send meeting-end event
-				otherClient.SendJSON(map[string]interface{}{
-					"type": "meeting-end",
-					"payload": map[string]string{
-						"peerId": userID,
-						"meetingId": state.MeetingID,
-						"reason": "user_left",
-					},
-				})
-			}
-			delete(s.MeetingStates, key)
+		if state.hasParticipant(userID) {
+			// Tell the others; a group meeting carries on without the user
+			s.leaveMeetingLocked(key, state, userID, MeetingEndUserLeft)
 		}
 	}
 	
+	// Drop pending enters involving this user
+	for _, pending := range s.PendingEnter {
+		for key := range pending {
+			if a, b, _ := strings.Cut(key, ":"); a == userID || b == userID {
+				delete(pending, key)
+			}
+		}
+	}
+
 	// Also clean up dwell timers
 	for key := range s.VideoDwellStart {
-		// key is "userA:userB"
-		if len(key) > len(userID) && (key[:len(userID)] == userID || key[len(key)-len(userID):] == userID) {
+		// key is "userA:userB"; compare whole IDs so "bob" doesn't match "bobby"
+		if a, b, _ := strings.Cut(key, ":"); a == userID || b == userID {
 			delete(s.VideoDwellStart, key)
 		}
 	}
@@ -147,10 +212,13 @@ func (s *Space) collectProximityLeavesLocked(userID string, media string) []Prox
 				delete(otherNeighbors, userID)
 			}
 			// Clean up dwell timer for video proximity
-			if media == "video" {
+			if media == config.MediaVideo {
 				key := dwellKey(userID, otherID)
 				delete(s.VideoDwellStart, key)
 			}
+			if media == config.MediaAudio {
+				delete(s.AudioVolume, dwellKey(userID, otherID))
+			}
 			events = append(events, ProximityEvent{
 				Type:   ProximityLeave,
 				UserA:  userID,
@@ -165,20 +233,81 @@ func (s *Space) collectProximityLeavesLocked(userID string, media string) []Prox
 }
 
 
-// GetUsers returns a slice of all users in the space except the given userID
+// GetUsers returns a slice of all users in the space except the given userID.
+// Users the excluded user is hidden from are left out too, since they must not
+// receive anything about them.
 func (s *Space) GetUsers(excludeUserID string) []*Client {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
+
+	var subject *Client
+	if excludeUserID != "" {
+		subject = s.Users[excludeUserID]
+	}
 	
 	users := make([]*Client, 0, len(s.Users))
 	for id, client := range s.Users {
-		if id != excludeUserID {
-			users = append(users, client)
+		if id == excludeUserID {
+			continue
+		}
+		if subject != nil && subject.HidesFrom(id) {
+			continue
+		}
+		users = append(users, client)
+	}
+	return users
+}
+
+// GetVisibleUsers returns all users in the space that viewerID is allowed to see
+func (s *Space) GetVisibleUsers(viewerID string) []*Client {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]*Client, 0, len(s.Users))
+	for _, client := range s.Users {
+		if client.HidesFrom(viewerID) {
+			continue
 		}
+		users = append(users, client)
 	}
 	return users
 }
 
+// Stats summarizes the space's occupancy and active meetings under its ID
+func (s *Space) Stats(id string, withUsers bool) messages.SpaceStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := messages.SpaceStats{SpaceID: id, UserCount: len(s.Users)}
+	for _, state := range s.MeetingStates {
+		if state.Status == MeetingStatusActive {
+			stats.ActiveMeetings++
+		}
+	}
+	if withUsers {
+		stats.UserIDs = make([]string, 0, len(s.Users))
+		for userID := range s.Users {
+			stats.UserIDs = append(stats.UserIDs, userID)
+		}
+		sort.Strings(stats.UserIDs)
+	}
+	return stats
+}
+
+// GetAudioNeighbors returns the clients currently within audio range of userID
+func (s *Space) GetAudioNeighbors(userID string) []*Client {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	neighbors := make([]*Client, 0)
+	for peerID := range s.Proximity[config.MediaAudio][userID] {
+		if client, ok := s.Users[peerID]; ok {
+			neighbors = append(neighbors, client)
+		}
+	}
+	return neighbors
+}
+
 // GetAllUsers returns all users in the space
 func (s *Space) GetAllUsers() []*Client {
 	s.mu.RLock()
@@ -191,6 +320,66 @@ func (s *Space) GetAllUsers() []*Client {
 	return users
 }
 
+// UserCount returns the number of users in the space
+func (s *Space) UserCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.Users)
+}
+
+// publishMeetingEnded announces that an active meeting ended for reason
+func (s *Space) publishMeetingEnded(state *MeetingState, reason string) {
+	s.Events.Publish(Event{
+		Type:      EventMeetingEnded,
+		SpaceID:   s.ID,
+		UserID:       state.UserA,
+		PeerID:       state.UserB,
+		Participants: state.participants(),
+		MeetingID:    state.MeetingID,
+		Reason:       reason,
+	})
+}
+
+// allowPromptLocked charges a prompt against the pair's long-term limit.
+// Returns false (without charging) once the pair has used up the current window.
+func (s *Space) allowPromptLocked(key string, now time.Time) bool {
+	limit := config.Current().MeetingPromptLimit
+	window := config.Current().MeetingPromptWindow
+	if limit <= 0 || window <= 0 {
+		return true
+	}
+
+	w, ok := s.PromptWindows[key]
+	if !ok || now.Sub(w.Start) >= window {
+		w = &PromptWindow{Start: now}
+		s.PromptWindows[key] = w
+	}
+	if w.Count >= limit {
+		return false
+	}
+	w.Count++
+	return true
+}
+
+// prunePromptWindowsLocked drops windows that have expired so the map doesn't grow without bound
+func (s *Space) prunePromptWindowsLocked(now time.Time) {
+	window := config.Current().MeetingPromptWindow
+	for key, w := range s.PromptWindows {
+		if now.Sub(w.Start) >= window {
+			delete(s.PromptWindows, key)
+		}
+	}
+}
+
+// hasRoomFor reports whether userID may join without taking the space past max users
+// (0 = unlimited). A user already in the space is replacing themselves, not adding one.
+func (s *Space) hasRoomFor(userID string, max int) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, present := s.Users[userID]
+	return max <= 0 || present || len(s.Users) < max
+}
+
 // IsEmpty returns true if the space has no users
 func (s *Space) IsEmpty() bool {
 	s.mu.RLock()
@@ -200,25 +389,39 @@ func (s *Space) IsEmpty() bool {
 
 // IsValidPosition checks if a position is within bounds
 func (s *Space) IsValidPosition(x, y float64) bool {
-	return x >= 0 && x < float64(s.Width) && y >= 0 && y < float64(s.Height)
+	c := s.Coords
+	return x >= c.OriginX && x < c.OriginX+float64(s.Width)*c.Scale &&
+		y >= c.OriginY && y < c.OriginY+float64(s.Height)*c.Scale
+}
+
+// ToClientCoords converts a point on the space's internal grid to client coordinates
+func (s *Space) ToClientCoords(x, y float64) (float64, float64) {
+	return s.Coords.OriginX + x*s.Coords.Scale, s.Coords.OriginY + y*s.Coords.Scale
 }
 
-// IsColliding checks if a position is occupied by a user or static element
+// IsColliding checks if an avatar at a position would overlap a user or static element
 // Returns true if colliding, false if free
 func (s *Space) IsColliding(x, y float64, excludeUserID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.isCollidingLocked(x, y, excludeUserID)
+}
+
+func (s *Space) isCollidingLocked(x, y float64, excludeUserID string) bool {
 	// Check bounds
 	if !s.IsValidPosition(x, y) {
 		return true
 	}
 
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	// Check static elements
-	// Using a simple "x,y" string key for now
-	key := posKey(x, y)
-	if s.Elements[key] {
-		return true
+	// Check static elements under the avatar's box; each occupies the unit cell at its key
+	x, y = snapCoord(x), snapCoord(y)
+	w, h := avatarSize()
+	for cx := math.Floor(x); cx < x+w; cx++ {
+		for cy := math.Floor(y); cy < y+h; cy++ {
+			if s.Elements[posKey(cx, cy)] {
+				return true
+			}
+		}
 	}
 
 	// Check other users
@@ -227,7 +430,7 @@ func (s *Space) IsColliding(x, y float64, excludeUserID string) bool {
 			continue
 		}
 		ux, uy := user.GetPosition()
-		if ux == x && uy == y {
+		if footprintsOverlap(x, y, ux, uy) {
 			return true
 		}
 	}
@@ -235,12 +438,72 @@ func (s *Space) IsColliding(x, y float64, excludeUserID string) bool {
 	return false
 }
 
+// snapScale is the resolution positions are snapped to before collision checks
+const snapScale = 1e6
+
+// snapCoord snaps v to a fixed fine grid so floating-point jitter (5.0 vs 5.0000001)
+// doesn't decide whether two avatars touch
+func snapCoord(v float64) float64 {
+	return math.Round(v*snapScale) / snapScale
+}
+
+// avatarSize returns the configured avatar collision box, at least 1x1
+func avatarSize() (w, h float64) {
+	w, h = config.Current().AvatarWidth, config.Current().AvatarHeight
+	if w <= 0 {
+		w = 1
+	}
+	if h <= 0 {
+		h = 1
+	}
+	return w, h
+}
+
+// footprintsOverlap reports whether avatars at (ax, ay) and (bx, by) overlap. Boxes that
+// only share an edge don't.
+func footprintsOverlap(ax, ay, bx, by float64) bool {
+	w, h := avatarSize()
+	return abs(snapCoord(ax)-snapCoord(bx)) < w && abs(snapCoord(ay)-snapCoord(by)) < h
+}
+
+// SweepPath walks the straight line from (fromX, fromY) to (toX, toY) in unit steps and
+// returns the furthest free point reached. clear is false if anything blocked the path.
+func (s *Space) SweepPath(fromX, fromY, toX, toY float64, excludeUserID string) (x, y float64, clear bool) {
+	dist := distance(fromX, fromY, toX, toY)
+	steps := int(math.Ceil(dist))
+	x, y = fromX, fromY
+
+	for i := 1; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		nx := NormalizeCoord(fromX + (toX-fromX)*t)
+		ny := NormalizeCoord(fromY + (toY-fromY)*t)
+		if nx == x && ny == y {
+			continue
+		}
+		if s.IsColliding(nx, ny, excludeUserID) {
+			return x, y, false
+		}
+		x, y = nx, ny
+	}
+	return x, y, true
+}
+
 // Helper to generate key for position map (rounds to nearest int)
 func posKey(x, y float64) string {
 	return fmt.Sprintf("%d,%d", int(x), int(y))
 }
 
 
+// NormalizeCoord rounds a client-supplied coordinate to the configured precision
+// so near-identical positions compare equal and payloads stay compact.
+func NormalizeCoord(v float64) float64 {
+	precision := config.Current().CoordinatePrecision
+	if precision <= 0 {
+		return v
+	}
+	return math.Round(v/precision) * precision
+}
+
 // IsValidMove checks if a movement is valid (at most 1 block in any direction)
 func IsValidMove(oldX, oldY, newX, newY float64) bool {
 	dx := abs(newX - oldX)
@@ -264,28 +527,74 @@ func dwellKey(u1, u2 string) string {
 	return u2 + ":" + u1
 }
 
+// SetDwellPaused freezes or resumes the dwell/meeting state machine for debugging.
+// On resume, dwell starts and meeting deadlines are shifted by the paused duration so
+// accumulated dwell is neither lost nor advanced. Returns false if nothing changed.
+func (s *Space) SetDwellPaused(paused bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dwellPaused == paused {
+		return false
+	}
+	s.dwellPaused = paused
+
+	if paused {
+		s.pausedAt = time.Now()
+		return true
+	}
+
+	frozen := time.Since(s.pausedAt)
+	for key, start := range s.VideoDwellStart {
+		s.VideoDwellStart[key] = start.Add(frozen)
+	}
+	for _, state := range s.MeetingStates {
+		state.ExpiresAt = state.ExpiresAt.Add(frozen)
+		if !state.CooldownUntil.IsZero() {
+			state.CooldownUntil = state.CooldownUntil.Add(frozen)
+		}
+	}
+	return true
+}
+
+// IsDwellPaused reports whether the dwell checker is paused for this space
+func (s *Space) IsDwellPaused() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.dwellPaused
+}
+
+// pairJitter returns a deterministic offset in [0, MeetingJitter) for a pair key, so pairs
+// that complete dwell (or cooldown) on the same tick are spread across later ticks
+func pairJitter(key string) time.Duration {
+	spread := config.Current().MeetingJitter
+	if spread <= 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return time.Duration(h.Sum64() % uint64(spread))
+}
+
 // CheckVideoDwellTimers checks all pending video dwell timers and emits MEETING PROMPTS directly via WebSocket.
 // This replaces the backend poller mechanism.
 func (s *Space) CheckVideoDwellTimers() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if s.dwellPaused {
+		return
+	}
+
 	now := time.Now()
 	toDelete := make([]string, 0)
+	busy := s.busyUsersLocked(now)
+	// edges joins free users whose dwell has completed; each connected component is prompted as one meeting
+	edges := make(map[string][]string)
+	edgeKeys := make(map[string][]string)
 
 	for key, dwellStart := range s.VideoDwellStart {
-		// Clean up expired or stale meetings logic is separate, 
-		// but here we check if we should TRIGGER a new meeting prompt.
-		
-		// Parse user IDs from key
-		var userA, userB string
-		for i := 0; i < len(key); i++ {
-			if key[i] == ':' {
-				userA = key[:i]
-				userB = key[i+1:]
-				break
-			}
-		}
+		userA, userB, _ := strings.Cut(key, ":")
 		if userA == "" || userB == "" {
 			toDelete = append(toDelete, key)
 			continue
@@ -303,88 +612,79 @@ func (s *Space) CheckVideoDwellTimers() {
 		xA, yA := clientA.GetPosition()
 		xB, yB := clientB.GetPosition()
 		dist := distance(xA, yA, xB, yB)
-		if dist > 120 { 
+		if dist > leaveRadius(config.Current().VideoRadius) {
 			// Dwell broken (moved away)
 			toDelete = append(toDelete, key)
 			continue
 		}
 
-		// Check if checking for dwell timer completion
-		if now.Sub(dwellStart) >= VideoDwellDuration {
-			// DWELL COMPLETE!
-			
-			// Check if already in a meeting or cooldown
-			meetingState, hasMeeting := s.MeetingStates[key]
-			
-			if hasMeeting {
-				if meetingState.Status == MeetingStatusActive {
-					// Already happy meeting, do nothing
-					continue 
-				}
-				if now.Before(meetingState.CooldownUntil) {
-					// In cooldown, ignore
-					continue
-				}
-				if meetingState.ExpiresAt.After(now) && meetingState.RequestID != "" {
-					// Prompt pending, ignore
-					continue
-				}
-			}
+		if now.Sub(dwellStart) < VideoDwellDuration+pairJitter(key) {
+			continue
+		}
 
-			// Create new meeting prompt
-			requestID := fmt.Sprintf("%d-%s-%s", now.UnixNano(), userA, userB)
-			meetingID := fmt.Sprintf("%s-%s-%d", userA, userB, now.Unix())
-			expiresAt := now.Add(MeetingTimeout)
-			
-			newState := &MeetingState{
-				MeetingID: meetingID,
-				RequestID: requestID,
-				UserA:     userA,
-				UserB:     userB,
-				ExpiresAt: expiresAt,
-				Status:    MeetingStatusPrompted,
+		// DWELL COMPLETE!
+		meetingA, meetingB := busy[userA], busy[userB]
+		switch {
+		case meetingA != "" && meetingA == meetingB:
+			// Already meeting (or being prompted) together, do nothing
+			continue
+		case meetingA != "" && meetingB != "":
+			// Each is busy with someone else
+			continue
+		case meetingA != "" || meetingB != "":
+			// Walking up to an active meeting joins it instead of prompting afresh
+			newcomer, joinKey := userB, meetingA
+			if meetingA == "" {
+				newcomer, joinKey = userA, meetingB
 			}
-			s.MeetingStates[key] = newState
-
-			log.Printf("Space %s: Sending meeting prompt to %s and %s (reqID: %s)", s.ID, userA, userB, requestID)
-
-			// Send WebSocket events
-			promptPayload := map[string]interface{}{
-				"type": "meeting-prompt",
-				"payload": map[string]interface{}{
-					"requestId": requestID,
-					"meetingId": meetingID,
-					"expiresAt": expiresAt.UnixMilli(),
-				},
+			state := s.MeetingStates[joinKey]
+			if state.Status != MeetingStatusActive || s.coolingDownLocked(key, now) {
+				continue
 			}
-
-			// Send to A (peer is B)
-			payloadA := promptPayload["payload"].(map[string]interface{})
-			payloadA["peerId"] = userB
-			clientA.SendJSON(promptPayload)
-
-			// Send to B (peer is A)
-			payloadB := make(map[string]interface{})
-			for k, v := range payloadA { payloadB[k] = v } // shallow copy
-			payloadB["peerId"] = userA
-			promptPayload["payload"] = payloadB
-			clientB.SendJSON(promptPayload)
-			
-			// We remove the dwell start so it doesn't trigger again immediately
-			// (wait for cooldown or next interaction)
+			s.joinMeetingLocked(joinKey, state, newcomer)
+			busy = s.busyUsersLocked(now)
 			toDelete = append(toDelete, key)
+			continue
+		}
+
+		if s.coolingDownLocked(key, now) {
+			// In cooldown, ignore
+			continue
+		}
+		edges[userA] = append(edges[userA], userB)
+		edges[userB] = append(edges[userB], userA)
+		edgeKeys[userA] = append(edgeKeys[userA], key)
+	}
+
+	for _, participants := range meetingComponents(edges) {
+		key := meetingKey(participants)
+		if len(participants) > 2 && s.coolingDownLocked(key, now) {
+			continue
+		}
+		if !s.allowPromptLocked(key, now) {
+			// Pair or group has been prompted too often lately; wait for the window to reset
+			continue
+		}
+		s.promptMeetingLocked(key, participants, now)
+
+		// We remove the dwell starts so they don't trigger again immediately
+		// (wait for cooldown or next interaction)
+		for _, id := range participants {
+			toDelete = append(toDelete, edgeKeys[id]...)
 		}
 	}
 
 	for _, key := range toDelete {
 		delete(s.VideoDwellStart, key)
 	}
+	s.prunePromptWindowsLocked(now)
 
 	// Also cleanup expired meeting states
 	for key, state := range s.MeetingStates {
 		if state.Status != MeetingStatusActive && state.ExpiresAt.Before(now) && state.CooldownUntil.IsZero() {
 			// Expired prompt, no cooldown set? Set cooldown
-			state.CooldownUntil = now.Add(MeetingCooldown)
+			meetingFunnel.PromptsExpired.Add(1)
+			state.CooldownUntil = now.Add(MeetingCooldown + pairJitter(key))
 			state.RequestID = ""
 		}
 		// If cooled down and inactive, can remove state entirely to allow fresh dwell
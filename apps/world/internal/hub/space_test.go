@@ -1,6 +1,13 @@
 package hub
 
-import "testing"
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"world/internal/config"
+	"world/internal/messages"
+)
 
 func TestIsValidMove(t *testing.T) {
 	tests := []struct {
@@ -82,40 +89,385 @@ func TestSpaceIsValidPosition(t *testing.T) {
 }
 
 func TestSpaceIsColliding(t *testing.T) {
+	setupTestConfig(t)
 	space := NewSpace("test-space", 10, 10)
 	
 	// Add a static element
 	space.Elements["5,5"] = true
 
-	// Add a user
+	// Add two users
 	user := &Client{UserID: "user1", X: 2, Y: 2}
 	space.AddUser(user)
+	space.AddUser(&Client{UserID: "user2", X: 7, Y: 7})
 
 	tests := []struct {
 		name     string
 		x        float64
 		y        float64
-		expected bool // true = collision
+		exclude  string // user whose own position is ignored
+		expected bool   // true = collision
 	}{
 		// No collision
-		{"empty spot", 0, 0, false},
-		{"near element", 5, 4, false},
-		{"near user", 2, 3, false},
+		{"empty spot", 0, 0, "", false},
+		{"near element", 5, 4, "", false},
+		{"near user", 2, 3, "", false},
+		{"own tile", 2, 2, "user1", false},
 
 		// Collisions
-		{"out of bounds negative", -1, 0, true},
-		{"out of bounds large", 10, 10, true}, // 0-9 is valid
-		{"static element", 5, 5, true},
-		{"user collision", 2, 2, true},
+		{"out of bounds negative", -1, 0, "", true},
+		{"out of bounds large", 10, 10, "", true}, // 0-9 is valid
+		{"static element", 5, 5, "", true},
+		{"static element when excluding", 5, 5, "user1", true},
+		{"user collision", 2, 2, "", true},
+		{"another user's tile", 2, 2, "user2", true},
+		{"other user moving onto own tile", 7, 7, "user1", true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := space.IsColliding(tt.x, tt.y, "")
+			result := space.IsColliding(tt.x, tt.y, tt.exclude)
 			if result != tt.expected {
-				t.Errorf("IsColliding(%f, %f) = %v; want %v",
-					tt.x, tt.y, result, tt.expected)
+				t.Errorf("IsColliding(%f, %f, %q) = %v; want %v",
+					tt.x, tt.y, tt.exclude, result, tt.expected)
 			}
 		})
 	}
 }
+
+func TestNormalizeCoord(t *testing.T) {
+	setupTestConfig(t)
+
+	tests := []struct {
+		precision float64
+		in        float64
+		expected  float64
+	}{
+		{1, 5.4, 5},
+		{1, 5.5, 6},
+		{1, -2.6, -3},
+		{0.5, 5.2, 5},
+		{0.5, 5.3, 5.5},
+		{0, 5.123456, 5.123456},
+	}
+
+	for _, tt := range tests {
+		updateTestConfig(func(cfg *config.Config) { cfg.CoordinatePrecision = tt.precision })
+		if got := NormalizeCoord(tt.in); got != tt.expected {
+			t.Errorf("NormalizeCoord(%v) with precision %v = %v; want %v", tt.in, tt.precision, got, tt.expected)
+		}
+	}
+}
+
+func TestMovementNormalizationEnablesCollision(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.CoordinatePrecision = 1 })
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	addTestClient(h, space, "a", 10, 10)
+	b := addTestClient(h, space, "b", 15, 10)
+
+	// 10.0000001 would never match 10 exactly without normalization
+	h.handleMovement(b, messages.IncomingPayload{X: 10.0000001, Y: 9.9999})
+	if x, y := b.GetPosition(); x != 15 || y != 10 {
+		t.Errorf("b moved onto a's tile: (%v, %v)", x, y)
+	}
+
+	h.handleMovement(b, messages.IncomingPayload{X: 12.2, Y: 10.4})
+	if x, y := b.GetPosition(); x != 12 || y != 10 {
+		t.Errorf("position = (%v, %v), want normalized (12, 10)", x, y)
+	}
+}
+
+func TestDwellPauseAndResume(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	admin := addTestClient(h, space, "a", 100, 100)
+	admin.Role = "admin"
+	b := addTestClient(h, space, "b", 150, 100)
+
+	key := dwellKey("a", "b")
+	space.VideoDwellStart[key] = time.Now().Add(-2 * time.Second) // 2s of the 3s dwell accumulated
+
+	h.ProcessMessage(b, []byte(`{"type":"pause-dwell","payload":{"enabled":true}}`))
+	if space.IsDwellPaused() {
+		t.Fatal("non-admin paused the dwell checker")
+	}
+
+	h.handlePauseDwell(admin, messages.IncomingPayload{Enabled: true})
+	if !space.IsDwellPaused() {
+		t.Fatal("admin could not pause the dwell checker")
+	}
+
+	// Even with the dwell long complete, nothing fires while paused
+	space.pausedAt = time.Now().Add(-time.Minute)
+	space.VideoDwellStart[key] = space.pausedAt.Add(-2 * time.Second)
+	space.CheckVideoDwellTimers()
+	if len(space.MeetingStates) != 0 {
+		t.Error("meeting prompt fired while paused")
+	}
+	if _, ok := space.VideoDwellStart[key]; !ok {
+		t.Fatal("dwell start lost while paused")
+	}
+
+	// Resuming keeps the 2s accumulated before the pause; the paused minute doesn't count
+	h.handlePauseDwell(admin, messages.IncomingPayload{Enabled: false})
+	if accumulated := time.Since(space.VideoDwellStart[key]); accumulated < 2*time.Second || accumulated > 3*time.Second {
+		t.Fatalf("accumulated dwell after resume = %v, want ~2s", accumulated)
+	}
+	space.CheckVideoDwellTimers()
+	if len(space.MeetingStates) != 0 {
+		t.Error("paused time counted toward the dwell")
+	}
+
+	// Once the remaining dwell elapses the prompt fires
+	space.VideoDwellStart[key] = space.VideoDwellStart[key].Add(-2 * time.Second)
+	space.CheckVideoDwellTimers()
+	if _, ok := space.MeetingStates[key]; !ok {
+		t.Error("prompt did not fire after resume")
+	}
+	if n := len(messagesOfType(drainMessages(t, b), messages.TypeMeetingPrompt)); n != 1 {
+		t.Errorf("b got %d meeting prompts, want 1", n)
+	}
+}
+
+func TestMeetingPromptJitterSpreadsPairs(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.MeetingJitter = 2 * time.Second })
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+
+	// Ten pairs, far enough apart to only see their own partner, all entering at once
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		y := 40 + float64(i)*90
+		a := addTestClient(h, space, fmt.Sprintf("a%d", i), 100, y)
+		b := addTestClient(h, space, fmt.Sprintf("b%d", i), 150, y)
+		space.VideoDwellStart[dwellKey(a.UserID, b.UserID)] = start
+	}
+
+	// Replay the 500ms checker ticks by moving the dwell starts back in time
+	ticksWithPrompts := 0
+	prompted := 0
+	for tick := 0; tick <= 12 && prompted < 10; tick++ {
+		for key := range space.VideoDwellStart {
+			space.VideoDwellStart[key] = start.Add(-VideoDwellDuration - time.Duration(tick)*500*time.Millisecond)
+		}
+		before := len(space.MeetingStates)
+		space.CheckVideoDwellTimers()
+		if n := len(space.MeetingStates) - before; n > 0 {
+			ticksWithPrompts++
+			prompted += n
+		}
+	}
+
+	if prompted != 10 {
+		t.Fatalf("prompted %d pairs, want 10", prompted)
+	}
+	if ticksWithPrompts < 2 {
+		t.Errorf("all prompts fired on %d tick(s), want them spread out", ticksWithPrompts)
+	}
+
+	// Jitter is stable per pair
+	if pairJitter("a0:b0") != pairJitter("a0:b0") {
+		t.Error("pair jitter is not deterministic")
+	}
+}
+
+func TestMeetingPromptLimitPerPair(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) {
+		cfg.MeetingPromptLimit = 3
+		cfg.MeetingPromptWindow = 10 * time.Minute
+	})
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	a := addTestClient(h, space, "a", 100, 100)
+	addTestClient(h, space, "b", 150, 100)
+	key := dwellKey("a", "b")
+
+	// Each round the pair dwells again after the previous prompt lapsed and cooled down
+	prompt := func() int {
+		delete(space.MeetingStates, key)
+		space.VideoDwellStart[key] = time.Now().Add(-time.Minute)
+		space.CheckVideoDwellTimers()
+		return len(messagesOfType(drainMessages(t, a), "meeting-prompt"))
+	}
+
+	for i := 0; i < 3; i++ {
+		if got := prompt(); got != 1 {
+			t.Fatalf("round %d: got %d prompts, want 1", i, got)
+		}
+	}
+	if got := prompt(); got != 0 {
+		t.Fatalf("got %d prompts after hitting the limit, want 0", got)
+	}
+
+	// Once the window has passed, the pair can be prompted again
+	space.PromptWindows[key].Start = time.Now().Add(-11 * time.Minute)
+	if got := prompt(); got != 1 {
+		t.Errorf("got %d prompts after the window reset, want 1", got)
+	}
+}
+
+func TestDwellCleanupMatchesWholeUserIDs(t *testing.T) {
+	setupTestConfig(t)
+
+	h := NewHub()
+	space := newTestSpace(h, "s1")
+	for _, id := range []string{"al", "bob", "bobby", "carl"} {
+		addTestClient(h, space, id, 100, 100)
+	}
+	now := time.Now()
+	for _, pair := range [][2]string{{"bob", "bobby"}, {"al", "bob"}, {"al", "bobby"}, {"bobby", "carl"}} {
+		space.VideoDwellStart[dwellKey(pair[0], pair[1])] = now
+	}
+
+	space.mu.Lock()
+	space.cleanupMeetingsForUserLocked("bob")
+	space.mu.Unlock()
+
+	for _, key := range []string{"al:bob", "bob:bobby"} {
+		if _, ok := space.VideoDwellStart[key]; ok {
+			t.Errorf("dwell timer %s involving bob was kept", key)
+		}
+	}
+	for _, key := range []string{"al:bobby", "bobby:carl"} {
+		if _, ok := space.VideoDwellStart[key]; !ok {
+			t.Errorf("unrelated dwell timer %s was removed", key)
+		}
+	}
+}
+
+func TestSpaceCoordinateSystem(t *testing.T) {
+	// Center origin at double scale: the 100x200 grid spans [-100, 100) x [-200, 200)
+	space := NewSpace("test-space", 100, 200)
+	space.Coords = config.SpaceCoordinates{OriginX: -100, OriginY: -200, Scale: 2}
+
+	tests := []struct {
+		name     string
+		x        float64
+		y        float64
+		expected bool
+	}{
+		{"origin corner", -100, -200, true},
+		{"center", 0, 0, true},
+		{"max valid", 199 - 100, 399 - 200, true},
+		{"x at edge", 100, 0, false},
+		{"y at edge", 0, 200, false},
+		{"left of origin", -101, 0, false},
+		{"above origin", 0, -201, false},
+	}
+	for _, tt := range tests {
+		if got := space.IsValidPosition(tt.x, tt.y); got != tt.expected {
+			t.Errorf("%s: IsValidPosition(%v, %v) = %v, want %v", tt.name, tt.x, tt.y, got, tt.expected)
+		}
+	}
+
+	if x, y := space.ToClientCoords(50, 100); x != 0 || y != 0 {
+		t.Errorf("ToClientCoords(50, 100) = (%v, %v), want (0, 0)", x, y)
+	}
+}
+
+func TestJoinSpawnsInSpaceCoordinates(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) {
+		cfg.SpaceCoords = map[string]config.SpaceCoordinates{
+			"s1": {OriginX: -640, OriginY: -480, Scale: 0.5},
+		}
+	})
+
+	h := NewHub()
+	c := joinTestClient(t, h, "s1", "u1")
+
+	// The default spawn area (705, 500) ± 50 maps to (-287.5, -230) ± 25
+	x, y := c.GetPosition()
+	if x < -313 || x > -262 || y < -255 || y > -205 {
+		t.Errorf("spawned at (%v, %v), want near (-287.5, -230)", x, y)
+	}
+	if !h.Spaces["s1"].IsValidPosition(x, y) {
+		t.Errorf("spawn (%v, %v) is out of bounds", x, y)
+	}
+}
+
+func TestAvatarFootprintCollision(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) {
+		cfg.AvatarWidth = 2
+		cfg.AvatarHeight = 2
+	})
+
+	space := NewSpace("test-space", 100, 100)
+	space.Elements[posKey(50, 50)] = true
+	space.AddUser(&Client{UserID: "user1", X: 10, Y: 10})
+
+	tests := []struct {
+		name     string
+		x, y     float64
+		expected bool
+	}{
+		{"same spot", 10, 10, true},
+		{"overlapping boxes", 11, 11, true},
+		{"overlapping from above left", 8.5, 9, true},
+		{"adjacent right", 12, 10, false},
+		{"adjacent below", 10, 12, false},
+		{"adjacent left", 8, 10, false},
+		{"diagonal corner touch", 12, 12, false},
+		{"box covers element", 49, 49, true},
+		{"box partly over element", 48.5, 50, true},
+		{"box next to element", 48, 50, false},
+	}
+	for _, tt := range tests {
+		if got := space.IsColliding(tt.x, tt.y, ""); got != tt.expected {
+			t.Errorf("%s: IsColliding(%v, %v) = %v, want %v", tt.name, tt.x, tt.y, got, tt.expected)
+		}
+	}
+}
+
+func TestFootprintFloatJitter(t *testing.T) {
+	setupTestConfig(t)
+
+	space := NewSpace("test-space", 100, 100)
+	space.AddUser(&Client{UserID: "user1", X: 5, Y: 5})
+
+	// A hair off the same spot is still the same spot
+	if !space.IsColliding(5.0000001, 5, "") {
+		t.Error("5.0000001 did not collide with an avatar at 5.0")
+	}
+	// A hair short of the neighbouring spot is the neighbouring spot, not an overlap
+	if space.IsColliding(5.9999999, 5, "") || space.IsColliding(4.0000001, 5, "") {
+		t.Error("jitter next to an adjacent spot counted as an overlap")
+	}
+	if !footprintsOverlap(5, 5, 5.0000001, 4.9999999) {
+		t.Error("footprintsOverlap missed jittered positions")
+	}
+}
+
+func TestResolveOverlapsUsesFootprints(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) {
+		cfg.AvatarWidth = 2
+		cfg.AvatarHeight = 2
+	})
+
+	space := NewSpace("test-space", 1280, 960)
+	space.AddUser(&Client{UserID: "a", X: 100, Y: 100})
+	space.AddUser(&Client{UserID: "b", X: 101, Y: 100})
+	space.AddUser(&Client{UserID: "c", X: 102, Y: 100})
+
+	moved := space.ResolveOverlaps()
+	if len(moved) != 1 || moved[0].UserID != "b" {
+		t.Fatalf("moved %v, want just b", moved)
+	}
+	bx, by := moved[0].GetPosition()
+	for _, id := range []string{"a", "c"} {
+		x, y := space.Users[id].GetPosition()
+		if footprintsOverlap(bx, by, x, y) {
+			t.Errorf("b at (%v, %v) still overlaps %s", bx, by, id)
+		}
+	}
+}
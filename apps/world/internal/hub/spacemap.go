@@ -0,0 +1,159 @@
+package hub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxMapElements caps how many blocked points one map may expand to
+const maxMapElements = 1 << 20
+
+// SpaceMap is a space's static collision layout, read from <MapDir>/<spaceID>.json.
+// Coordinates are client coordinates, like user positions.
+type SpaceMap struct {
+	// Obstacles are blocked rectangles (walls, furniture); every whole point inside is blocked
+	Obstacles []MapRect `json:"obstacles"`
+	// Tiles are single blocked points
+	Tiles []MapPoint `json:"tiles"`
+	// SpawnZones are where new arrivals are placed (see Space.spawnZone)
+	SpawnZones []SpawnZone `json:"spawnZones"`
+	// Portals are doors a user standing in can teleport through
+	Portals []Portal `json:"portals"`
+}
+
+// MapRect is a rectangle with its top-left corner at (X, Y)
+type MapRect struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// Contains reports whether (x, y) lies in the rectangle; like obstacles, the right and
+// bottom edges are outside
+func (r MapRect) Contains(x, y float64) bool {
+	return x >= r.X && x < r.X+r.Width && y >= r.Y && y < r.Y+r.Height
+}
+
+// SpawnZone is a named rectangle users can spawn in
+type SpawnZone struct {
+	Name string `json:"name"`
+	MapRect
+}
+
+// Portal is a door: a user stepping into its rectangle is moved to To, in ToSpace if
+// set and otherwise in the same space
+type Portal struct {
+	Name string `json:"name"`
+	MapRect
+	To      MapPoint `json:"to"`
+	ToSpace string   `json:"toSpace,omitempty"`
+}
+
+// MapPoint is a single point
+type MapPoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Elements expands the map into Space.Elements keys
+func (m *SpaceMap) Elements() (map[string]bool, error) {
+	elements := make(map[string]bool)
+	for i, r := range m.Obstacles {
+		if r.Width <= 0 || r.Height <= 0 {
+			return nil, fmt.Errorf("obstacle %d: width and height must be positive", i)
+		}
+		x0, x1 := int(math.Floor(r.X)), int(math.Ceil(r.X+r.Width))
+		y0, y1 := int(math.Floor(r.Y)), int(math.Ceil(r.Y+r.Height))
+		if (x1-x0)*(y1-y0) > maxMapElements-len(elements) {
+			return nil, fmt.Errorf("obstacle %d: map expands to more than %d elements", i, maxMapElements)
+		}
+		for x := x0; x < x1; x++ {
+			for y := y0; y < y1; y++ {
+				elements[posKey(float64(x), float64(y))] = true
+			}
+		}
+	}
+	for _, t := range m.Tiles {
+		elements[posKey(math.Floor(t.X), math.Floor(t.Y))] = true
+	}
+	return elements, nil
+}
+
+// validateAreas checks every spawn zone and portal has an area
+func (m *SpaceMap) validateAreas() error {
+	for i, z := range m.SpawnZones {
+		if z.Width <= 0 || z.Height <= 0 {
+			return fmt.Errorf("spawn zone %d (%q): width and height must be positive", i, z.Name)
+		}
+	}
+	for i, p := range m.Portals {
+		if p.Width <= 0 || p.Height <= 0 {
+			return fmt.Errorf("portal %d (%q): width and height must be positive", i, p.Name)
+		}
+	}
+	return nil
+}
+
+// LoadSpaceMap reads the map for spaceID from dir. A space without a map file (or an
+// ID that can't name one) gets an empty map.
+func LoadSpaceMap(dir, spaceID string) (*SpaceMap, error) {
+	if dir == "" || spaceID == "" || spaceID != filepath.Base(spaceID) || strings.HasPrefix(spaceID, ".") {
+		return &SpaceMap{}, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, spaceID+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return &SpaceMap{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var m SpaceMap
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	if err := m.validateAreas(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// LoadSpaceElements reads the collision map for spaceID from dir. A space without
+// a map file (or an ID that can't name one) has no elements.
+func LoadSpaceElements(dir, spaceID string) (map[string]bool, error) {
+	m, err := LoadSpaceMap(dir, spaceID)
+	if err != nil {
+		return nil, err
+	}
+	return m.Elements()
+}
+
+// applySpaceMap loads a new space's map into it. A broken map is logged and the
+// space starts without obstacles, spawn zones or portals rather than refusing the join.
+func applySpaceMap(space *Space, dir string) {
+	m, err := LoadSpaceMap(dir, space.ID)
+	var elements map[string]bool
+	if err == nil {
+		elements, err = m.Elements()
+	}
+	if err != nil {
+		log.Printf("WARNING: ignoring collision map for space %s: %v", space.ID, err)
+		space.Elements = map[string]bool{}
+		return
+	}
+	if len(elements) > 0 {
+		log.Printf("Loaded %d collision elements for space %s", len(elements), space.ID)
+	}
+	if len(m.SpawnZones) > 0 || len(m.Portals) > 0 {
+		log.Printf("Loaded %d spawn zones and %d portals for space %s", len(m.SpawnZones), len(m.Portals), space.ID)
+	}
+	space.Elements, space.SpawnZones, space.Portals = elements, m.SpawnZones, m.Portals
+}
@@ -0,0 +1,133 @@
+package hub
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"world/internal/config"
+)
+
+// writeMap writes a map file for spaceID into dir
+func writeMap(t *testing.T, dir, spaceID, body string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, spaceID+".json"), []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJoinLoadsSpaceMap(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.MapDir = t.TempDir() })
+	writeMap(t, config.Current().MapDir, "office", `{
+		"obstacles": [{"x": 100, "y": 200, "width": 50, "height": 10}],
+		"tiles": [{"x": 400.6, "y": 300}]
+	}`)
+	h := NewHub()
+
+	joinTestClient(t, h, "office", "a")
+	space := h.Spaces["office"]
+
+	tests := []struct {
+		name   string
+		x, y   float64
+		expect bool
+	}{
+		{"wall corner", 100, 200, true},
+		{"inside wall", 125, 205, true},
+		{"far wall corner", 149, 209, true},
+		{"right of wall", 150, 205, false},
+		{"below wall", 125, 210, false},
+		{"tile", 400, 300, true},
+		{"open floor", 600, 600, false},
+	}
+	for _, tt := range tests {
+		if got := space.IsColliding(tt.x, tt.y, "a"); got != tt.expect {
+			t.Errorf("%s: IsColliding(%v, %v) = %v, want %v", tt.name, tt.x, tt.y, got, tt.expect)
+		}
+	}
+
+	// Moving into the wall is rejected like any other collision
+	x, y, clear := space.SweepPath(125, 150, 125, 250, "a")
+	if clear || y >= 200 || x != 125 {
+		t.Errorf("sweep through wall stopped at (%v, %v), clear = %v", x, y, clear)
+	}
+}
+
+func TestSpaceWithoutMapHasNoElements(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.MapDir = t.TempDir() })
+	writeMap(t, config.Current().MapDir, "broken", `{"obstacles": [{"x": 1, "y": 1, "width": 0, "height": 5}]}`)
+	h := NewHub()
+
+	joinTestClient(t, h, "plain", "a")
+	joinTestClient(t, h, "broken", "b")
+	for _, id := range []string{"plain", "broken"} {
+		if n := len(h.Spaces[id].Elements); n != 0 {
+			t.Errorf("space %s has %d elements, want none", id, n)
+		}
+	}
+}
+
+func TestLoadSpaceElementsIgnoresPathsInSpaceID(t *testing.T) {
+	dir := t.TempDir()
+	writeMap(t, dir, "secret", `{"tiles": [{"x": 1, "y": 1}]}`)
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"../secret", "sub/../secret", ".."} {
+		elements, err := LoadSpaceElements(sub, id)
+		if err != nil || len(elements) != 0 {
+			t.Errorf("LoadSpaceElements(%q) = %v, %v; want no elements", id, elements, err)
+		}
+	}
+}
+
+func TestJoinSpawnsInsideMapSpawnZone(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.MapDir = t.TempDir() })
+	writeMap(t, config.Current().MapDir, "hall", `{
+		"obstacles": [{"x": 200, "y": 100, "width": 10, "height": 5}],
+		"spawnZones": [
+			{"name": "stage", "x": 900, "y": 700, "width": 40, "height": 40},
+			{"name": "default", "x": 200, "y": 100, "width": 20, "height": 10}
+		]
+	}`)
+	h := NewHub()
+	zone := MapRect{X: 200, Y: 100, Width: 20, Height: 10}
+
+	// The wall covers part of the zone, so random picks often collide and must retry
+	for i := 0; i < 30; i++ {
+		userID := fmt.Sprintf("u%d", i)
+		c := joinTestClient(t, h, "hall", userID)
+		x, y := c.GetPosition()
+		if !zone.Contains(x, y) {
+			t.Fatalf("%s spawned at (%v, %v), outside the default zone", userID, x, y)
+		}
+		if h.Spaces["hall"].IsColliding(x, y, userID) {
+			t.Fatalf("%s spawned on a blocked tile at (%v, %v)", userID, x, y)
+		}
+	}
+}
+
+func TestSpawnZoneValidation(t *testing.T) {
+	setupTestConfig(t)
+	updateTestConfig(func(cfg *config.Config) { cfg.MapDir = t.TempDir() })
+	writeMap(t, config.Current().MapDir, "bad", `{
+		"tiles": [{"x": 1, "y": 1}],
+		"spawnZones": [{"name": "flat", "x": 10, "y": 10, "width": 5, "height": 0}]
+	}`)
+	h := NewHub()
+
+	joinTestClient(t, h, "bad", "a")
+	space := h.Spaces["bad"]
+	if len(space.Elements) != 0 || len(space.SpawnZones) != 0 {
+		t.Errorf("invalid map applied: %d elements, %d spawn zones", len(space.Elements), len(space.SpawnZones))
+	}
+	if zone := space.spawnZone(); zone.Name != "default" || zone.X != 655 || zone.Y != 450 || zone.Width != 101 {
+		t.Errorf("fallback zone = %+v, want 101x101 around (705, 500)", zone)
+	}
+}
@@ -0,0 +1,59 @@
+package hub
+
+import (
+	"sort"
+
+	"world/internal/auth"
+)
+
+// SpaceStore answers membership questions the hub can't decide from the token alone.
+// A hub without a store runs in public mode: every space is open to every user.
+type SpaceStore interface {
+	CanJoinSpace(userID, spaceID string) (bool, error)
+}
+
+// AllowedSpaces returns the known spaces userID may join, sorted by ID
+func (h *Hub) AllowedSpaces(userID string) ([]string, error) {
+	h.mu.RLock()
+	store := h.Store
+	ids := make([]string, 0, len(h.Spaces))
+	for id := range h.Spaces {
+		ids = append(ids, id)
+	}
+	h.mu.RUnlock()
+
+	sort.Strings(ids)
+	if store == nil {
+		return ids, nil
+	}
+
+	// The store may hit the backend, so don't hold the hub lock while asking it
+	allowed := make([]string, 0, len(ids))
+	for _, id := range ids {
+		ok, err := store.CanJoinSpace(userID, id)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			allowed = append(allowed, id)
+		}
+	}
+	return allowed, nil
+}
+
+// authorizeJoin decides whether the token's user may join spaceID: the token's
+// spaces claim is checked first, then the store if the hub has one. An error means
+// the store couldn't be asked, not that the user was refused.
+func (h *Hub) authorizeJoin(claims *auth.Claims, spaceID string) (bool, error) {
+	if !claims.AllowsSpace(spaceID) {
+		return false, nil
+	}
+
+	h.mu.RLock()
+	store := h.Store
+	h.mu.RUnlock()
+	if store == nil {
+		return true, nil
+	}
+	return store.CanJoinSpace(claims.UserID, spaceID)
+}
@@ -0,0 +1,194 @@
+package hub
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"world/internal/auth"
+	"world/internal/config"
+	"world/internal/messages"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// memberStore authorizes users for an explicit set of spaces
+type memberStore struct {
+	members map[string][]string
+	err     error
+}
+
+func (s *memberStore) CanJoinSpace(userID, spaceID string) (bool, error) {
+	if s.err != nil {
+		return false, s.err
+	}
+	for _, id := range s.members[userID] {
+		if id == spaceID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestAllowedSpacesPublicMode(t *testing.T) {
+	h := NewHub()
+	newTestSpace(h, "b")
+	newTestSpace(h, "a")
+
+	got, err := h.AllowedSpaces("u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("allowed = %v, want %v", got, want)
+	}
+}
+
+func TestAllowedSpacesFiltersByStore(t *testing.T) {
+	h := NewHub()
+	newTestSpace(h, "a")
+	newTestSpace(h, "b")
+	newTestSpace(h, "c")
+	h.Store = &memberStore{members: map[string][]string{"u1": {"a", "c", "unknown"}}}
+
+	got, err := h.AllowedSpaces("u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("allowed = %v, want %v", got, want)
+	}
+
+	got, _ = h.AllowedSpaces("stranger")
+	if len(got) != 0 {
+		t.Errorf("stranger allowed = %v, want none", got)
+	}
+}
+
+func TestServeAllowedSpaces(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	newTestSpace(h, "a")
+	newTestSpace(h, "b")
+	h.Store = &memberStore{members: map[string][]string{"u1": {"b"}}}
+
+	req := httptest.NewRequest(http.MethodGet, "/spaces/allowed", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken(t, "u1", "user"))
+	rec := httptest.NewRecorder()
+	h.ServeAllowedSpaces(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var body messages.AllowedSpacesPayload
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"b"}; !reflect.DeepEqual(body.Spaces, want) {
+		t.Errorf("spaces = %v, want %v", body.Spaces, want)
+	}
+
+	// Missing token
+	rec = httptest.NewRecorder()
+	h.ServeAllowedSpaces(rec, httptest.NewRequest(http.MethodGet, "/spaces/allowed", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status without token = %d, want 401", rec.Code)
+	}
+
+	// Store failure
+	h.Store = &memberStore{err: errors.New("backend down")}
+	rec = httptest.NewRecorder()
+	h.ServeAllowedSpaces(rec, req)
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("status on store error = %d, want 502", rec.Code)
+	}
+}
+
+// joinWithSpacesClaim joins userID to spaceID with a token restricted to spaces
+func joinWithSpacesClaim(t *testing.T, h *Hub, spaceID, userID string, spaces []string) *Client {
+	t.Helper()
+	claims := &auth.Claims{
+		UserID: userID,
+		Role:   "user",
+		Spaces: spaces,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(config.Current().JWTSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{Hub: h, Send: make(chan []byte, 256)}
+	h.Clients[c] = true
+	h.handleJoin(c, messages.IncomingPayload{SpaceID: spaceID, Token: token})
+	return c
+}
+
+// joinErrorCode returns the code of the join-error c received, or "" if it joined
+func joinErrorCode(t *testing.T, c *Client) string {
+	t.Helper()
+	msgs := drainMessages(t, c)
+	errs := messagesOfType(msgs, messages.TypeJoinError)
+	if len(errs) == 0 {
+		if len(messagesOfType(msgs, messages.TypeSpaceJoined)) != 1 {
+			t.Fatalf("got neither space-joined nor join-error: %+v", msgs)
+		}
+		return ""
+	}
+	var payload messages.JoinErrorPayload
+	if err := json.Unmarshal(errs[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	return payload.Code
+}
+
+func TestJoinChecksSpacesClaim(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+
+	if code := joinErrorCode(t, joinWithSpacesClaim(t, h, "team", "a", []string{"lobby", "team"})); code != "" {
+		t.Errorf("authorized join rejected with %q", code)
+	}
+	if code := joinErrorCode(t, joinWithSpacesClaim(t, h, "secret", "b", []string{"lobby"})); code != messages.JoinErrorNotAuthorized {
+		t.Errorf("unauthorized join code = %q, want %q", code, messages.JoinErrorNotAuthorized)
+	}
+	if _, exists := h.Spaces["secret"]; exists {
+		t.Error("unauthorized join created the space")
+	}
+	// Tokens without the claim aren't restricted
+	if code := joinErrorCode(t, joinTestClient(t, h, "secret", "c")); code != "" {
+		t.Errorf("unrestricted join rejected with %q", code)
+	}
+}
+
+func TestJoinChecksStore(t *testing.T) {
+	setupTestConfig(t)
+	h := NewHub()
+	h.Store = &memberStore{members: map[string][]string{"a": {"team"}}}
+
+	if code := joinErrorCode(t, joinTestClient(t, h, "team", "a")); code != "" {
+		t.Errorf("member join rejected with %q", code)
+	}
+	if code := joinErrorCode(t, joinTestClient(t, h, "team", "b")); code != messages.JoinErrorNotAuthorized {
+		t.Errorf("non-member join code = %q, want %q", code, messages.JoinErrorNotAuthorized)
+	}
+	if got := h.Spaces["team"].UserCount(); got != 1 {
+		t.Errorf("user count = %d, want 1", got)
+	}
+
+	// The claim narrows what the store allows
+	h.Store = &memberStore{members: map[string][]string{"d": {"team", "other"}}}
+	if code := joinErrorCode(t, joinWithSpacesClaim(t, h, "other", "d", []string{"team"})); code != messages.JoinErrorNotAuthorized {
+		t.Errorf("join outside the claim code = %q, want %q", code, messages.JoinErrorNotAuthorized)
+	}
+
+	h.Store = &memberStore{err: errors.New("backend down")}
+	if code := joinErrorCode(t, joinTestClient(t, h, "team", "e")); code != messages.JoinErrorAuthUnavailable {
+		t.Errorf("join on store error code = %q, want %q", code, messages.JoinErrorAuthUnavailable)
+	}
+}
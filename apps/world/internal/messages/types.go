@@ -1,22 +1,81 @@
 package messages
 
+import "encoding/json"
+
 // Message types for WebSocket communication
 const (
 	TypeJoin             = "join"
 	TypeSpaceJoined      = "space-joined"
 	TypeJoinError        = "join-error"
+	TypeJoinTimeout      = "join-timeout"
 	TypeUserJoin         = "user-join"
 	TypeMovement         = "movement"
-	TypeTeleport         = "teleport" // For meeting navigation - bypasses step validation
+	TypeTeleport         = "teleport" // For meeting navigation and portals - bypasses step validation
 	TypeMeetingAccepted  = "meeting-accepted"
 	TypeMovementRejected = "movement-rejected"
+	TypeMovementAccepted = "movement-accepted"
 	TypeUserLeft         = "user-left"
 	TypeMeetingPrompt    = "meeting-prompt"
 	TypeMeetingStart     = "meeting-start"
 	TypeMeetingEnd       = "meeting-end"
+	TypeMeetingJoin      = "meeting-join"
+	TypeMeetingLeave     = "meeting-leave"
 	TypeProximityUpdate  = "proximity-update"
+	TypeProximityVolume  = "proximity-volume"
+	TypeProximityBatch   = "proximity-batch"
 	TypeMeetingResponse  = "meeting-response"
+	TypeMeetingInvite    = "meeting-invite"
+	TypeTyping           = "typing"
 	TypeCameraToggle     = "camera-toggle"
+	TypeHideFrom         = "hide-from"
+	TypeUnhideFrom       = "unhide-from"
+	TypeCustomBroadcast  = "custom-broadcast"
+	TypeCustomEvent      = "custom-event"
+	TypeUserCount        = "user-count"
+	TypeRenegotiate      = "renegotiate"
+	TypeSignal           = "signal"
+	TypeSignalError      = "signal-error"
+	TypeRaiseHand        = "raise-hand"
+	TypeLowerHand        = "lower-hand"
+	TypeAdvanceHand      = "advance-hand"
+	TypeClearHands       = "clear-hands"
+	TypeHandQueue        = "hand-queue"
+	TypeListSpaces       = "list-spaces"
+	TypeSpaceList        = "space-list"
+	TypeLobbyChat        = "lobby-chat"
+	TypeChat             = "chat"
+	TypeDirectMessage    = "dm"
+	TypeDMError          = "dm-error"
+	TypeEmote            = "emote"
+	TypeAnimation        = "animation"
+	TypeMoveIntent       = "move-intent"
+	TypePauseDwell       = "pause-dwell"
+	TypeLatency          = "latency"
+	TypeReport           = "report"
+	TypeReportResult     = "report-result"
+	TypeForbidden        = "forbidden"
+	TypeMessageTooLarge  = "message-too-large"
+	TypeServerShutdown   = "server-shutdown"
+	TypeSessionExpiring  = "session-expiring"
+	TypeSessionExpired   = "session-expired"
+	TypeAuthRefresh      = "auth-refresh"
+	TypeAuthRefreshed    = "auth-refreshed"
+	TypeAuthRefreshError = "auth-refresh-error"
+	TypeKickUser         = "kick"
+	TypeKicked           = "kicked"
+	TypeAnnouncement     = "announcement"
+	TypeBan              = "ban"
+	TypeUnban            = "unban"
+	TypeBanned           = "banned"
+	TypePlaceObject      = "place-object"
+	TypeRemoveObject     = "remove-object"
+	TypeObjectRejected   = "object-rejected"
+	TypeFollow           = "follow"
+	TypeUnfollow         = "unfollow"
+	TypeUserIdle         = "user-idle"
+	TypeUserActive       = "user-active"
+	TypeRequestUsers     = "get-users"
+	TypeUserList         = "user-list"
 )
 
 // BaseMessage represents the common structure for all messages
@@ -34,17 +93,29 @@ type JoinPayload struct {
 // SpaceJoinedPayload is sent to client after successful join
 type SpaceJoinedPayload struct {
 	SessionID string     `json:"sessionId"`
+	// SpaceID is the space joined, which a portal can change without a new join
+	SpaceID   string     `json:"spaceId,omitempty"`
 	Spawn     Position   `json:"spawn"`
 	Users     []UserInfo `json:"users"`
+	// SpawnFallback is set when no free spawn was found and the spawn may overlap
+	// something; clients should reposition the user
+	SpawnFallback bool `json:"spawnFallback,omitempty"`
+	// Resumed is set when a reconnect picked up the user's previous session;
+	// Spawn is then their saved position
+	Resumed bool `json:"resumed,omitempty"`
+	// Objects are those builders have placed in the space
+	Objects []ObjectPayload `json:"objects,omitempty"`
 }
 
-// UserJoinPayload is broadcast when a new user joins
+// UserJoinPayload is broadcast when a new user joins, or spawns a user coming into view.
+// Anim is the animation they're playing, so late viewers don't see them idle.
 type UserJoinPayload struct {
 	UserID     string  `json:"userId"`
 	X          float64 `json:"x"`
 	Y          float64 `json:"y"`
 	Name       string  `json:"name,omitempty"`
 	AvatarName string  `json:"avatarName,omitempty"`
+	Anim       string  `json:"anim,omitempty"`
 }
 
 // MovementPayload is used for movement requests and broadcasts
@@ -53,6 +124,9 @@ type MovementPayload struct {
 	Y      float64 `json:"y"`
 	UserID string  `json:"userId,omitempty"`
 	Anim   string  `json:"anim,omitempty"`
+	// DurationMs lets peers interpolate to (X, Y) over that long instead of snapping;
+	// 0 (teleports and other server relocations) means snap
+	DurationMs *int64 `json:"durationMs,omitempty"`
 }
 
 // MovementRejectedPayload is sent when a movement is blocked
@@ -61,49 +135,315 @@ type MovementRejectedPayload struct {
 	Y float64 `json:"y"`
 }
 
-// UserLeftPayload is broadcast when a user leaves
+// MovementAcceptedPayload confirms the committed position to clients that opted in
+type MovementAcceptedPayload struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// ProximityUpdatePayload tells a user that a peer entered or left one of their proximity radii
+type ProximityUpdatePayload struct {
+	Type   string `json:"type"` // "enter" or "leave"
+	PeerID string `json:"peerId"`
+	Media  string `json:"media,omitempty"`
+	// Volume is set for audio: 1 at distance 0 down to 0 at the radius, 0 on leave
+	Volume *float64 `json:"volume,omitempty"`
+}
+
+// ProximityBatchPayload carries every proximity update for one recipient from a single
+// round of events, in order, for clients connected with ?batchProximity=1
+type ProximityBatchPayload struct {
+	Updates []ProximityUpdatePayload `json:"updates"`
+}
+
+// ProximityVolumePayload updates the audio volume for a peer already in range
+type ProximityVolumePayload struct {
+	PeerID string  `json:"peerId"`
+	Volume float64 `json:"volume"`
+}
+
+// MeetingJoinPayload tells every participant of an active meeting, the newcomer
+// included, that UserID walked up and joined it
+type MeetingJoinPayload struct {
+	MeetingID    string   `json:"meetingId"`
+	UserID       string   `json:"userId"`
+	Participants []string `json:"participants"`
+}
+
+// UserLeftPayload is broadcast when a user leaves. Reason is set when they left by
+// disconnecting: UserLeftTimeout if the connection went quiet, UserLeftClosed otherwise.
 type UserLeftPayload struct {
 	UserID string `json:"userId"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// User left reasons
+const (
+	UserLeftTimeout = "timeout"
+	UserLeftClosed  = "closed"
+)
+
+// MessageTooLargePayload is sent before disconnecting a client whose message of Size
+// bytes was over the server's Limit
+type MessageTooLargePayload struct {
+	Size  int `json:"size"`
+	Limit int `json:"limit"`
+}
+
+// UserIdlePayload is broadcast when a user goes idle (user-idle) or comes back (user-active)
+type UserIdlePayload struct {
+	UserID string `json:"userId"`
+}
+
+// UserCountPayload carries the number of users currently in a space
+type UserCountPayload struct {
+	Count int `json:"count"`
 }
 
 // JoinErrorPayload is sent when a join request fails
 type JoinErrorPayload struct {
 	Error string `json:"error"`
+	Code  string `json:"code,omitempty"`
+}
+
+// Join error codes
+const (
+	JoinErrorServerMisconfigured = "server_misconfigured"
+	JoinErrorTooFrequent         = "join_too_frequent"
+	JoinErrorNotAuthorized       = "not_authorized"
+	JoinErrorAuthUnavailable     = "auth_unavailable"
+	JoinErrorSpaceFull           = "space_full"
+	JoinErrorBanned              = "banned"
+)
+
+// CustomEventPayload relays an admin-defined event to clients in a space
+type CustomEventPayload struct {
+	Subtype string          `json:"subtype"`
+	From    string          `json:"from"`
+	Data    json.RawMessage `json:"data,omitempty"`
 }
 
+// RenegotiatePayload relays an opaque WebRTC renegotiation blob to a meeting peer
+type RenegotiatePayload struct {
+	PeerID string          `json:"peerId"`
+	Data   json.RawMessage `json:"data,omitempty"`
+}
+
+// SignalPayload relays a WebRTC signaling message (offer/answer/ICE) to a meeting peer
+type SignalPayload struct {
+	PeerID     string          `json:"peerId"`
+	SignalType string          `json:"signalType"`
+	Data       json.RawMessage `json:"data,omitempty"`
+}
+
+// SignalErrorPayload is sent when a signal cannot be relayed
+type SignalErrorPayload struct {
+	TargetUserID string `json:"targetUserId"`
+	Error        string `json:"error"`
+}
+
+// HandQueuePayload carries the ordered raise-hand queue for a space
+type HandQueuePayload struct {
+	Queue []string `json:"queue"`
+}
+
+// SpaceSummary describes an active space for the lobby
+type SpaceSummary struct {
+	SpaceID   string `json:"spaceId"`
+	UserCount int    `json:"userCount"`
+}
+
+// SpaceListPayload lists active spaces and their occupancy
+type SpaceListPayload struct {
+	Spaces []SpaceSummary `json:"spaces"`
+}
+
+// SpaceStats describes an active space for operators
+type SpaceStats struct {
+	SpaceID        string   `json:"spaceId"`
+	UserCount      int      `json:"userCount"`
+	ActiveMeetings int      `json:"activeMeetings"`
+	UserIDs        []string `json:"userIds,omitempty"`
+}
+
+// AllowedSpacesPayload lists the space IDs a user is authorized to join
+type AllowedSpacesPayload struct {
+	Spaces []string `json:"spaces"`
+}
+
+// ForbiddenPayload tells a client its role may not send a message type
+type ForbiddenPayload struct {
+	Type string `json:"type"`
+}
+
+// KickedPayload tells a user an admin removed them from the space
+type KickedPayload struct {
+	By     string `json:"by"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// FollowPayload confirms a follow, or says why one ended with Reason (for unfollow)
+type FollowPayload struct {
+	TargetUserID string `json:"targetUserId"`
+	Reason       string `json:"reason,omitempty"`
+}
+
+// Reasons a follow ends
+const (
+	FollowEndRequested   = "requested"
+	FollowEndMoved       = "moved"
+	FollowEndTargetLeft  = "target_left"
+	FollowEndUnavailable = "unavailable"
+)
+
+// BannedPayload tells a user an admin banned them from the space. ExpiresAt (Unix
+// ms) is unset for a permanent ban.
+type BannedPayload struct {
+	By        string `json:"by"`
+	Reason    string `json:"reason,omitempty"`
+	ExpiresAt int64  `json:"expiresAt,omitempty"`
+}
+
+// ObjectPayload is an object a builder placed in the space (or removed, for
+// remove-object). UserID is set by the server to whoever made the change.
+type ObjectPayload struct {
+	UserID     string  `json:"userId"`
+	ObjectType string  `json:"objectType"`
+	X          float64 `json:"x"`
+	Y          float64 `json:"y"`
+}
+
+// Object rejection codes
+const (
+	ObjectErrorOutOfBounds = "out_of_bounds"
+	ObjectErrorOccupied    = "occupied"
+	ObjectErrorNoObject    = "no_object"
+	ObjectErrorInvalidType = "invalid_type"
+)
+
+// ObjectRejectedPayload tells a builder why their place-object or remove-object at
+// (X, Y) did nothing
+type ObjectRejectedPayload struct {
+	X    float64 `json:"x"`
+	Y    float64 `json:"y"`
+	Code string  `json:"code"`
+}
+
+// ReportResultPayload tells a reporter whether their report was accepted
+type ReportResultPayload struct {
+	Accepted bool   `json:"accepted"`
+	Error    string `json:"error,omitempty"`
+}
+
+// LobbyChatPayload is a chat message between clients that haven't joined a space
+type LobbyChatPayload struct {
+	Name      string `json:"name,omitempty"`
+	Text      string `json:"text"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// ChatPayload is a text message broadcast to a space. UserID and Timestamp are set by the server.
+type ChatPayload struct {
+	UserID    string `json:"userId"`
+	Text      string `json:"text"`
+	Timestamp int64  `json:"timestamp"`
+	Scope     string `json:"scope"`
+}
+
+// TypingPayload tells users in the chat scope whether UserID is typing. UserID is set by the server.
+type TypingPayload struct {
+	UserID   string `json:"userId"`
+	IsTyping bool   `json:"isTyping"`
+	Scope    string `json:"scope"`
+}
+
+// DirectMessagePayload is a private message between two users in a space. UserID
+// (the sender) and Timestamp are set by the server.
+type DirectMessagePayload struct {
+	UserID       string `json:"userId"`
+	TargetUserID string `json:"targetUserId"`
+	Text         string `json:"text"`
+	Timestamp    int64  `json:"timestamp"`
+}
+
+// DMErrorPayload is sent when a direct message cannot be delivered
+type DMErrorPayload struct {
+	TargetUserID string `json:"targetUserId"`
+	Error        string `json:"error"`
+}
+
+// EmotePayload is a reaction shown above a user's avatar for DurationMs. UserID is
+// set and DurationMs clamped by the server.
+type EmotePayload struct {
+	UserID     string `json:"userId"`
+	Emote      string `json:"emote"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// AnimationPayload tells the space that UserID's avatar now plays Anim where it stands.
+// UserID is set by the server.
+type AnimationPayload struct {
+	UserID string `json:"userId"`
+	Anim   string `json:"anim"`
+}
+
+// SessionExpiringPayload warns that the client's token expires at ExpiresAt (Unix ms)
+type SessionExpiringPayload struct {
+	ExpiresAt int64 `json:"expiresAt"`
+}
+
+// AuthRefreshedPayload confirms an auth-refresh; the session now expires at ExpiresAt
+// (Unix ms, 0 if the new token doesn't expire)
+type AuthRefreshedPayload struct {
+	ExpiresAt int64 `json:"expiresAt"`
+}
+
+// AuthRefreshErrorPayload is sent when an auth-refresh is rejected; the old token stays in effect
+type AuthRefreshErrorPayload struct {
+	Error string `json:"error"`
+}
+
+// Chat scopes
+const (
+	ChatScopeSpace = "space" // everyone in the space (default)
+	ChatScopeLocal = "local" // only users within audio range
+)
+
+// AnnouncementPayload is a banner an admin shows to everyone in a space
+type AnnouncementPayload struct {
+	From      string `json:"from"`
+	Text      string `json:"text"`
+	Level     string `json:"level"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// Announcement levels
+const (
+	AnnouncementLevelInfo    = "info" // default
+	AnnouncementLevelWarning = "warning"
+)
+
 // Position represents x,y coordinates
 type Position struct {
 	X float64 `json:"x"`
 	Y float64 `json:"y"`
 }
 
-// UserInfo represents user data for space listing
-// Note: Spec says "id" for user list, but "userId" for other events. 
-// However, looking at "users": [{ "id": 1 }] in the prompt, let's stick to UserID string 
-// but map JSON field "id" for this specific struct if strictly needed, 
-// BUT other events use "userId". 
-// The prompt example for "Space joined" shows: "users": [{ "id": 1 }]
-// The prompt example for "Join event" shows: "userId": 1
-// I will support "id" in UserInfo to match the "Space joined" example.
+// UserInfo describes a user already in the space, as listed in space-joined. It has the
+// fields of UserJoinPayload plus Idle; x and y are always sent since (0, 0) is a valid spawn.
 type UserInfo struct {
-	UserID string `json:"id"` 
-	// The prompt example for "Space joined" doesn't explicitly show x/y in the user list, 
-	// but usually you need to know where they are. 
-	// Wait, the prompt example is: "users": [{ "id": 1 }] 
-	// It doesn't show x/y? That would be strange for a "spawn" event?
-	// Actually, if it's just ID, how do I render them?
-	// I'll assume x/y is needed, or maybe it's just the ID?
-	// Let's keep X/Y for now as it's critical, but I'll use "id" for the json tag.
-	// Actually, let's re-read carefully.
-	// "Space joined" payload: "users": [{ "id": 1 }]
-	// It misses X/Y. That might be an oversight in the prompt or imply they are invisible/at 0,0?
-	// But later "Join event" has x,y.
-	// I will keep X,Y in UserInfo because it logicially makes sense, 
-	// and if the frontend ignores it, it's fine.
-	X          float64 `json:"x,omitempty"`
-	Y          float64 `json:"y,omitempty"`
+	UserID     string  `json:"userId"`
+	X          float64 `json:"x"`
+	Y          float64 `json:"y"`
 	Name       string  `json:"name,omitempty"`
 	AvatarName string  `json:"avatarName,omitempty"`
+	Anim       string  `json:"anim,omitempty"`
+	Idle       bool    `json:"idle,omitempty"`
+}
+
+// UserListPayload answers get-users with the space's roster, the requester included
+type UserListPayload struct {
+	Users []UserInfo `json:"users"`
 }
 
 // IncomingMessage for parsing client messages
@@ -117,6 +457,8 @@ type IncomingPayload struct {
 	// For join
 	SpaceID string `json:"spaceId,omitempty"`
 	Token   string `json:"token,omitempty"`
+	// JoinNearUserID asks to spawn next to a friend already in the space
+	JoinNearUserID string `json:"joinNearUserId,omitempty"`
 	// For movement
 	X          float64 `json:"x,omitempty"`
 	Y          float64 `json:"y,omitempty"`
@@ -134,4 +476,34 @@ type IncomingPayload struct {
 	RequestID string `json:"requestId,omitempty"`
 	Accept    bool   `json:"accept,omitempty"`
 	Enabled   bool   `json:"enabled,omitempty"`
+
+	// Custom broadcast fields
+	Subtype string          `json:"subtype,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Radius  float64         `json:"radius,omitempty"`
+
+	// Signaling fields
+	SignalType string `json:"signalType,omitempty"`
+
+	// Chat fields
+	Text  string `json:"text,omitempty"`
+	Scope string `json:"scope,omitempty"`
+	// Typing indicator state
+	IsTyping bool `json:"isTyping,omitempty"`
+	// Announcement level
+	Level string `json:"level,omitempty"`
+
+	// Emote fields; DurationMs also sets a temporary ban
+	Emote      string `json:"emote,omitempty"`
+	DurationMs int64  `json:"durationMs,omitempty"`
+
+	// Client-measured round trip time, for latency reports
+	RTTMs int64 `json:"rttMs,omitempty"`
+
+	// Report fields
+	Reason  string `json:"reason,omitempty"`
+	Details string `json:"details,omitempty"`
+
+	// Kind of object for place-object
+	ObjectType string `json:"objectType,omitempty"`
 }
@@ -0,0 +1,38 @@
+package messages
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSpaceJoinedKeepsUserAtOrigin(t *testing.T) {
+	data, err := json.Marshal(SpaceJoinedPayload{
+		SessionID: "me",
+		Users:     []UserInfo{{UserID: "origin", X: 0, Y: 0}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded struct {
+		Users []map[string]interface{} `json:"users"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Users) != 1 {
+		t.Fatalf("got %d users in %s, want 1", len(decoded.Users), data)
+	}
+	user := decoded.Users[0]
+	if user["userId"] != "origin" {
+		t.Errorf("userId = %v in %s", user["userId"], data)
+	}
+	for _, key := range []string{"x", "y"} {
+		if v, ok := user[key]; !ok || v != 0.0 {
+			t.Errorf("%s = %v (present %v) in %s, want 0", key, v, ok, data)
+		}
+	}
+	if _, ok := user["id"]; ok {
+		t.Errorf("users should no longer carry id: %s", data)
+	}
+}
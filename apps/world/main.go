@@ -1,8 +1,13 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"world/internal/config"
 	"world/internal/hub"
@@ -11,25 +16,18 @@ import (
 	"github.com/gorilla/websocket"
 )
 
-var allowedOrigins = map[string]bool{
-	"http://localhost:3001":           true,
-	"https://raashed.cloud":             true,
-	"https://game.raashed.cloud":        true,
-	"https://k8s-game.raashed.cloud": true,
-	"https://metaverse.raashed.cloud":   	true,
-	"https://k8s-metaverse.raashed.cloud":  true,
-}
+// shutdownTimeout bounds how long shutdown waits for connections to close
+const shutdownTimeout = 10 * time.Second
 
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
+	// Offer permessage-deflate (unless WS_COMPRESSION is off, see main); each
+	// connection decides whether to use it for writes
+	EnableCompression: true,
 	CheckOrigin: func(r *http.Request) bool {
-		origin := r.Header.Get("Origin")
-		// Allow if origin is in whitelist or empty (same-origin)
-		if origin == "" {
-			return true
-		}
-		return allowedOrigins[origin]
+		// Allow if origin is in ALLOWED_ORIGINS or empty (same-origin)
+		return config.Current().OriginAllowed(r.Header.Get("Origin"))
 	},
 }
 
@@ -38,11 +36,42 @@ func main() {
 	if err := config.Load(); err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	upgrader.EnableCompression = config.Current().WSCompression
 
 	// Create and start the hub
 	h := hub.NewHub()
 	go h.Run()
 
+	// Bring back the space layouts saved before the last shutdown
+	if dir := config.Current().SnapshotDir; dir != "" {
+		n, err := h.RestoreSpaces(dir)
+		if err != nil {
+			log.Fatalf("Failed to restore space snapshots: %v", err)
+		}
+		log.Printf("Restored %d spaces from %s", n, dir)
+		if config.Current().SnapshotInterval > 0 {
+			go h.RunSnapshots(dir, config.Current().SnapshotInterval)
+		}
+	}
+
+	// Forward proximity changes to the media backend
+	reporter, err := hub.NewProximityReporter(config.Current())
+	if err != nil {
+		log.Fatalf("Failed to configure proximity bridge: %v", err)
+	}
+	if reporter != nil {
+		h.StartProximityBridge(reporter)
+	}
+
+	// Reload proximity radii on SIGHUP without dropping connections
+	go func() {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		for range hup {
+			h.ReloadProximityRadii()
+		}
+	}()
+
 	// Set up router
 	r := mux.NewRouter()
 
@@ -51,6 +80,21 @@ func main() {
 		serveWs(h, w, r)
 	})
 
+	// Live spaces for operators (requires the world server secret)
+	r.HandleFunc("/spaces", h.ServeSpaces).Methods(http.MethodGet)
+
+	// Spaces the token's user may join, for filtering the lobby room list
+	r.HandleFunc("/spaces/allowed", h.ServeAllowedSpaces).Methods(http.MethodGet)
+
+	// Admin API to read or teleport a user's position
+	r.HandleFunc("/admin/spaces/{spaceId}/users/{userId}/position", h.ServeUserPosition).Methods(http.MethodGet, http.MethodPut)
+
+	// Meeting funnel counters for product analytics (admin token required)
+	r.HandleFunc("/metrics/meetings", hub.ServeMeetingMetrics).Methods(http.MethodGet)
+
+	// Prometheus metrics: connections, spaces, meetings, joins/leaves and rejected moves
+	r.Handle("/metrics", h.MetricsHandler()).Methods(http.MethodGet)
+
 	// Health check endpoint
 	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -58,13 +102,37 @@ func main() {
 		w.Write([]byte(`{"status":"ok"}`))
 	})
 
-	addr := ":" + config.AppConfig.Port
+	addr := ":" + config.Current().Port
+	srv := &http.Server{Addr: addr, Handler: r}
 	log.Printf("world ws-server starting on %s", addr)
 	log.Printf("ws endpoint: ws://localhost%s/ws", addr)
 
-	if err := http.ListenAndServe(addr, r); err != nil {
-		log.Fatalf("Server failed: %v", err)
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Server failed: %v", err)
+		}
+	}()
+
+	// On SIGINT/SIGTERM stop accepting connections, then close the open ones cleanly
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	<-stop
+	log.Printf("Shutdown signal received")
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("HTTP shutdown: %v", err)
+	}
+	if err := h.Shutdown(ctx); err != nil {
+		log.Printf("Hub shutdown: %v", err)
 	}
+	if dir := config.Current().SnapshotDir; dir != "" {
+		if err := h.SaveSnapshots(dir); err != nil {
+			log.Printf("Saving space snapshots: %v", err)
+		}
+	}
+	log.Printf("world ws-server stopped")
 }
 
 // serveWs handles websocket requests from clients
@@ -75,7 +143,14 @@ func serveWs(h *hub.Hub, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	client := hub.NewClient(h, conn)
+	// Low-power clients can opt out of write compression with ?compress=0
+	compress := config.Current().WSCompression && r.URL.Query().Get("compress") != "0"
+
+	client := hub.NewClient(h, conn, compress)
+	// Server-authoritative clients ask for every accepted move to be confirmed
+	client.ConfirmMoves = r.URL.Query().Get("confirmMoves") == "1"
+	// Clients that can apply proximity changes together ask for them in one message
+	client.BatchProximity = r.URL.Query().Get("batchProximity") == "1"
 	h.Register <- client
 
 	// Start read and write pumps in separate goroutines